@@ -0,0 +1,193 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+// These tests keep the constants of this package in sync with the generated
+// CRDs, so that a change to a kubebuilder marker without a matching change
+// here (or vice versa) fails.
+
+const crdDir = "../../../config/crd"
+
+type schemaCheck struct {
+	path     string
+	key      string
+	expected interface{}
+}
+
+// loadSchema returns the openAPIV3Schema of the single version of a CRD.
+func loadSchema(t *testing.T, channel, file string) map[string]interface{} {
+	data, err := os.ReadFile(filepath.Join(crdDir, channel, file))
+	require.NoError(t, err)
+	crd := map[string]interface{}{}
+	require.NoError(t, yaml.Unmarshal(data, &crd))
+	versions := crd["spec"].(map[string]interface{})["versions"].([]interface{})
+	require.Len(t, versions, 1)
+	return versions[0].(map[string]interface{})["schema"].(map[string]interface{})["openAPIV3Schema"].(map[string]interface{})
+}
+
+// schemaAt walks a dotted path such as "spec.egress[].to[]", where [] steps into the items of an array.
+// It returns nil if the path doesn't exist, which happens for experimental fields in the standard channel.
+func schemaAt(schema map[string]interface{}, path string) map[string]interface{} {
+	if path == "" {
+		return schema
+	}
+	for _, part := range strings.Split(path, ".") {
+		name := strings.TrimSuffix(part, "[]")
+		properties, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if schema, ok = properties[name].(map[string]interface{}); !ok {
+			return nil
+		}
+		if strings.HasSuffix(part, "[]") {
+			schema = schema["items"].(map[string]interface{})
+		}
+	}
+	return schema
+}
+
+func propertyNames(schema map[string]interface{}) []string {
+	var names []string
+	for name := range schema["properties"].(map[string]interface{}) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func celMessages(schema map[string]interface{}) []interface{} {
+	var messages []interface{}
+	rules, _ := schema["x-kubernetes-validations"].([]interface{})
+	for _, rule := range rules {
+		messages = append(messages, rule.(map[string]interface{})["message"])
+	}
+	return messages
+}
+
+func runSchemaChecks(t *testing.T, schema map[string]interface{}, checks []schemaCheck) {
+	for _, check := range checks {
+		s := schemaAt(schema, check.path)
+		if s == nil {
+			continue
+		}
+		var actual interface{}
+		switch check.key {
+		case "properties":
+			actual = propertyNames(s)
+		case "x-kubernetes-validations":
+			actual = celMessages(s)
+		default:
+			actual = s[check.key]
+			// numbers are decoded as float64
+			if f, ok := actual.(float64); ok {
+				actual = int(f)
+			}
+		}
+		require.Equal(t, check.expected, actual, "%s: %s", check.path, check.key)
+	}
+}
+
+// sharedChecks apply to the subject, ingress rules and ports of both kinds of policy.
+var sharedChecks = []schemaCheck{
+	{"spec.subject", "minProperties", 1},
+	{"spec.subject", "maxProperties", 1},
+	{"spec.subject", "properties", []string{"namespaces", "pods"}},
+	{"spec.ingress", "maxItems", MaxRules},
+	{"spec.egress", "maxItems", MaxRules},
+	{"spec.ingress[].name", "maxLength", MaxRuleNameLength},
+	{"spec.egress[].name", "maxLength", MaxRuleNameLength},
+	{"spec.ingress[].from", "minItems", 1},
+	{"spec.ingress[].from", "maxItems", MaxPeers},
+	{"spec.ingress[].from[]", "minProperties", 1},
+	{"spec.ingress[].from[]", "maxProperties", 1},
+	{"spec.ingress[].from[]", "properties", []string{"namespaces", "pods"}},
+	{"spec.egress[].to", "minItems", 1},
+	{"spec.egress[].to", "maxItems", MaxPeers},
+	{"spec.egress[].to[]", "minProperties", 1},
+	{"spec.egress[].to[]", "maxProperties", 1},
+	{"spec.egress[].to[].networks", "minItems", 1},
+	{"spec.egress[].to[].networks", "maxItems", MaxNetworks},
+	{"spec.egress[].to[].networks[]", "maxLength", MaxCIDRLength},
+	{"spec.egress[].to[].networks[]", "x-kubernetes-validations", []interface{}{cidrFamilyMsg}},
+	{"spec.ingress[].ports", "minItems", 1},
+	{"spec.ingress[].ports", "maxItems", MaxPorts},
+	{"spec.egress[].ports", "minItems", 1},
+	{"spec.egress[].ports", "maxItems", MaxPorts},
+	{"spec.egress[].ports[]", "minProperties", 1},
+	{"spec.egress[].ports[]", "maxProperties", 1},
+	{"spec.egress[].ports[].portNumber.port", "minimum", MinPort},
+	{"spec.egress[].ports[].portNumber.port", "maximum", MaxPort},
+	{"spec.egress[].ports[].portRange.start", "minimum", MinPort},
+	{"spec.egress[].ports[].portRange.start", "maximum", MaxPort},
+	{"spec.egress[].ports[].portRange.end", "minimum", MinPort},
+	{"spec.egress[].ports[].portRange.end", "maximum", MaxPort},
+}
+
+func TestAdminNetworkPolicyCRD(t *testing.T) {
+	checks := append([]schemaCheck{
+		{"spec.priority", "minimum", MinPriority},
+		{"spec.priority", "maximum", MaxPriority},
+		{"spec.ingress[].action", "enum", []interface{}{"Allow", "Deny", "Pass"}},
+		{"spec.egress[].action", "enum", []interface{}{"Allow", "Deny", "Pass"}},
+		{"spec.egress[].to[].domainNames", "minItems", 1},
+		{"spec.egress[].to[].domainNames", "maxItems", MaxDomainNames},
+		{"spec.egress[].to[].domainNames[]", "pattern", DomainNameFmt},
+	}, sharedChecks...)
+
+	for _, channel := range []string{"standard", "experimental"} {
+		t.Run(channel, func(t *testing.T) {
+			schema := loadSchema(t, channel, "policy.networking.k8s.io_adminnetworkpolicies.yaml")
+			runSchemaChecks(t, schema, checks)
+		})
+	}
+
+	schema := loadSchema(t, "experimental", "policy.networking.k8s.io_adminnetworkpolicies.yaml")
+	require.Equal(t, []string{"domainNames", "namespaces", "networks", "nodes", "pods"}, propertyNames(schemaAt(schema, "spec.egress[].to[]")))
+	require.Equal(t, []interface{}{namedPortWithIPPeerMsg}, celMessages(schemaAt(schema, "spec.egress[]")))
+	require.Equal(t, []string{"namedPort", "portNumber", "portRange"}, propertyNames(schemaAt(schema, "spec.egress[].ports[]")))
+}
+
+func TestBaselineAdminNetworkPolicyCRD(t *testing.T) {
+	checks := append([]schemaCheck{
+		{"", "x-kubernetes-validations", []interface{}{banpNameMsg}},
+		{"spec.ingress[].action", "enum", []interface{}{"Allow", "Deny"}},
+		{"spec.egress[].action", "enum", []interface{}{"Allow", "Deny"}},
+	}, sharedChecks...)
+
+	for _, channel := range []string{"standard", "experimental"} {
+		t.Run(channel, func(t *testing.T) {
+			schema := loadSchema(t, channel, "policy.networking.k8s.io_baselineadminnetworkpolicies.yaml")
+			runSchemaChecks(t, schema, checks)
+		})
+	}
+
+	schema := loadSchema(t, "experimental", "policy.networking.k8s.io_baselineadminnetworkpolicies.yaml")
+	require.Equal(t, []string{"namespaces", "networks", "nodes", "pods"}, propertyNames(schemaAt(schema, "spec.egress[].to[]")))
+	require.Equal(t, []interface{}{namedPortWithIPPeerMsg}, celMessages(schemaAt(schema, "spec.egress[]")))
+}
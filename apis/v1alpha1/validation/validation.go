@@ -0,0 +1,396 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package validation checks AdminNetworkPolicies and
+// BaselineAdminNetworkPolicies against the constraints that the CRDs enforce
+// through OpenAPI schema and CEL rules, so that they can be validated without
+// an API server (for example in CI linters, admission webhooks and offline
+// analysis tools). It covers the experimental channel, which is a superset of
+// the standard channel.
+package validation
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+const (
+	// MinPriority and MaxPriority bound AdminNetworkPolicySpec.Priority.
+	MinPriority = 0
+	MaxPriority = 1000
+
+	// MaxRules is the maximum number of ingress or egress rules in a policy.
+	MaxRules = 100
+	// MaxRuleNameLength is the maximum length of a rule name.
+	MaxRuleNameLength = 100
+	// MaxPeers is the maximum number of peers in a rule's from or to list.
+	MaxPeers = 100
+	// MaxPorts is the maximum number of ports in a rule.
+	MaxPorts = 100
+	// MaxNetworks is the maximum number of CIDRs in a networks peer.
+	MaxNetworks = 25
+	// MaxDomainNames is the maximum number of domain names in a domainNames peer.
+	MaxDomainNames = 25
+	// MaxCIDRLength is the maximum length of a CIDR string.
+	MaxCIDRLength = 43
+
+	// MinPort and MaxPort bound port numbers and port range endpoints.
+	MinPort = 1
+	MaxPort = 65535
+
+	// BaselineAdminNetworkPolicyName is the only name a BaselineAdminNetworkPolicy may have.
+	BaselineAdminNetworkPolicyName = "default"
+
+	// DomainNameFmt is the pattern that a DomainName must match.
+	DomainNameFmt = `^(\*\.)?([a-zA-z0-9]([-a-zA-Z0-9_]*[a-zA-Z0-9])?\.)+[a-zA-z0-9]([-a-zA-Z0-9_]*[a-zA-Z0-9])?\.?$`
+
+	// Messages of the CEL rules in the CRDs.
+	cidrFamilyMsg          = "CIDR must be either an IPv4 or IPv6 address. IPv4 address embedded in IPv6 addresses are not supported"
+	namedPortWithIPPeerMsg = "networks/nodes peer cannot be set with namedPorts since there are no namedPorts for networks/nodes"
+	banpNameMsg            = `Only one baseline admin network policy with metadata.name="default" can be created in the cluster`
+)
+
+var domainNameRegexp = regexp.MustCompile(DomainNameFmt)
+
+var (
+	anpActions = []v1alpha1.AdminNetworkPolicyRuleAction{
+		v1alpha1.AdminNetworkPolicyRuleActionAllow,
+		v1alpha1.AdminNetworkPolicyRuleActionDeny,
+		v1alpha1.AdminNetworkPolicyRuleActionPass,
+	}
+	banpActions = []v1alpha1.BaselineAdminNetworkPolicyRuleAction{
+		v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow,
+		v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+	}
+)
+
+// ValidateAdminNetworkPolicy validates an AdminNetworkPolicy.
+func ValidateAdminNetworkPolicy(anp *v1alpha1.AdminNetworkPolicy) field.ErrorList {
+	return ValidateAdminNetworkPolicySpec(&anp.Spec, field.NewPath("spec"))
+}
+
+// ValidateAdminNetworkPolicySpec validates the spec of an AdminNetworkPolicy.
+func ValidateAdminNetworkPolicySpec(spec *v1alpha1.AdminNetworkPolicySpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.Priority < MinPriority || spec.Priority > MaxPriority {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("priority"), spec.Priority, validation.InclusiveRangeError(MinPriority, MaxPriority)))
+	}
+	allErrs = append(allErrs, validateSubject(&spec.Subject, fldPath.Child("subject"))...)
+
+	ingressPath := fldPath.Child("ingress")
+	if len(spec.Ingress) > MaxRules {
+		allErrs = append(allErrs, field.TooMany(ingressPath, len(spec.Ingress), MaxRules))
+	}
+	for i, rule := range spec.Ingress {
+		rulePath := ingressPath.Index(i)
+		allErrs = append(allErrs, validateRuleName(rule.Name, rulePath.Child("name"))...)
+		allErrs = append(allErrs, validateANPAction(rule.Action, rulePath.Child("action"))...)
+		allErrs = append(allErrs, validateIngressPeers(rule.From, rulePath.Child("from"))...)
+		allErrs = append(allErrs, validatePorts(rule.Ports, rulePath.Child("ports"))...)
+	}
+
+	egressPath := fldPath.Child("egress")
+	if len(spec.Egress) > MaxRules {
+		allErrs = append(allErrs, field.TooMany(egressPath, len(spec.Egress), MaxRules))
+	}
+	for i, rule := range spec.Egress {
+		rulePath := egressPath.Index(i)
+		allErrs = append(allErrs, validateRuleName(rule.Name, rulePath.Child("name"))...)
+		allErrs = append(allErrs, validateANPAction(rule.Action, rulePath.Child("action"))...)
+
+		toPath := rulePath.Child("to")
+		allErrs = append(allErrs, validateListSize(len(rule.To), 1, MaxPeers, toPath)...)
+		hasIPPeer := false
+		for j := range rule.To {
+			peer := &rule.To[j]
+			hasIPPeer = hasIPPeer || peer.Networks != nil || peer.Nodes != nil
+			allErrs = append(allErrs, validateANPEgressPeer(peer, toPath.Index(j))...)
+		}
+
+		allErrs = append(allErrs, validatePorts(rule.Ports, rulePath.Child("ports"))...)
+		if hasIPPeer {
+			allErrs = append(allErrs, validateNoNamedPorts(rule.Ports, rulePath.Child("ports"))...)
+		}
+	}
+	return allErrs
+}
+
+// ValidateBaselineAdminNetworkPolicy validates a BaselineAdminNetworkPolicy.
+func ValidateBaselineAdminNetworkPolicy(banp *v1alpha1.BaselineAdminNetworkPolicy) field.ErrorList {
+	var allErrs field.ErrorList
+	if banp.Name != BaselineAdminNetworkPolicyName {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "name"), banp.Name, banpNameMsg))
+	}
+	return append(allErrs, ValidateBaselineAdminNetworkPolicySpec(&banp.Spec, field.NewPath("spec"))...)
+}
+
+// ValidateBaselineAdminNetworkPolicySpec validates the spec of a BaselineAdminNetworkPolicy.
+func ValidateBaselineAdminNetworkPolicySpec(spec *v1alpha1.BaselineAdminNetworkPolicySpec, fldPath *field.Path) field.ErrorList {
+	allErrs := validateSubject(&spec.Subject, fldPath.Child("subject"))
+
+	ingressPath := fldPath.Child("ingress")
+	if len(spec.Ingress) > MaxRules {
+		allErrs = append(allErrs, field.TooMany(ingressPath, len(spec.Ingress), MaxRules))
+	}
+	for i, rule := range spec.Ingress {
+		rulePath := ingressPath.Index(i)
+		allErrs = append(allErrs, validateRuleName(rule.Name, rulePath.Child("name"))...)
+		allErrs = append(allErrs, validateBANPAction(rule.Action, rulePath.Child("action"))...)
+		allErrs = append(allErrs, validateIngressPeers(rule.From, rulePath.Child("from"))...)
+		allErrs = append(allErrs, validatePorts(rule.Ports, rulePath.Child("ports"))...)
+	}
+
+	egressPath := fldPath.Child("egress")
+	if len(spec.Egress) > MaxRules {
+		allErrs = append(allErrs, field.TooMany(egressPath, len(spec.Egress), MaxRules))
+	}
+	for i, rule := range spec.Egress {
+		rulePath := egressPath.Index(i)
+		allErrs = append(allErrs, validateRuleName(rule.Name, rulePath.Child("name"))...)
+		allErrs = append(allErrs, validateBANPAction(rule.Action, rulePath.Child("action"))...)
+
+		toPath := rulePath.Child("to")
+		allErrs = append(allErrs, validateListSize(len(rule.To), 1, MaxPeers, toPath)...)
+		hasIPPeer := false
+		for j := range rule.To {
+			peer := &rule.To[j]
+			hasIPPeer = hasIPPeer || peer.Networks != nil || peer.Nodes != nil
+			allErrs = append(allErrs, validateBANPEgressPeer(peer, toPath.Index(j))...)
+		}
+
+		allErrs = append(allErrs, validatePorts(rule.Ports, rulePath.Child("ports"))...)
+		if hasIPPeer {
+			allErrs = append(allErrs, validateNoNamedPorts(rule.Ports, rulePath.Child("ports"))...)
+		}
+	}
+	return allErrs
+}
+
+// ValidateCIDR validates a CIDR from a networks peer.
+func ValidateCIDR(cidr v1alpha1.CIDR, fldPath *field.Path) field.ErrorList {
+	s := string(cidr)
+	if len(s) > MaxCIDRLength {
+		return field.ErrorList{field.TooLong(fldPath, s, MaxCIDRLength)}
+	}
+	if strings.Contains(s, ":") == strings.Contains(s, ".") {
+		return field.ErrorList{field.Invalid(fldPath, s, cidrFamilyMsg)}
+	}
+	if _, _, err := net.ParseCIDR(s); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, s, err.Error())}
+	}
+	return nil
+}
+
+// ValidateDomainName validates a DomainName from a domainNames peer.
+func ValidateDomainName(domainName v1alpha1.DomainName, fldPath *field.Path) field.ErrorList {
+	if !domainNameRegexp.MatchString(string(domainName)) {
+		return field.ErrorList{field.Invalid(fldPath, string(domainName), validation.RegexError("must be a domain name, optionally prefixed with '*.'", DomainNameFmt, "kubernetes.io", "*.kubernetes.io"))}
+	}
+	return nil
+}
+
+func validateSubject(subject *v1alpha1.AdminNetworkPolicySubject, fldPath *field.Path) field.ErrorList {
+	return validateOneOf(fldPath,
+		oneOfField{"namespaces", subject.Namespaces != nil},
+		oneOfField{"pods", subject.Pods != nil})
+}
+
+func validateIngressPeers(peers []v1alpha1.AdminNetworkPolicyIngressPeer, fldPath *field.Path) field.ErrorList {
+	allErrs := validateListSize(len(peers), 1, MaxPeers, fldPath)
+	for i, peer := range peers {
+		allErrs = append(allErrs, validateOneOf(fldPath.Index(i),
+			oneOfField{"namespaces", peer.Namespaces != nil},
+			oneOfField{"pods", peer.Pods != nil})...)
+	}
+	return allErrs
+}
+
+func validateANPEgressPeer(peer *v1alpha1.AdminNetworkPolicyEgressPeer, fldPath *field.Path) field.ErrorList {
+	allErrs := validateOneOf(fldPath,
+		oneOfField{"namespaces", peer.Namespaces != nil},
+		oneOfField{"pods", peer.Pods != nil},
+		oneOfField{"nodes", peer.Nodes != nil},
+		oneOfField{"networks", peer.Networks != nil},
+		oneOfField{"domainNames", peer.DomainNames != nil})
+	if peer.Networks != nil {
+		allErrs = append(allErrs, validateNetworks(peer.Networks, fldPath.Child("networks"))...)
+	}
+	if peer.DomainNames != nil {
+		allErrs = append(allErrs, validateDomainNames(peer.DomainNames, fldPath.Child("domainNames"))...)
+	}
+	return allErrs
+}
+
+func validateBANPEgressPeer(peer *v1alpha1.BaselineAdminNetworkPolicyEgressPeer, fldPath *field.Path) field.ErrorList {
+	allErrs := validateOneOf(fldPath,
+		oneOfField{"namespaces", peer.Namespaces != nil},
+		oneOfField{"pods", peer.Pods != nil},
+		oneOfField{"nodes", peer.Nodes != nil},
+		oneOfField{"networks", peer.Networks != nil})
+	if peer.Networks != nil {
+		allErrs = append(allErrs, validateNetworks(peer.Networks, fldPath.Child("networks"))...)
+	}
+	return allErrs
+}
+
+func validateNetworks(networks []v1alpha1.CIDR, fldPath *field.Path) field.ErrorList {
+	allErrs := validateListSize(len(networks), 1, MaxNetworks, fldPath)
+	seen := sets.New[v1alpha1.CIDR]()
+	for i, cidr := range networks {
+		if seen.Has(cidr) {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), string(cidr)))
+			continue
+		}
+		seen.Insert(cidr)
+		allErrs = append(allErrs, ValidateCIDR(cidr, fldPath.Index(i))...)
+	}
+	return allErrs
+}
+
+func validateDomainNames(domainNames []v1alpha1.DomainName, fldPath *field.Path) field.ErrorList {
+	allErrs := validateListSize(len(domainNames), 1, MaxDomainNames, fldPath)
+	seen := sets.New[v1alpha1.DomainName]()
+	for i, domainName := range domainNames {
+		if seen.Has(domainName) {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), string(domainName)))
+			continue
+		}
+		seen.Insert(domainName)
+		allErrs = append(allErrs, ValidateDomainName(domainName, fldPath.Index(i))...)
+	}
+	return allErrs
+}
+
+func validatePorts(ports *[]v1alpha1.AdminNetworkPolicyPort, fldPath *field.Path) field.ErrorList {
+	if ports == nil {
+		return nil
+	}
+	allErrs := validateListSize(len(*ports), 1, MaxPorts, fldPath)
+	for i, port := range *ports {
+		portPath := fldPath.Index(i)
+		allErrs = append(allErrs, validateOneOf(portPath,
+			oneOfField{"portNumber", port.PortNumber != nil},
+			oneOfField{"namedPort", port.NamedPort != nil},
+			oneOfField{"portRange", port.PortRange != nil})...)
+		if port.PortNumber != nil {
+			allErrs = append(allErrs, validatePortNumber(port.PortNumber.Port, portPath.Child("portNumber", "port"))...)
+		}
+		if port.PortRange != nil {
+			rangePath := portPath.Child("portRange")
+			allErrs = append(allErrs, validatePortNumber(port.PortRange.Start, rangePath.Child("start"))...)
+			allErrs = append(allErrs, validatePortNumber(port.PortRange.End, rangePath.Child("end"))...)
+			if port.PortRange.Start >= port.PortRange.End {
+				allErrs = append(allErrs, field.Invalid(rangePath.Child("end"), port.PortRange.End, "must be greater than start"))
+			}
+		}
+	}
+	return allErrs
+}
+
+// validateNoNamedPorts enforces the CEL rule on egress rules with networks or nodes peers.
+func validateNoNamedPorts(ports *[]v1alpha1.AdminNetworkPolicyPort, fldPath *field.Path) field.ErrorList {
+	if ports == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	for i, port := range *ports {
+		if port.NamedPort != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Index(i).Child("namedPort"), namedPortWithIPPeerMsg))
+		}
+	}
+	return allErrs
+}
+
+func validatePortNumber(port int32, fldPath *field.Path) field.ErrorList {
+	if port < MinPort || port > MaxPort {
+		return field.ErrorList{field.Invalid(fldPath, port, validation.InclusiveRangeError(MinPort, MaxPort))}
+	}
+	return nil
+}
+
+func validateRuleName(name string, fldPath *field.Path) field.ErrorList {
+	if len(name) > MaxRuleNameLength {
+		return field.ErrorList{field.TooLong(fldPath, name, MaxRuleNameLength)}
+	}
+	return nil
+}
+
+func validateANPAction(action v1alpha1.AdminNetworkPolicyRuleAction, fldPath *field.Path) field.ErrorList {
+	if action == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	for _, a := range anpActions {
+		if action == a {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(fldPath, action, anpActions)}
+}
+
+func validateBANPAction(action v1alpha1.BaselineAdminNetworkPolicyRuleAction, fldPath *field.Path) field.ErrorList {
+	if action == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	for _, a := range banpActions {
+		if action == a {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(fldPath, action, banpActions)}
+}
+
+// validateListSize enforces MinItems and MaxItems on a list that is present.
+func validateListSize(size, minItems, maxItems int, fldPath *field.Path) field.ErrorList {
+	if size < minItems {
+		return field.ErrorList{field.Required(fldPath, fmt.Sprintf("must have at least %d item(s)", minItems))}
+	}
+	if size > maxItems {
+		return field.ErrorList{field.TooMany(fldPath, size, maxItems)}
+	}
+	return nil
+}
+
+type oneOfField struct {
+	name string
+	set  bool
+}
+
+// validateOneOf enforces MinProperties=1 and MaxProperties=1 on structs whose fields are all optional.
+func validateOneOf(fldPath *field.Path, fields ...oneOfField) field.ErrorList {
+	var names, set []string
+	for _, f := range fields {
+		names = append(names, f.name)
+		if f.set {
+			set = append(set, f.name)
+		}
+	}
+	switch {
+	case len(set) == 0:
+		return field.ErrorList{field.Required(fldPath, fmt.Sprintf("exactly one of %s must be set", strings.Join(names, ", ")))}
+	case len(set) > 1:
+		return field.ErrorList{field.Forbidden(fldPath.Child(set[1]), fmt.Sprintf("may not be set together with %s", set[0]))}
+	default:
+		return nil
+	}
+}
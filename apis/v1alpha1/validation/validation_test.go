@@ -0,0 +1,323 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+type expectedError struct {
+	errType field.ErrorType
+	field   string
+}
+
+func errorsOf(errs field.ErrorList) []expectedError {
+	var out []expectedError
+	for _, err := range errs {
+		out = append(out, expectedError{err.Type, err.Field})
+	}
+	return out
+}
+
+func validANP() *v1alpha1.AdminNetworkPolicy {
+	return &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "anp"},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: 10,
+			Subject:  v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{{
+				Name:   "allow-from-monitoring",
+				Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+				From: []v1alpha1.AdminNetworkPolicyIngressPeer{{
+					Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "monitoring"}},
+				}},
+				Ports: &[]v1alpha1.AdminNetworkPolicyPort{
+					{PortNumber: &v1alpha1.Port{Protocol: v1.ProtocolTCP, Port: 8080}},
+					{NamedPort: ptr.To("metrics")},
+				},
+			}},
+			Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
+				{
+					Name:   "allow-dns",
+					Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+					To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+						{Networks: []v1alpha1.CIDR{"10.0.0.0/8", "fd00::/8"}},
+						{Nodes: &metav1.LabelSelector{}},
+					},
+					Ports: &[]v1alpha1.AdminNetworkPolicyPort{
+						{PortRange: &v1alpha1.PortRange{Protocol: v1.ProtocolUDP, Start: 53, End: 54}},
+					},
+				},
+				{
+					Name:   "allow-kubernetes-io",
+					Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+					To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{DomainNames: []v1alpha1.DomainName{"kubernetes.io", "*.kubernetes.io"}}},
+				},
+				{
+					Name:   "pass-to-pods",
+					Action: v1alpha1.AdminNetworkPolicyRuleActionPass,
+					To: []v1alpha1.AdminNetworkPolicyEgressPeer{{
+						Pods: &v1alpha1.NamespacedPod{PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func validBANP() *v1alpha1.BaselineAdminNetworkPolicy {
+	return &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Pods: &v1alpha1.NamespacedPod{}},
+			Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{{
+				Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+				From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+			}},
+			Egress: []v1alpha1.BaselineAdminNetworkPolicyEgressRule{{
+				Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+				To:     []v1alpha1.BaselineAdminNetworkPolicyEgressPeer{{Networks: []v1alpha1.CIDR{"0.0.0.0/0"}}},
+				Ports:  &[]v1alpha1.AdminNetworkPolicyPort{{PortNumber: &v1alpha1.Port{Protocol: v1.ProtocolTCP, Port: 443}}},
+			}},
+		},
+	}
+}
+
+func TestValidateAdminNetworkPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(anp *v1alpha1.AdminNetworkPolicy)
+		expected []expectedError
+	}{{
+		name:   "valid",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {},
+	}, {
+		name:     "priority too high",
+		mutate:   func(anp *v1alpha1.AdminNetworkPolicy) { anp.Spec.Priority = 1001 },
+		expected: []expectedError{{field.ErrorTypeInvalid, "spec.priority"}},
+	}, {
+		name:     "negative priority",
+		mutate:   func(anp *v1alpha1.AdminNetworkPolicy) { anp.Spec.Priority = -1 },
+		expected: []expectedError{{field.ErrorTypeInvalid, "spec.priority"}},
+	}, {
+		name:     "empty subject",
+		mutate:   func(anp *v1alpha1.AdminNetworkPolicy) { anp.Spec.Subject = v1alpha1.AdminNetworkPolicySubject{} },
+		expected: []expectedError{{field.ErrorTypeRequired, "spec.subject"}},
+	}, {
+		name: "subject with namespaces and pods",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Subject.Pods = &v1alpha1.NamespacedPod{}
+		},
+		expected: []expectedError{{field.ErrorTypeForbidden, "spec.subject.pods"}},
+	}, {
+		name: "too many egress rules",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			rule := anp.Spec.Egress[2]
+			anp.Spec.Egress = nil
+			for i := 0; i < MaxRules+1; i++ {
+				anp.Spec.Egress = append(anp.Spec.Egress, rule)
+			}
+		},
+		expected: []expectedError{{field.ErrorTypeTooMany, "spec.egress"}},
+	}, {
+		name: "rule name too long",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Ingress[0].Name = strings.Repeat("a", MaxRuleNameLength+1)
+		},
+		expected: []expectedError{{field.ErrorTypeTooLong, "spec.ingress[0].name"}},
+	}, {
+		name:     "missing action",
+		mutate:   func(anp *v1alpha1.AdminNetworkPolicy) { anp.Spec.Egress[0].Action = "" },
+		expected: []expectedError{{field.ErrorTypeRequired, "spec.egress[0].action"}},
+	}, {
+		name:     "unsupported action",
+		mutate:   func(anp *v1alpha1.AdminNetworkPolicy) { anp.Spec.Ingress[0].Action = "Log" },
+		expected: []expectedError{{field.ErrorTypeNotSupported, "spec.ingress[0].action"}},
+	}, {
+		name:     "no ingress peers",
+		mutate:   func(anp *v1alpha1.AdminNetworkPolicy) { anp.Spec.Ingress[0].From = nil },
+		expected: []expectedError{{field.ErrorTypeRequired, "spec.ingress[0].from"}},
+	}, {
+		name:     "empty ingress peer",
+		mutate:   func(anp *v1alpha1.AdminNetworkPolicy) { anp.Spec.Ingress[0].From[0].Namespaces = nil },
+		expected: []expectedError{{field.ErrorTypeRequired, "spec.ingress[0].from[0]"}},
+	}, {
+		name: "egress peer with namespaces and networks",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Egress[0].To[0].Namespaces = &metav1.LabelSelector{}
+		},
+		expected: []expectedError{{field.ErrorTypeForbidden, "spec.egress[0].to[0].networks"}},
+	}, {
+		name: "empty ports",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Ingress[0].Ports = &[]v1alpha1.AdminNetworkPolicyPort{}
+		},
+		expected: []expectedError{{field.ErrorTypeRequired, "spec.ingress[0].ports"}},
+	}, {
+		name: "port with number and name",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			(*anp.Spec.Ingress[0].Ports)[0].NamedPort = ptr.To("http")
+		},
+		expected: []expectedError{{field.ErrorTypeForbidden, "spec.ingress[0].ports[0].namedPort"}},
+	}, {
+		name: "port number out of range",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			(*anp.Spec.Ingress[0].Ports)[0].PortNumber.Port = 0
+		},
+		expected: []expectedError{{field.ErrorTypeInvalid, "spec.ingress[0].ports[0].portNumber.port"}},
+	}, {
+		name: "port range end before start",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			(*anp.Spec.Egress[0].Ports)[0].PortRange.End = 52
+		},
+		expected: []expectedError{{field.ErrorTypeInvalid, "spec.egress[0].ports[0].portRange.end"}},
+	}, {
+		name: "port range end out of range",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			(*anp.Spec.Egress[0].Ports)[0].PortRange.End = 65536
+		},
+		expected: []expectedError{{field.ErrorTypeInvalid, "spec.egress[0].ports[0].portRange.end"}},
+	}, {
+		name: "named port with networks peer",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Egress[0].Ports = &[]v1alpha1.AdminNetworkPolicyPort{{NamedPort: ptr.To("dns")}}
+		},
+		expected: []expectedError{{field.ErrorTypeForbidden, "spec.egress[0].ports[0].namedPort"}},
+	}, {
+		name: "named port with pods peer",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Egress[2].Ports = &[]v1alpha1.AdminNetworkPolicyPort{{NamedPort: ptr.To("dns")}}
+		},
+	}, {
+		name:     "empty networks",
+		mutate:   func(anp *v1alpha1.AdminNetworkPolicy) { anp.Spec.Egress[0].To[0].Networks = []v1alpha1.CIDR{} },
+		expected: []expectedError{{field.ErrorTypeRequired, "spec.egress[0].to[0].networks"}},
+	}, {
+		name: "too many networks",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Egress[0].To[0].Networks = nil
+			for i := 0; i < MaxNetworks+1; i++ {
+				anp.Spec.Egress[0].To[0].Networks = append(anp.Spec.Egress[0].To[0].Networks, v1alpha1.CIDR(fmt.Sprintf("10.0.%d.0/24", i)))
+			}
+		},
+		expected: []expectedError{{field.ErrorTypeTooMany, "spec.egress[0].to[0].networks"}},
+	}, {
+		name: "duplicate network",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Egress[0].To[0].Networks = []v1alpha1.CIDR{"10.0.0.0/8", "10.0.0.0/8"}
+		},
+		expected: []expectedError{{field.ErrorTypeDuplicate, "spec.egress[0].to[0].networks[1]"}},
+	}, {
+		name: "invalid CIDRs",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Egress[0].To[0].Networks = []v1alpha1.CIDR{
+				"10.0.0.0/33",
+				"10.0.0.0",
+				"::ffff:10.0.0.0/104",
+				v1alpha1.CIDR(strings.Repeat("f", 44)),
+			}
+		},
+		expected: []expectedError{
+			{field.ErrorTypeInvalid, "spec.egress[0].to[0].networks[0]"},
+			{field.ErrorTypeInvalid, "spec.egress[0].to[0].networks[1]"},
+			{field.ErrorTypeInvalid, "spec.egress[0].to[0].networks[2]"},
+			{field.ErrorTypeTooLong, "spec.egress[0].to[0].networks[3]"},
+		},
+	}, {
+		name: "invalid domain names",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Egress[1].To[0].DomainNames = []v1alpha1.DomainName{"kubernetes", "www.*.kubernetes.io", "-kubernetes.io", "kubernetes.io."}
+		},
+		expected: []expectedError{
+			{field.ErrorTypeInvalid, "spec.egress[1].to[0].domainNames[0]"},
+			{field.ErrorTypeInvalid, "spec.egress[1].to[0].domainNames[1]"},
+			{field.ErrorTypeInvalid, "spec.egress[1].to[0].domainNames[2]"},
+		},
+	}, {
+		name: "duplicate domain name",
+		mutate: func(anp *v1alpha1.AdminNetworkPolicy) {
+			anp.Spec.Egress[1].To[0].DomainNames = []v1alpha1.DomainName{"kubernetes.io", "kubernetes.io"}
+		},
+		expected: []expectedError{{field.ErrorTypeDuplicate, "spec.egress[1].to[0].domainNames[1]"}},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			anp := validANP()
+			tc.mutate(anp)
+			require.Equal(t, tc.expected, errorsOf(ValidateAdminNetworkPolicy(anp)))
+		})
+	}
+}
+
+func TestValidateBaselineAdminNetworkPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(banp *v1alpha1.BaselineAdminNetworkPolicy)
+		expected []expectedError
+	}{{
+		name:   "valid",
+		mutate: func(banp *v1alpha1.BaselineAdminNetworkPolicy) {},
+	}, {
+		name:     "name other than default",
+		mutate:   func(banp *v1alpha1.BaselineAdminNetworkPolicy) { banp.Name = "baseline" },
+		expected: []expectedError{{field.ErrorTypeInvalid, "metadata.name"}},
+	}, {
+		name:     "pass action",
+		mutate:   func(banp *v1alpha1.BaselineAdminNetworkPolicy) { banp.Spec.Egress[0].Action = "Pass" },
+		expected: []expectedError{{field.ErrorTypeNotSupported, "spec.egress[0].action"}},
+	}, {
+		name: "egress peer with nodes and networks",
+		mutate: func(banp *v1alpha1.BaselineAdminNetworkPolicy) {
+			banp.Spec.Egress[0].To[0].Nodes = &metav1.LabelSelector{}
+		},
+		expected: []expectedError{{field.ErrorTypeForbidden, "spec.egress[0].to[0].networks"}},
+	}, {
+		name: "named port with nodes peer",
+		mutate: func(banp *v1alpha1.BaselineAdminNetworkPolicy) {
+			banp.Spec.Egress[0].To = []v1alpha1.BaselineAdminNetworkPolicyEgressPeer{{Nodes: &metav1.LabelSelector{}}}
+			banp.Spec.Egress[0].Ports = &[]v1alpha1.AdminNetworkPolicyPort{{NamedPort: ptr.To("https")}}
+		},
+		expected: []expectedError{{field.ErrorTypeForbidden, "spec.egress[0].ports[0].namedPort"}},
+	}, {
+		name:     "invalid CIDR",
+		mutate:   func(banp *v1alpha1.BaselineAdminNetworkPolicy) { banp.Spec.Egress[0].To[0].Networks[0] = "0.0.0.0/-1" },
+		expected: []expectedError{{field.ErrorTypeInvalid, "spec.egress[0].to[0].networks[0]"}},
+	}, {
+		name:     "no egress peers",
+		mutate:   func(banp *v1alpha1.BaselineAdminNetworkPolicy) { banp.Spec.Egress[0].To = nil },
+		expected: []expectedError{{field.ErrorTypeRequired, "spec.egress[0].to"}},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			banp := validBANP()
+			tc.mutate(banp)
+			require.Equal(t, tc.expected, errorsOf(ValidateBaselineAdminNetworkPolicy(banp)))
+		})
+	}
+}
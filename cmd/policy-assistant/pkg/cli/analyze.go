@@ -8,6 +8,7 @@ import (
 	"github.com/mattfenwick/cyclonus/examples"
 	"github.com/mattfenwick/cyclonus/pkg/kube/netpol"
 	"github.com/olekukonko/tablewriter"
+	"golang.org/x/exp/slices"
 	"golang.org/x/net/context"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/mattfenwick/cyclonus/pkg/kube"
 	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	"github.com/mattfenwick/cyclonus/pkg/render"
 	"github.com/mattfenwick/cyclonus/pkg/utils"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -28,23 +30,27 @@ import (
 )
 
 const (
-	// ParseMode        = "parse"
-	ExplainMode = "explain"
-	// QueryTrafficMode = "query-traffic"
-	// QueryTargetMode  = "query-target"
+	ExplainMode            = "explain"
+	QueryTrafficMode       = "query-traffic"
+	QueryTargetMode        = "query-target"
 	ProbeMode              = "probe"
 	VerdictWalkthroughMode = "walkthrough"
+	ShadowedRulesMode      = "shadowed-rules"
+	PriorityConflictsMode  = "priority-conflicts"
+	ReachabilityMode       = "reachability"
+	DiffMode               = "diff"
 )
 
-// should we remove commented out modes or implement them later?
-// code for them is in analyze_unimplemented.go
 var AllModes = []string{
-	// ParseMode,
 	ExplainMode,
-	// QueryTrafficMode,
-	// QueryTargetMode,
+	QueryTrafficMode,
+	QueryTargetMode,
 	ProbeMode,
 	VerdictWalkthroughMode,
+	ShadowedRulesMode,
+	PriorityConflictsMode,
+	ReachabilityMode,
+	DiffMode,
 }
 
 const DefaultTimeout = 3 * time.Minute
@@ -54,26 +60,42 @@ type AnalyzeArgs struct {
 	Namespaces         []string
 	UseExamplePolicies bool
 	PolicyPath         string
+	KustomizeDir       string
+	HelmChart          string
+	HelmValues         []string
 	Context            string
+	SnapshotPath       string
 	SimplifyPolicies   bool
 
 	Modes []string
 
+	// Output is the output format: table, json or yaml
+	Output string
+
 	// traffic
 	TrafficPath string
+	HostsFile   string
 
 	// targets
 	TargetPodPath string
 
-	// synthetic probe
+	// synthetic probe; its resources are also used for priority conflicts
 	ProbePath string
 
+	// reachability
+	GroupByWorkload bool
+
+	// diff
+	ComparePolicyPath string
+
 	Timeout time.Duration
 
 	SourceWorkloadTraffic string
 
 	DestinationWorkloadTraffic string
 
+	DestinationService string
+
 	Port int
 
 	Protocol string
@@ -95,17 +117,26 @@ func SetupAnalyzeCommand() *cobra.Command {
 	command.Flags().BoolVarP(&args.AllNamespaces, "all-namespaces", "A", false, "reads kube resources from all namespaces; same as kubectl's '--all-namespaces'/'-A' flag")
 	command.Flags().StringSliceVarP(&args.Namespaces, "namespace", "n", []string{}, "namespaces to read kube resources from; similar to kubectl's '--namespace'/'-n' flag, except that multiple namespaces may be passed in and is empty if not set explicitly (instead of 'default' as in kubectl)")
 	command.Flags().StringVar(&args.PolicyPath, "policy-path", "", "may be a file or a directory; if set, will attempt to read policies from the path")
+	command.Flags().StringVar(&args.KustomizeDir, "kustomize-dir", "", "if set, reads policies from the output of building this kustomization directory, as 'kustomize build' does")
+	command.Flags().StringVar(&args.HelmChart, "helm-chart", "", "if set, reads policies from the templates of this local chart directory or archive, rendered as 'helm template' does; dependencies must be in its charts/ directory")
+	command.Flags().StringSliceVar(&args.HelmValues, "helm-values", []string{}, "values files for --helm-chart; later files take precedence")
+	command.Flags().StringVar(&args.SnapshotPath, "snapshot", "", "path to a file written by 'cyclonus snapshot'; if set, kube resources are read from it instead of from a cluster")
 	command.Flags().StringVar(&args.Context, "context", "", "selects kube context to read policies from; only reads from kube if one or more namespaces or all namespaces are specified")
 	command.Flags().BoolVar(&args.SimplifyPolicies, "simplify-policies", true, "if true, reduce policies to simpler form while preserving semantics (only applies to NPv1 currently)")
 
 	command.Flags().StringSliceVar(&args.Modes, "mode", []string{ExplainMode}, "analysis modes to run; allowed values are "+strings.Join(AllModes, ","))
+	command.Flags().StringVarP(&args.Output, "output", "o", TableOutput, "output format; allowed values are "+strings.Join(AllOutputFormats, ","))
 
 	command.Flags().StringVar(&args.TargetPodPath, "target-pod-path", "", "path to json target pod file -- json array of dicts")
 	command.Flags().StringVar(&args.TrafficPath, "traffic-path", "", "path to json traffic file, containing of a list of traffic objects")
+	command.Flags().StringVar(&args.HostsFile, "hosts-file", "", "path to a file in /etc/hosts format, used to resolve hostnames of external traffic peers offline")
 	command.Flags().StringVar(&args.ProbePath, "probe-path", "", "path to json model file for synthetic probe")
+	command.Flags().BoolVar(&args.GroupByWorkload, "group-by-workload", false, "for reachability mode: show one row and column per Deployment, StatefulSet, DaemonSet, etc. instead of per pod")
+	command.Flags().StringVar(&args.ComparePolicyPath, "compare-policy-path", "", "for diff mode: may be a file or a directory; policies to compare against those from --policy-path")
 	command.Flags().DurationVar(&args.Timeout, "kube-client-timeout", DefaultTimeout, "kube client timeout")
 	command.Flags().StringVar(&args.SourceWorkloadTraffic, "src-workload", "", "Source workload traffic in this form namespace/workloadType/workloadName")
 	command.Flags().StringVar(&args.DestinationWorkloadTraffic, "dst-workload", "", "Destination workload traffic Name in this form namespace/workloadType/workloadName")
+	command.Flags().StringVar(&args.DestinationService, "dst-service", "", "Destination service in this form namespace/serviceName; instead of --dst-workload, evaluates traffic to each pod backing the service, with --port as the service's port")
 	command.Flags().IntVar(&args.Port, "port", 0, "port used for testing network policies")
 	command.Flags().StringVar(&args.Protocol, "protocol", "", "protocol used for testing network policies")
 
@@ -113,6 +144,13 @@ func SetupAnalyzeCommand() *cobra.Command {
 }
 
 func RunAnalyzeCommand(args *AnalyzeArgs) {
+	if args.Output == "" {
+		args.Output = TableOutput
+	}
+	if !slices.Contains(AllOutputFormats, args.Output) {
+		logrus.Fatalf("%+v", errors.Errorf("invalid output format %s; allowed values are %s", args.Output, strings.Join(AllOutputFormats, ",")))
+	}
+
 	// 1. read policies from kube
 	var kubePolicies []*networkingv1.NetworkPolicy
 	var kubeANPs []*v1alpha1.AdminNetworkPolicy
@@ -120,27 +158,47 @@ func RunAnalyzeCommand(args *AnalyzeArgs) {
 	var kubePods []v1.Pod
 	var kubeNamespaces []v1.Namespace
 	var netpolErr, anpErr, banpErr error
-	if args.AllNamespaces || len(args.Namespaces) > 0 {
-		kubeClient, err := kube.NewKubernetesForContext(args.Context)
+	var kubeClient kube.IKubernetes
+	includeANPs, includeBANP := true, true
+	if args.SnapshotPath != "" {
+		snapshot, err := kube.ReadSnapshotFile(args.SnapshotPath)
 		utils.DoOrDie(err)
-
+		kubeClient = kube.NewSnapshotKubernetes(snapshot)
+	} else if args.AllNamespaces || len(args.Namespaces) > 0 {
+		client, err := kube.NewKubernetesForContext(args.Context)
+		utils.DoOrDie(err)
+		includeANPs, includeBANP = shouldIncludeANPandBANP(client.ClientSet)
+		kubeClient = client
+	}
+	if kubeClient != nil {
 		namespaces := args.Namespaces
-		if args.AllNamespaces {
+		// a snapshot is read in full unless namespaces are given
+		if args.AllNamespaces || len(namespaces) == 0 {
 			nsList, err := kubeClient.GetAllNamespaces()
 			utils.DoOrDie(err)
 			kubeNamespaces = nsList.Items
 			namespaces = []string{v1.NamespaceAll}
+		} else {
+			for _, ns := range namespaces {
+				kubeNs, err := kubeClient.GetNamespace(ns)
+				utils.DoOrDie(err)
+				kubeNamespaces = append(kubeNamespaces, *kubeNs)
+			}
 		}
 
-		includeANPS, includeBANPSs := shouldIncludeANPandBANP(kubeClient.ClientSet)
+		var err error
+		kubePods, err = kube.GetPodsInNamespaces(kubeClient, namespaces)
+		if err != nil {
+			logrus.Errorf("unable to read pods from kube, ns '%s': %+v", namespaces, err)
+		}
 
 		ctx, cancel := context.WithTimeout(context.TODO(), args.Timeout)
 		defer cancel()
 
-		kubePolicies, kubeANPs, kubeBANP, netpolErr, anpErr, banpErr = kube.ReadNetworkPoliciesFromKube(ctx, kubeClient, namespaces, includeANPS, includeBANPSs)
+		kubePolicies, kubeANPs, kubeBANP, netpolErr, anpErr, banpErr = kube.ReadNetworkPoliciesFromKube(ctx, kubeClient, namespaces, includeANPs, includeBANP)
 
 		if netpolErr != nil {
-			logrus.Errorf("unable to read network policies from kube, ns '%s': %+v", namespaces, err)
+			logrus.Errorf("unable to read network policies from kube, ns '%s': %+v", namespaces, netpolErr)
 		}
 		if anpErr != nil {
 			logrus.Errorf("Unable to fetch admin network policies: %s \n", anpErr)
@@ -149,47 +207,349 @@ func RunAnalyzeCommand(args *AnalyzeArgs) {
 			logrus.Errorf("Unable to fetch base admin network policies: %s \n", banpErr)
 		}
 	}
-	// 2. read policies from file
-	if args.PolicyPath != "" {
-		policiesFromPath, anpsFromPath, banpFromPath, err := kube.ReadNetworkPoliciesFromPath(args.PolicyPath)
-		utils.DoOrDie(err)
-		kubePolicies = append(kubePolicies, policiesFromPath...)
-		kubeANPs = append(kubeANPs, anpsFromPath...)
-		if banpFromPath != nil && kubeBANP != nil {
-			logrus.Debugf("More that one banp parsed - setting banp from file")
-		}
-		kubeBANP = banpFromPath
-	}
-	// 3. read example policies
-	if args.UseExamplePolicies {
-		kubePolicies = append(kubePolicies, netpol.AllExamples...)
+	// 2. read policies from file and example policies
+	netpolsFromKube, anpsFromKube, banpFromKube := kubePolicies, kubeANPs, kubeBANP
+	rendered := renderPolicies(args)
+	kubePolicies, kubeANPs, kubeBANP = addPoliciesFromPathAndExamples(args.PolicyPath, rendered, args.UseExamplePolicies, kubePolicies, kubeANPs, kubeBANP)
 
-		kubeANPs = append(kubeANPs, examples.CoreGressRulesCombinedANB...)
-		if kubeBANP != nil {
-			logrus.Debugf("More that onew banp parsed - setting banp from the examples")
+	logrus.Debugf("parsed policies:\n%s", json.MustMarshalToString(kubePolicies))
+	policies, policyErrors := matcher.BuildV1AndV2NetPols(args.SimplifyPolicies, kubePolicies, kubeANPs, kubeBANP)
+	isTable := args.Output == TableOutput
+	results := NewAnalyzeResults()
+	if len(policyErrors) > 0 {
+		if isTable {
+			fmt.Println("invalid policies (skipped):")
+			fmt.Println(PolicyErrorsTable(policyErrors))
+		} else {
+			results.InvalidPolicies = NewPolicyErrorResults(policyErrors)
 		}
-		kubeBANP = examples.CoreGressRulesCombinedBANB
 	}
 
-	logrus.Debugf("parsed policies:\n%s", json.MustMarshalToString(kubePolicies))
-	policies := matcher.BuildV1AndV2NetPols(args.SimplifyPolicies, kubePolicies, kubeANPs, kubeBANP)
-
 	for _, mode := range args.Modes {
-		// see analyze_unimplemented.go for unimplemented modes and the "case" statements for them
 		switch mode {
 		case ExplainMode:
-			fmt.Println("explained policies:")
-			ExplainPolicies(policies)
+			if isTable {
+				fmt.Println("explained policies:")
+				ExplainPolicies(policies)
+			} else {
+				results.Explanation = policies.Explain()
+			}
 		case ProbeMode:
-			fmt.Println("probe (simulated connectivity):")
-			ProbeSyntheticConnectivity(policies, args.ProbePath, kubePods, kubeNamespaces)
+			probes := SyntheticProbes(policies, args.ProbePath, kubePods, kubeNamespaces)
+			if isTable {
+				fmt.Println("probe (simulated connectivity):")
+				PrintSyntheticProbes(probes)
+			} else {
+				results.Probe = []*ProbeResult{}
+				for _, p := range probes {
+					results.Probe = append(results.Probe, p.Result())
+				}
+			}
+		case QueryTargetMode:
+			targets, tables := QueryTargets(policies, QueryTargetPods(args.TargetPodPath, kubePods, kubeNamespaces))
+			if isTable {
+				fmt.Println("query target:")
+				fmt.Println(tables)
+			} else {
+				results.QueryTarget = targets
+			}
+		case QueryTrafficMode:
+			var resolver matcher.DomainNameResolver
+			kubeClient, resolver = trafficLookups(kubeClient, args.HostsFile)
+			traffic, tables := QueryTraffic(kubeClient, policies, args.TrafficPath, resolver)
+			if isTable {
+				fmt.Println("query traffic:")
+				fmt.Println(tables)
+			} else {
+				results.QueryTraffic = traffic
+			}
 		case VerdictWalkthroughMode:
-			fmt.Println("verdict walkthrough:")
-			VerdictWalkthrough(policies, args.SourceWorkloadTraffic, args.DestinationWorkloadTraffic, args.Port, args.Protocol, args.TrafficPath)
+			var resolver matcher.DomainNameResolver
+			kubeClient, resolver = trafficLookups(kubeClient, args.HostsFile)
+			verdicts := VerdictWalkthrough(kubeClient, policies, args.SourceWorkloadTraffic, args.DestinationWorkloadTraffic, args.DestinationService, args.Port, args.Protocol, args.TrafficPath, resolver)
+			if isTable {
+				fmt.Println("verdict walkthrough:")
+				fmt.Println(VerdictWalkthroughTable(verdicts))
+			} else {
+				results.Walkthrough = verdicts
+			}
+		case ShadowedRulesMode:
+			shadowedRules := policies.ShadowedRules()
+			if isTable {
+				fmt.Println("shadowed rules:")
+				fmt.Println(ShadowedRulesTable(shadowedRules))
+			} else {
+				results.ShadowedRules = NewShadowedRuleResults(shadowedRules)
+			}
+		case PriorityConflictsMode:
+			conflicts, conflictErrors := PriorityConflicts(kubeANPs, args.ProbePath, kubePods, kubeNamespaces)
+			if isTable {
+				fmt.Println("priority conflicts:")
+				if len(conflictErrors) > 0 {
+					fmt.Println(PolicyErrorsTable(conflictErrors))
+				}
+				fmt.Println(PriorityConflictsTable(conflicts))
+			} else {
+				results.PriorityConflicts = NewPriorityConflictsResult(conflicts, conflictErrors)
+			}
+		case ReachabilityMode:
+			reachability := Reachability(policies, kubePods, kubeNamespaces, args.GroupByWorkload)
+			if isTable {
+				fmt.Println("reachability:")
+				if reachability != nil {
+					fmt.Printf("%s\n", reachability.RenderTable())
+				}
+			} else if reachability != nil {
+				results.Reachability = reachability.Result()
+			}
+		case DiffMode:
+			if args.ComparePolicyPath == "" {
+				logrus.Fatalf("%+v", errors.Errorf("diff mode requires --compare-policy-path"))
+			}
+			compareNetpols, compareANPs, compareBANP := addPoliciesFromPathAndExamples(args.ComparePolicyPath, rendered, args.UseExamplePolicies, netpolsFromKube, anpsFromKube, banpFromKube)
+			comparePolicies, compareErrors := matcher.BuildV1AndV2NetPols(args.SimplifyPolicies, compareNetpols, compareANPs, compareBANP)
+			diff := DiffPolicies(policies, comparePolicies, args.ProbePath, kubePods, kubeNamespaces)
+			if isTable {
+				fmt.Println("diff:")
+				if len(compareErrors) > 0 {
+					fmt.Println("invalid policies from --compare-policy-path (skipped):")
+					fmt.Println(PolicyErrorsTable(compareErrors))
+				}
+				if diff != nil {
+					fmt.Println(DiffTable(diff))
+				}
+			} else {
+				if diff == nil {
+					diff = &DiffResult{Changes: []*TrafficDiffResult{}}
+				}
+				diff.InvalidPolicies = NewPolicyErrorResults(compareErrors)
+				results.Diff = diff
+			}
 		default:
 			panic(errors.Errorf("unrecognized mode %s", mode))
 		}
 	}
+
+	if !isTable {
+		rendered, err := results.Render(args.Output)
+		utils.DoOrDie(err)
+		fmt.Println(rendered)
+	}
+}
+
+// addPoliciesFromPathAndExamples adds policies read from a path, if set, policies from rendered yaml, and the
+// example policies, if enabled, to policies read from kube.  A BANP from files or examples replaces the one from kube.
+func addPoliciesFromPathAndExamples(policyPath string, rendered []*kube.YamlSource, useExamplePolicies bool, netpols []*networkingv1.NetworkPolicy, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) ([]*networkingv1.NetworkPolicy, []*v1alpha1.AdminNetworkPolicy, *v1alpha1.BaselineAdminNetworkPolicy) {
+	// copy, so that policies read from kube can be shared by several policy sets
+	netpols = append([]*networkingv1.NetworkPolicy{}, netpols...)
+	anps = append([]*v1alpha1.AdminNetworkPolicy{}, anps...)
+
+	var sources []*kube.YamlSource
+	if policyPath != "" {
+		fileSources, err := kube.ReadYamlSources(policyPath)
+		utils.DoOrDie(err)
+		sources = append(sources, fileSources...)
+	}
+	sources = append(sources, rendered...)
+	if len(sources) > 0 {
+		policiesFromFiles, err := kube.ReadPoliciesFromYaml(sources)
+		utils.DoOrDie(err)
+		netpols = append(netpols, policiesFromFiles.NetworkPolicies...)
+		anps = append(anps, policiesFromFiles.AdminNetworkPolicies...)
+		if policiesFromFiles.BaselineAdminNetworkPolicy != nil {
+			if banp != nil {
+				logrus.Debugf("More that one banp parsed - setting banp from file")
+			}
+			banp = policiesFromFiles.BaselineAdminNetworkPolicy
+		}
+	}
+	if useExamplePolicies {
+		netpols = append(netpols, netpol.AllExamples...)
+
+		anps = append(anps, examples.CoreGressRulesCombinedANB...)
+		if banp != nil {
+			logrus.Debugf("More that onew banp parsed - setting banp from the examples")
+		}
+		banp = examples.CoreGressRulesCombinedBANB
+	}
+	return netpols, anps, banp
+}
+
+// renderPolicies renders the kustomization and the helm chart, if set.
+func renderPolicies(args *AnalyzeArgs) []*kube.YamlSource {
+	var rendered []*kube.YamlSource
+	if args.KustomizeDir != "" {
+		source, err := render.Kustomize(args.KustomizeDir)
+		utils.DoOrDie(err)
+		rendered = append(rendered, source)
+	}
+	if args.HelmChart != "" {
+		sources, err := render.HelmChart(args.HelmChart, args.HelmValues)
+		utils.DoOrDie(err)
+		rendered = append(rendered, sources...)
+	} else if len(args.HelmValues) > 0 {
+		logrus.Fatalf("%+v", errors.Errorf("--helm-values requires --helm-chart"))
+	}
+	return rendered
+}
+
+func PolicyErrorsTable(policyErrors []*matcher.PolicyError) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCells(true)
+
+	table.SetHeader([]string{"Kind", "Policy", "Rule", "Field", "Error"})
+	for _, err := range policyErrors {
+		rule := ""
+		if err.RuleIndex != matcher.NoRuleIndex {
+			rule = fmt.Sprintf("%d", err.RuleIndex)
+		}
+		table.Append([]string{string(err.Kind), err.PolicyName(), rule, err.Field(), err.Err.ErrorBody()})
+	}
+
+	table.Render()
+	return tableString.String()
+}
+
+func ShadowedRulesTable(shadowedRules []*matcher.ShadowedRule) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"Type", "Rule", "Shadowed By", "Reason"})
+	for _, s := range shadowedRules {
+		direction := "Egress"
+		if s.IsIngress {
+			direction = "Ingress"
+		}
+		shadowedBy := ""
+		if s.ShadowedBy != nil {
+			shadowedBy = s.ShadowedBy.String()
+		} else {
+			var netpols []string
+			for _, id := range s.NetworkPolicies {
+				netpols = append(netpols, string(id))
+			}
+			shadowedBy = strings.Join(netpols, "\n")
+		}
+		table.Append([]string{direction, s.Rule.String(), shadowedBy, string(s.Reason)})
+	}
+
+	table.Render()
+	return tableString.String()
+}
+
+func PriorityConflictsTable(conflicts []*matcher.PriorityConflict) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetAutoMergeCells(true)
+
+	table.SetHeader([]string{"Priority", "Policies", "Common Pods", "Type", "Conflicting Verdicts", "Example"})
+	for _, c := range conflicts {
+		priority := fmt.Sprintf("%d", c.Priority)
+		policies := c.PolicyName + "\n" + c.OtherPolicy
+		var pods []string
+		for _, pod := range c.Pods {
+			pods = append(pods, pod.String())
+		}
+		commonPods := strings.Join(pods, "\n")
+		if len(c.Verdicts) == 0 {
+			table.Append([]string{priority, policies, commonPods, "", "none found", ""})
+			continue
+		}
+		for _, v := range c.Verdicts {
+			direction := "Egress"
+			if v.IsIngress {
+				direction = "Ingress"
+			}
+			table.Append([]string{priority, policies, commonPods, direction, v.Rule.String() + "\n" + v.OtherRule.String(), v.Example})
+		}
+	}
+
+	table.Render()
+	return tableString.String()
+}
+
+// PriorityConflicts finds ANPs with the same priority whose subjects overlap.  Pods and namespaces are
+// read from the resources of the probe model file if one is given, and from kube otherwise.
+func PriorityConflicts(anps []*v1alpha1.AdminNetworkPolicy, modelPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) ([]*matcher.PriorityConflict, []*matcher.PolicyError) {
+	var pods []*matcher.PodResource
+	if modelPath != "" {
+		config, err := json.ParseFile[SyntheticProbeConnectivityConfig](modelPath)
+		utils.DoOrDie(err)
+		if config.Resources == nil {
+			logrus.Fatalf("%+v", errors.Errorf("no resources found in model file %s", modelPath))
+		}
+		pods = podResourcesFromProbeResources(config.Resources)
+	} else {
+		pods = podResourcesFromKube(kubePods, kubeNamespaces)
+	}
+	if len(pods) == 0 {
+		logrus.Warnf("no pods found: set --probe-path or read from kube with --namespace or --all-namespaces")
+	}
+
+	return matcher.PriorityConflicts(anps, pods)
+}
+
+func podResourcesFromProbeResources(resources *probe.Resources) []*matcher.PodResource {
+	// kube sets the name label on every namespace, so policies may rely on it even if the model file doesn't
+	nsLabels := map[string]map[string]string{}
+	for ns, labels := range resources.Namespaces {
+		nsLabels[ns] = map[string]string{v1.LabelMetadataName: ns}
+		for k, v := range labels {
+			nsLabels[ns][k] = v
+		}
+	}
+
+	var pods []*matcher.PodResource
+	for _, pod := range resources.Pods {
+		var ports []*matcher.PodPort
+		for _, cont := range pod.Containers {
+			for _, port := range cont.Ports {
+				ports = append(ports, &matcher.PodPort{Port: port.Port, Name: port.PortName, Protocol: port.Protocol})
+			}
+		}
+		pods = append(pods, &matcher.PodResource{
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			Labels:          pod.Labels,
+			NamespaceLabels: nsLabels[pod.Namespace],
+			IP:              pod.IP,
+			Ports:           ports,
+		})
+	}
+	return pods
+}
+
+func podResourcesFromKube(kubePods []v1.Pod, kubeNamespaces []v1.Namespace) []*matcher.PodResource {
+	nsLabels := map[string]map[string]string{}
+	for _, ns := range kubeNamespaces {
+		nsLabels[ns.Name] = ns.Labels
+	}
+
+	var pods []*matcher.PodResource
+	for _, pod := range kubePods {
+		var ports []*matcher.PodPort
+		for _, cont := range pod.Spec.Containers {
+			for _, port := range cont.Ports {
+				ports = append(ports, &matcher.PodPort{Port: int(port.ContainerPort), Name: port.Name, Protocol: port.Protocol})
+			}
+		}
+		pods = append(pods, &matcher.PodResource{
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			Labels:          pod.Labels,
+			NamespaceLabels: nsLabels[pod.Namespace],
+			IP:              pod.Status.PodIP,
+			Ports:           ports,
+		})
+	}
+	return pods
 }
 
 func ExplainPolicies(explainedPolicies *matcher.Policy) {
@@ -201,86 +561,172 @@ type SyntheticProbeConnectivityConfig struct {
 	Probes    []*generator.PortProtocol
 }
 
-func ProbeSyntheticConnectivity(explainedPolicies *matcher.Policy, modelPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) {
+// SyntheticProbe is the simulated connectivity for a probe on a port and protocol, or on all available ports
+// if PortProtocol is nil, over the IPs of a family.
+type SyntheticProbe struct {
+	PortProtocol *generator.PortProtocol
+	IPFamily     v1.IPFamily
+	Table        *probe.Table
+}
+
+func (p *SyntheticProbe) Result() *ProbeResult {
+	result := &ProbeResult{IPFamily: p.IPFamily, TableResult: p.Table.Result()}
+	if p.PortProtocol != nil {
+		result.Port = p.PortProtocol.Port.String()
+		result.Protocol = p.PortProtocol.Protocol
+	}
+	return result
+}
+
+// SyntheticProbes simulates the probes of the model file, if one is given, or probes all available ports of
+// the pods read from kube.  Each probe is run once per IP family of the pods.
+func SyntheticProbes(explainedPolicies *matcher.Policy, modelPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) []*SyntheticProbe {
+	jobBuilder := &probe.JobBuilder{TimeoutSeconds: 10}
+	simRunner := probe.NewSimulatedRunner(explainedPolicies, jobBuilder)
+	runProbe := func(portProtocol *generator.PortProtocol, probeConfig *generator.ProbeConfig, resources *probe.Resources) []*SyntheticProbe {
+		var probes []*SyntheticProbe
+		for _, t := range simRunner.RunProbeForConfigPerIPFamily(probeConfig, resources) {
+			probes = append(probes, &SyntheticProbe{PortProtocol: portProtocol, IPFamily: t.IPFamily, Table: t.Table})
+		}
+		return probes
+	}
+
 	if modelPath != "" {
 		config, err := json.ParseFile[SyntheticProbeConnectivityConfig](modelPath)
 		utils.DoOrDie(err)
 
-		jobBuilder := &probe.JobBuilder{TimeoutSeconds: 10}
-
 		if len(config.Probes) == 0 {
-			gen := generator.ProbeAllAvailable
-			simRunner := probe.NewSimulatedRunner(explainedPolicies, jobBuilder)
-
-			probeResult := simRunner.RunProbeForConfig(gen, config.Resources)
-
 			logrus.Info("probing all available ports")
-			fmt.Printf("Ingress:\n%s\n", probeResult.RenderIngress())
-			fmt.Printf("Egress:\n%s\n", probeResult.RenderEgress())
-			fmt.Printf("Combined:\n%s\n\n\n", probeResult.RenderTable())
-
-			return
+			return runProbe(nil, generator.ProbeAllAvailable, config.Resources)
 		}
 
 		// run probes
+		var probes []*SyntheticProbe
 		for _, probeConfig := range config.Probes {
 			gen := generator.NewProbeConfig(probeConfig.Port, probeConfig.Protocol, generator.ProbeModeServiceName)
-			simRunner := probe.NewSimulatedRunner(explainedPolicies, jobBuilder)
-			probeResult := simRunner.RunProbeForConfig(gen, config.Resources)
-
 			logrus.Infof("probe on port %s, protocol %s", probeConfig.Port.String(), probeConfig.Protocol)
-			fmt.Printf("Ingress:\n%s\n", probeResult.RenderIngress())
-			fmt.Printf("Egress:\n%s\n", probeResult.RenderEgress())
-			fmt.Printf("Combined:\n%s\n\n\n", probeResult.RenderTable())
+			probes = append(probes, runProbe(probeConfig, gen, config.Resources)...)
 		}
+		return probes
+	}
+
+	resources := probe.NewResourcesFromKubePods(kubePods, kubeNamespaces, false)
+	return runProbe(nil, generator.ProbeAllAvailable, resources)
+}
 
-		return
+// PrintSyntheticProbes prints the tables of each probe, naming the IP family of each if there's more than one.
+func PrintSyntheticProbes(probes []*SyntheticProbe) {
+	families := map[v1.IPFamily]bool{}
+	for _, p := range probes {
+		families[p.IPFamily] = true
+	}
+	for _, p := range probes {
+		if len(families) > 1 {
+			fmt.Printf("%s:\n", p.IPFamily)
+		}
+		fmt.Printf("Ingress:\n%s\n", p.Table.RenderIngress())
+		fmt.Printf("Egress:\n%s\n", p.Table.RenderEgress())
+		fmt.Printf("Combined:\n%s\n\n\n", p.Table.RenderTable())
 	}
+}
 
-	resources := &probe.Resources{
-		Namespaces: map[string]map[string]string{},
-		Pods:       []*probe.Pod{},
+func ProbeSyntheticConnectivity(explainedPolicies *matcher.Policy, modelPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) {
+	PrintSyntheticProbes(SyntheticProbes(explainedPolicies, modelPath, kubePods, kubeNamespaces))
+}
+
+// Reachability evaluates the policies for traffic between every pair of pods read from kube, on the
+// container ports declared by the destination pod.  It returns nil if there are no such pods.
+func Reachability(policies *matcher.Policy, kubePods []v1.Pod, kubeNamespaces []v1.Namespace, groupByWorkload bool) *probe.Table {
+	resources := probe.NewResourcesFromKubePods(kubePods, kubeNamespaces, groupByWorkload)
+	if len(resources.Pods) == 0 {
+		logrus.Warnf("no pods with declared container ports found: read from kube with --namespace or --all-namespaces")
+		return nil
 	}
 
-	nsMap := map[string]v1.Namespace{}
-	for _, ns := range kubeNamespaces {
-		nsMap[ns.Name] = ns
-		resources.Namespaces[ns.Name] = ns.Labels
+	simRunner := probe.NewSimulatedRunner(policies, &probe.JobBuilder{TimeoutSeconds: 10})
+	return simRunner.RunProbeForConfig(generator.ProbeAllAvailable, resources)
+}
+
+// DiffPolicies finds the traffic between pods whose verdict differs between two sets of policies.  It returns nil
+// if there are no pods.
+// Pods and namespaces are read from the resources of the probe model file if one is given, and from kube otherwise.
+func DiffPolicies(before *matcher.Policy, after *matcher.Policy, modelPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) *DiffResult {
+	resources := probe.NewResourcesFromKubePods(kubePods, kubeNamespaces, false)
+	if modelPath != "" {
+		config, err := json.ParseFile[SyntheticProbeConnectivityConfig](modelPath)
+		utils.DoOrDie(err)
+		resources = config.Resources
+	}
+	if resources == nil || len(resources.Pods) == 0 {
+		logrus.Warnf("no pods found: set --probe-path or read from kube with --namespace, --all-namespaces or --snapshot")
+		return nil
 	}
 
-	for _, pod := range kubePods {
-		var containers []*probe.Container
-		for _, cont := range pod.Spec.Containers {
-			if len(cont.Ports) == 0 {
-				logrus.Warnf("skipping container %s/%s/%s, no ports available", pod.Namespace, pod.Name, cont.Name)
-				continue
-			}
-			port := cont.Ports[0]
-			containers = append(containers, &probe.Container{
-				Name:     cont.Name,
-				Port:     int(port.ContainerPort),
-				Protocol: port.Protocol,
-				PortName: port.Name,
-			})
-		}
-		if len(containers) == 0 {
-			logrus.Warnf("skipping pod %s/%s, no containers available", pod.Namespace, pod.Name)
+	jobBuilder := &probe.JobBuilder{TimeoutSeconds: 10}
+	var jobs []*probe.Job
+	var traffic []*matcher.Traffic
+	for _, job := range jobBuilder.GetJobsAllAvailableServers(resources, generator.ProbeModeServiceName).Valid {
+		if job.FromKey == job.ToKey {
 			continue
 		}
-		resources.Pods = append(resources.Pods, &probe.Pod{
-			Namespace:  pod.Namespace,
-			Name:       pod.Name,
-			Labels:     pod.Labels,
-			IP:         pod.Status.PodIP,
-			Containers: containers,
+		jobs = append(jobs, job)
+		traffic = append(traffic, job.Traffic())
+	}
+	jobsByTraffic := map[*matcher.Traffic]*probe.Job{}
+	for i, t := range traffic {
+		jobsByTraffic[t] = jobs[i]
+	}
+
+	result := &DiffResult{TotalConnections: len(traffic), Changes: []*TrafficDiffResult{}}
+	for _, diff := range matcher.DiffTraffic(before, after, traffic) {
+		job := jobsByTraffic[diff.Traffic]
+		result.Changes = append(result.Changes, &TrafficDiffResult{
+			Source:      job.FromKey,
+			Destination: job.ToKey,
+			Port:        job.ResolvedPort,
+			Protocol:    job.Protocol,
+			Before:      matcher.NewVerdictResult(diff.Before),
+			After:       matcher.NewVerdictResult(diff.After),
 		})
 	}
+	return result
+}
+
+func DiffTable(diff *DiffResult) string {
+	summary := fmt.Sprintf("%d of %d connections changed\n", len(diff.Changes), diff.TotalConnections)
+	if len(diff.Changes) == 0 {
+		return summary
+	}
+
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"Source", "Destination", "Port/Protocol", "Before", "After"})
+	for _, change := range diff.Changes {
+		table.Append([]string{change.Source, change.Destination, fmt.Sprintf("%d/%s", change.Port, change.Protocol), verdictFlows(change.Before), verdictFlows(change.After)})
+	}
+
+	table.Render()
+	return summary + tableString.String()
+}
 
-	simRunner := probe.NewSimulatedRunner(explainedPolicies, &probe.JobBuilder{TimeoutSeconds: 10})
-	simulatedProbe := simRunner.RunProbeForConfig(generator.ProbeAllAvailable, resources)
-	fmt.Printf("Ingress:\n%s\n", simulatedProbe.RenderIngress())
-	fmt.Printf("Egress:\n%s\n", simulatedProbe.RenderEgress())
-	fmt.Printf("Combined:\n%s\n\n\n", simulatedProbe.RenderTable())
+func verdictFlows(result *matcher.VerdictResult) string {
+	ingressFlow, egressFlow := directionFlows(result)
+	return fmt.Sprintf("%s\ningress: %s\negress: %s", result.Verdict, ingressFlow, egressFlow)
+}
+
+func directionFlows(result *matcher.VerdictResult) (string, string) {
+	ingressFlow := result.Ingress.Flow
+	if ingressFlow == "" {
+		ingressFlow = "no policies targeting ingress"
+	}
+	egressFlow := result.Egress.Flow
+	if egressFlow == "" {
+		egressFlow = "no policies targeting egress"
+	}
+	return ingressFlow, egressFlow
 }
 
 func shouldIncludeANPandBANP(client *kubernetes.Clientset) (bool, bool) {
@@ -308,78 +754,54 @@ func shouldIncludeANPandBANP(client *kubernetes.Clientset) (bool, bool) {
 	return includeANP, includeBANP
 }
 
-func VerdictWalkthrough(policies *matcher.Policy, sourceWorkloadTraffic string, destinationWorkloadTraffic string, port int, protocol string, trafficPath string) {
+// trafficLookups returns the kube client and resolver for looking up the peers of traffic.  Without a kube client,
+// workloads, nodes and services are looked up in the default context, if there is one.
+func trafficLookups(kubeClient kube.IKubernetes, hostsFile string) (kube.IKubernetes, matcher.DomainNameResolver) {
+	var resolver matcher.DomainNameResolver
+	if hostsFile != "" {
+		hostsResolver, err := matcher.ReadHostsFile(hostsFile)
+		utils.DoOrDie(err)
+		resolver = hostsResolver
+	}
+	if kubeClient == nil {
+		client, err := kube.NewKubernetesForContext("")
+		if err != nil {
+			logrus.Debugf("unable to instantiate kube client for default context: %+v", err)
+		} else {
+			kubeClient = client
+		}
+	}
+	return kubeClient, resolver
+}
+
+// VerdictWalkthrough evaluates the policies for traffic read from a file, or between two workloads, or from a
+// workload to a service.
+func VerdictWalkthrough(kubeClient kube.IKubernetes, policies *matcher.Policy, sourceWorkloadTraffic string, destinationWorkloadTraffic string, destinationService string, port int, protocol string, trafficPath string, resolver matcher.DomainNameResolver) []*matcher.TrafficVerdictResult {
 	var sourceWorkloadInfo matcher.TrafficPeer
 	var destinationWorkloadInfo matcher.TrafficPeer
 	var allTraffic []*matcher.Traffic
 
-	if trafficPath != "" && (sourceWorkloadTraffic != "" || destinationWorkloadTraffic != "" || port != 0 || protocol != "") {
+	if trafficPath != "" && (sourceWorkloadTraffic != "" || destinationWorkloadTraffic != "" || destinationService != "" || port != 0 || protocol != "") {
 		logrus.Fatalf("%+v", errors.Errorf("If using traffic path, you can't input traffic via CLI and viceversa"))
-	} else if trafficPath == "" && (sourceWorkloadTraffic == "" || destinationWorkloadTraffic == "" || port == 0 || protocol == "") {
-		logrus.Fatalf("%+v", errors.Errorf("For this mode, you must either set --traffic-path or set all of --src-workload (<namespace>/<workloadType>/workloadName), --dst-workload (<namespace>/<workloadType>/workloadName), --port (integer from 0 to 65535) and --protocol (TCP, UDP and SCTP) parameters"))
+	} else if trafficPath == "" && (sourceWorkloadTraffic == "" || (destinationWorkloadTraffic == "") == (destinationService == "") || port == 0 || protocol == "") {
+		logrus.Fatalf("%+v", errors.Errorf("For this mode, you must either set --traffic-path or set all of --src-workload (<namespace>/<workloadType>/workloadName), one of --dst-workload (<namespace>/<workloadType>/workloadName) or --dst-service (<namespace>/<serviceName>), --port (integer from 0 to 65535) and --protocol (TCP, UDP and SCTP) parameters"))
 	}
 
 	if trafficPath != "" {
-		allTraffics, err := json.ParseFile[[]*matcher.Traffic](trafficPath)
-		utils.DoOrDie(err)
-		for _, traffic := range *allTraffics {
-			var podA, podB *matcher.TrafficPeer
-
-			// Determine source and destination peer information
-			sourceInternal := traffic.Source.Internal
-			destinationInternal := traffic.Destination.Internal
-
-			podA = matcher.CreateTrafficPeer(traffic.Source.IP, nil)
-			podB = matcher.CreateTrafficPeer(traffic.Destination.IP, nil)
-
-			// Update podA and podB if internal information is available
-			if sourceInternal != nil {
-				podA = matcher.CreateTrafficPeer(traffic.Source.IP, &matcher.InternalPeer{
-					PodLabels:       sourceInternal.PodLabels,
-					NamespaceLabels: sourceInternal.NamespaceLabels,
-					Namespace:       sourceInternal.Namespace,
-					Workload:        sourceInternal.Workload,
-				})
-			}
-
-			if destinationInternal != nil {
-				podB = matcher.CreateTrafficPeer(traffic.Destination.IP, &matcher.InternalPeer{
-					PodLabels:       destinationInternal.PodLabels,
-					NamespaceLabels: destinationInternal.NamespaceLabels,
-					Namespace:       destinationInternal.Namespace,
-					Workload:        destinationInternal.Workload,
-				})
-			}
-
-			// Special case handling for workload-specific traffic (internal vs. external)
-			if sourceInternal != nil {
-				if sourceInternal.Workload != "" {
-					podA = matcher.GetInternalPeerInfo(sourceInternal.Workload)
-				}
-			}
-
-			if destinationInternal != nil {
-				if destinationInternal.Workload != "" {
-					podB = matcher.GetInternalPeerInfo(destinationInternal.Workload)
-				}
-			}
-
-			// Append the resolved traffic to the allTraffic slice
-			allTraffic = append(allTraffic, matcher.CreateTraffic(podA, podB, traffic.ResolvedPort, string(traffic.Protocol)))
-		}
+		allTraffic = ReadTrafficFile(kubeClient, trafficPath, resolver)
 	} else {
 
 		if protocol != "TCP" && protocol != "UDP" && protocol != "SCTP" {
 			logrus.Fatalf("Bad Protocol Value: protocols supported are TCP, UDP and SCTP")
 		}
 
-		sourceWorkloadInfo = matcher.WorkloadStringToTrafficPeer(sourceWorkloadTraffic)
-		destinationWorkloadInfo = matcher.WorkloadStringToTrafficPeer(destinationWorkloadTraffic)
-
-		if sourceWorkloadInfo.Internal.Pods == nil || destinationWorkloadInfo.Internal.Pods == nil {
-			return
+		requireKubeClient(kubeClient, "workloads")
+		var err error
+		sourceWorkloadInfo, err = matcher.WorkloadStringToTrafficPeer(kubeClient, sourceWorkloadTraffic)
+		utils.DoOrDie(err)
+		if sourceWorkloadInfo.Internal.Pods == nil {
+			return nil
 		}
-
 		podA := &matcher.TrafficPeer{
 			Internal: &matcher.InternalPeer{
 				PodLabels:       sourceWorkloadInfo.Internal.PodLabels,
@@ -389,6 +811,30 @@ func VerdictWalkthrough(policies *matcher.Policy, sourceWorkloadTraffic string,
 			},
 			IP: sourceWorkloadInfo.Internal.Pods[0].IP,
 		}
+
+		if destinationService != "" {
+			serviceNamespace, serviceName, ok := strings.Cut(destinationService, "/")
+			if !ok || serviceNamespace == "" || serviceName == "" || strings.Contains(serviceName, "/") {
+				logrus.Fatalf("%+v", errors.Errorf("Bad Service structure: 2 fields are required with this structure, <namespace>/<serviceName>"))
+			}
+			allTraffic = []*matcher.Traffic{
+				{
+					Source:       podA,
+					Destination:  &matcher.TrafficPeer{Service: &matcher.ServicePeer{Namespace: serviceNamespace, Name: serviceName}},
+					ResolvedPort: port,
+					Protocol:     v1.Protocol(protocol),
+				},
+			}
+			return evaluateTraffic(kubeClient, policies, allTraffic)
+		}
+
+		destinationWorkloadInfo, err = matcher.WorkloadStringToTrafficPeer(kubeClient, destinationWorkloadTraffic)
+		utils.DoOrDie(err)
+
+		if destinationWorkloadInfo.Internal.Pods == nil {
+			return nil
+		}
+
 		podB := &matcher.TrafficPeer{
 			Internal: &matcher.InternalPeer{
 				PodLabels:       destinationWorkloadInfo.Internal.PodLabels,
@@ -408,6 +854,117 @@ func VerdictWalkthrough(policies *matcher.Policy, sourceWorkloadTraffic string,
 		}
 	}
 
+	return evaluateTraffic(kubeClient, policies, allTraffic)
+}
+
+// ReadTrafficFile reads traffic from a json file, looking up workloads, nodes and services in kube, and resolving
+// the hostnames and IPs of external peers.
+func ReadTrafficFile(kubeClient kube.IKubernetes, trafficPath string, resolver matcher.DomainNameResolver) []*matcher.Traffic {
+	var allTraffic []*matcher.Traffic
+	allTraffics, err := json.ParseFile[[]*matcher.Traffic](trafficPath)
+	utils.DoOrDie(err)
+	for _, traffic := range *allTraffics {
+		var podA, podB *matcher.TrafficPeer
+
+		// Determine source and destination peer information
+		sourceInternal := traffic.Source.Internal
+		destinationInternal := traffic.Destination.Internal
+
+		podA = matcher.CreateTrafficPeer(traffic.Source.IP, nil)
+		podB = matcher.CreateTrafficPeer(traffic.Destination.IP, nil)
+
+		// Update podA and podB if internal information is available
+		if sourceInternal != nil {
+			podA = matcher.CreateTrafficPeer(traffic.Source.IP, &matcher.InternalPeer{
+				PodLabels:       sourceInternal.PodLabels,
+				NamespaceLabels: sourceInternal.NamespaceLabels,
+				Namespace:       sourceInternal.Namespace,
+				Workload:        sourceInternal.Workload,
+			})
+		}
+
+		if destinationInternal != nil {
+			podB = matcher.CreateTrafficPeer(traffic.Destination.IP, &matcher.InternalPeer{
+				PodLabels:       destinationInternal.PodLabels,
+				NamespaceLabels: destinationInternal.NamespaceLabels,
+				Namespace:       destinationInternal.Namespace,
+				Workload:        destinationInternal.Workload,
+			})
+		}
+
+		// Special case handling for workload-specific traffic (internal vs. external)
+		if sourceInternal != nil {
+			if sourceInternal.Workload != "" {
+				requireKubeClient(kubeClient, sourceInternal.Workload)
+				podA, err = matcher.GetInternalPeerInfo(kubeClient, sourceInternal.Workload)
+				utils.DoOrDie(err)
+			}
+		}
+
+		if destinationInternal != nil {
+			if destinationInternal.Workload != "" {
+				requireKubeClient(kubeClient, destinationInternal.Workload)
+				podB, err = matcher.GetInternalPeerInfo(kubeClient, destinationInternal.Workload)
+				utils.DoOrDie(err)
+			}
+		}
+
+		// Node peers: fill in missing node labels/IP from the cluster
+		if traffic.Source.Node != nil {
+			podA, err = matcher.ResolveNodePeer(kubeClient, traffic.Source.Node, traffic.Source.IP)
+			utils.DoOrDie(err)
+		}
+		if traffic.Destination.Node != nil {
+			podB, err = matcher.ResolveNodePeer(kubeClient, traffic.Destination.Node, traffic.Destination.IP)
+			utils.DoOrDie(err)
+		}
+
+		// Service destinations are resolved to their backends when evaluating
+		if traffic.Destination.Service != nil {
+			requireKubeClient(kubeClient, "service "+traffic.Destination.Service.Namespace+"/"+traffic.Destination.Service.Name)
+			podB = &matcher.TrafficPeer{Service: traffic.Destination.Service}
+		}
+
+		// External peers: resolve hostname and IP against each other
+		if podA.IsExternal() && !podA.IsNode() {
+			podA.Hostname = traffic.Source.Hostname
+			utils.DoOrDie(matcher.ResolveHostname(resolver, podA))
+		}
+		if podB.IsExternal() && !podB.IsNode() && podB.Service == nil {
+			podB.Hostname = traffic.Destination.Hostname
+			utils.DoOrDie(matcher.ResolveHostname(resolver, podB))
+		}
+
+		// Append the resolved traffic to the allTraffic slice
+		resolved := matcher.CreateTraffic(podA, podB, traffic.ResolvedPort, string(traffic.Protocol))
+		resolved.ResolvedPortName = traffic.ResolvedPortName
+		allTraffic = append(allTraffic, resolved)
+	}
+	return allTraffic
+}
+
+func requireKubeClient(kubeClient kube.IKubernetes, what string) {
+	if kubeClient == nil {
+		logrus.Fatalf("%+v", errors.Errorf("unable to look up %s: no kube client, set --snapshot or configure a kube context", what))
+	}
+}
+
+func evaluateTraffic(kubeClient kube.IKubernetes, policies *matcher.Policy, allTraffic []*matcher.Traffic) []*matcher.TrafficVerdictResult {
+	var verdicts []*matcher.TrafficVerdictResult
+	for _, traffic := range allTraffic {
+		if traffic.Destination.Service != nil {
+			backends, err := matcher.ResolveServiceBackends(kubeClient, traffic)
+			utils.DoOrDie(err)
+			reachable, results := policies.IsServiceTrafficAllowed(backends)
+			verdicts = append(verdicts, matcher.NewServiceTrafficVerdictResult(traffic, reachable, backends, results))
+		} else {
+			verdicts = append(verdicts, matcher.NewTrafficVerdictResult(traffic, policies.IsTrafficAllowed(traffic)))
+		}
+	}
+	return verdicts
+}
+
+func VerdictWalkthroughTable(verdicts []*matcher.TrafficVerdictResult) string {
 	tableString := &strings.Builder{}
 	table := tablewriter.NewWriter(tableString)
 	table.SetAutoWrapText(false)
@@ -415,19 +972,19 @@ func VerdictWalkthrough(policies *matcher.Policy, sourceWorkloadTraffic string,
 	table.SetAutoMergeCells(true)
 
 	table.SetHeader([]string{"Traffic", "Verdict", "Ingress Walkthrough", "Egress Walkthrough"})
-	for _, traffic := range allTraffic {
-		trafficResult := policies.IsTrafficAllowed(traffic)
-		ingressFlow := trafficResult.Ingress.Flow()
-		egressFlow := trafficResult.Egress.Flow()
-		if ingressFlow == "" {
-			ingressFlow = "no policies targeting ingress"
-		}
-		if egressFlow == "" {
-			egressFlow = "no policies targeting egress"
+	for _, verdict := range verdicts {
+		if verdict.VerdictResult == nil {
+			table.Append([]string{verdict.Traffic, fmt.Sprintf("%s of %d endpoints reachable", verdict.Reachable, len(verdict.Backends)), "", ""})
+			for _, backend := range verdict.Backends {
+				ingressFlow, egressFlow := directionFlows(backend.VerdictResult)
+				table.Append([]string{"  endpoint: " + backend.Traffic, backend.Verdict, ingressFlow, egressFlow})
+			}
+			continue
 		}
-		table.Append([]string{traffic.PrettyString(), trafficResult.Verdict(), ingressFlow, egressFlow})
+		ingressFlow, egressFlow := directionFlows(verdict.VerdictResult)
+		table.Append([]string{verdict.Traffic, verdict.Verdict, ingressFlow, egressFlow})
 	}
 
 	table.Render()
-	fmt.Println(tableString.String())
+	return tableString.String()
 }
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattfenwick/cyclonus/pkg/convert"
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+type ConvertArgs struct {
+	PolicyPath    string
+	OutputPath    string
+	SnapshotPath  string
+	AllNamespaces bool
+	Namespaces    []string
+	Context       string
+	Timeout       time.Duration
+}
+
+func SetupConvertCommand() *cobra.Command {
+	args := &ConvertArgs{}
+
+	command := &cobra.Command{
+		Use:   "convert",
+		Short: "propose ANPs and a BANP equivalent to a set of NetworkPolicies",
+		Long: `Propose AdminNetworkPolicies, with priorities, and a BaselineAdminNetworkPolicy which are equivalent to a
+set of NetworkPolicies, so that per-namespace policies can be lifted into central ones.
+NetworkPolicy constructs which ANPs and BANPs can't express are reported.  Equivalence is checked by simulating
+traffic between the pods of the snapshot or cluster, on their container ports, with and without the conversion.`,
+		Args: cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			utils.DoOrDie(RunConvertCommand(args))
+		},
+	}
+
+	command.Flags().StringVar(&args.PolicyPath, "policy-path", "", "may be a file or a directory; if set, reads policies from the path in addition to those of the snapshot or cluster")
+	command.Flags().StringVarP(&args.OutputPath, "output", "o", "", "path to write the proposed ANPs and BANP to")
+	utils.DoOrDie(command.MarkFlagRequired("output"))
+	command.Flags().StringVar(&args.SnapshotPath, "snapshot", "", "path to a file written by 'cyclonus snapshot'; if set, kube resources are read from it instead of from a cluster")
+	command.Flags().BoolVarP(&args.AllNamespaces, "all-namespaces", "A", false, "reads kube resources from all namespaces; same as kubectl's '--all-namespaces'/'-A' flag")
+	command.Flags().StringSliceVarP(&args.Namespaces, "namespace", "n", []string{}, "namespaces to read kube resources from; similar to kubectl's '--namespace'/'-n' flag, except that multiple namespaces may be passed in")
+	command.Flags().StringVar(&args.Context, "context", "", "kubernetes context to use; if empty, uses default context")
+	command.Flags().DurationVar(&args.Timeout, "kube-client-timeout", DefaultTimeout, "kube client timeout")
+
+	return command
+}
+
+// RunConvertCommand writes the proposed ANPs and BANP to the output path.  It returns an error if the conversion
+// changes the verdict of any traffic between the pods of the snapshot or cluster.
+func RunConvertCommand(args *ConvertArgs) error {
+	snapshot := readSnapshotOrCluster(args.SnapshotPath, args.AllNamespaces, args.Namespaces, args.Context, args.Timeout)
+	if snapshot == nil {
+		if args.PolicyPath == "" {
+			logrus.Fatalf("%+v", errors.Errorf("no policies to convert: set --policy-path, --snapshot, --namespace or --all-namespaces"))
+		}
+		snapshot = &kube.Snapshot{Version: kube.SnapshotVersion}
+	}
+
+	var netpols []*networkingv1.NetworkPolicy
+	for i := range snapshot.NetworkPolicies {
+		netpols = append(netpols, &snapshot.NetworkPolicies[i])
+	}
+	var anps []*v1alpha1.AdminNetworkPolicy
+	for i := range snapshot.AdminNetworkPolicies {
+		anps = append(anps, &snapshot.AdminNetworkPolicies[i])
+	}
+	banp := snapshot.BaselineAdminNetworkPolicy
+	if args.PolicyPath != "" {
+		netpolsFromPath, anpsFromPath, banpFromPath, err := kube.ReadNetworkPoliciesFromPath(args.PolicyPath)
+		utils.DoOrDie(err)
+		netpols = append(netpols, netpolsFromPath...)
+		anps = append(anps, anpsFromPath...)
+		if banpFromPath != nil {
+			banp = banpFromPath
+		}
+	}
+
+	result, policyErrors, err := convert.ConvertNetworkPolicies(netpols, anps, banp)
+	utils.DoOrDie(err)
+	if len(policyErrors) > 0 {
+		fmt.Println("invalid policies (skipped):")
+		fmt.Println(PolicyErrorsTable(policyErrors))
+	}
+	if len(result.Issues) > 0 {
+		fmt.Println("constructs which can't be converted (left out):")
+		fmt.Println(ConvertIssuesTable(result.Issues))
+	}
+
+	if _, convertedErrors := matcher.BuildV1AndV2NetPols(false, nil, result.AdminNetworkPolicies, result.BaselineAdminNetworkPolicy); len(convertedErrors) > 0 {
+		logrus.Fatalf("%+v", errors.Errorf("converted policies are invalid: %s", convertedErrors[0].Error()))
+	}
+	convertedBANP := banp
+	if result.BaselineAdminNetworkPolicy != nil {
+		convertedBANP = result.BaselineAdminNetworkPolicy
+	}
+	before, _ := matcher.BuildV1AndV2NetPols(false, netpols, anps, banp)
+	after, _ := matcher.BuildV1AndV2NetPols(false, nil, slices.Concat(anps, result.AdminNetworkPolicies), convertedBANP)
+	diff := DiffPolicies(before, after, "", snapshot.Pods, snapshot.Namespaces)
+	if diff != nil {
+		fmt.Println("equivalence over the pods of the snapshot or cluster:")
+		fmt.Println(DiffTable(diff))
+	}
+
+	var documents []string
+	for _, anp := range result.AdminNetworkPolicies {
+		documents = append(documents, utils.YamlString(anp))
+	}
+	if result.BaselineAdminNetworkPolicy != nil {
+		documents = append(documents, utils.YamlString(result.BaselineAdminNetworkPolicy))
+	}
+	if err := os.WriteFile(args.OutputPath, []byte(strings.Join(documents, "---\n")), 0644); err != nil {
+		return errors.Wrapf(err, "unable to write %s", args.OutputPath)
+	}
+	logrus.Infof("wrote %d policies converted from %d NetworkPolicies to %s", len(documents), len(netpols), args.OutputPath)
+
+	if diff != nil && len(diff.Changes) > 0 {
+		return errors.Errorf("converted policies aren't equivalent: %d of %d connections changed", len(diff.Changes), diff.TotalConnections)
+	}
+	return nil
+}
+
+func ConvertIssuesTable(issues []*convert.Issue) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"Policy", "Field", "Reason"})
+	for _, issue := range issues {
+		table.Append([]string{issue.Namespace + "/" + issue.Name, issue.Field, issue.Reason})
+	}
+
+	table.Render()
+	return tableString.String()
+}
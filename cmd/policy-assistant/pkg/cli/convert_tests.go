@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// writeConvertInputs writes a snapshot of pods x/a and x/b, serving on port 80, and a file with the policy.
+func writeConvertInputs(policy string) *ConvertArgs {
+	snapshot := &kube.Snapshot{
+		Version:    kube.SnapshotVersion,
+		Namespaces: []v1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "x", Labels: map[string]string{kube.DefaultNamespaceLabel: "x"}}}},
+	}
+	for i, name := range []string{"a", "b"} {
+		ip := []string{"10.0.0.1", "10.0.0.2"}[i]
+		snapshot.Pods = append(snapshot.Pods, v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: name, Labels: map[string]string{"pod": name}},
+			Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name:  "cont",
+				Ports: []v1.ContainerPort{{Name: "serve-80-tcp", ContainerPort: 80, Protocol: v1.ProtocolTCP}},
+			}}},
+			Status: v1.PodStatus{Phase: v1.PodRunning, PodIP: ip, PodIPs: []v1.PodIP{{IP: ip}}},
+		})
+	}
+
+	dir := GinkgoT().TempDir()
+	args := &ConvertArgs{
+		PolicyPath:   filepath.Join(dir, "policies.yaml"),
+		OutputPath:   filepath.Join(dir, "converted.yaml"),
+		SnapshotPath: filepath.Join(dir, "snapshot.json"),
+	}
+	Expect(snapshot.WriteFile(args.SnapshotPath)).To(Succeed())
+	Expect(os.WriteFile(args.PolicyPath, []byte(policy), 0644)).To(Succeed())
+	return args
+}
+
+func RunConvertCommandTests() {
+	Describe("Convert command", func() {
+		It("Should write equivalent policies", func() {
+			args := writeConvertInputs(`apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-from-a
+  namespace: x
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - podSelector:
+        matchLabels:
+          pod: a
+`)
+			Expect(RunConvertCommand(args)).To(Succeed())
+			Expect(args.OutputPath).To(BeAnExistingFile())
+		})
+
+		It("Should fail if the conversion changes verdicts", func() {
+			args := writeConvertInputs(`apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-from-ip-block
+  namespace: x
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - ipBlock:
+        cidr: 10.0.0.0/8
+`)
+			err := RunConvertCommand(args)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("converted policies aren't equivalent"))
+			Expect(args.OutputPath).To(BeAnExistingFile())
+		})
+	})
+}
@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/cyclonus/pkg/connectivity"
+	"github.com/mattfenwick/cyclonus/pkg/connectivity/probe"
+	"github.com/mattfenwick/cyclonus/pkg/fuzz"
+	"github.com/mattfenwick/cyclonus/pkg/generator"
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type FuzzArgs struct {
+	Seed                      int64
+	Cases                     int
+	MaxSteps                  int
+	MaxActionsPerStep         int
+	AdminNetworkPolicies      bool
+	Shrink                    bool
+	OutputDirectory           string
+	Replay                    string
+	Noisy                     bool
+	IgnoreLoopback            bool
+	PerturbationWaitSeconds   int
+	PodCreationTimeoutSeconds int
+	Retries                   int
+	Context                   string
+	ServerPorts               []int
+	ServerProtocols           []string
+	ServerNamespaces          []string
+	ServerPods                []string
+	CleanupNamespaces         bool
+	FailFast                  bool
+	Mock                      bool
+	JobTimeoutSeconds         int
+	JunitResultsFile          string
+	ImageRegistry             string
+	DeleteExistingBANP        bool
+}
+
+func SetupFuzzCommand() *cobra.Command {
+	args := &FuzzArgs{}
+
+	command := &cobra.Command{
+		Use:   "fuzz",
+		Short: "generate random network policies and compare the CNI to cyclonus",
+		Long:  "generate random stacks of network policies, ANPs and BANPs along with label changes, create and probe against kubernetes, and compare to expected results.  Failing test cases are shrunk and saved so they can be replayed",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunFuzzCommand(args)
+		},
+	}
+
+	command.Flags().Int64Var(&args.Seed, "seed", 0, "seed for the random generator; test case i uses seed+i.  If 0, a time-based seed is picked")
+	command.Flags().IntVar(&args.Cases, "cases", 20, "number of random test cases to run")
+	command.Flags().IntVar(&args.MaxSteps, "max-steps", 3, "maximum number of steps in a test case")
+	command.Flags().IntVar(&args.MaxActionsPerStep, "max-actions-per-step", 3, "maximum number of actions in a test case step")
+	command.Flags().BoolVar(&args.AdminNetworkPolicies, "admin-network-policies", true, "if true, generate ANPs and BANPs; requires their CRDs to be installed")
+	command.Flags().BoolVar(&args.Shrink, "shrink", true, "if true, shrink failing test cases to a minimal reproducer")
+	command.Flags().StringVar(&args.OutputDirectory, "output-dir", ".", "directory to save failing test cases to, as fuzz-<seed>.yaml")
+	command.Flags().StringVar(&args.Replay, "replay", "", "if set, run the test cases from this file instead of generating random ones")
+
+	command.Flags().StringSliceVar(&args.ServerProtocols, "server-protocol", []string{"TCP", "UDP", "SCTP"}, "protocols to run server on")
+	command.Flags().IntSliceVar(&args.ServerPorts, "server-port", []int{80, 81}, "ports to run server on")
+	command.Flags().StringSliceVar(&args.ServerNamespaces, "namespace", []string{"x", "y", "z"}, "namespaces to create/use pods in")
+	command.Flags().StringSliceVar(&args.ServerPods, "pod", []string{"a", "b", "c"}, "pods to create in namespaces")
+
+	command.Flags().IntVar(&args.Retries, "retries", 1, "number of kube probe retries to allow, if probe fails")
+	command.Flags().BoolVar(&args.Noisy, "noisy", false, "if true, print all results")
+	command.Flags().BoolVar(&args.IgnoreLoopback, "ignore-loopback", false, "if true, ignore loopback for truthtable correctness verification")
+	command.Flags().IntVar(&args.PerturbationWaitSeconds, "perturbation-wait-seconds", 5, "number of seconds to wait after perturbing the cluster (i.e. create a network policy, modify a ns/pod label) before running probes, to give the CNI time to update the cluster state")
+	command.Flags().IntVar(&args.PodCreationTimeoutSeconds, "pod-creation-timeout-seconds", 60, "number of seconds to wait for pods to create, be running and have IP addresses")
+	command.Flags().StringVar(&args.Context, "context", "", "kubernetes context to use; if empty, uses default context")
+	command.Flags().BoolVar(&args.CleanupNamespaces, "cleanup-namespaces", false, "if true, clean up namespaces after completion")
+	command.Flags().BoolVar(&args.FailFast, "fail-fast", false, "if true, stop running tests after the first failure")
+	command.Flags().IntVar(&args.JobTimeoutSeconds, "job-timeout-seconds", 10, "number of seconds to pass on to 'agnhost connect --timeout=%ds' flag")
+
+	command.Flags().BoolVar(&args.DeleteExistingBANP, "delete-existing-banp", false, "if true, delete a BaselineAdminNetworkPolicy which cyclonus didn't create, so that test cases can create their own; otherwise, only ANPs and BANPs created by cyclonus are deleted")
+
+	command.Flags().BoolVar(&args.Mock, "mock", false, "if true, use a mock kube runner (i.e. don't actually run tests against kubernetes; instead, product fake results")
+
+	command.Flags().StringVar(&args.JunitResultsFile, "junit-results-file", "", "output junit results to the specified file")
+	command.Flags().StringVar(&args.ImageRegistry, "image-registry", "registry.k8s.io", "Image registry for agnhost")
+
+	return command
+}
+
+func RunFuzzCommand(args *FuzzArgs) {
+	if args.Seed == 0 {
+		args.Seed = time.Now().UnixNano()
+	}
+	fmt.Printf("args: \n%s\n", json.MustMarshalToString(args))
+
+	RunVersionCommand()
+
+	if args.MaxSteps < 1 || args.MaxActionsPerStep < 1 {
+		logrus.Fatalf("%+v", errors.Errorf("max-steps and max-actions-per-step must be at least 1"))
+	}
+
+	externalIPs := []string{}
+
+	var kubernetes kube.IKubernetes
+	if args.Mock {
+		kubernetes = kube.NewMockKubernetes(1.0)
+	} else {
+		kubeClient, err := kube.NewKubernetesForContext(args.Context)
+		utils.DoOrDie(err)
+		info, err := kubeClient.ClientSet.ServerVersion()
+		utils.DoOrDie(err)
+		fmt.Printf("Kubernetes server version: \n%s\n", json.MustMarshalToString(info))
+		kubernetes = kubeClient
+	}
+
+	serverProtocols := parseProtocols(args.ServerProtocols)
+
+	batchJobs := false
+	resources, err := probe.NewDefaultResources(kubernetes, args.ServerNamespaces, args.ServerPods, args.ServerPorts, serverProtocols, externalIPs, args.PodCreationTimeoutSeconds, batchJobs, args.ImageRegistry)
+	utils.DoOrDie(err)
+
+	interpreterConfig := &connectivity.InterpreterConfig{
+		ResetClusterBeforeTestCase:       true,
+		KubeProbeRetries:                 args.Retries,
+		PerturbationWaitSeconds:          args.PerturbationWaitSeconds,
+		VerifyClusterStateBeforeTestCase: true,
+		BatchJobs:                        batchJobs,
+		IgnoreLoopback:                   args.IgnoreLoopback,
+		JobTimeoutSeconds:                args.JobTimeoutSeconds,
+		FailFast:                         args.FailFast,
+		DeleteExistingBANP:               args.DeleteExistingBANP,
+	}
+	interpreter := connectivity.NewInterpreter(kubernetes, resources, interpreterConfig)
+	printer := &connectivity.Printer{
+		Noisy:            args.Noisy,
+		IgnoreLoopback:   args.IgnoreLoopback,
+		JunitResultsFile: args.JunitResultsFile,
+	}
+
+	config := &fuzz.Config{
+		Namespaces:           args.ServerNamespaces,
+		Pods:                 args.ServerPods,
+		Ports:                args.ServerPorts,
+		Protocols:            serverProtocols,
+		AdminNetworkPolicies: args.AdminNetworkPolicies,
+		MaxSteps:             args.MaxSteps,
+		MaxActionsPerStep:    args.MaxActionsPerStep,
+	}
+
+	var testCases []*generator.TestCase
+	if args.Replay != "" {
+		testCases, err = generator.ReadTestCasesFromPath(args.Replay)
+		utils.DoOrDie(err)
+	} else {
+		for i := 0; i < args.Cases; i++ {
+			testCases = append(testCases, fuzz.TestCase(config, args.Seed+int64(i)))
+		}
+	}
+
+	// a test case which errors -- for example, because a shrunk test case updates a policy that it no longer
+	// creates -- doesn't count as failing
+	fails := func(testCase *generator.TestCase) bool {
+		result := interpreter.ExecuteTestCase(testCase)
+		return result.Err == nil && !result.Passed(args.IgnoreLoopback)
+	}
+
+	for i, testCase := range testCases {
+		fmt.Printf("starting test case #%d: %s\n", i+1, testCase.Description)
+
+		result := interpreter.ExecuteTestCase(testCase)
+		utils.DoOrDie(result.Err)
+
+		if !result.Passed(args.IgnoreLoopback) && args.Replay == "" {
+			if args.Shrink {
+				logrus.Infof("shrinking failing test case '%s'", testCase.Description)
+				testCase = fuzz.Shrink(testCase, fails)
+				result = interpreter.ExecuteTestCase(testCase)
+				utils.DoOrDie(result.Err)
+			}
+			path := filepath.Join(args.OutputDirectory, fmt.Sprintf("fuzz-%d.yaml", args.Seed+int64(i)))
+			utils.DoOrDie(generator.WriteTestCasesToPath(path, []*generator.TestCase{testCase}))
+			fmt.Printf("saved failing test case to %s; replay it with 'cyclonus fuzz --replay %s'\n", path, path)
+		}
+
+		printer.PrintTestCaseResult(result)
+		fmt.Printf("finished test case #%d\n", i+1)
+
+		if args.FailFast && !(result.Passed(args.IgnoreLoopback) && result.PassedExpected(args.IgnoreLoopback)) {
+			logrus.Warn("failing fast due to failure")
+			break
+		}
+	}
+
+	printer.PrintSummary()
+
+	if args.CleanupNamespaces {
+		for _, ns := range args.ServerNamespaces {
+			logrus.Infof("cleaning up namespace %s", ns)
+			err = kubernetes.DeleteNamespace(ns)
+			if err != nil {
+				logrus.Warnf("%+v", err)
+			}
+		}
+	}
+}
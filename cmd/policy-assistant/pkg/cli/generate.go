@@ -20,7 +20,8 @@ var (
 		generator.TagUpstreamE2E,
 		generator.TagExample,
 		generator.TagEndPort,
-		generator.TagNamespacesByDefaultLabel}
+		generator.TagNamespacesByDefaultLabel,
+		generator.TagANP}
 )
 
 type GenerateArgs struct {
@@ -45,6 +46,9 @@ type GenerateArgs struct {
 	JobTimeoutSeconds         int
 	JunitResultsFile          string
 	ImageRegistry             string
+	DeleteExistingBANP        bool
+	TestCaseFile              string
+	DumpTestCases             string
 	//BatchJobs                 bool
 }
 
@@ -80,7 +84,12 @@ func SetupGenerateCommand() *cobra.Command {
 	command.Flags().IntVar(&args.JobTimeoutSeconds, "job-timeout-seconds", 10, "number of seconds to pass on to 'agnhost connect --timeout=%ds' flag")
 
 	command.Flags().StringSliceVar(&args.Include, "include", []string{}, "include tests with any of these tags; if empty, all tests will be included.  Valid tags:\n"+strings.Join(generator.TagSlice, "\n"))
-	command.Flags().StringSliceVar(&args.Exclude, "exclude", DefaultExcludeTags, "exclude tests with any of these tags.  See 'include' field for valid tags")
+	command.Flags().StringSliceVar(&args.Exclude, "exclude", DefaultExcludeTags, "exclude tests with any of these tags, unless they're also included.  See 'include' field for valid tags")
+
+	command.Flags().StringVar(&args.TestCaseFile, "test-case-file", "", "if set, run the test cases from this file instead of the built-in ones; include and exclude are ignored")
+	command.Flags().StringVar(&args.DumpTestCases, "dump-test-cases", "", "if set, write the selected built-in test cases to this file instead of running them")
+
+	command.Flags().BoolVar(&args.DeleteExistingBANP, "delete-existing-banp", false, "if true, delete a BaselineAdminNetworkPolicy which cyclonus didn't create, so that test cases can create their own; otherwise, only ANPs and BANPs created by cyclonus are deleted")
 
 	command.Flags().BoolVar(&args.Mock, "mock", false, "if true, use a mock kube runner (i.e. don't actually run tests against kubernetes; instead, product fake results")
 	command.Flags().BoolVar(&args.DryRun, "dry-run", false, "if true, don't actually do anything: just print out what would be done")
@@ -127,6 +136,7 @@ func RunGenerateCommand(args *GenerateArgs) {
 		IgnoreLoopback:                   args.IgnoreLoopback,
 		JobTimeoutSeconds:                args.JobTimeoutSeconds,
 		FailFast:                         args.FailFast,
+		DeleteExistingBANP:               args.DeleteExistingBANP,
 	}
 	interpreter := connectivity.NewInterpreter(kubernetes, resources, interpreterConfig)
 	printer := &connectivity.Printer{
@@ -138,9 +148,14 @@ func RunGenerateCommand(args *GenerateArgs) {
 	zcPod, err := resources.GetPod("z", "c")
 	utils.DoOrDie(err)
 
-	testCaseGenerator := generator.NewTestCaseGenerator(args.AllowDNS, zcPod.IP, args.ServerNamespaces, args.Include, args.Exclude)
-
-	testCases := testCaseGenerator.GenerateTestCases()
+	var testCases []*generator.TestCase
+	if args.TestCaseFile != "" {
+		testCases, err = generator.ReadTestCasesFromPath(args.TestCaseFile)
+		utils.DoOrDie(err)
+	} else {
+		testCaseGenerator := generator.NewTestCaseGenerator(args.AllowDNS, zcPod.IP, args.ServerNamespaces, args.Include, args.Exclude)
+		testCases = testCaseGenerator.GenerateTestCases()
+	}
 	fmt.Printf("test cases to run by tag:\n")
 	for tag, count := range generator.CountTestCasesByTag(testCases) {
 		fmt.Printf("- %s: %d\n", tag, count)
@@ -150,6 +165,12 @@ func RunGenerateCommand(args *GenerateArgs) {
 		fmt.Printf("test #%d: %s\n - tags: %+v\n", i+1, testCase.Description, strings.Join(testCase.Tags.Keys(), ", "))
 	}
 
+	if args.DumpTestCases != "" {
+		utils.DoOrDie(generator.WriteTestCasesToPath(args.DumpTestCases, testCases))
+		fmt.Printf("wrote %d test cases to %s\n", len(testCases), args.DumpTestCases)
+		return
+	}
+
 	if args.DryRun {
 		return
 	}
@@ -173,7 +194,7 @@ func RunGenerateCommand(args *GenerateArgs) {
 		printer.PrintTestCaseResult(result)
 		fmt.Printf("finished policy #%d\n", i+1)
 
-		if args.FailFast && !result.Passed(interpreter.Config.IgnoreLoopback) {
+		if args.FailFast && !(result.Passed(interpreter.Config.IgnoreLoopback) && result.PassedExpected(interpreter.Config.IgnoreLoopback)) {
 			logrus.Warn("failing fast due to failure")
 			break
 		}
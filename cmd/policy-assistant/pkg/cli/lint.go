@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/cyclonus/pkg/lint"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+const SarifOutput = "sarif"
+
+var AllLintOutputFormats = []string{TableOutput, SarifOutput}
+
+type LintArgs struct {
+	PolicyPath string
+	Output     string
+}
+
+func SetupLintCommand() *cobra.Command {
+	args := &LintArgs{}
+
+	command := &cobra.Command{
+		Use:   "lint",
+		Short: "report problems with policy files, along with the lines they're on",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunLintCommand(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.PolicyPath, "policy-path", "", "may be a file or a directory; policies to lint")
+	utils.DoOrDie(command.MarkFlagRequired("policy-path"))
+	command.Flags().StringVarP(&args.Output, "output", "o", TableOutput, "output format; allowed values are "+strings.Join(AllLintOutputFormats, ","))
+
+	return command
+}
+
+func RunLintCommand(args *LintArgs) {
+	if !slices.Contains(AllLintOutputFormats, args.Output) {
+		logrus.Fatalf("%+v", errors.Errorf("invalid output format %s; allowed values are %s", args.Output, strings.Join(AllLintOutputFormats, ",")))
+	}
+
+	findings, err := lint.LintPath(args.PolicyPath)
+	utils.DoOrDie(err)
+
+	switch args.Output {
+	case SarifOutput:
+		fmt.Println(json.MustMarshalToString(lint.NewSarifLog(findings, version)))
+	default:
+		fmt.Println(FindingsTable(findings))
+	}
+}
+
+func FindingsTable(findings []*lint.Finding) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"Level", "Rule", "Location", "Message"})
+	for _, finding := range findings {
+		table.Append([]string{string(finding.Rule.Level), finding.Rule.ID, finding.Location(), finding.Message})
+	}
+
+	table.Render()
+	return tableString.String()
+}
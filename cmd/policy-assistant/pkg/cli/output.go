@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"encoding/json"
+
+	"github.com/mattfenwick/cyclonus/pkg/connectivity/probe"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	TableOutput = "table"
+	JSONOutput  = "json"
+	YAMLOutput  = "yaml"
+)
+
+var AllOutputFormats = []string{TableOutput, JSONOutput, YAMLOutput}
+
+// AnalyzeResultsVersion is the version of the AnalyzeResults format.
+// Fields may be added within a version; it's bumped if fields are removed or change meaning.
+const AnalyzeResultsVersion = "v1"
+
+// AnalyzeResults is the output of 'cyclonus analyze' for the json and yaml output formats.
+// Only the fields of the modes that were run are set.
+type AnalyzeResults struct {
+	Version           string                          `json:"version"`
+	InvalidPolicies   []*PolicyErrorResult            `json:"invalidPolicies,omitempty"`
+	Explanation       []*matcher.ExplanationResult    `json:"explanation,omitempty"`
+	QueryTarget       []*QueryTargetResult            `json:"queryTarget,omitempty"`
+	QueryTraffic      []*matcher.TrafficVerdictResult `json:"queryTraffic,omitempty"`
+	Probe             []*ProbeResult                  `json:"probe,omitempty"`
+	Walkthrough       []*matcher.TrafficVerdictResult `json:"walkthrough,omitempty"`
+	ShadowedRules     []*ShadowedRuleResult           `json:"shadowedRules,omitempty"`
+	PriorityConflicts *PriorityConflictsResult        `json:"priorityConflicts,omitempty"`
+	Reachability      *probe.TableResult              `json:"reachability,omitempty"`
+	Diff              *DiffResult                     `json:"diff,omitempty"`
+}
+
+func NewAnalyzeResults() *AnalyzeResults {
+	return &AnalyzeResults{Version: AnalyzeResultsVersion}
+}
+
+// Render serializes the results in the given format, which must be json or yaml.
+func (r *AnalyzeResults) Render(format string) (string, error) {
+	switch format {
+	case JSONOutput:
+		bytes, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to marshal json")
+		}
+		return string(bytes), nil
+	case YAMLOutput:
+		bytes, err := yaml.Marshal(r)
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to marshal yaml")
+		}
+		return string(bytes), nil
+	default:
+		return "", errors.Errorf("unable to render results as %s: allowed formats are %s and %s", format, JSONOutput, YAMLOutput)
+	}
+}
+
+type PolicyErrorResult struct {
+	Kind   matcher.PolicyKind `json:"kind"`
+	Policy string             `json:"policy"`
+	// RuleIndex is nil if the error isn't in a rule
+	RuleIndex *int   `json:"ruleIndex,omitempty"`
+	Field     string `json:"field"`
+	Error     string `json:"error"`
+}
+
+func NewPolicyErrorResults(policyErrors []*matcher.PolicyError) []*PolicyErrorResult {
+	var results []*PolicyErrorResult
+	for _, err := range policyErrors {
+		result := &PolicyErrorResult{
+			Kind:   err.Kind,
+			Policy: err.PolicyName(),
+			Field:  err.Field(),
+			Error:  err.Err.ErrorBody(),
+		}
+		if err.RuleIndex != matcher.NoRuleIndex {
+			ruleIndex := err.RuleIndex
+			result.RuleIndex = &ruleIndex
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+type ProbeResult struct {
+	// Port and Protocol are empty if all available ports were probed
+	Port     string      `json:"port,omitempty"`
+	Protocol v1.Protocol `json:"protocol,omitempty"`
+	// IPFamily is empty if the IPs of the pods aren't known
+	IPFamily v1.IPFamily `json:"ipFamily,omitempty"`
+	*probe.TableResult
+}
+
+type AdminRuleResult struct {
+	Kind   matcher.PolicyKind `json:"kind"`
+	Policy string             `json:"policy"`
+	// Priority is only set for ANPs
+	Priority  int             `json:"priority,omitempty"`
+	RuleIndex int             `json:"ruleIndex"`
+	RuleName  string          `json:"ruleName"`
+	Verdict   matcher.Verdict `json:"verdict"`
+}
+
+func NewAdminRuleResult(rule *matcher.AdminRule) *AdminRuleResult {
+	return &AdminRuleResult{
+		Kind:      rule.Kind,
+		Policy:    rule.PolicyName,
+		Priority:  rule.Priority,
+		RuleIndex: rule.RuleIndex,
+		RuleName:  rule.RuleName,
+		Verdict:   rule.Verdict,
+	}
+}
+
+type ShadowedRuleResult struct {
+	Direction string           `json:"direction"`
+	Rule      *AdminRuleResult `json:"rule"`
+	// exactly one of ShadowedBy and NetworkPolicies is set
+	ShadowedBy      *AdminRuleResult `json:"shadowedBy,omitempty"`
+	NetworkPolicies []string         `json:"networkPolicies,omitempty"`
+	Reason          string           `json:"reason"`
+}
+
+func NewShadowedRuleResults(shadowedRules []*matcher.ShadowedRule) []*ShadowedRuleResult {
+	results := []*ShadowedRuleResult{}
+	for _, s := range shadowedRules {
+		result := &ShadowedRuleResult{
+			Direction: directionString(s.IsIngress),
+			Rule:      NewAdminRuleResult(s.Rule),
+			Reason:    string(s.Reason),
+		}
+		if s.ShadowedBy != nil {
+			result.ShadowedBy = NewAdminRuleResult(s.ShadowedBy)
+		}
+		for _, id := range s.NetworkPolicies {
+			result.NetworkPolicies = append(result.NetworkPolicies, string(id))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+type VerdictConflictResult struct {
+	Direction string           `json:"direction"`
+	Rule      *AdminRuleResult `json:"rule"`
+	OtherRule *AdminRuleResult `json:"otherRule"`
+	Example   string           `json:"example"`
+}
+
+type PriorityConflictResult struct {
+	Priority    int                      `json:"priority"`
+	Policy      string                   `json:"policy"`
+	OtherPolicy string                   `json:"otherPolicy"`
+	Pods        []string                 `json:"pods"`
+	Verdicts    []*VerdictConflictResult `json:"verdicts,omitempty"`
+}
+
+type PriorityConflictsResult struct {
+	InvalidPolicies []*PolicyErrorResult      `json:"invalidPolicies,omitempty"`
+	Conflicts       []*PriorityConflictResult `json:"conflicts"`
+}
+
+func NewPriorityConflictsResult(conflicts []*matcher.PriorityConflict, policyErrors []*matcher.PolicyError) *PriorityConflictsResult {
+	result := &PriorityConflictsResult{
+		InvalidPolicies: NewPolicyErrorResults(policyErrors),
+		Conflicts:       []*PriorityConflictResult{},
+	}
+	for _, c := range conflicts {
+		conflict := &PriorityConflictResult{Priority: c.Priority, Policy: c.PolicyName, OtherPolicy: c.OtherPolicy}
+		for _, pod := range c.Pods {
+			conflict.Pods = append(conflict.Pods, pod.String())
+		}
+		for _, v := range c.Verdicts {
+			conflict.Verdicts = append(conflict.Verdicts, &VerdictConflictResult{
+				Direction: directionString(v.IsIngress),
+				Rule:      NewAdminRuleResult(v.Rule),
+				OtherRule: NewAdminRuleResult(v.OtherRule),
+				Example:   v.Example,
+			})
+		}
+		result.Conflicts = append(result.Conflicts, conflict)
+	}
+	return result
+}
+
+type TrafficDiffResult struct {
+	Source      string                 `json:"source"`
+	Destination string                 `json:"destination"`
+	Port        int                    `json:"port"`
+	Protocol    v1.Protocol            `json:"protocol"`
+	Before      *matcher.VerdictResult `json:"before"`
+	After       *matcher.VerdictResult `json:"after"`
+}
+
+type DiffResult struct {
+	// InvalidPolicies are the policies from --compare-policy-path which were skipped
+	InvalidPolicies  []*PolicyErrorResult `json:"invalidPolicies,omitempty"`
+	TotalConnections int                  `json:"totalConnections"`
+	Changes          []*TrafficDiffResult `json:"changes"`
+}
+
+func directionString(isIngress bool) string {
+	if isIngress {
+		return "Ingress"
+	}
+	return "Egress"
+}
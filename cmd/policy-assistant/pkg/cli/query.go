@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// QueryTargetPod is a pod to find the targets of.  NPv1 targets match by exact namespace and by pod labels,
+// while ANP and BANP targets match by namespace labels and by pod labels.
+type QueryTargetPod struct {
+	Namespace       string
+	Labels          map[string]string
+	NamespaceLabels map[string]string
+}
+
+func (p *QueryTargetPod) internalPeer() *matcher.InternalPeer {
+	return &matcher.InternalPeer{
+		Namespace:       p.Namespace,
+		PodLabels:       p.Labels,
+		NamespaceLabels: p.NamespaceLabels,
+	}
+}
+
+// QueryTargetResult is the targets applying to a pod, ingress then egress, each ordered by precedence.
+type QueryTargetResult struct {
+	Namespace       string                       `json:"namespace"`
+	Labels          map[string]string            `json:"labels"`
+	NamespaceLabels map[string]string            `json:"namespaceLabels,omitempty"`
+	Targets         []*matcher.ExplanationResult `json:"targets"`
+}
+
+// QueryTargetPods returns the pods from kube, followed by those from podPath if it's set.
+func QueryTargetPods(podPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) []*QueryTargetPod {
+	nsLabels := map[string]map[string]string{}
+	for _, ns := range kubeNamespaces {
+		nsLabels[ns.Name] = ns.Labels
+	}
+	var pods []*QueryTargetPod
+	for _, p := range kubePods {
+		pods = append(pods, &QueryTargetPod{
+			Namespace:       p.Namespace,
+			Labels:          p.Labels,
+			NamespaceLabels: nsLabels[p.Namespace],
+		})
+	}
+	if podPath != "" {
+		podsFromFile, err := json.ParseFile[[]*QueryTargetPod](podPath)
+		utils.DoOrDie(err)
+		pods = append(pods, *podsFromFile...)
+	}
+	if len(pods) == 0 {
+		logrus.Warnf("no pods found: set --target-pod-path or read from kube with --namespace, --all-namespaces or --snapshot")
+	}
+	return pods
+}
+
+// QueryTargets finds the ingress and egress targets of each pod, across NPv1s, ANPs and BANPs, in the order
+// their rules take effect.
+func QueryTargets(policies *matcher.Policy, pods []*QueryTargetPod) ([]*QueryTargetResult, string) {
+	var results []*QueryTargetResult
+	tables := &strings.Builder{}
+	for _, pod := range pods {
+		ingresses, egresses := QueryTargetHelper(policies, pod)
+		results = append(results, &QueryTargetResult{
+			Namespace:       pod.Namespace,
+			Labels:          pod.Labels,
+			NamespaceLabels: pod.NamespaceLabels,
+			Targets:         matcher.ExplainTargets(ingresses, egresses),
+		})
+		fmt.Fprintf(tables, "pod in ns %s with labels %+v and namespace labels %+v:\n", pod.Namespace, pod.Labels, pod.NamespaceLabels)
+		if len(ingresses) == 0 && len(egresses) == 0 {
+			fmt.Fprintf(tables, "no targets\n\n")
+			continue
+		}
+		fmt.Fprintf(tables, "%s\n", matcher.ExplainTargetsTable(ingresses, egresses))
+	}
+	return results, tables.String()
+}
+
+// QueryTargetHelper returns the ingress and egress targets applying to a pod, ordered by precedence.
+func QueryTargetHelper(policies *matcher.Policy, pod *QueryTargetPod) ([]*matcher.Target, []*matcher.Target) {
+	podInfo := pod.internalPeer()
+	return policies.TargetsApplyingToPodByPrecedence(true, podInfo), policies.TargetsApplyingToPodByPrecedence(false, podInfo)
+}
+
+// QueryTraffic evaluates traffic from a file, showing the effect of each rule in the order rules are evaluated.
+// Traffic to a service is evaluated for each of its backends.
+func QueryTraffic(kubeClient kube.IKubernetes, policies *matcher.Policy, trafficPath string, resolver matcher.DomainNameResolver) ([]*matcher.TrafficVerdictResult, string) {
+	if trafficPath == "" {
+		logrus.Fatalf("%+v", errors.Errorf("path to traffic file required for query-traffic mode: set --traffic-path"))
+	}
+
+	var results []*matcher.TrafficVerdictResult
+	tables := &strings.Builder{}
+	printTraffic := func(traffic *matcher.Traffic, allowed *matcher.AllowedResult) {
+		fmt.Fprintf(tables, "Traffic:\n%s\n", traffic.Table())
+		fmt.Fprintf(tables, "Is traffic allowed?\n%s\n\n", allowed.Table())
+	}
+	for _, traffic := range ReadTrafficFile(kubeClient, trafficPath, resolver) {
+		if traffic.Destination.Service == nil {
+			allowed := policies.IsTrafficAllowed(traffic)
+			printTraffic(traffic, allowed)
+			results = append(results, matcher.NewTrafficVerdictResult(traffic, sortedByPrecedence(allowed)))
+			continue
+		}
+		backends, err := matcher.ResolveServiceBackends(kubeClient, traffic)
+		utils.DoOrDie(err)
+		reachable, allowedResults := policies.IsServiceTrafficAllowed(backends)
+		fmt.Fprintf(tables, "Traffic to %s: %s of %d endpoints reachable\n\n", traffic.PrettyString(), reachable, len(backends))
+		for i, backend := range backends {
+			printTraffic(backend.Traffic, allowedResults[i])
+			allowedResults[i] = sortedByPrecedence(allowedResults[i])
+		}
+		results = append(results, matcher.NewServiceTrafficVerdictResult(traffic, reachable, backends, allowedResults))
+	}
+	return results, tables.String()
+}
+
+func sortedByPrecedence(allowed *matcher.AllowedResult) *matcher.AllowedResult {
+	return &matcher.AllowedResult{Ingress: allowed.Ingress.SortedByPrecedence(), Egress: allowed.Egress.SortedByPrecedence()}
+}
@@ -35,8 +35,14 @@ func SetupRootCommand() *cobra.Command {
 
 	command.AddCommand(SetupAnalyzeCommand())
 	//command.AddCommand(SetupCompareCommand())
+	command.AddCommand(SetupConvertCommand())
+	command.AddCommand(SetupFuzzCommand())
 	command.AddCommand(SetupGenerateCommand())
+	command.AddCommand(SetupLintCommand())
 	command.AddCommand(SetupProbeCommand())
+	command.AddCommand(SetupShellCommand())
+	command.AddCommand(SetupSnapshotCommand())
+	command.AddCommand(SetupSynthesizeCommand())
 	command.AddCommand(SetupVersionCommand())
 
 	return command
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"os"
+	"time"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/shell"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+type ShellArgs struct {
+	PolicyPath    string
+	SnapshotPath  string
+	AllNamespaces bool
+	Namespaces    []string
+	Context       string
+	Timeout       time.Duration
+}
+
+func SetupShellCommand() *cobra.Command {
+	args := &ShellArgs{}
+
+	command := &cobra.Command{
+		Use:   "shell",
+		Short: "interactively explore policy verdicts, with policies and cluster resources loaded once",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunShellCommand(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.PolicyPath, "policy-path", "", "may be a file or a directory; if set, reads policies from the path in addition to those of the cluster")
+	command.Flags().StringVar(&args.SnapshotPath, "snapshot", "", "path to a file written by 'cyclonus snapshot'; if set, kube resources are read from it instead of from a cluster")
+	command.Flags().BoolVarP(&args.AllNamespaces, "all-namespaces", "A", false, "reads kube resources from all namespaces; same as kubectl's '--all-namespaces'/'-A' flag")
+	command.Flags().StringSliceVarP(&args.Namespaces, "namespace", "n", []string{}, "namespaces to read kube resources from; similar to kubectl's '--namespace'/'-n' flag, except that multiple namespaces may be passed in")
+	command.Flags().StringVar(&args.Context, "context", "", "kubernetes context to use; if empty, uses default context")
+	command.Flags().DurationVar(&args.Timeout, "kube-client-timeout", DefaultTimeout, "kube client timeout")
+
+	return command
+}
+
+func RunShellCommand(args *ShellArgs) {
+	snapshot := readSnapshotOrCluster(args.SnapshotPath, args.AllNamespaces, args.Namespaces, args.Context, args.Timeout)
+	if snapshot == nil {
+		logrus.Warnf("no pods loaded: set --snapshot, --namespace or --all-namespaces to ask about pods")
+		snapshot = &kube.Snapshot{Version: kube.SnapshotVersion}
+	}
+
+	if args.PolicyPath != "" {
+		// policies from files are loaded along with those of the cluster, so that they can't be undone
+		netpols, anps, banp, err := kube.ReadNetworkPoliciesFromPath(args.PolicyPath)
+		utils.DoOrDie(err)
+		for _, netpol := range netpols {
+			snapshot.NetworkPolicies = append(snapshot.NetworkPolicies, *netpol)
+		}
+		for _, anp := range anps {
+			snapshot.AdminNetworkPolicies = append(snapshot.AdminNetworkPolicies, *anp)
+		}
+		if banp != nil {
+			snapshot.BaselineAdminNetworkPolicy = banp
+		}
+	}
+
+	session, errs := shell.NewSession(snapshot)
+	for _, err := range errs {
+		logrus.Warnf("skipping invalid policy: %+v", err)
+	}
+
+	logrus.Infof("loaded %d policies, %d namespaces and %d pods; run 'help' to list commands", len(session.Policies), len(snapshot.Namespaces), len(snapshot.Pods))
+	utils.DoOrDie(session.Run(os.Stdin, os.Stdout))
+}
+
+// readSnapshotOrCluster reads kube resources from the snapshot at snapshotPath if it's set, and otherwise from
+// the namespaces of the cluster.  Returns nil if neither a snapshot nor namespaces are set.
+func readSnapshotOrCluster(snapshotPath string, allNamespaces bool, namespaces []string, kubeContext string, timeout time.Duration) *kube.Snapshot {
+	if snapshotPath != "" {
+		snapshot, err := kube.ReadSnapshotFile(snapshotPath)
+		utils.DoOrDie(err)
+		return snapshot
+	}
+	if !allNamespaces && len(namespaces) == 0 {
+		return nil
+	}
+	kubeClient, err := kube.NewKubernetesForContext(kubeContext)
+	utils.DoOrDie(err)
+	includeANPs, includeBANP := shouldIncludeANPandBANP(kubeClient.ClientSet)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+	defer cancel()
+
+	if allNamespaces {
+		namespaces = nil
+	}
+	snapshot, err := kube.TakeSnapshot(ctx, kubeClient, namespaces, includeANPs, includeBANP)
+	utils.DoOrDie(err)
+	return snapshot
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+type SnapshotArgs struct {
+	AllNamespaces bool
+	Namespaces    []string
+	Context       string
+	OutputPath    string
+	Timeout       time.Duration
+}
+
+func SetupSnapshotCommand() *cobra.Command {
+	args := &SnapshotArgs{}
+
+	command := &cobra.Command{
+		Use:   "snapshot",
+		Short: "save the cluster resources read by analyze to a file, to analyze them later without a cluster",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunSnapshotCommand(args)
+		},
+	}
+
+	command.Flags().BoolVarP(&args.AllNamespaces, "all-namespaces", "A", false, "reads kube resources from all namespaces; same as kubectl's '--all-namespaces'/'-A' flag")
+	command.Flags().StringSliceVarP(&args.Namespaces, "namespace", "n", []string{}, "namespaces to read kube resources from; similar to kubectl's '--namespace'/'-n' flag, except that multiple namespaces may be passed in")
+	command.Flags().StringVar(&args.Context, "context", "", "kubernetes context to use; if empty, uses default context")
+	command.Flags().StringVarP(&args.OutputPath, "output", "o", "", "path to write the snapshot to")
+	utils.DoOrDie(command.MarkFlagRequired("output"))
+	command.Flags().DurationVar(&args.Timeout, "kube-client-timeout", DefaultTimeout, "kube client timeout")
+
+	return command
+}
+
+func RunSnapshotCommand(args *SnapshotArgs) {
+	if !args.AllNamespaces && len(args.Namespaces) == 0 {
+		logrus.Fatalf("must set --namespace or --all-namespaces")
+	}
+
+	kubeClient, err := kube.NewKubernetesForContext(args.Context)
+	utils.DoOrDie(err)
+	includeANPs, includeBANP := shouldIncludeANPandBANP(kubeClient.ClientSet)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), args.Timeout)
+	defer cancel()
+
+	namespaces := args.Namespaces
+	if args.AllNamespaces {
+		namespaces = nil
+	}
+	snapshot, err := kube.TakeSnapshot(ctx, kubeClient, namespaces, includeANPs, includeBANP)
+	utils.DoOrDie(err)
+	utils.DoOrDie(snapshot.WriteFile(args.OutputPath))
+
+	logrus.Infof("wrote snapshot of %d namespaces, %d pods and %d nodes to %s", len(snapshot.Namespaces), len(snapshot.Pods), len(snapshot.Nodes), args.OutputPath)
+}
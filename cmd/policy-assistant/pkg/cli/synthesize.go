@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattfenwick/cyclonus/pkg/synthesize"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+)
+
+type SynthesizeArgs struct {
+	FlowsPath       string
+	DefaultDenyBANP bool
+	OutputPath      string
+	SnapshotPath    string
+	AllNamespaces   bool
+	Namespaces      []string
+	Context         string
+	Timeout         time.Duration
+}
+
+func SetupSynthesizeCommand() *cobra.Command {
+	args := &SynthesizeArgs{}
+
+	command := &cobra.Command{
+		Use:   "synthesize",
+		Short: "synthesize minimal policies allowing exactly a set of observed flows",
+		Long: `Synthesize ingress NetworkPolicies which allow a set of observed flows, and deny all other traffic
+between known workloads: the pods read from a snapshot or cluster, and the pods of the flows.
+The policies are verified with the same engine as 'analyze' before they're written.`,
+		Args: cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunSynthesizeCommand(args)
+		},
+	}
+
+	command.Flags().StringVar(&args.FlowsPath, "flows-path", "", "path to allowed flows: a json list of traffic, as for '--traffic-path', or a flow-log .csv of src,dst,port,proto records, with peers as <namespace>/<pod> or an IP")
+	utils.DoOrDie(command.MarkFlagRequired("flows-path"))
+	command.Flags().BoolVar(&args.DefaultDenyBANP, "default-deny-banp", false, "if set, workloads which don't receive flows are denied by a BANP instead of a NetworkPolicy per namespace")
+	command.Flags().StringVarP(&args.OutputPath, "output", "o", "", "path to write the policies to; if empty, writes to stdout")
+	command.Flags().StringVar(&args.SnapshotPath, "snapshot", "", "path to a file written by 'cyclonus snapshot'; if set, kube resources are read from it instead of from a cluster")
+	command.Flags().BoolVarP(&args.AllNamespaces, "all-namespaces", "A", false, "reads kube resources from all namespaces; same as kubectl's '--all-namespaces'/'-A' flag")
+	command.Flags().StringSliceVarP(&args.Namespaces, "namespace", "n", []string{}, "namespaces to read kube resources from; similar to kubectl's '--namespace'/'-n' flag, except that multiple namespaces may be passed in")
+	command.Flags().StringVar(&args.Context, "context", "", "kubernetes context to use; if empty, uses default context")
+	command.Flags().DurationVar(&args.Timeout, "kube-client-timeout", DefaultTimeout, "kube client timeout")
+
+	return command
+}
+
+func RunSynthesizeCommand(args *SynthesizeArgs) {
+	var pods []v1.Pod
+	var namespaces []v1.Namespace
+	if snapshot := readSnapshotOrCluster(args.SnapshotPath, args.AllNamespaces, args.Namespaces, args.Context, args.Timeout); snapshot != nil {
+		pods, namespaces = snapshot.Pods, snapshot.Namespaces
+	} else {
+		logrus.Warnf("no pods loaded: only the pods of the flows are known workloads; set --snapshot, --namespace or --all-namespaces to deny traffic to other pods")
+	}
+
+	flows, err := synthesize.ReadFlowsFromPath(args.FlowsPath, pods, namespaces)
+	utils.DoOrDie(err)
+	endpoints := synthesize.Endpoints(pods, namespaces, flows)
+
+	result, err := synthesize.Synthesize(flows, endpoints, args.DefaultDenyBANP)
+	utils.DoOrDie(err)
+	mismatches, err := synthesize.Verify(result, flows, endpoints)
+	utils.DoOrDie(err)
+	if len(mismatches) > 0 {
+		for _, mismatch := range mismatches {
+			expected := "denied"
+			if mismatch.Expected {
+				expected = "allowed"
+			}
+			logrus.Errorf("traffic %s should be %s", mismatch.Traffic.PrettyString(), expected)
+		}
+		logrus.Fatalf("%+v", errors.Errorf("synthesized policies don't match the flows: the labels of a workload receiving flows must not all be labels of another workload in its namespace"))
+	}
+
+	var documents []string
+	for _, netpol := range result.NetworkPolicies {
+		documents = append(documents, utils.YamlString(netpol))
+	}
+	if result.BaselineAdminNetworkPolicy != nil {
+		documents = append(documents, utils.YamlString(result.BaselineAdminNetworkPolicy))
+	}
+	output := strings.Join(documents, "---\n")
+
+	logrus.Infof("synthesized %d NetworkPolicies for %d flows between %d workloads", len(result.NetworkPolicies), len(flows), len(endpoints))
+	if args.OutputPath == "" {
+		fmt.Print(output)
+		return
+	}
+	utils.DoOrDie(errors.Wrapf(os.WriteFile(args.OutputPath, []byte(output), 0644), "unable to write %s", args.OutputPath))
+}
@@ -0,0 +1,131 @@
+package connectivity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/cyclonus/pkg/connectivity/probe"
+	"github.com/mattfenwick/cyclonus/pkg/generator"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+)
+
+// ExpectationCheck is a flow with hand-written expected connectivity, along with the connectivity that kube and the
+// simulator found for it.  A flow which wasn't probed has unknown connectivity.
+type ExpectationCheck struct {
+	From         string
+	To           string
+	PortProtocol string
+	Expected     probe.Connectivity
+	Kube         probe.Connectivity
+	Simulated    probe.Connectivity
+}
+
+func (c *ExpectationCheck) KubeMatches() bool {
+	return c.Kube == c.Expected
+}
+
+func (c *ExpectationCheck) SimulatedMatches() bool {
+	return c.Simulated == c.Expected
+}
+
+func tableHasPods(table *probe.Table, from string, to string) bool {
+	_, ok := table.Wrapped.Values[from]
+	return ok && slices.Contains(table.Wrapped.Tos, to)
+}
+
+func jobResultConnectivity(table *probe.Table, from string, to string, key string) probe.Connectivity {
+	if !tableHasPods(table, from, to) {
+		return probe.ConnectivityUnknown
+	}
+	if jr, ok := table.Get(from, to).JobResults[key]; ok {
+		return jr.Combined
+	}
+	return probe.ConnectivityUnknown
+}
+
+// flowPortProtocol formats a flow's port and protocol like job result keys, with '*' for any
+func flowPortProtocol(flow *generator.ExpectedFlow) string {
+	protocol, port := "*", "*"
+	if flow.Protocol != "" {
+		protocol = string(flow.Protocol)
+	}
+	if flow.Port != nil {
+		port = flow.Port.String()
+	}
+	return fmt.Sprintf("%s/%s", protocol, port)
+}
+
+// NewExpectationChecks checks hand-written expected connectivity against kube and simulated probes.
+func NewExpectationChecks(expected *generator.ExpectedConnectivity, kubeProbe *probe.Table, simulatedProbe *probe.Table, ignoreLoopback bool) []*ExpectationCheck {
+	allowed, denied, err := expected.Flows()
+	if err != nil {
+		// the interpreter validates expected connectivity before running a test case, so this really shouldn't happen
+		panic(errors.WithMessagef(err, "invalid expected connectivity"))
+	}
+
+	var checks []*ExpectationCheck
+	addChecks := func(flows []*generator.ExpectedFlow, connectivity probe.Connectivity) {
+		for _, flow := range flows {
+			if ignoreLoopback && flow.From == flow.To {
+				continue
+			}
+			found := false
+			if tableHasPods(kubeProbe, flow.From, flow.To) {
+				jobResults := kubeProbe.Get(flow.From, flow.To).JobResults
+				for _, key := range slice.Sort(maps.Keys(jobResults)) {
+					jr := jobResults[key]
+					if !flow.Matches(jr.Job.ResolvedPort, jr.Job.ResolvedPortName, jr.Job.Protocol) {
+						continue
+					}
+					found = true
+					checks = append(checks, &ExpectationCheck{
+						From:         flow.From,
+						To:           flow.To,
+						PortProtocol: key,
+						Expected:     connectivity,
+						Kube:         jr.Combined,
+						Simulated:    jobResultConnectivity(simulatedProbe, flow.From, flow.To, key),
+					})
+				}
+			}
+			if !found {
+				checks = append(checks, &ExpectationCheck{
+					From:         flow.From,
+					To:           flow.To,
+					PortProtocol: flowPortProtocol(flow),
+					Expected:     connectivity,
+					Kube:         probe.ConnectivityUnknown,
+					Simulated:    probe.ConnectivityUnknown,
+				})
+			}
+		}
+	}
+	addChecks(allowed, probe.ConnectivityAllowed)
+	addChecks(denied, probe.ConnectivityBlocked)
+	return checks
+}
+
+// RenderExpectationChecks renders the checks which either kube or the simulator got wrong, or all of them if noisy.
+func RenderExpectationChecks(checks []*ExpectationCheck, noisy bool) string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetHeader([]string{"From", "To", "Port/Protocol", "Expected", "Kube", "Simulated"})
+	mark := func(connectivity probe.Connectivity, matches bool) string {
+		if matches {
+			return string(connectivity)
+		}
+		return fmt.Sprintf("%s %s", connectivity, failSymbol)
+	}
+	for _, check := range checks {
+		if noisy || !check.KubeMatches() || !check.SimulatedMatches() {
+			table.Append([]string{check.From, check.To, check.PortProtocol, string(check.Expected),
+				mark(check.Kube, check.KubeMatches()), mark(check.Simulated, check.SimulatedMatches())})
+		}
+	}
+	table.Render()
+	return tableString.String()
+}
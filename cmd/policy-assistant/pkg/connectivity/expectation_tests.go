@@ -0,0 +1,138 @@
+package connectivity
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mattfenwick/cyclonus/pkg/connectivity/probe"
+	"github.com/mattfenwick/cyclonus/pkg/generator"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// newExpectationTestTable builds a table of pods x/a and x/b probed on TCP 80 and 81, in which everything is allowed
+// except for the given blocked flows, written as from,to,port.
+func newExpectationTestTable(blocked ...string) *probe.Table {
+	table := probe.NewTable([]string{"x/a", "x/b"})
+	isBlocked := map[string]bool{}
+	for _, flow := range blocked {
+		isBlocked[flow] = true
+	}
+	for _, from := range []string{"x/a", "x/b"} {
+		for _, to := range []string{"x/a", "x/b"} {
+			for _, port := range []int{80, 81} {
+				combined := probe.ConnectivityAllowed
+				if isBlocked[fmt.Sprintf("%s,%s,%d", from, to, port)] {
+					combined = probe.ConnectivityBlocked
+				}
+				Expect(table.Get(from, to).AddJobResult(&probe.JobResult{
+					Job: &probe.Job{
+						FromKey:          from,
+						ToKey:            to,
+						ResolvedPort:     port,
+						ResolvedPortName: fmt.Sprintf("serve-%d-tcp", port),
+						Protocol:         v1.ProtocolTCP,
+					},
+					Combined: combined,
+				})).To(Succeed())
+			}
+		}
+	}
+	return table
+}
+
+func RunExpectationTests() {
+	Describe("Hand-written expectations", func() {
+		It("Checks a truth table against kube and the simulator separately", func() {
+			stepResult := NewStepResult(newExpectationTestTable(), nil, nil)
+			stepResult.AddKubeProbe(newExpectationTestTable("x/b,x/a,80", "x/b,x/a,81"))
+			stepResult.Expected = &generator.ExpectedConnectivity{TruthTable: `
+				-   x/a x/b
+				x/a #   .
+				x/b X   #`}
+
+			checks := stepResult.ExpectationChecks(false)
+			Expect(checks).To(HaveLen(4))
+			Expect(stepResult.PassedExpected(false)).To(BeTrue())
+			Expect(stepResult.SimulatedPassedExpected(false)).To(BeFalse())
+			Expect(stepResult.Passed(false)).To(BeFalse())
+		})
+
+		It("Checks flows on specific ports", func() {
+			port80 := intstr.FromInt(80)
+			port81 := intstr.FromString("serve-81-tcp")
+			stepResult := NewStepResult(newExpectationTestTable("x/a,x/b,80"), nil, nil)
+			stepResult.AddKubeProbe(newExpectationTestTable())
+			stepResult.Expected = &generator.ExpectedConnectivity{
+				Allowed: []*generator.ExpectedFlow{{From: "x/a", To: "x/b", Port: &port81, Protocol: v1.ProtocolTCP}},
+				Denied:  []*generator.ExpectedFlow{{From: "x/a", To: "x/b", Port: &port80}},
+			}
+
+			checks := stepResult.ExpectationChecks(false)
+			Expect(checks).To(Equal([]*ExpectationCheck{
+				{From: "x/a", To: "x/b", PortProtocol: "TCP/81", Expected: probe.ConnectivityAllowed, Kube: probe.ConnectivityAllowed, Simulated: probe.ConnectivityAllowed},
+				{From: "x/a", To: "x/b", PortProtocol: "TCP/80", Expected: probe.ConnectivityBlocked, Kube: probe.ConnectivityAllowed, Simulated: probe.ConnectivityBlocked},
+			}))
+			Expect(stepResult.PassedExpected(false)).To(BeFalse())
+			Expect(stepResult.SimulatedPassedExpected(false)).To(BeTrue())
+		})
+
+		It("Fails flows which weren't probed, and skips loopback if ignored", func() {
+			stepResult := NewStepResult(newExpectationTestTable(), nil, nil)
+			stepResult.AddKubeProbe(newExpectationTestTable())
+			stepResult.Expected = &generator.ExpectedConnectivity{
+				Allowed: []*generator.ExpectedFlow{{From: "x/a", To: "y/a"}, {From: "x/a", To: "x/a", Protocol: v1.ProtocolUDP}},
+			}
+
+			checks := stepResult.ExpectationChecks(false)
+			Expect(checks).To(HaveLen(2))
+			Expect(checks[0].Kube).To(Equal(probe.ConnectivityUnknown))
+			Expect(checks[1].PortProtocol).To(Equal("UDP/*"))
+			Expect(stepResult.PassedExpected(false)).To(BeFalse())
+			Expect(stepResult.ExpectationChecks(true)).To(HaveLen(1))
+		})
+
+		It("Reports hand-written expectations as separate junit test cases", func() {
+			stepResult := NewStepResult(newExpectationTestTable(), nil, nil)
+			stepResult.AddKubeProbe(newExpectationTestTable())
+			stepResult.Expected = &generator.ExpectedConnectivity{
+				Denied: []*generator.ExpectedFlow{{From: "x/a", To: "x/b"}},
+			}
+			step := generator.NewTestStep(generator.NewAllAvailable(generator.ProbeModePodIP))
+			step.Expected = stepResult.Expected
+			result := &Result{
+				TestCase: generator.NewTestCase("deny x/a to x/b", generator.NewStringSet(), step),
+				Steps:    []*StepResult{stepResult},
+			}
+			Expect(result.Passed(false)).To(BeTrue())
+			Expect(result.PassedExpected(false)).To(BeFalse())
+			Expect(result.SimulatedPassedExpected(false)).To(BeFalse())
+
+			path := GinkgoT().TempDir() + "/junit.xml"
+			Expect(PrintJUnitResults(path, []*Result{result}, false)).To(Succeed())
+			contents, err := os.ReadFile(path)
+			Expect(err).To(BeNil())
+			Expect(string(contents)).To(ContainSubstring(`failures="2"`))
+			Expect(string(contents)).To(ContainSubstring(`name="deny x/a to x/b (expected)"`))
+			Expect(string(contents)).To(ContainSubstring(`name="deny x/a to x/b (expected, simulator)"`))
+		})
+
+		It("Fails test cases which failed to execute", func() {
+			step := generator.NewTestStep(generator.NewAllAvailable(generator.ProbeModePodIP))
+			step.Expected = &generator.ExpectedConnectivity{
+				Denied: []*generator.ExpectedFlow{{From: "x/a", To: "x/b"}},
+			}
+			result := &Result{
+				TestCase: generator.NewTestCase("deny x/a to x/b", generator.NewStringSet(), step),
+				Err:      errors.Errorf("unable to create policy"),
+			}
+			Expect(result.HasExpected()).To(BeTrue())
+			Expect(result.Passed(false)).To(BeFalse())
+			Expect(result.PassedExpected(false)).To(BeFalse())
+			Expect(result.SimulatedPassedExpected(false)).To(BeFalse())
+		})
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 )
 
 const (
@@ -29,6 +30,8 @@ type InterpreterConfig struct {
 	IgnoreLoopback                   bool
 	JobTimeoutSeconds                int
 	FailFast                         bool
+	// DeleteExistingBANP allows resetting the cluster to delete a BANP which cyclonus didn't create
+	DeleteExistingBANP bool
 }
 
 func (i *InterpreterConfig) PerturbationWaitDuration() time.Duration {
@@ -72,6 +75,18 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 		Kubernetes: t.kubernetes,
 		Resources:  t.resources,
 		Policies:   []*networkingv1.NetworkPolicy{},
+
+		DeleteExistingBANP: t.Config.DeleteExistingBANP,
+	}
+
+	for stepIndex, step := range testCase.Steps {
+		if step.Expected == nil {
+			continue
+		}
+		if _, _, err = step.Expected.Flows(); err != nil {
+			result.Err = errors.WithMessagef(err, "invalid expected connectivity at step %d", stepIndex)
+			return result
+		}
 	}
 
 	if t.Config.ResetClusterBeforeTestCase {
@@ -103,6 +118,18 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 				err = testCaseState.UpdatePolicy(action.UpdatePolicy.Policy)
 			} else if action.DeletePolicy != nil {
 				err = testCaseState.DeletePolicy(action.DeletePolicy.Namespace, action.DeletePolicy.Name)
+			} else if action.CreateAdminNetworkPolicy != nil {
+				err = testCaseState.CreateAdminNetworkPolicy(action.CreateAdminNetworkPolicy.Policy)
+			} else if action.UpdateAdminNetworkPolicy != nil {
+				err = testCaseState.UpdateAdminNetworkPolicy(action.UpdateAdminNetworkPolicy.Policy)
+			} else if action.DeleteAdminNetworkPolicy != nil {
+				err = testCaseState.DeleteAdminNetworkPolicy(action.DeleteAdminNetworkPolicy.Name)
+			} else if action.CreateBaselineAdminNetworkPolicy != nil {
+				err = testCaseState.CreateBaselineAdminNetworkPolicy(action.CreateBaselineAdminNetworkPolicy.Policy)
+			} else if action.UpdateBaselineAdminNetworkPolicy != nil {
+				err = testCaseState.UpdateBaselineAdminNetworkPolicy(action.UpdateBaselineAdminNetworkPolicy.Policy)
+			} else if action.DeleteBaselineAdminNetworkPolicy != nil {
+				err = testCaseState.DeleteBaselineAdminNetworkPolicy(action.DeleteBaselineAdminNetworkPolicy.Name)
 			} else if action.CreateNamespace != nil {
 				err = testCaseState.CreateNamespace(action.CreateNamespace.Namespace, action.CreateNamespace.Labels)
 			} else if action.SetNamespaceLabels != nil {
@@ -130,10 +157,15 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 		logrus.Infof("step %d: waiting %d seconds for perturbation to take effect", stepIndex+1, t.Config.PerturbationWaitSeconds)
 		time.Sleep(t.Config.PerturbationWaitDuration())
 
-		stepResult := t.runProbe(testCaseState, step.Probe)
-		result.Steps = append(result.Steps, stepResult)
+		stepResults := t.runProbe(testCaseState, step)
+		passed := true
+		for _, stepResult := range stepResults {
+			stepResult.Step = stepIndex
+			result.Steps = append(result.Steps, stepResult)
+			passed = passed && stepResult.Passed(t.Config.IgnoreLoopback) && stepResult.PassedExpected(t.Config.IgnoreLoopback)
+		}
 
-		if t.Config.FailFast && !stepResult.Passed(t.Config.IgnoreLoopback) {
+		if t.Config.FailFast && !passed {
 			break
 		}
 	}
@@ -141,27 +173,40 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 	return result
 }
 
-func (t *Interpreter) runProbe(testCaseState *TestCaseState, probeConfig *generator.ProbeConfig) *StepResult {
-	parsedPolicy := matcher.BuildNetworkPolicies(true, testCaseState.Policies)
+// runProbe runs the step's probe once per IP family of the pods, returning a result per family.
+func (t *Interpreter) runProbe(testCaseState *TestCaseState, step *generator.TestStep) []*StepResult {
+	probeConfig := step.Probe
+	parsedPolicy, policyErrors := matcher.BuildV1AndV2NetPols(true, testCaseState.Policies, testCaseState.ANPs, testCaseState.BANP)
+	for _, err := range policyErrors {
+		logrus.Errorf("skipping invalid policy in simulated probe: %s", err)
+	}
 
 	logrus.Infof("running probe %+v", probeConfig)
 	logrus.Debugf("with resources:\n%s", testCaseState.Resources.RenderTable())
 
 	simRunner := probe.NewSimulatedRunner(parsedPolicy, t.jobBuilder)
 
-	stepResult := NewStepResult(
-		simRunner.RunProbeForConfig(probeConfig, testCaseState.Resources),
-		parsedPolicy,
-		append([]*networkingv1.NetworkPolicy{}, testCaseState.Policies...)) // this looks weird, but just making a new copy to avoid accidentally mutating it elsewhere
-
-	for i := 0; i <= t.Config.KubeProbeRetries; i++ {
-		logrus.Infof("running kube probe on try %d", i+1)
-		stepResult.AddKubeProbe(t.kubeRunner.RunProbeForConfig(probeConfig, testCaseState.Resources))
-		// no differences between synthetic and kube probes?  then we can stop
-		if stepResult.Passed(t.Config.IgnoreLoopback) {
-			break
+	var stepResults []*StepResult
+	for _, resources := range testCaseState.Resources.SplitByIPFamily() {
+		stepResult := NewStepResult(
+			simRunner.RunProbeForConfig(probeConfig, resources),
+			parsedPolicy,
+			append([]*networkingv1.NetworkPolicy{}, testCaseState.Policies...)) // this looks weird, but just making a new copy to avoid accidentally mutating it elsewhere
+		stepResult.IPFamily = resources.IPFamily
+		stepResult.ANPs = append([]*v1alpha1.AdminNetworkPolicy{}, testCaseState.ANPs...)
+		stepResult.BANP = testCaseState.BANP
+		stepResult.Expected = step.Expected
+
+		for i := 0; i <= t.Config.KubeProbeRetries; i++ {
+			logrus.Infof("running kube probe on try %d", i+1)
+			stepResult.AddKubeProbe(t.kubeRunner.RunProbeForConfig(probeConfig, resources))
+			// no differences between synthetic and kube probes, or hand-written expectations?  then we can stop
+			if stepResult.Passed(t.Config.IgnoreLoopback) && stepResult.PassedExpected(t.Config.IgnoreLoopback) {
+				break
+			}
 		}
+		stepResults = append(stepResults, stepResult)
 	}
 
-	return stepResult
+	return stepResults
 }
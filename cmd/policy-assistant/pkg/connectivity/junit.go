@@ -23,6 +23,16 @@ func PrintJUnitResults(filename string, results []*Result, ignoreLoopback bool)
 			Passed: result.Passed(ignoreLoopback),
 			Name:   result.TestCase.Description,
 		})
+		// hand-written expectations are reported separately from the simulator
+		if result.HasExpected() {
+			junitResults = append(junitResults, &JUnitTestResult{
+				Passed: result.PassedExpected(ignoreLoopback),
+				Name:   result.TestCase.Description + " (expected)",
+			}, &JUnitTestResult{
+				Passed: result.SimulatedPassedExpected(ignoreLoopback),
+				Name:   result.TestCase.Description + " (expected, simulator)",
+			})
+		}
 	}
 
 	f, err := os.Create(filename)
@@ -199,13 +199,11 @@ func (t *Printer) PrintTestCaseResult(result *Result) {
 
 	fmt.Printf("evaluating test case: %s\n", result.TestCase.Description)
 	stepCount := len(result.TestCase.Steps)
-	resultCount := len(result.Steps)
-	if stepCount != resultCount {
-		panic(errors.Errorf("found %d test steps, but %d result steps", stepCount, resultCount))
-	}
-
-	for i := range result.Steps {
-		t.PrintStep(i+1, result.TestCase.Steps[i], result.Steps[i])
+	for _, stepResult := range result.Steps {
+		if stepResult.Step >= stepCount {
+			panic(errors.Errorf("found %d test steps, but a result for step %d", stepCount, stepResult.Step+1))
+		}
+		t.PrintStep(stepResult.Step+1, result.TestCase.Steps[stepResult.Step], stepResult)
 	}
 	//fmt.Println("features:")
 	//for feature := range result.TestCase.GetFeatures() {
@@ -216,10 +214,14 @@ func (t *Printer) PrintTestCaseResult(result *Result) {
 }
 
 func (t *Printer) PrintStep(i int, step *generator.TestStep, stepResult *StepResult) {
+	family := ""
+	if stepResult.IPFamily != "" {
+		family = fmt.Sprintf(" over %s", stepResult.IPFamily)
+	}
 	if step.Probe.PortProtocol != nil {
-		fmt.Printf("step %d on port %s, protocol %s:\n", i, step.Probe.PortProtocol.Port.String(), step.Probe.PortProtocol.Protocol)
+		fmt.Printf("step %d on port %s, protocol %s%s:\n", i, step.Probe.PortProtocol.Port.String(), step.Probe.PortProtocol.Protocol, family)
 	} else {
-		fmt.Printf("step %d on all available ports/protocols:\n", i)
+		fmt.Printf("step %d on all available ports/protocols%s:\n", i, family)
 	}
 	policy := stepResult.Policy
 
@@ -233,6 +235,12 @@ func (t *Printer) PrintStep(i int, step *generator.TestStep, stepResult *StepRes
 	} else {
 		fmt.Println("no network policies")
 	}
+	for _, anp := range stepResult.ANPs {
+		fmt.Printf("Admin network policy:\n\n%s\n", utils.YamlString(anp))
+	}
+	if stepResult.BANP != nil {
+		fmt.Printf("Baseline admin network policy:\n\n%s\n", utils.YamlString(stepResult.BANP))
+	}
 
 	if len(stepResult.KubeProbes) == 0 {
 		panic(errors.Errorf("found 0 KubeResults for step, expected 1 or more"))
@@ -260,6 +268,26 @@ func (t *Printer) PrintStep(i int, step *generator.TestStep, stepResult *StepRes
 	} else {
 		fmt.Printf("%s\n", stepResult.LastKubeProbe().RenderTable())
 	}
+
+	if stepResult.Expected != nil {
+		checks := stepResult.ExpectationChecks(t.IgnoreLoopback)
+		kubeWrong, simulatedWrong := 0, 0
+		for _, check := range checks {
+			if !check.KubeMatches() {
+				kubeWrong++
+			}
+			if !check.SimulatedMatches() {
+				simulatedWrong++
+			}
+		}
+		if kubeWrong > 0 {
+			fmt.Printf("Hand-written expectation discrepancy found:")
+		}
+		fmt.Printf("hand-written expectation: %d checked, %d wrong in kube, %d wrong in simulation\n", len(checks), kubeWrong, simulatedWrong)
+		if kubeWrong > 0 || simulatedWrong > 0 || t.Noisy {
+			fmt.Printf("Expected (hand-written) vs kube and simulation:\n%s\n", RenderExpectationChecks(checks, t.Noisy))
+		}
+	}
 }
 
 func PrintNetworkPolicy(p *networkingv1.NetworkPolicy) string {
@@ -46,6 +46,8 @@ type Job struct {
 	ResolvedPort     int
 	ResolvedPortName string
 	Protocol         v1.Protocol
+	// IPFamily is the family of FromIP and ToIP, if the job was built from resources of a single family
+	IPFamily v1.IPFamily
 
 	TimeoutSeconds int
 }
@@ -42,6 +42,7 @@ func (j *JobBuilder) GetJobsForNamedPortProtocol(resources *Resources, port ints
 				ResolvedPort:        -1,
 				ResolvedPortName:    "",
 				Protocol:            protocol,
+				IPFamily:            resources.IPFamily,
 				TimeoutSeconds:      j.TimeoutSeconds,
 			}
 
@@ -79,26 +80,29 @@ func (j *JobBuilder) GetJobsAllAvailableServers(resources *Resources, mode gener
 	for _, podFrom := range resources.Pods {
 		for _, podTo := range resources.Pods {
 			for _, contTo := range podTo.Containers {
-				jobs = append(jobs, &Job{
-					FromKey:             podFrom.PodString().String(),
-					FromNamespace:       podFrom.Namespace,
-					FromNamespaceLabels: resources.Namespaces[podFrom.Namespace],
-					FromPod:             podFrom.Name,
-					FromPodLabels:       podFrom.Labels,
-					FromContainer:       podFrom.Containers[0].Name,
-					FromIP:              podFrom.IP,
-					ToKey:               podTo.PodString().String(),
-					ToHost:              podTo.Host(mode),
-					ToNamespace:         podTo.Namespace,
-					ToNamespaceLabels:   resources.Namespaces[podTo.Namespace],
-					ToPodLabels:         podTo.Labels,
-					ToContainer:         contTo.Name,
-					ToIP:                podTo.IP,
-					ResolvedPort:        contTo.Port,
-					ResolvedPortName:    contTo.PortName,
-					Protocol:            contTo.Protocol,
-					TimeoutSeconds:      j.TimeoutSeconds,
-				})
+				for _, portTo := range contTo.Ports {
+					jobs = append(jobs, &Job{
+						FromKey:             podFrom.PodString().String(),
+						FromNamespace:       podFrom.Namespace,
+						FromNamespaceLabels: resources.Namespaces[podFrom.Namespace],
+						FromPod:             podFrom.Name,
+						FromPodLabels:       podFrom.Labels,
+						FromContainer:       podFrom.Containers[0].Name,
+						FromIP:              podFrom.IP,
+						ToKey:               podTo.PodString().String(),
+						ToHost:              podTo.Host(mode),
+						ToNamespace:         podTo.Namespace,
+						ToNamespaceLabels:   resources.Namespaces[podTo.Namespace],
+						ToPodLabels:         podTo.Labels,
+						ToContainer:         contTo.Name,
+						ToIP:                podTo.IP,
+						ResolvedPort:        portTo.Port,
+						ResolvedPortName:    portTo.PortName,
+						Protocol:            portTo.Protocol,
+						IPFamily:            resources.IPFamily,
+						TimeoutSeconds:      j.TimeoutSeconds,
+					})
+				}
 			}
 		}
 	}
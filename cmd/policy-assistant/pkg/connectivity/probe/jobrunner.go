@@ -9,6 +9,7 @@ import (
 	"github.com/mattfenwick/cyclonus/pkg/matcher"
 	"github.com/mattfenwick/cyclonus/pkg/worker"
 	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 )
 
 type Runner struct {
@@ -32,6 +33,23 @@ func (p *Runner) RunProbeForConfig(probeConfig *generator.ProbeConfig, resources
 	return NewTableFromJobResults(resources, p.runProbe(p.JobBuilder.GetJobsForProbeConfig(resources, probeConfig)))
 }
 
+// IPFamilyTable is the result of a probe over the IPs of a single family.  IPFamily is empty if the IPs of the pods
+// aren't known.
+type IPFamilyTable struct {
+	IPFamily v1.IPFamily
+	Table    *Table
+}
+
+// RunProbeForConfigPerIPFamily runs the probe once per IP family of the resources' pods, so that dual-stack clusters
+// are probed over both IPv4 and IPv6.  See Resources.SplitByIPFamily.
+func (p *Runner) RunProbeForConfigPerIPFamily(probeConfig *generator.ProbeConfig, resources *Resources) []*IPFamilyTable {
+	var tables []*IPFamilyTable
+	for _, familyResources := range resources.SplitByIPFamily() {
+		tables = append(tables, &IPFamilyTable{IPFamily: familyResources.IPFamily, Table: p.RunProbeForConfig(probeConfig, familyResources)})
+	}
+	return tables
+}
+
 func (p *Runner) runProbe(jobs *Jobs) []*JobResult {
 	resultSlice := p.JobRunner.RunJobs(jobs.Valid)
 
@@ -1,7 +1,9 @@
 package probe
 
 import (
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/mattfenwick/collections/pkg/slice"
@@ -44,14 +46,77 @@ func NewDefaultPod(ns string, name string, ports []int, protocols []v1.Protocol,
 }
 
 type Pod struct {
-	Namespace  string
-	Name       string
-	Labels     map[string]string
-	ServiceIP  string
-	IP         string
+	Namespace string
+	Name      string
+	Labels    map[string]string
+	ServiceIP string
+	IP        string
+	// ServiceIPs and IPs hold one IP per family on dual-stack clusters; ServiceIP and IP are used if they're empty
+	ServiceIPs []string `json:",omitempty"`
+	IPs        []string `json:",omitempty"`
 	Containers []*Container
 }
 
+// SetKubeIPs sets the IPs of the pod from the status of its kube pod, and its service IPs from its kube service.
+func (p *Pod) SetKubeIPs(kubePod *v1.Pod, kubeService *v1.Service) {
+	p.IP = kubePod.Status.PodIP
+	p.IPs = kube.PodIPs(kubePod)
+	if kubeService != nil {
+		p.ServiceIP = kubeService.Spec.ClusterIP
+		p.ServiceIPs = kube.ServiceClusterIPs(kubeService)
+	}
+}
+
+// AllIPs returns the IPs of the pod, or its single IP for models without IPs.
+func (p *Pod) AllIPs() []string {
+	if len(p.IPs) > 0 {
+		return p.IPs
+	}
+	if p.IP != "" {
+		return []string{p.IP}
+	}
+	return nil
+}
+
+// AllServiceIPs returns the IPs of the pod's service, or its single service IP for models without service IPs.
+func (p *Pod) AllServiceIPs() []string {
+	if len(p.ServiceIPs) > 0 {
+		return p.ServiceIPs
+	}
+	if p.ServiceIP != "" {
+		return []string{p.ServiceIP}
+	}
+	return nil
+}
+
+// IPFamilies returns the families of the pod's IPs, in order.  IPs that can't be parsed, such as those of pods
+// that haven't been created yet, are skipped.
+func (p *Pod) IPFamilies() []v1.IPFamily {
+	var families []v1.IPFamily
+	for _, ip := range p.AllIPs() {
+		family, err := kube.IPFamily(ip)
+		if err == nil && !slices.Contains(families, family) {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// ForIPFamily returns a copy of the pod whose IP and service IP are those of the family, or empty if the pod doesn't
+// have one.  It should not affect the original Pod object.
+func (p *Pod) ForIPFamily(family v1.IPFamily) *Pod {
+	return &Pod{
+		Namespace:  p.Namespace,
+		Name:       p.Name,
+		Labels:     p.Labels,
+		ServiceIP:  kube.IPForFamily(p.AllServiceIPs(), family),
+		IP:         kube.IPForFamily(p.AllIPs(), family),
+		ServiceIPs: p.ServiceIPs,
+		IPs:        p.IPs,
+		Containers: p.Containers,
+	}
+}
+
 func (p *Pod) Host(probeMode generator.ProbeMode) string {
 	switch probeMode {
 	case generator.ProbeModeServiceName:
@@ -72,14 +137,17 @@ func (p *Pod) IsEqualToKubePod(kubePod v1.Pod) (string, bool) {
 	}
 	for i, kubeCont := range kubeConts {
 		cont := p.Containers[i]
-		if len(kubeCont.Ports) != 1 {
-			return fmt.Sprintf("container %d: expected 1 port, found %d", i, len(kubeCont.Ports)), false
+		if len(kubeCont.Ports) != len(cont.Ports) {
+			return fmt.Sprintf("container %d: expected %d ports, found %d", i, len(cont.Ports), len(kubeCont.Ports)), false
 		}
-		if int(kubeCont.Ports[0].ContainerPort) != cont.Port {
-			return fmt.Sprintf("container %d: expected port %d, found %d", i, cont.Port, kubeCont.Ports[0].ContainerPort), false
-		}
-		if kubeCont.Ports[0].Protocol != cont.Protocol {
-			return fmt.Sprintf("container %d: expected protocol %s, found %s", i, cont.Protocol, kubeCont.Ports[0].Protocol), false
+		for j, kubePort := range kubeCont.Ports {
+			port := cont.Ports[j]
+			if int(kubePort.ContainerPort) != port.Port {
+				return fmt.Sprintf("container %d: expected port %d, found %d", i, port.Port, kubePort.ContainerPort), false
+			}
+			if kubePort.Protocol != port.Protocol {
+				return fmt.Sprintf("container %d: expected protocol %s, found %s", i, port.Protocol, kubePort.Protocol), false
+			}
 		}
 	}
 
@@ -112,7 +180,7 @@ func (p *Pod) KubeService() *v1.Service {
 			Namespace: p.Namespace,
 		},
 		Spec: v1.ServiceSpec{
-			Ports:    slice.Map(func(cont *Container) v1.ServicePort { return cont.KubeServicePort() }, p.Containers),
+			Ports:    slices.Concat(slice.Map(func(cont *Container) []v1.ServicePort { return cont.KubeServicePorts() }, p.Containers)...),
 			Selector: p.Labels,
 		},
 	}
@@ -124,8 +192,10 @@ func (p *Pod) KubeContainers() []v1.Container {
 
 func (p *Pod) ResolveNamedPort(port string) (int, error) {
 	for _, c := range p.Containers {
-		if c.PortName == port {
-			return c.Port, nil
+		for _, containerPort := range c.Ports {
+			if containerPort.PortName == port {
+				return containerPort.Port, nil
+			}
 		}
 	}
 	return 0, errors.Errorf("unable to resolve named port %s on pod %s/%s", port, p.Namespace, p.Name)
@@ -133,8 +203,10 @@ func (p *Pod) ResolveNamedPort(port string) (int, error) {
 
 func (p *Pod) ResolveNumberedPort(port int) (string, error) {
 	for _, c := range p.Containers {
-		if c.Port == port {
-			return c.PortName, nil
+		for _, containerPort := range c.Ports {
+			if containerPort.Port == port {
+				return containerPort.PortName, nil
+			}
 		}
 	}
 	return "", errors.Errorf("unable to resolve numbered port %d on pod %s/%s", port, p.Namespace, p.Name)
@@ -142,8 +214,10 @@ func (p *Pod) ResolveNumberedPort(port int) (string, error) {
 
 func (p *Pod) IsServingPortProtocol(port int, protocol v1.Protocol) bool {
 	for _, cont := range p.Containers {
-		if cont.Port == port && cont.Protocol == protocol {
-			return true
+		for _, containerPort := range cont.Ports {
+			if containerPort.Port == port && containerPort.Protocol == protocol {
+				return true
+			}
 		}
 	}
 	return false
@@ -154,7 +228,10 @@ func (p *Pod) SetLabels(labels map[string]string) *Pod {
 		Namespace:  p.Namespace,
 		Name:       p.Name,
 		Labels:     labels,
+		ServiceIP:  p.ServiceIP,
 		IP:         p.IP,
+		ServiceIPs: p.ServiceIPs,
+		IPs:        p.IPs,
 		Containers: p.Containers,
 	}
 }
@@ -163,32 +240,62 @@ func (p *Pod) PodString() PodString {
 	return NewPodString(p.Namespace, p.Name)
 }
 
+// ContainerPort is a port a container serves on.
+type ContainerPort struct {
+	Port     int
+	Protocol v1.Protocol
+	PortName string
+}
+
+// Container is a container and the ports it serves on.  Containers created by cyclonus serve on a single port, while
+// containers modeled from existing pods serve on the ports they declare.
 type Container struct {
 	Name          string
-	Port          int
-	Protocol      v1.Protocol
-	PortName      string
+	Ports         []*ContainerPort
 	BatchJobs     bool
 	ImageRegistry string
 }
 
 func NewDefaultContainer(port int, protocol v1.Protocol, batchJobs bool, imageRegistry string) *Container {
 	return &Container{
-		Name:          fmt.Sprintf("cont-%d-%s", port, strings.ToLower(string(protocol))),
-		Port:          port,
-		Protocol:      protocol,
-		PortName:      fmt.Sprintf("serve-%d-%s", port, strings.ToLower(string(protocol))),
+		Name: fmt.Sprintf("cont-%d-%s", port, strings.ToLower(string(protocol))),
+		Ports: []*ContainerPort{{
+			Port:     port,
+			Protocol: protocol,
+			PortName: fmt.Sprintf("serve-%d-%s", port, strings.ToLower(string(protocol))),
+		}},
 		BatchJobs:     batchJobs,
 		ImageRegistry: imageRegistry,
 	}
 }
 
-func (c *Container) KubeServicePort() v1.ServicePort {
-	return v1.ServicePort{
-		Name:     fmt.Sprintf("service-port-%s-%d", strings.ToLower(string(c.Protocol)), c.Port),
-		Protocol: c.Protocol,
-		Port:     int32(c.Port),
+// UnmarshalJSON also reads containers of model files which set a single Port, Protocol and PortName instead of Ports.
+func (c *Container) UnmarshalJSON(data []byte) error {
+	type container Container
+	var singlePort struct {
+		container
+		Port     int
+		Protocol v1.Protocol
+		PortName string
+	}
+	if err := json.Unmarshal(data, &singlePort); err != nil {
+		return err
 	}
+	*c = Container(singlePort.container)
+	if singlePort.Port != 0 {
+		c.Ports = append(c.Ports, &ContainerPort{Port: singlePort.Port, Protocol: singlePort.Protocol, PortName: singlePort.PortName})
+	}
+	return nil
+}
+
+func (c *Container) KubeServicePorts() []v1.ServicePort {
+	return slice.Map(func(port *ContainerPort) v1.ServicePort {
+		return v1.ServicePort{
+			Name:     fmt.Sprintf("service-port-%s-%d", strings.ToLower(string(port.Protocol)), port.Port),
+			Protocol: port.Protocol,
+			Port:     int32(port.Port),
+		}
+	}, c.Ports)
 }
 
 func (c *Container) Image() string {
@@ -198,24 +305,29 @@ func (c *Container) Image() string {
 	return c.ImageRegistry + "/" + agnhostImage
 }
 
+// KubeContainer is the container created by cyclonus to serve on the container's port.
 func (c *Container) KubeContainer() v1.Container {
+	if len(c.Ports) != 1 {
+		panic(errors.Errorf("container %s: expected 1 port to serve on, found %d", c.Name, len(c.Ports)))
+	}
+	port := c.Ports[0]
 	var cmd []string
 	var env []v1.EnvVar
 
-	switch c.Protocol {
+	switch port.Protocol {
 	case v1.ProtocolTCP:
-		cmd = []string{"/agnhost", "serve-hostname", "--tcp", "--http=false", "--port", fmt.Sprintf("%d", c.Port)}
+		cmd = []string{"/agnhost", "serve-hostname", "--tcp", "--http=false", "--port", fmt.Sprintf("%d", port.Port)}
 	case v1.ProtocolUDP:
-		cmd = []string{"/agnhost", "serve-hostname", "--udp", "--http=false", "--port", fmt.Sprintf("%d", c.Port)}
+		cmd = []string{"/agnhost", "serve-hostname", "--udp", "--http=false", "--port", fmt.Sprintf("%d", port.Port)}
 	case v1.ProtocolSCTP:
-		//cmd = []string{"/agnhost", "netexec", "--sctp-port", fmt.Sprintf("%d", c.Port)}
+		//cmd = []string{"/agnhost", "netexec", "--sctp-port", fmt.Sprintf("%d", port.Port)}
 		env = append(env, v1.EnvVar{
-			Name:  fmt.Sprintf("SERVE_SCTP_PORT_%d", c.Port),
+			Name:  fmt.Sprintf("SERVE_SCTP_PORT_%d", port.Port),
 			Value: "foo",
 		})
 		cmd = []string{"/agnhost", "porter"}
 	default:
-		panic(errors.Errorf("invalid protocol %s", c.Protocol))
+		panic(errors.Errorf("invalid protocol %s", port.Protocol))
 	}
 	return v1.Container{
 		Name:            c.Name,
@@ -226,9 +338,9 @@ func (c *Container) KubeContainer() v1.Container {
 		SecurityContext: &v1.SecurityContext{},
 		Ports: []v1.ContainerPort{
 			{
-				ContainerPort: int32(c.Port),
-				Name:          c.PortName,
-				Protocol:      c.Protocol,
+				ContainerPort: int32(port.Port),
+				Name:          port.PortName,
+				Protocol:      port.Protocol,
 			},
 		},
 	}
@@ -36,14 +36,16 @@ func (r *Resources) RenderTable() string {
 		for _, pod := range nsToPod[ns] {
 			podLabelLines := labelsToLines(pod.Labels)
 			for _, cont := range pod.Containers {
-				table.Append([]string{
-					ns,
-					nsLabelLines,
-					pod.Name,
-					podLabelLines,
-					fmt.Sprintf("pod: %s\nservice: %s", pod.IP, pod.ServiceIP),
-					fmt.Sprintf("%s, port %s: %d on %s", cont.Name, cont.PortName, cont.Port, cont.Protocol),
-				})
+				for _, port := range cont.Ports {
+					table.Append([]string{
+						ns,
+						nsLabelLines,
+						pod.Name,
+						podLabelLines,
+						fmt.Sprintf("pod: %s\nservice: %s", strings.Join(pod.AllIPs(), ", "), strings.Join(pod.AllServiceIPs(), ", ")),
+						fmt.Sprintf("%s, port %s: %d on %s", cont.Name, port.PortName, port.Port, port.Protocol),
+					})
+				}
 			}
 		}
 	}
@@ -1,6 +1,7 @@
 package probe
 
 import (
+	"slices"
 	"time"
 
 	"github.com/mattfenwick/collections/pkg/slice"
@@ -15,6 +16,8 @@ import (
 type Resources struct {
 	Namespaces map[string]map[string]string
 	Pods       []*Pod
+	// IPFamily is set on resources returned by ForIPFamily
+	IPFamily v1.IPFamily `json:",omitempty"`
 	//ExternalIPs []string
 	ports     []int
 	protocols []v1.Protocol
@@ -53,6 +56,64 @@ func NewDefaultResources(kubernetes kube.IKubernetes, namespaces []string, podNa
 	return r, nil
 }
 
+// NewResourcesFromKubePods models existing pods and namespaces, without creating anything in kube.
+// Each container with declared ports is modeled with those ports as probe targets; pods without declared ports are
+// skipped.
+// If groupByWorkload is true, each workload is represented by its first pod, named `<kind>/<name>`,
+// so that for example the pods of a Deployment show up as `namespace/deployment/name`.
+func NewResourcesFromKubePods(kubePods []v1.Pod, kubeNamespaces []v1.Namespace, groupByWorkload bool) *Resources {
+	r := &Resources{Namespaces: map[string]map[string]string{}}
+	for _, ns := range kubeNamespaces {
+		r.Namespaces[ns.Name] = ns.Labels
+	}
+
+	workloads := map[string]bool{}
+	for _, pod := range kubePods {
+		var containers []*Container
+		for _, cont := range pod.Spec.Containers {
+			if len(cont.Ports) == 0 {
+				continue
+			}
+			container := &Container{Name: cont.Name}
+			for _, port := range cont.Ports {
+				container.Ports = append(container.Ports, &ContainerPort{
+					Port:     int(port.ContainerPort),
+					Protocol: port.Protocol,
+					PortName: port.Name,
+				})
+			}
+			containers = append(containers, container)
+		}
+		if len(containers) == 0 {
+			logrus.Warnf("skipping pod %s/%s, no container ports declared", pod.Namespace, pod.Name)
+			continue
+		}
+
+		name := pod.Name
+		if groupByWorkload {
+			kind, workload := kube.PodWorkload(&pod)
+			name = kind + "/" + workload
+			key := pod.Namespace + "/" + name
+			if workloads[key] {
+				continue
+			}
+			workloads[key] = true
+		}
+		if _, ok := r.Namespaces[pod.Namespace]; !ok {
+			r.Namespaces[pod.Namespace] = map[string]string{v1.LabelMetadataName: pod.Namespace}
+		}
+		newPod := &Pod{
+			Namespace:  pod.Namespace,
+			Name:       name,
+			Labels:     pod.Labels,
+			Containers: containers,
+		}
+		newPod.SetKubeIPs(&pod, nil)
+		r.Pods = append(r.Pods, newPod)
+	}
+	return r
+}
+
 func (r *Resources) waitForPodsReady(kubernetes kube.IKubernetes, timeoutSeconds int) error {
 	sleep := 5
 	for i := 0; i < timeoutSeconds; i += sleep {
@@ -92,14 +153,13 @@ func (r *Resources) getPodIPsFromKube(kubernetes kube.IKubernetes) error {
 		if err != nil {
 			return errors.Errorf("unable to find pod %s/%s in resources", kubePod.Namespace, kubePod.Name)
 		}
-		pod.IP = kubePod.Status.PodIP
 		kubeService, err := kubernetes.GetService(pod.Namespace, pod.ServiceName())
 		if err != nil {
 			return err
 		}
-		pod.ServiceIP = kubeService.Spec.ClusterIP
+		pod.SetKubeIPs(&kubePod, kubeService)
 
-		logrus.Debugf("ip for pod %s/%s: %s", pod.Namespace, pod.Name, pod.IP)
+		logrus.Debugf("ips for pod %s/%s: %+v", pod.Namespace, pod.Name, pod.AllIPs())
 	}
 
 	return nil
@@ -122,6 +182,56 @@ func (r *Resources) getNamespaceLabelsFromKube(kubernetes kube.IKubernetes) erro
 	return nil
 }
 
+// IPFamilies returns the families of the pods' IPs, IPv4 first.
+func (r *Resources) IPFamilies() []v1.IPFamily {
+	var families []v1.IPFamily
+	for _, family := range []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol} {
+		for _, pod := range r.Pods {
+			if slices.Contains(pod.IPFamilies(), family) {
+				families = append(families, family)
+				break
+			}
+		}
+	}
+	return families
+}
+
+// ForIPFamily returns a new object with the pods that have an IP of the family, whose IPs and service IPs are those
+// of the family.  It should not affect the original Resources object.
+func (r *Resources) ForIPFamily(family v1.IPFamily) *Resources {
+	var pods []*Pod
+	for _, pod := range r.Pods {
+		if slices.Contains(pod.IPFamilies(), family) {
+			pods = append(pods, pod.ForIPFamily(family))
+		}
+	}
+	return &Resources{
+		Namespaces: r.Namespaces,
+		Pods:       pods,
+		IPFamily:   family,
+		ports:      r.ports,
+		protocols:  r.protocols,
+	}
+}
+
+// SplitByIPFamily returns resources for each IP family of the pods, as returned by ForIPFamily, if there's more than
+// one.  Otherwise, it returns the resources with their family set, keeping pods whose IPs aren't known yet.
+func (r *Resources) SplitByIPFamily() []*Resources {
+	families := r.IPFamilies()
+	if len(families) < 2 {
+		resources := *r
+		if len(families) == 1 {
+			resources.IPFamily = families[0]
+		}
+		return []*Resources{&resources}
+	}
+	var split []*Resources
+	for _, family := range families {
+		split = append(split, r.ForIPFamily(family))
+	}
+	return split
+}
+
 func (r *Resources) GetPod(ns string, name string) (*Pod, error) {
 	for _, pod := range r.Pods {
 		if pod.Namespace == ns && pod.Name == name {
@@ -1,8 +1,15 @@
 package probe
 
 import (
+	"encoding/json"
+
+	"github.com/mattfenwick/cyclonus/pkg/generator"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func RunResourcesTests() {
@@ -49,5 +56,136 @@ func RunResourcesTests() {
 			Expect(r.Pods[0].Labels).To(Equal(labels))
 			Expect(r2.Pods[0].Labels).To(Equal(map[string]string{}))
 		})
+
+		It("Should model kube pods on their declared ports", func() {
+			isController := true
+			webPod := func(name string) v1.Pod {
+				return v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace:       "x",
+						Name:            name,
+						Labels:          map[string]string{"app": "web", "pod-template-hash": "5d4f8c"},
+						OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d4f8c", Controller: &isController}},
+					},
+					Spec: v1.PodSpec{Containers: []v1.Container{{
+						Name:  "web",
+						Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 80, Protocol: v1.ProtocolTCP}, {ContainerPort: 53, Protocol: v1.ProtocolUDP}},
+					}}},
+				}
+			}
+			noPorts := v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "client"}, Spec: v1.PodSpec{Containers: []v1.Container{{Name: "client"}}}}
+			kubePods := []v1.Pod{webPod("web-5d4f8c-abcde"), webPod("web-5d4f8c-fghij"), noPorts}
+			kubeNamespaces := []v1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "x", Labels: map[string]string{"ns": "x"}}}}
+
+			r := NewResourcesFromKubePods(kubePods, kubeNamespaces, false)
+			Expect(r.Namespaces).To(Equal(map[string]map[string]string{"x": {"ns": "x"}}))
+			Expect(r.SortedPodNames()).To(Equal([]string{"x/web-5d4f8c-abcde", "x/web-5d4f8c-fghij"}))
+			Expect(r.Pods[0].Containers).To(Equal([]*Container{{
+				Name: "web",
+				Ports: []*ContainerPort{
+					{Port: 80, Protocol: v1.ProtocolTCP, PortName: "http"},
+					{Port: 53, Protocol: v1.ProtocolUDP},
+				},
+			}}))
+
+			grouped := NewResourcesFromKubePods(kubePods, kubeNamespaces, true)
+			Expect(grouped.SortedPodNames()).To(Equal([]string{"x/deployment/web"}))
+
+			jobs := (&JobBuilder{TimeoutSeconds: 1}).GetJobsAllAvailableServers(grouped, generator.ProbeModePodIP)
+			Expect(jobs.Valid).To(HaveLen(2))
+			Expect(jobs.Valid[0].ToContainer).To(Equal("web"))
+			Expect(jobs.Valid[0].ResolvedPortName).To(Equal("http"))
+			Expect(jobs.Valid[1].ToContainer).To(Equal("web"))
+			Expect(jobs.Valid[1].Protocol).To(Equal(v1.ProtocolUDP))
+		})
+
+		It("Should read containers of model files with a single port", func() {
+			var containers []*Container
+			Expect(json.Unmarshal([]byte(`[
+				{"Name": "cont-1", "Port": 80, "PortName": "serve-80-tcp", "Protocol": "TCP"},
+				{"Name": "cont-2", "Ports": [{"Port": 53, "Protocol": "UDP"}, {"Port": 53, "Protocol": "TCP"}]}
+			]`), &containers)).To(Succeed())
+			Expect(containers).To(Equal([]*Container{
+				{Name: "cont-1", Ports: []*ContainerPort{{Port: 80, Protocol: v1.ProtocolTCP, PortName: "serve-80-tcp"}}},
+				{Name: "cont-2", Ports: []*ContainerPort{{Port: 53, Protocol: v1.ProtocolUDP}, {Port: 53, Protocol: v1.ProtocolTCP}}},
+			}))
+		})
+	})
+
+	Describe("Dual-stack resources", func() {
+		containers := []*Container{NewDefaultContainer(80, v1.ProtocolTCP, false, "")}
+		r := &Resources{
+			Namespaces: map[string]map[string]string{"x": {"ns": "x"}},
+			Pods: []*Pod{
+				{Namespace: "x", Name: "a", Labels: map[string]string{"pod": "a"}, IP: "10.244.0.5", IPs: []string{"10.244.0.5", "fd00:10:244::5"}, ServiceIP: "10.96.0.5", ServiceIPs: []string{"10.96.0.5", "fd00:10:96::5"}, Containers: containers},
+				{Namespace: "x", Name: "b", Labels: map[string]string{"pod": "b"}, IP: "fd00:10:244::6", IPs: []string{"fd00:10:244::6", "10.244.0.6"}, Containers: containers},
+				{Namespace: "x", Name: "c", Labels: map[string]string{"pod": "c"}, IP: "10.244.0.7", Containers: containers},
+			},
+		}
+
+		It("Should find the IP families of pods", func() {
+			Expect(r.Pods[0].IPFamilies()).To(Equal([]v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}))
+			Expect(r.Pods[1].IPFamilies()).To(Equal([]v1.IPFamily{v1.IPv6Protocol, v1.IPv4Protocol}))
+			Expect(r.Pods[2].IPFamilies()).To(Equal([]v1.IPFamily{v1.IPv4Protocol}))
+			Expect((&Pod{IP: "TODO"}).IPFamilies()).To(BeEmpty())
+			Expect(r.IPFamilies()).To(Equal([]v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}))
+		})
+
+		It("Should pick the IPs of a family nondestructively", func() {
+			v6 := r.ForIPFamily(v1.IPv6Protocol)
+			Expect(v6.IPFamily).To(Equal(v1.IPv6Protocol))
+			Expect(v6.SortedPodNames()).To(Equal([]string{"x/a", "x/b"}))
+			Expect(v6.Pods[0].IP).To(Equal("fd00:10:244::5"))
+			Expect(v6.Pods[0].ServiceIP).To(Equal("fd00:10:96::5"))
+			Expect(v6.Pods[1].IP).To(Equal("fd00:10:244::6"))
+			Expect(v6.Pods[1].ServiceIP).To(Equal(""))
+
+			Expect(r.IPFamily).To(Equal(v1.IPFamily("")))
+			Expect(r.Pods[0].IP).To(Equal("10.244.0.5"))
+		})
+
+		It("Should split by IP family only if there's more than one", func() {
+			split := r.SplitByIPFamily()
+			Expect(split).To(HaveLen(2))
+			Expect(split[0].IPFamily).To(Equal(v1.IPv4Protocol))
+			Expect(split[0].SortedPodNames()).To(Equal([]string{"x/a", "x/b", "x/c"}))
+			Expect(split[1].IPFamily).To(Equal(v1.IPv6Protocol))
+
+			singleStack := &Resources{Namespaces: r.Namespaces, Pods: []*Pod{r.Pods[2], {Namespace: "x", Name: "d", IP: "TODO"}}}
+			split = singleStack.SplitByIPFamily()
+			Expect(split).To(HaveLen(1))
+			Expect(split[0].IPFamily).To(Equal(v1.IPv4Protocol))
+			Expect(split[0].Pods).To(HaveLen(2))
+		})
+
+		It("Should run a probe per IP family", func() {
+			policy := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "allow-ipv4-egress"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{},
+					Egress:      []networkingv1.NetworkPolicyEgressRule{{To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}}}}},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				},
+			}
+			policies, errs := matcher.BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{policy})
+			Expect(errs).To(BeEmpty())
+
+			runner := NewSimulatedRunner(policies, &JobBuilder{TimeoutSeconds: 1})
+			tables := runner.RunProbeForConfigPerIPFamily(generator.NewAllAvailable(generator.ProbeModePodIP), r)
+			Expect(tables).To(HaveLen(2))
+
+			Expect(tables[0].IPFamily).To(Equal(v1.IPv4Protocol))
+			ipv4 := tables[0].Table.Get("x/a", "x/b").JobResults["TCP/80"]
+			Expect(ipv4.Job.IPFamily).To(Equal(v1.IPv4Protocol))
+			Expect(ipv4.Job.ToHost).To(Equal("10.244.0.6"))
+			Expect(ipv4.Combined).To(Equal(ConnectivityAllowed))
+
+			Expect(tables[1].IPFamily).To(Equal(v1.IPv6Protocol))
+			Expect(tables[1].Table.Result().Pods).To(Equal([]string{"x/a", "x/b"}))
+			ipv6 := tables[1].Table.Get("x/a", "x/b").JobResults["TCP/80"]
+			Expect(ipv6.Job.ToHost).To(Equal("fd00:10:244::6"))
+			Expect(ipv6.Job.ToAddress()).To(Equal("[fd00:10:244::6]:80"))
+			Expect(ipv6.Combined).To(Equal(ConnectivityBlocked))
+		})
 	})
 }
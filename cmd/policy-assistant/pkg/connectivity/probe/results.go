@@ -0,0 +1,52 @@
+package probe
+
+import (
+	"github.com/mattfenwick/collections/pkg/slice"
+	"golang.org/x/exp/maps"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ConnectionResult is the connectivity of a single connection from a probe truth table.
+type ConnectionResult struct {
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Port     int          `json:"port"`
+	PortName string       `json:"portName,omitempty"`
+	Protocol v1.Protocol  `json:"protocol"`
+	Ingress  Connectivity `json:"ingress,omitempty"`
+	Egress   Connectivity `json:"egress,omitempty"`
+	Combined Connectivity `json:"combined"`
+}
+
+// TableResult holds the same information as a Table, as a flat list of connections.
+type TableResult struct {
+	Pods        []string            `json:"pods"`
+	Connections []*ConnectionResult `json:"connections"`
+}
+
+// Result returns every job result of the table, ordered by source, destination and then port/protocol.
+func (t *Table) Result() *TableResult {
+	result := &TableResult{Pods: t.Wrapped.Froms, Connections: []*ConnectionResult{}}
+	for _, key := range t.Wrapped.Keys() {
+		jobResults := t.Get(key.From, key.To).JobResults
+		for _, k := range slice.Sort(maps.Keys(jobResults)) {
+			jr := jobResults[k]
+			connection := &ConnectionResult{
+				From:     key.From,
+				To:       key.To,
+				Port:     jr.Job.ResolvedPort,
+				PortName: jr.Job.ResolvedPortName,
+				Protocol: jr.Job.Protocol,
+				Combined: jr.Combined,
+			}
+			if jr.Ingress != nil {
+				connection.Ingress = *jr.Ingress
+			}
+			if jr.Egress != nil {
+				connection.Egress = *jr.Egress
+			}
+			result.Connections = append(result.Connections, connection)
+		}
+	}
+	return result
+}
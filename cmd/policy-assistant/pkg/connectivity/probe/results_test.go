@@ -0,0 +1,31 @@
+package probe
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+)
+
+func RunResultsTests() {
+	Describe("Table.Result", func() {
+		It("lists every job result, ordered by source, destination and port/protocol", func() {
+			allowed, blocked := ConnectivityAllowed, ConnectivityBlocked
+			table := NewTable([]string{"x/a", "x/b"})
+			for _, jr := range []*JobResult{
+				{Job: &Job{FromKey: "x/a", ToKey: "x/b", ResolvedPort: 81, Protocol: v1.ProtocolTCP}, Ingress: &blocked, Egress: &allowed, Combined: ConnectivityBlocked},
+				{Job: &Job{FromKey: "x/a", ToKey: "x/b", ResolvedPort: 80, Protocol: v1.ProtocolTCP}, Ingress: &allowed, Egress: &allowed, Combined: ConnectivityAllowed},
+				{Job: &Job{FromKey: "x/b", ToKey: "x/a", ResolvedPort: 80, Protocol: v1.ProtocolUDP}, Combined: ConnectivityUnknown},
+			} {
+				Expect(table.Get(jr.Job.FromKey, jr.Job.ToKey).AddJobResult(jr)).To(Succeed())
+			}
+
+			result := table.Result()
+			Expect(result.Pods).To(Equal([]string{"x/a", "x/b"}))
+			Expect(result.Connections).To(Equal([]*ConnectionResult{
+				{From: "x/a", To: "x/b", Port: 80, Protocol: v1.ProtocolTCP, Ingress: ConnectivityAllowed, Egress: ConnectivityAllowed, Combined: ConnectivityAllowed},
+				{From: "x/a", To: "x/b", Port: 81, Protocol: v1.ProtocolTCP, Ingress: ConnectivityBlocked, Egress: ConnectivityAllowed, Combined: ConnectivityBlocked},
+				{From: "x/b", To: "x/a", Port: 80, Protocol: v1.ProtocolUDP, Combined: ConnectivityUnknown},
+			}))
+		})
+	})
+}
@@ -10,5 +10,6 @@ import (
 func TestProbe(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunResourcesTests()
+	RunResultsTests()
 	RunSpecs(t, "generator suite")
 }
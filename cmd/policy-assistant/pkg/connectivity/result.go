@@ -30,7 +30,11 @@ func (r *Result) Features() map[string][]string {
 	return r.TestCase.GetFeatures()
 }
 
+// Passed compares kube to the simulator.  A test case that failed to execute hasn't passed.
 func (r *Result) Passed(ignoreLoopback bool) bool {
+	if r.Err != nil {
+		return false
+	}
 	for _, step := range r.Steps {
 		if !step.Passed(ignoreLoopback) {
 			return false
@@ -38,3 +42,39 @@ func (r *Result) Passed(ignoreLoopback bool) bool {
 	}
 	return true
 }
+
+// HasExpected checks whether any steps of the test case have hand-written expected connectivity.
+func (r *Result) HasExpected() bool {
+	for _, step := range r.TestCase.Steps {
+		if step.Expected != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// PassedExpected compares kube to the hand-written expected connectivity of each step that has it.
+func (r *Result) PassedExpected(ignoreLoopback bool) bool {
+	if r.Err != nil {
+		return false
+	}
+	for _, step := range r.Steps {
+		if !step.PassedExpected(ignoreLoopback) {
+			return false
+		}
+	}
+	return true
+}
+
+// SimulatedPassedExpected compares the simulator to the hand-written expected connectivity of each step that has it.
+func (r *Result) SimulatedPassedExpected(ignoreLoopback bool) bool {
+	if r.Err != nil {
+		return false
+	}
+	for _, step := range r.Steps {
+		if !step.SimulatedPassedExpected(ignoreLoopback) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,20 +2,28 @@ package connectivity
 
 import (
 	"github.com/mattfenwick/cyclonus/pkg/connectivity/probe"
+	"github.com/mattfenwick/cyclonus/pkg/generator"
 	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 
 	networkingv1 "k8s.io/api/networking/v1"
 )
 
+// StepResult is the result of a step of a test case.  On dual-stack clusters, each step has one result per IP family.
 type StepResult struct {
+	// Step is the index of the step in the test case
+	Step           int
+	IPFamily       v1.IPFamily
 	SimulatedProbe *probe.Table
 	KubeProbes     []*probe.Table
 	Policy         *matcher.Policy
 	KubePolicies   []*networkingv1.NetworkPolicy
 	ANPs           []*v1alpha1.AdminNetworkPolicy
 	BANP           *v1alpha1.BaselineAdminNetworkPolicy
-	comparisons    []*ComparisonTable
+	// Expected is the step's optional hand-written expected connectivity
+	Expected    *generator.ExpectedConnectivity
+	comparisons []*ComparisonTable
 }
 
 func NewStepResult(simulated *probe.Table, policy *matcher.Policy, kubePolicies []*networkingv1.NetworkPolicy) *StepResult {
@@ -46,6 +54,35 @@ func (s *StepResult) LastKubeProbe() *probe.Table {
 	return s.KubeProbes[len(s.KubeProbes)-1]
 }
 
+// Passed compares the last kube probe to the simulated probe.
 func (s *StepResult) Passed(ignoreLoopback bool) bool {
 	return s.LastComparison().ValueCounts(ignoreLoopback)[DifferentComparison] == 0
 }
+
+// ExpectationChecks compares the last kube probe, and the simulated probe, to the hand-written expected connectivity.
+func (s *StepResult) ExpectationChecks(ignoreLoopback bool) []*ExpectationCheck {
+	if s.Expected == nil {
+		return nil
+	}
+	return NewExpectationChecks(s.Expected, s.LastKubeProbe(), s.SimulatedProbe, ignoreLoopback)
+}
+
+// PassedExpected compares the last kube probe to the hand-written expected connectivity, if there is any.
+func (s *StepResult) PassedExpected(ignoreLoopback bool) bool {
+	for _, check := range s.ExpectationChecks(ignoreLoopback) {
+		if !check.KubeMatches() {
+			return false
+		}
+	}
+	return true
+}
+
+// SimulatedPassedExpected compares the simulated probe to the hand-written expected connectivity, if there is any.
+func (s *StepResult) SimulatedPassedExpected(ignoreLoopback bool) bool {
+	for _, check := range s.ExpectationChecks(ignoreLoopback) {
+		if !check.SimulatedMatches() {
+			return false
+		}
+	}
+	return true
+}
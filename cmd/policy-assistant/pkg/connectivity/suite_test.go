@@ -11,5 +11,6 @@ func TestConnectivity(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunTestCaseStateTests()
 	RunPrinterTests()
+	RunExpectationTests()
 	RunSpecs(t, "connectivity suite")
 }
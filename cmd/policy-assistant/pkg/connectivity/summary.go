@@ -63,8 +63,27 @@ func NewSummaryTableFromResults(ignoreLoopback bool, results []*Result) *Summary
 			testResult, "", "", "", "",
 			"", "", "",
 		})
+		if result.HasExpected() {
+			passedOrFailed := func(passed bool) string {
+				if passed {
+					return "passed"
+				}
+				return "failed"
+			}
+			summary.Tests = append(summary.Tests, []string{
+				"", "hand-written: " + passedOrFailed(result.PassedExpected(ignoreLoopback)), "", "", "", "",
+				"", "", "",
+			}, []string{
+				"", "hand-written, simulator: " + passedOrFailed(result.SimulatedPassedExpected(ignoreLoopback)), "", "", "", "",
+				"", "", "",
+			})
+		}
 
-		for stepNumber, step := range result.Steps {
+		for _, step := range result.Steps {
+			stepName := fmt.Sprintf("Step %d", step.Step+1)
+			if step.IPFamily != "" {
+				stepName = fmt.Sprintf("Step %d (%s)", step.Step+1, step.IPFamily)
+			}
 			for tryNumber := range step.KubeProbes {
 				counts := step.Comparison(tryNumber).ValueCounts(ignoreLoopback)
 				tryProtocolCounts := step.Comparison(tryNumber).ValueCountsByProtocol(ignoreLoopback)
@@ -74,7 +93,7 @@ func NewSummaryTableFromResults(ignoreLoopback bool, results []*Result) *Summary
 				summary.Tests = append(summary.Tests, []string{
 					"",
 					"",
-					fmt.Sprintf("Step %d, try %d", stepNumber+1, tryNumber+1),
+					fmt.Sprintf("%s, try %d", stepName, tryNumber+1),
 					intToString(counts[DifferentComparison]),
 					intToString(counts[SameComparison]),
 					intToString(counts[IgnoredComparison]),
@@ -2,6 +2,7 @@ package connectivity
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/mattfenwick/cyclonus/pkg/connectivity/probe"
@@ -10,12 +11,42 @@ import (
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 )
 
 type TestCaseState struct {
 	Kubernetes kube.IKubernetes
 	Resources  *probe.Resources
 	Policies   []*networkingv1.NetworkPolicy
+	ANPs       []*v1alpha1.AdminNetworkPolicy
+	BANP       *v1alpha1.BaselineAdminNetworkPolicy
+	// DeleteExistingBANP allows resetting the cluster to delete a BANP which cyclonus didn't create
+	DeleteExistingBANP bool
+}
+
+// managedObjectMeta adds ManagedLabel to a copy of an ANP's or BANP's metadata, so that only the policies cyclonus
+// created are cleaned up.
+func managedObjectMeta(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	meta = *meta.DeepCopy()
+	if meta.Labels == nil {
+		meta.Labels = map[string]string{}
+	}
+	meta.Labels[kube.ManagedLabel] = kube.ManagedLabelValue
+	return meta
+}
+
+func managedANP(policy *v1alpha1.AdminNetworkPolicy) *v1alpha1.AdminNetworkPolicy {
+	return &v1alpha1.AdminNetworkPolicy{ObjectMeta: managedObjectMeta(policy.ObjectMeta), Spec: policy.Spec}
+}
+
+func managedBANP(policy *v1alpha1.BaselineAdminNetworkPolicy) *v1alpha1.BaselineAdminNetworkPolicy {
+	return &v1alpha1.BaselineAdminNetworkPolicy{ObjectMeta: managedObjectMeta(policy.ObjectMeta), Spec: policy.Spec}
+}
+
+func isManaged(meta metav1.ObjectMeta) bool {
+	return meta.Labels[kube.ManagedLabel] == kube.ManagedLabelValue
 }
 
 func (t *TestCaseState) CreatePolicy(policy *networkingv1.NetworkPolicy) error {
@@ -51,6 +82,69 @@ func (t *TestCaseState) UpdatePolicy(policy *networkingv1.NetworkPolicy) error {
 	return err
 }
 
+func (t *TestCaseState) CreateAdminNetworkPolicy(policy *v1alpha1.AdminNetworkPolicy) error {
+	for _, anp := range t.ANPs {
+		if anp.Name == policy.Name {
+			return errors.Errorf("cannot create admin network policy %s: already exists", policy.Name)
+		}
+	}
+	t.ANPs = append(t.ANPs, policy)
+
+	_, err := t.Kubernetes.CreateAdminNetworkPolicy(context.TODO(), managedANP(policy))
+	return err
+}
+
+func (t *TestCaseState) UpdateAdminNetworkPolicy(policy *v1alpha1.AdminNetworkPolicy) error {
+	index := slices.IndexFunc(t.ANPs, func(anp *v1alpha1.AdminNetworkPolicy) bool { return anp.Name == policy.Name })
+	if index == -1 {
+		return errors.Errorf("cannot update admin network policy %s: not found", policy.Name)
+	}
+
+	t.ANPs[index] = policy
+	_, err := t.Kubernetes.UpdateAdminNetworkPolicy(context.TODO(), managedANP(policy))
+	return err
+}
+
+func (t *TestCaseState) DeleteAdminNetworkPolicy(name string) error {
+	index := slices.IndexFunc(t.ANPs, func(anp *v1alpha1.AdminNetworkPolicy) bool { return anp.Name == name })
+	if index == -1 {
+		return errors.Errorf("cannot delete admin network policy %s: not found", name)
+	}
+
+	t.ANPs = slices.Delete(slices.Clone(t.ANPs), index, index+1)
+	return t.Kubernetes.DeleteAdminNetworkPolicy(context.TODO(), name)
+}
+
+func (t *TestCaseState) CreateBaselineAdminNetworkPolicy(policy *v1alpha1.BaselineAdminNetworkPolicy) error {
+	// there's at most one BANP in a cluster
+	if t.BANP != nil {
+		return errors.Errorf("cannot create baseline admin network policy %s: %s already exists", policy.Name, t.BANP.Name)
+	}
+	t.BANP = policy
+
+	_, err := t.Kubernetes.CreateBaselineAdminNetworkPolicy(context.TODO(), managedBANP(policy))
+	return err
+}
+
+func (t *TestCaseState) UpdateBaselineAdminNetworkPolicy(policy *v1alpha1.BaselineAdminNetworkPolicy) error {
+	if t.BANP == nil || t.BANP.Name != policy.Name {
+		return errors.Errorf("cannot update baseline admin network policy %s: not found", policy.Name)
+	}
+
+	t.BANP = policy
+	_, err := t.Kubernetes.UpdateBaselineAdminNetworkPolicy(context.TODO(), managedBANP(policy))
+	return err
+}
+
+func (t *TestCaseState) DeleteBaselineAdminNetworkPolicy(name string) error {
+	if t.BANP == nil || t.BANP.Name != name {
+		return errors.Errorf("cannot delete baseline admin network policy %s: not found", name)
+	}
+
+	t.BANP = nil
+	return t.Kubernetes.DeleteBaselineAdminNetworkPolicy(context.TODO(), name)
+}
+
 func (t *TestCaseState) CreateNamespace(ns string, labels map[string]string) error {
 	newResources, err := t.Resources.CreateNamespace(ns, labels)
 	if err != nil {
@@ -94,7 +188,7 @@ func (t *TestCaseState) CreatePod(ns string, pod string, labels map[string]strin
 	if err != nil {
 		return err
 	}
-	_, err = t.Kubernetes.CreateService(newPod.KubeService())
+	kubeService, err := t.Kubernetes.CreateService(newPod.KubeService())
 	if err != nil {
 		return err
 	}
@@ -105,7 +199,7 @@ func (t *TestCaseState) CreatePod(ns string, pod string, labels map[string]strin
 			return err
 		}
 		if kubePod.Status.Phase == "Running" && kubePod.Status.PodIP != "" {
-			newPod.IP = kubePod.Status.PodIP
+			newPod.SetKubeIPs(kubePod, kubeService)
 			return nil
 		}
 		time.Sleep(5 * time.Second)
@@ -199,8 +293,8 @@ func (t *TestCaseState) verifyClusterStateHelper() error {
 			if !NewLabelsDiff(actualPod.Labels, expectedPod.Labels).AreLabelsEqual() {
 				return errors.Errorf("for pod %s, expected labels %+v (found %+v)", expectedPod.PodString().String(), expectedPod.Labels, actualPod.Labels)
 			}
-			if actualPod.Status.PodIP != expectedPod.IP {
-				return errors.Errorf("for pod %s, expected ip %s (found %s)", expectedPod.PodString().String(), expectedPod.IP, actualPod.Status.PodIP)
+			if actualIPs := kube.PodIPs(&actualPod); !slices.Equal(actualIPs, expectedPod.AllIPs()) {
+				return errors.Errorf("for pod %s, expected ips %+v (found %+v)", expectedPod.PodString().String(), expectedPod.AllIPs(), actualIPs)
 			}
 			if diff, ok := expectedPod.IsEqualToKubePod(actualPod); !ok {
 				return errors.Errorf("for pod %s, %s", expectedPod.PodString().String(), diff)
@@ -308,12 +402,46 @@ func (t *TestCaseState) resetLabelsInKubeHelper() error {
 	return nil
 }
 
+// getAdminNetworkPolicies reads the ANPs and BANP of the cluster.  A cluster without the ANP and BANP CRDs has none.
+func (t *TestCaseState) getAdminNetworkPolicies() ([]v1alpha1.AdminNetworkPolicy, *v1alpha1.BaselineAdminNetworkPolicy, error) {
+	anps, err := t.Kubernetes.GetAdminNetworkPolicies(context.TODO())
+	if kerrors.IsNotFound(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to get admin network policies")
+	}
+	banp, err := t.Kubernetes.GetBaselineAdminNetworkPolicy(context.TODO())
+	if kerrors.IsNotFound(err) {
+		return anps, nil, nil
+	}
+	return anps, banp, errors.Wrapf(err, "unable to get baseline admin network policy")
+}
+
 func (t *TestCaseState) ResetClusterState() error {
 	err := kube.DeleteAllNetworkPoliciesInNamespaces(t.Kubernetes, t.Resources.NamespacesSlice())
 	if err != nil {
 		return err
 	}
 
+	// ANPs and BANPs are cluster-scoped, so only delete those which cyclonus created: others belong to the cluster
+	anps, banp, err := t.getAdminNetworkPolicies()
+	if err != nil {
+		return err
+	}
+	for _, anp := range anps {
+		if !isManaged(anp.ObjectMeta) {
+			continue
+		}
+		if err := t.Kubernetes.DeleteAdminNetworkPolicy(context.TODO(), anp.Name); err != nil {
+			return err
+		}
+	}
+	if banp != nil && (isManaged(banp.ObjectMeta) || t.DeleteExistingBANP) {
+		if err := t.Kubernetes.DeleteBaselineAdminNetworkPolicy(context.TODO(), banp.Name); err != nil {
+			return err
+		}
+	}
+
 	return t.resetLabelsInKubeHelper()
 }
 
@@ -330,5 +458,22 @@ func (t *TestCaseState) VerifyClusterState() error {
 	if len(policies) > 0 {
 		return errors.Errorf("expected 0 policies in namespaces %+v, found %d", t.Resources.NamespacesSlice(), len(policies))
 	}
+
+	anps, banp, err := t.getAdminNetworkPolicies()
+	if err != nil {
+		return err
+	}
+	for _, anp := range anps {
+		if isManaged(anp.ObjectMeta) {
+			return errors.Errorf("expected 0 admin network policies created by cyclonus, found %s", anp.Name)
+		}
+		logrus.Warnf("admin network policy %s wasn't created by cyclonus, and isn't simulated: it may cause discrepancies", anp.Name)
+	}
+	if banp != nil {
+		if isManaged(banp.ObjectMeta) {
+			return errors.Errorf("expected no baseline admin network policy created by cyclonus, found %s", banp.Name)
+		}
+		logrus.Warnf("baseline admin network policy %s wasn't created by cyclonus, and isn't simulated: it may cause discrepancies, and test cases can't create a BANP.  Delete it by setting delete-existing-banp", banp.Name)
+	}
 	return nil
 }
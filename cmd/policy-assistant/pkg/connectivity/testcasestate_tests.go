@@ -2,8 +2,13 @@ package connectivity
 
 import (
 	"fmt"
+
+	"github.com/mattfenwick/cyclonus/pkg/connectivity/probe"
+	"github.com/mattfenwick/cyclonus/pkg/kube"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 )
 
 type buildLabelDiffCase struct {
@@ -103,4 +108,63 @@ func RunTestCaseStateTests() {
 			}
 		})
 	})
+	Describe("TestCaseState admin network policies", func() {
+		anp := func(name string, priority int32) *v1alpha1.AdminNetworkPolicy {
+			return &v1alpha1.AdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: v1alpha1.AdminNetworkPolicySpec{Priority: priority}}
+		}
+		banp := &v1alpha1.BaselineAdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+
+		It("Tracks ANPs and the BANP in kube and the simulated state", func() {
+			kubernetes := kube.NewMockKubernetes(1.0)
+			state := &TestCaseState{Kubernetes: kubernetes, Resources: &probe.Resources{}}
+
+			Expect(state.CreateAdminNetworkPolicy(anp("a", 10))).To(Succeed())
+			Expect(state.CreateAdminNetworkPolicy(anp("b", 20))).To(Succeed())
+			Expect(state.CreateAdminNetworkPolicy(anp("a", 30))).ToNot(Succeed())
+			Expect(state.UpdateAdminNetworkPolicy(anp("b", 5))).To(Succeed())
+			Expect(state.UpdateAdminNetworkPolicy(anp("c", 5))).ToNot(Succeed())
+			Expect(state.DeleteAdminNetworkPolicy("a")).To(Succeed())
+			Expect(state.DeleteAdminNetworkPolicy("a")).ToNot(Succeed())
+			Expect(state.ANPs).To(Equal([]*v1alpha1.AdminNetworkPolicy{anp("b", 5)}))
+			Expect(kubernetes.AdminNetworkPolicies).To(Equal([]v1alpha1.AdminNetworkPolicy{*managedANP(anp("b", 5))}))
+			Expect(kubernetes.AdminNetworkPolicies[0].Labels).To(HaveKeyWithValue(kube.ManagedLabel, kube.ManagedLabelValue))
+
+			Expect(state.CreateBaselineAdminNetworkPolicy(banp)).To(Succeed())
+			Expect(state.CreateBaselineAdminNetworkPolicy(banp)).ToNot(Succeed())
+			Expect(state.BANP).To(Equal(banp))
+			Expect(kubernetes.BaselineNetworkPolicy).To(Equal(managedBANP(banp)))
+			Expect(state.DeleteBaselineAdminNetworkPolicy("other")).ToNot(Succeed())
+			Expect(state.DeleteBaselineAdminNetworkPolicy("default")).To(Succeed())
+			Expect(state.BANP).To(BeNil())
+			Expect(kubernetes.BaselineNetworkPolicy).To(BeNil())
+		})
+
+		It("Deletes only the ANPs and BANP created by cyclonus when resetting the cluster", func() {
+			kubernetes := kube.NewMockKubernetes(1.0)
+			kubernetes.AdminNetworkPolicies = []v1alpha1.AdminNetworkPolicy{*anp("foreign", 10)}
+			state := &TestCaseState{Kubernetes: kubernetes, Resources: &probe.Resources{}}
+			Expect(state.CreateAdminNetworkPolicy(anp("a", 20))).To(Succeed())
+			Expect(state.CreateBaselineAdminNetworkPolicy(banp)).To(Succeed())
+
+			Expect(state.VerifyClusterState()).ToNot(Succeed())
+			Expect(state.ResetClusterState()).To(Succeed())
+			Expect(kubernetes.AdminNetworkPolicies).To(Equal([]v1alpha1.AdminNetworkPolicy{*anp("foreign", 10)}))
+			Expect(kubernetes.BaselineNetworkPolicy).To(BeNil())
+			Expect(state.VerifyClusterState()).To(Succeed())
+		})
+
+		It("Deletes a BANP not created by cyclonus only if allowed", func() {
+			kubernetes := kube.NewMockKubernetes(1.0)
+			kubernetes.BaselineNetworkPolicy = banp
+			state := &TestCaseState{Kubernetes: kubernetes, Resources: &probe.Resources{}}
+
+			Expect(state.ResetClusterState()).To(Succeed())
+			Expect(kubernetes.BaselineNetworkPolicy).To(Equal(banp))
+			Expect(state.VerifyClusterState()).To(Succeed())
+
+			state.DeleteExistingBANP = true
+			Expect(state.ResetClusterState()).To(Succeed())
+			Expect(kubernetes.BaselineNetworkPolicy).To(BeNil())
+		})
+	})
 }
@@ -0,0 +1,435 @@
+package convert
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+// MaxPriority is the largest priority an ANP may have.
+const MaxPriority = 1000
+
+// allNetworks matches every IP, for egress rules to all destinations.
+var allNetworks = []v1alpha1.CIDR{"0.0.0.0/0", "::/0"}
+
+// Issue is a construct of a v1 NetPol which ANPs and BANPs can't express.  Constructs with issues are left
+// out of the converted policies.
+type Issue struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Field is the path to the construct, such as spec.ingress[0].from[1].ipBlock
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// Result is the ANPs, and BANP, proposed to replace a set of v1 NetPols.
+type Result struct {
+	AdminNetworkPolicies       []*v1alpha1.AdminNetworkPolicy
+	BaselineAdminNetworkPolicy *v1alpha1.BaselineAdminNetworkPolicy
+	Issues                     []*Issue
+}
+
+// subject is the pods selected by one or more v1 NetPols with the same namespace and pod selector.
+type subject struct {
+	namespace   string
+	podSelector metav1.LabelSelector
+	netpols     []*networkingv1.NetworkPolicy
+	ingress     bool
+	egress      bool
+}
+
+func (s *subject) isNamespaceWide() bool {
+	return kube.IsLabelSelectorEmpty(s.podSelector)
+}
+
+// ConvertNetworkPolicies proposes ANPs, and a BANP, equivalent to v1 NetPols.  The v1 NetPols are grouped into
+// subjects, as by matcher.BuildNetworkPolicies, and invalid ones are skipped and returned as errors.
+//
+// Each v1 NetPol becomes an ANP allowing what its rules allow.  Since v1 NetPols isolate the pods they select,
+// each subject then gets an ANP denying all traffic, at a lower priority than all of the allowing ANPs, so that
+// traffic allowed by any v1 NetPol selecting a pod is still allowed.  Namespace-wide subjects are instead denied by
+// a BANP, if there isn't one already.  Converted ANPs have lower priorities than the existing ANPs, which are
+// evaluated before v1 NetPols.  So traffic passed by an existing ANP skips the converted ANPs: Pass rules of existing
+// ANPs whose subjects may overlap converted subjects are reported as issues.
+func ConvertNetworkPolicies(netpols []*networkingv1.NetworkPolicy, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) (*Result, []*matcher.PolicyError, error) {
+	policy, policyErrors := matcher.BuildNetworkPolicies(false, netpols)
+	netpolsByID := map[matcher.NetPolID]*networkingv1.NetworkPolicy{}
+	for _, netpol := range netpols {
+		netpolsByID[matcher.NetworkPolicyID(netpol)] = netpol
+	}
+
+	subjects := map[string]*subject{}
+	ingressIDs, egressIDs := map[matcher.NetPolID]bool{}, map[matcher.NetPolID]bool{}
+	addTargets := func(targets map[string]*matcher.Target, directionIDs map[matcher.NetPolID]bool) {
+		for key, target := range targets {
+			s, ok := subjects[key]
+			if !ok {
+				first := netpolsByID[target.SourceRules[0]]
+				s = &subject{namespace: namespaceOf(first), podSelector: first.Spec.PodSelector}
+				subjects[key] = s
+			}
+			for _, id := range target.SourceRules {
+				if !ingressIDs[id] && !egressIDs[id] {
+					s.netpols = append(s.netpols, netpolsByID[id])
+				}
+				directionIDs[id] = true
+			}
+		}
+	}
+	addTargets(policy.Ingress, ingressIDs)
+	addTargets(policy.Egress, egressIDs)
+	var keys []string
+	for key, s := range subjects {
+		keys = append(keys, key)
+		s.ingress = policy.Ingress[key] != nil
+		s.egress = policy.Egress[key] != nil
+		sort.Slice(s.netpols, func(i, j int) bool { return s.netpols[i].Name < s.netpols[j].Name })
+	}
+	sort.Strings(keys)
+
+	priority := int32(0)
+	for _, anp := range anps {
+		if anp.Spec.Priority >= priority {
+			priority = anp.Spec.Priority + 1
+		}
+	}
+
+	result := &Result{}
+	for _, key := range keys {
+		s := subjects[key]
+		result.Issues = append(result.Issues, passRuleIssues(s, anps)...)
+		for _, netpol := range s.netpols {
+			id := matcher.NetworkPolicyID(netpol)
+			anp, issues := allowANP(netpol, ingressIDs[id], egressIDs[id])
+			result.Issues = append(result.Issues, issues...)
+			if anp != nil {
+				anp.Spec.Priority = priority
+				priority++
+				result.AdminNetworkPolicies = append(result.AdminNetworkPolicies, anp)
+			}
+		}
+	}
+
+	banpIngress, banpEgress, banpNamespaces := namespaceWideDenials(keys, subjects, banp == nil)
+	if len(banpNamespaces) > 0 {
+		result.BaselineAdminNetworkPolicy = denyBANP(banpNamespaces, banpIngress, banpEgress)
+	}
+	for _, key := range keys {
+		s := subjects[key]
+		ingress := s.ingress && !(banpIngress && s.isNamespaceWide())
+		egress := s.egress && !(banpEgress && s.isNamespaceWide())
+		if !ingress && !egress {
+			continue
+		}
+		anp := denyANP(s, ingress, egress)
+		anp.Spec.Priority = priority
+		priority++
+		result.AdminNetworkPolicies = append(result.AdminNetworkPolicies, anp)
+	}
+
+	if priority-1 > MaxPriority {
+		return nil, policyErrors, errors.Errorf("unable to convert: %d ANPs would need priorities up to %d, but the maximum is %d", len(result.AdminNetworkPolicies), priority-1, MaxPriority)
+	}
+	return result, policyErrors, nil
+}
+
+// passRuleIssues reports the Pass rules of existing ANPs whose subjects may select pods of the subject.  Since those
+// ANPs have higher priorities than the converted ANPs, passed traffic skips the converted ANPs, and is decided by the
+// BANP instead.  Subjects only provably don't overlap if the ANP's namespace selector restricts namespace names.
+func passRuleIssues(s *subject, anps []*v1alpha1.AdminNetworkPolicy) []*Issue {
+	var issues []*Issue
+	report := func(anp *v1alpha1.AdminNetworkPolicy, fldPath *field.Path, ruleName string) {
+		for _, netpol := range s.netpols {
+			issues = append(issues, &Issue{
+				Namespace: s.namespace,
+				Name:      netpol.Name,
+				Field:     field.NewPath("spec", "podSelector").String(),
+				Reason:    fmt.Sprintf("Pass rule %s (%s) of existing ANP %s may select these pods, and traffic it passes skips the converted ANPs", fldPath, ruleName, anp.Name),
+			})
+		}
+	}
+	for _, anp := range anps {
+		if !subjectMayIncludeNamespace(&anp.Spec.Subject, s.namespace) {
+			continue
+		}
+		if s.ingress {
+			for i, rule := range anp.Spec.Ingress {
+				if rule.Action == v1alpha1.AdminNetworkPolicyRuleActionPass {
+					report(anp, field.NewPath("spec", "ingress").Index(i), rule.Name)
+				}
+			}
+		}
+		if s.egress {
+			for i, rule := range anp.Spec.Egress {
+				if rule.Action == v1alpha1.AdminNetworkPolicyRuleActionPass {
+					report(anp, field.NewPath("spec", "egress").Index(i), rule.Name)
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// subjectMayIncludeNamespace is false only if the subject's namespace selector restricts the
+// `kubernetes.io/metadata.name` label so that it excludes the namespace.
+func subjectMayIncludeNamespace(subject *v1alpha1.AdminNetworkPolicySubject, namespace string) bool {
+	var selector metav1.LabelSelector
+	switch {
+	case subject.Namespaces != nil:
+		selector = *subject.Namespaces
+	case subject.Pods != nil:
+		selector = subject.Pods.NamespaceSelector
+	}
+	if name, ok := selector.MatchLabels[v1.LabelMetadataName]; ok && name != namespace {
+		return false
+	}
+	for _, exp := range selector.MatchExpressions {
+		if exp.Key != v1.LabelMetadataName {
+			continue
+		}
+		if exp.Operator == metav1.LabelSelectorOpIn && !slices.Contains(exp.Values, namespace) ||
+			exp.Operator == metav1.LabelSelectorOpNotIn && slices.Contains(exp.Values, namespace) ||
+			exp.Operator == metav1.LabelSelectorOpDoesNotExist {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaceWideDenials chooses the namespace-wide subjects to deny with a BANP.  A BANP has a single subject, so
+// egress is only denied by the BANP if the namespaces isolated for egress are those isolated for ingress.
+func namespaceWideDenials(keys []string, subjects map[string]*subject, allowBANP bool) (bool, bool, []string) {
+	if !allowBANP {
+		return false, false, nil
+	}
+	var ingressNamespaces, egressNamespaces []string
+	for _, key := range keys {
+		s := subjects[key]
+		if !s.isNamespaceWide() {
+			continue
+		}
+		if s.ingress {
+			ingressNamespaces = append(ingressNamespaces, s.namespace)
+		}
+		if s.egress {
+			egressNamespaces = append(egressNamespaces, s.namespace)
+		}
+	}
+	sort.Strings(ingressNamespaces)
+	sort.Strings(egressNamespaces)
+	if len(ingressNamespaces) == 0 {
+		return false, len(egressNamespaces) > 0, egressNamespaces
+	}
+	return true, fmt.Sprint(ingressNamespaces) == fmt.Sprint(egressNamespaces), ingressNamespaces
+}
+
+func allowANP(netpol *networkingv1.NetworkPolicy, ingress bool, egress bool) (*v1alpha1.AdminNetworkPolicy, []*Issue) {
+	namespace := namespaceOf(netpol)
+	var issues []*Issue
+	report := func(fldPath *field.Path, reason string) {
+		issues = append(issues, &Issue{Namespace: namespace, Name: netpol.Name, Field: fldPath.String(), Reason: reason})
+	}
+
+	anp := newANP("allow-"+namespace+"-"+netpol.Name, namespace, netpol.Spec.PodSelector)
+	if ingress {
+		report(field.NewPath("spec", "policyTypes"), "traffic from outside the cluster isn't denied: ANP and BANP ingress peers are only namespaces and pods")
+		for i, rule := range netpol.Spec.Ingress {
+			fldPath := field.NewPath("spec", "ingress").Index(i)
+			var from []v1alpha1.AdminNetworkPolicyIngressPeer
+			if len(rule.From) == 0 {
+				from = append(from, v1alpha1.AdminNetworkPolicyIngressPeer{Namespaces: &metav1.LabelSelector{}})
+			}
+			for j, peer := range rule.From {
+				if peer.IPBlock != nil {
+					report(fldPath.Child("from").Index(j).Child("ipBlock"), "ANP and BANP ingress peers are only namespaces and pods")
+					continue
+				}
+				namespaces, pods := podPeer(namespace, peer)
+				from = append(from, v1alpha1.AdminNetworkPolicyIngressPeer{Namespaces: namespaces, Pods: pods})
+			}
+			if len(from) > 0 {
+				anp.Spec.Ingress = append(anp.Spec.Ingress, v1alpha1.AdminNetworkPolicyIngressRule{
+					Name:   fmt.Sprintf("ingress-%d", i),
+					Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+					From:   from,
+					Ports:  ports(rule.Ports),
+				})
+			}
+		}
+	}
+	if egress {
+		for i, rule := range netpol.Spec.Egress {
+			fldPath := field.NewPath("spec", "egress").Index(i)
+			namedPort := hasNamedPort(rule.Ports)
+			var to []v1alpha1.AdminNetworkPolicyEgressPeer
+			if len(rule.To) == 0 {
+				to = append(to, v1alpha1.AdminNetworkPolicyEgressPeer{Namespaces: &metav1.LabelSelector{}})
+				if namedPort {
+					report(fldPath.Child("to"), "traffic to outside the cluster isn't allowed: ANP and BANP networks peers can't be used with named ports")
+				} else {
+					to = append(to, v1alpha1.AdminNetworkPolicyEgressPeer{Networks: allNetworks})
+				}
+			}
+			for j, peer := range rule.To {
+				peerPath := fldPath.Child("to").Index(j).Child("ipBlock")
+				switch {
+				case peer.IPBlock != nil && len(peer.IPBlock.Except) > 0:
+					report(peerPath.Child("except"), "ANP and BANP networks peers have no except")
+				case peer.IPBlock != nil && namedPort:
+					report(peerPath, "ANP and BANP networks peers can't be used with named ports")
+				case peer.IPBlock != nil:
+					to = append(to, v1alpha1.AdminNetworkPolicyEgressPeer{Networks: []v1alpha1.CIDR{v1alpha1.CIDR(peer.IPBlock.CIDR)}})
+				default:
+					namespaces, pods := podPeer(namespace, peer)
+					to = append(to, v1alpha1.AdminNetworkPolicyEgressPeer{Namespaces: namespaces, Pods: pods})
+				}
+			}
+			if len(to) > 0 {
+				anp.Spec.Egress = append(anp.Spec.Egress, v1alpha1.AdminNetworkPolicyEgressRule{
+					Name:   fmt.Sprintf("egress-%d", i),
+					Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+					To:     to,
+					Ports:  ports(rule.Ports),
+				})
+			}
+		}
+	}
+
+	if len(anp.Spec.Ingress) == 0 && len(anp.Spec.Egress) == 0 {
+		return nil, issues
+	}
+	return anp, issues
+}
+
+func denyANP(s *subject, ingress bool, egress bool) *v1alpha1.AdminNetworkPolicy {
+	anp := newANP("deny-"+s.namespace+"-"+s.netpols[0].Name, s.namespace, s.podSelector)
+	if ingress {
+		anp.Spec.Ingress = []v1alpha1.AdminNetworkPolicyIngressRule{{
+			Name:   "default-deny",
+			Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+			From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+		}}
+	}
+	if egress {
+		anp.Spec.Egress = []v1alpha1.AdminNetworkPolicyEgressRule{{
+			Name:   "default-deny",
+			Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+			To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Namespaces: &metav1.LabelSelector{}}, {Networks: allNetworks}},
+		}}
+	}
+	return anp
+}
+
+func denyBANP(namespaces []string, ingress bool, egress bool) *v1alpha1.BaselineAdminNetworkPolicy {
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{Kind: "BaselineAdminNetworkPolicy", APIVersion: "policy.networking.k8s.io/v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: kube.DefaultNamespaceLabel, Operator: metav1.LabelSelectorOpIn, Values: namespaces}},
+			}},
+		},
+		Status: v1alpha1.BaselineAdminNetworkPolicyStatus{Conditions: []metav1.Condition{}},
+	}
+	if ingress {
+		banp.Spec.Ingress = []v1alpha1.BaselineAdminNetworkPolicyIngressRule{{
+			Name:   "default-deny",
+			Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+			From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+		}}
+	}
+	if egress {
+		banp.Spec.Egress = []v1alpha1.BaselineAdminNetworkPolicyEgressRule{{
+			Name:   "default-deny",
+			Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+			To:     []v1alpha1.BaselineAdminNetworkPolicyEgressPeer{{Namespaces: &metav1.LabelSelector{}}, {Networks: allNetworks}},
+		}}
+	}
+	return banp
+}
+
+func newANP(name string, namespace string, podSelector metav1.LabelSelector) *v1alpha1.AdminNetworkPolicy {
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return &v1alpha1.AdminNetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{Kind: "AdminNetworkPolicy", APIVersion: "policy.networking.k8s.io/v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Pods: &v1alpha1.NamespacedPod{
+				NamespaceSelector: namespaceSelector(namespace),
+				PodSelector:       podSelector,
+			}},
+		},
+		Status: v1alpha1.AdminNetworkPolicyStatus{Conditions: []metav1.Condition{}},
+	}
+}
+
+// podPeer converts a v1 NetPol peer of pods to an ANP peer: a pod selector alone selects pods in the
+// policy's namespace.
+func podPeer(namespace string, peer networkingv1.NetworkPolicyPeer) (*metav1.LabelSelector, *v1alpha1.NamespacedPod) {
+	if peer.PodSelector == nil {
+		return peer.NamespaceSelector.DeepCopy(), nil
+	}
+	nsSelector := namespaceSelector(namespace)
+	if peer.NamespaceSelector != nil {
+		nsSelector = *peer.NamespaceSelector.DeepCopy()
+	}
+	return nil, &v1alpha1.NamespacedPod{NamespaceSelector: nsSelector, PodSelector: *peer.PodSelector.DeepCopy()}
+}
+
+func namespaceSelector(namespace string) metav1.LabelSelector {
+	return metav1.LabelSelector{MatchLabels: map[string]string{kube.DefaultNamespaceLabel: namespace}}
+}
+
+// ports converts v1 NetPol ports.  A port without a number is all ports on its protocol.
+func ports(npPorts []networkingv1.NetworkPolicyPort) *[]v1alpha1.AdminNetworkPolicyPort {
+	if len(npPorts) == 0 {
+		return nil
+	}
+	var anpPorts []v1alpha1.AdminNetworkPolicyPort
+	for _, npPort := range npPorts {
+		protocol := v1.ProtocolTCP
+		if npPort.Protocol != nil {
+			protocol = *npPort.Protocol
+		}
+		switch {
+		case npPort.Port == nil:
+			anpPorts = append(anpPorts, v1alpha1.AdminNetworkPolicyPort{PortRange: &v1alpha1.PortRange{Protocol: protocol, Start: 1, End: 65535}})
+		case npPort.Port.Type == intstr.String:
+			name := npPort.Port.StrVal
+			anpPorts = append(anpPorts, v1alpha1.AdminNetworkPolicyPort{NamedPort: &name})
+		case npPort.EndPort != nil:
+			anpPorts = append(anpPorts, v1alpha1.AdminNetworkPolicyPort{PortRange: &v1alpha1.PortRange{Protocol: protocol, Start: npPort.Port.IntVal, End: *npPort.EndPort}})
+		default:
+			anpPorts = append(anpPorts, v1alpha1.AdminNetworkPolicyPort{PortNumber: &v1alpha1.Port{Protocol: protocol, Port: npPort.Port.IntVal}})
+		}
+	}
+	return &anpPorts
+}
+
+func hasNamedPort(npPorts []networkingv1.NetworkPolicyPort) bool {
+	for _, npPort := range npPorts {
+		if npPort.Port != nil && npPort.Port.Type == intstr.String {
+			return true
+		}
+	}
+	return false
+}
+
+func namespaceOf(netpol *networkingv1.NetworkPolicy) string {
+	if netpol.Namespace == "" {
+		return metav1.NamespaceDefault
+	}
+	return netpol.Namespace
+}
@@ -0,0 +1,249 @@
+package convert
+
+import (
+	"fmt"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+const netpolsYaml = `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-a-to-b
+  namespace: x
+spec:
+  podSelector:
+    matchLabels:
+      pod: b
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - podSelector:
+        matchLabels:
+          pod: a
+    ports:
+    - port: 80
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: default-deny
+  namespace: x
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-from-y
+  namespace: x
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - namespaceSelector:
+        matchLabels:
+          kubernetes.io/metadata.name: "y"
+      podSelector:
+        matchLabels:
+          pod: c
+    ports:
+    - port: serve-81-tcp
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: egress-to-x
+  namespace: "y"
+spec:
+  podSelector:
+    matchLabels:
+      pod: a
+  policyTypes:
+  - Egress
+  egress:
+  - to:
+    - namespaceSelector:
+        matchLabels:
+          kubernetes.io/metadata.name: x
+  - to:
+    - ipBlock:
+        cidr: 192.168.0.0/16
+    ports:
+    - port: 53
+      protocol: UDP
+`
+
+const unconvertibleYaml = `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: unconvertible
+  namespace: x
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  - Egress
+  ingress:
+  - from:
+    - ipBlock:
+        cidr: 10.0.0.0/8
+  egress:
+  - to:
+    - ipBlock:
+        cidr: 10.0.0.0/8
+        except:
+        - 10.1.0.0/16
+  - to:
+    - ipBlock:
+        cidr: 1.2.3.4/32
+    ports:
+    - port: serve-80-tcp
+`
+
+// trafficBetweenPods is traffic between every pair of pods in namespaces x and y, on ports 80 and 81.
+func trafficBetweenPods() []*matcher.Traffic {
+	var peers []*matcher.TrafficPeer
+	for i, ns := range []string{"x", "y"} {
+		for j, pod := range []string{"a", "b", "c"} {
+			peers = append(peers, &matcher.TrafficPeer{
+				Internal: &matcher.InternalPeer{
+					PodLabels:       map[string]string{"pod": pod},
+					NamespaceLabels: map[string]string{kube.DefaultNamespaceLabel: ns},
+					Namespace:       ns,
+				},
+				IP: fmt.Sprintf("192.168.%d.%d", i, j),
+			})
+		}
+	}
+	var traffic []*matcher.Traffic
+	for _, src := range peers {
+		for _, dst := range peers {
+			for _, port := range []int{80, 81} {
+				traffic = append(traffic, &matcher.Traffic{
+					Source:           src,
+					Destination:      dst,
+					ResolvedPort:     port,
+					ResolvedPortName: fmt.Sprintf("serve-%d-tcp", port),
+					Protocol:         v1.ProtocolTCP,
+				})
+			}
+		}
+	}
+	return traffic
+}
+
+func readNetworkPolicies(yaml string) []*networkingv1.NetworkPolicy {
+	policies, err := kube.ReadPoliciesFromYaml([]*kube.YamlSource{{Path: "policies.yaml", Yaml: []byte(yaml)}})
+	Expect(err).To(BeNil())
+	return policies.NetworkPolicies
+}
+
+func RunConvertTests() {
+	Describe("Convert NetworkPolicies", func() {
+		It("Should propose equivalent ANPs and BANP", func() {
+			netpols := readNetworkPolicies(netpolsYaml)
+			result, policyErrors, err := ConvertNetworkPolicies(netpols, nil, nil)
+			Expect(err).To(BeNil())
+			Expect(policyErrors).To(BeEmpty())
+
+			var names []string
+			for _, anp := range result.AdminNetworkPolicies {
+				names = append(names, fmt.Sprintf("%d %s", anp.Spec.Priority, anp.Name))
+			}
+			Expect(names).To(Equal([]string{
+				"0 allow-x-allow-a-to-b",
+				"1 allow-x-allow-from-y",
+				"2 allow-y-egress-to-x",
+				"3 deny-x-allow-a-to-b",
+				"4 deny-y-egress-to-x",
+			}))
+			Expect(result.BaselineAdminNetworkPolicy).ToNot(BeNil())
+			Expect(result.BaselineAdminNetworkPolicy.Spec.Subject.Namespaces.MatchExpressions[0].Values).To(Equal([]string{"x"}))
+
+			before, errs := matcher.BuildNetworkPolicies(false, netpols)
+			Expect(errs).To(BeEmpty())
+			after, errs := matcher.BuildV1AndV2NetPols(false, nil, result.AdminNetworkPolicies, result.BaselineAdminNetworkPolicy)
+			Expect(errs).To(BeEmpty())
+			Expect(matcher.DiffTraffic(before, after, trafficBetweenPods())).To(BeEmpty())
+		})
+
+		It("Should deny with ANPs, after existing ANPs, if there's already a BANP", func() {
+			netpols := readNetworkPolicies(netpolsYaml)
+			existingANP := &v1alpha1.AdminNetworkPolicy{Spec: v1alpha1.AdminNetworkPolicySpec{Priority: 10}}
+			existingANP.Name = "existing"
+			existingBANP := &v1alpha1.BaselineAdminNetworkPolicy{}
+			existingBANP.Name = "default"
+			result, _, err := ConvertNetworkPolicies(netpols, []*v1alpha1.AdminNetworkPolicy{existingANP}, existingBANP)
+			Expect(err).To(BeNil())
+			Expect(result.BaselineAdminNetworkPolicy).To(BeNil())
+			Expect(result.AdminNetworkPolicies).To(HaveLen(6))
+			Expect(result.AdminNetworkPolicies[0].Spec.Priority).To(Equal(int32(11)))
+			Expect(result.AdminNetworkPolicies[5].Name).To(Equal("deny-y-egress-to-x"))
+		})
+
+		It("Should report Pass rules of existing ANPs which may skip the converted ANPs", func() {
+			netpols := readNetworkPolicies(netpolsYaml)
+			passANP := func(name string, namespaces metav1.LabelSelector) *v1alpha1.AdminNetworkPolicy {
+				return &v1alpha1.AdminNetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Spec: v1alpha1.AdminNetworkPolicySpec{
+						Priority: 10,
+						Subject:  v1alpha1.AdminNetworkPolicySubject{Namespaces: &namespaces},
+						Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+							{Name: "pass-all", Action: v1alpha1.AdminNetworkPolicyRuleActionPass, From: []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}}},
+						},
+					},
+				}
+			}
+			anps := []*v1alpha1.AdminNetworkPolicy{
+				passANP("pass-x", metav1.LabelSelector{MatchLabels: map[string]string{v1.LabelMetadataName: "x"}}),
+				passANP("pass-z", metav1.LabelSelector{MatchLabels: map[string]string{v1.LabelMetadataName: "z"}}),
+			}
+			result, _, err := ConvertNetworkPolicies(netpols, anps, nil)
+			Expect(err).To(BeNil())
+
+			var issues []string
+			for _, issue := range result.Issues {
+				if issue.Field == "spec.podSelector" {
+					issues = append(issues, fmt.Sprintf("%s/%s: %s", issue.Namespace, issue.Name, issue.Reason))
+				}
+			}
+			Expect(issues).To(ConsistOf(
+				"x/allow-a-to-b: Pass rule spec.ingress[0] (pass-all) of existing ANP pass-x may select these pods, and traffic it passes skips the converted ANPs",
+				"x/allow-from-y: Pass rule spec.ingress[0] (pass-all) of existing ANP pass-x may select these pods, and traffic it passes skips the converted ANPs",
+				"x/default-deny: Pass rule spec.ingress[0] (pass-all) of existing ANP pass-x may select these pods, and traffic it passes skips the converted ANPs",
+			))
+		})
+
+		It("Should report constructs which ANPs can't express", func() {
+			result, _, err := ConvertNetworkPolicies(readNetworkPolicies(unconvertibleYaml), nil, nil)
+			Expect(err).To(BeNil())
+			var fields []string
+			for _, issue := range result.Issues {
+				fields = append(fields, issue.Field)
+			}
+			Expect(fields).To(Equal([]string{
+				"spec.policyTypes",
+				"spec.ingress[0].from[0].ipBlock",
+				"spec.egress[0].to[0].ipBlock.except",
+				"spec.egress[1].to[0].ipBlock",
+			}))
+			Expect(result.AdminNetworkPolicies).To(BeEmpty())
+			Expect(result.BaselineAdminNetworkPolicy.Spec.Ingress).To(HaveLen(1))
+			Expect(result.BaselineAdminNetworkPolicy.Spec.Egress).To(HaveLen(1))
+		})
+	})
+}
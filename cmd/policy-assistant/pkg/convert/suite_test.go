@@ -0,0 +1,14 @@
+package convert
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestConvert(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunConvertTests()
+	RunSpecs(t, "convert suite")
+}
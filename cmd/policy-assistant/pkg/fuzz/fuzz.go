@@ -0,0 +1,425 @@
+package fuzz
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/mattfenwick/cyclonus/pkg/generator"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+// fuzzLabel is added to pods and namespaces by label perturbations, and selected by generated policies.
+const fuzzLabel = "fuzz"
+
+var (
+	fuzzLabelValues = []string{"a", "b"}
+	allNetworks     = []string{"0.0.0.0/0", "::/0"}
+)
+
+// Config is the cluster which test cases run against, and the shape of the test cases.
+type Config struct {
+	Namespaces []string
+	Pods       []string
+	Ports      []int
+	Protocols  []v1.Protocol
+	// AdminNetworkPolicies enables ANPs and the BANP, which need their CRDs in the cluster
+	AdminNetworkPolicies bool
+	MaxSteps             int
+	MaxActionsPerStep    int
+}
+
+// Fuzzer randomly generates test cases: stacks of NetworkPolicies, ANPs and a BANP, which are created, updated
+// and deleted across steps, along with pod and namespace label changes.  The policies only select the test pods and
+// namespaces, by their 'pod', 'ns' and 'fuzz' labels, and probes go to pod IPs, so that DNS isn't needed.
+type Fuzzer struct {
+	Config *Config
+	rand   *rand.Rand
+
+	// state of the test case being generated
+	netpols        []*networkingv1.NetworkPolicy
+	anps           []*v1alpha1.AdminNetworkPolicy
+	banp           *v1alpha1.BaselineAdminNetworkPolicy
+	nextPolicyID   int
+	usedPriorities map[int32]bool
+}
+
+func NewFuzzer(config *Config, seed int64) *Fuzzer {
+	return &Fuzzer{Config: config, rand: rand.New(rand.NewSource(seed))}
+}
+
+// TestCase generates a test case.  The same seed generates the same test case.
+func TestCase(config *Config, seed int64) *generator.TestCase {
+	return NewFuzzer(config, seed).TestCase(fmt.Sprintf("fuzz seed %d", seed))
+}
+
+func (f *Fuzzer) TestCase(description string) *generator.TestCase {
+	f.netpols, f.anps, f.banp, f.nextPolicyID, f.usedPriorities = nil, nil, nil, 0, map[int32]bool{}
+
+	tags := generator.NewStringSet(generator.TagFuzz)
+	var steps []*generator.TestStep
+	for i := 0; i < 1+f.rand.Intn(f.Config.MaxSteps); i++ {
+		var actions []*generator.Action
+		for j := 0; j < 1+f.rand.Intn(f.Config.MaxActionsPerStep); j++ {
+			action, tag := f.action()
+			actions = append(actions, action)
+			tags.Add(tag)
+		}
+		steps = append(steps, generator.NewTestStep(generator.NewAllAvailable(generator.ProbeModePodIP), actions...))
+	}
+	return generator.NewTestCase(description, tags, steps...)
+}
+
+// action picks an action which is valid in the current state, returning its tag.
+func (f *Fuzzer) action() (*generator.Action, string) {
+	type choice struct {
+		tag    string
+		action func() *generator.Action
+	}
+	choices := []choice{
+		{generator.TagCreatePolicy, func() *generator.Action {
+			netpol := f.networkPolicy(f.newPolicyName(), f.pick(f.Config.Namespaces))
+			f.netpols = append(f.netpols, netpol)
+			return generator.CreatePolicy(netpol)
+		}},
+		{generator.TagSetPodLabels, func() *generator.Action {
+			ns, pod := f.pick(f.Config.Namespaces), f.pick(f.Config.Pods)
+			return generator.SetPodLabels(ns, pod, f.perturbedLabels("pod", pod))
+		}},
+		{generator.TagSetNamespaceLabels, func() *generator.Action {
+			ns := f.pick(f.Config.Namespaces)
+			return generator.SetNamespaceLabels(ns, f.perturbedLabels("ns", ns))
+		}},
+	}
+	if len(f.netpols) > 0 {
+		choices = append(choices,
+			choice{generator.TagUpdatePolicy, func() *generator.Action {
+				i := f.rand.Intn(len(f.netpols))
+				f.netpols[i] = f.networkPolicy(f.netpols[i].Name, f.netpols[i].Namespace)
+				return generator.UpdatePolicy(f.netpols[i])
+			}},
+			choice{generator.TagDeletePolicy, func() *generator.Action {
+				i := f.rand.Intn(len(f.netpols))
+				netpol := f.netpols[i]
+				f.netpols = append(f.netpols[:i:i], f.netpols[i+1:]...)
+				return generator.DeletePolicy(netpol.Namespace, netpol.Name)
+			}})
+	}
+	if f.Config.AdminNetworkPolicies {
+		choices = append(choices, choice{generator.TagCreateANP, func() *generator.Action {
+			anp := f.adminNetworkPolicy(f.newPolicyName(), f.newPriority())
+			f.anps = append(f.anps, anp)
+			return generator.CreateAdminNetworkPolicy(anp)
+		}})
+		if len(f.anps) > 0 {
+			choices = append(choices,
+				choice{generator.TagUpdateANP, func() *generator.Action {
+					i := f.rand.Intn(len(f.anps))
+					f.anps[i] = f.adminNetworkPolicy(f.anps[i].Name, f.anps[i].Spec.Priority)
+					return generator.UpdateAdminNetworkPolicy(f.anps[i])
+				}},
+				choice{generator.TagDeleteANP, func() *generator.Action {
+					i := f.rand.Intn(len(f.anps))
+					anp := f.anps[i]
+					f.anps = append(f.anps[:i:i], f.anps[i+1:]...)
+					return generator.DeleteAdminNetworkPolicy(anp.Name)
+				}})
+		}
+		if f.banp == nil {
+			choices = append(choices, choice{generator.TagCreateBANP, func() *generator.Action {
+				f.banp = f.baselineAdminNetworkPolicy()
+				return generator.CreateBaselineAdminNetworkPolicy(f.banp)
+			}})
+		} else {
+			choices = append(choices, choice{generator.TagDeleteBANP, func() *generator.Action {
+				name := f.banp.Name
+				f.banp = nil
+				return generator.DeleteBaselineAdminNetworkPolicy(name)
+			}})
+		}
+	}
+	c := choices[f.rand.Intn(len(choices))]
+	return c.action(), c.tag
+}
+
+func (f *Fuzzer) newPolicyName() string {
+	f.nextPolicyID++
+	return fmt.Sprintf("fuzz-%d", f.nextPolicyID)
+}
+
+// newPriority picks an unused ANP priority, since ANPs with the same priority are ambiguous.
+func (f *Fuzzer) newPriority() int32 {
+	for {
+		priority := int32(f.rand.Intn(100))
+		if !f.usedPriorities[priority] {
+			f.usedPriorities[priority] = true
+			return priority
+		}
+	}
+}
+
+func (f *Fuzzer) pick(choices []string) string {
+	return choices[f.rand.Intn(len(choices))]
+}
+
+func (f *Fuzzer) chance(percent int) bool {
+	return f.rand.Intn(100) < percent
+}
+
+// perturbedLabels keeps the label identifying a pod or namespace, and maybe adds a fuzz label.
+func (f *Fuzzer) perturbedLabels(key string, value string) map[string]string {
+	labels := map[string]string{key: value}
+	if f.chance(75) {
+		labels[fuzzLabel] = f.pick(fuzzLabelValues)
+	}
+	return labels
+}
+
+// selector selects by the identifying key, 'pod' or 'ns', or by the fuzz label.
+func (f *Fuzzer) selector(key string, values []string) *metav1.LabelSelector {
+	switch f.rand.Intn(5) {
+	case 0:
+		return &metav1.LabelSelector{}
+	case 1:
+		return &metav1.LabelSelector{MatchLabels: map[string]string{key: f.pick(values)}}
+	case 2:
+		return &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: key, Operator: metav1.LabelSelectorOpIn, Values: []string{f.pick(values), f.pick(values)}}}}
+	case 3:
+		return &metav1.LabelSelector{MatchLabels: map[string]string{fuzzLabel: f.pick(fuzzLabelValues)}}
+	default:
+		operator := metav1.LabelSelectorOpExists
+		if f.chance(50) {
+			operator = metav1.LabelSelectorOpDoesNotExist
+		}
+		return &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: fuzzLabel, Operator: operator}}}
+	}
+}
+
+func (f *Fuzzer) podSelector() *metav1.LabelSelector {
+	return f.selector("pod", f.Config.Pods)
+}
+
+// namespaceSelector never selects namespaces other than the test namespaces.
+func (f *Fuzzer) namespaceSelector() *metav1.LabelSelector {
+	selector := f.selector("ns", f.Config.Namespaces)
+	if len(selector.MatchLabels) == 0 {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key: "ns", Operator: metav1.LabelSelectorOpIn, Values: append([]string{}, f.Config.Namespaces...)})
+	}
+	return selector
+}
+
+func (f *Fuzzer) protocol() v1.Protocol {
+	return f.Config.Protocols[f.rand.Intn(len(f.Config.Protocols))]
+}
+
+func (f *Fuzzer) portNumber() int {
+	return f.Config.Ports[f.rand.Intn(len(f.Config.Ports))]
+}
+
+func namedPort(port int, protocol v1.Protocol) string {
+	return fmt.Sprintf("serve-%d-%s", port, strings.ToLower(string(protocol)))
+}
+
+func (f *Fuzzer) networkPolicy(name string, namespace string) *networkingv1.NetworkPolicy {
+	netpol := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       networkingv1.NetworkPolicySpec{PodSelector: *f.podSelector()},
+	}
+	ingress, egress := f.chance(70), f.chance(50)
+	if !ingress && !egress {
+		ingress = true
+	}
+	if ingress {
+		netpol.Spec.PolicyTypes = append(netpol.Spec.PolicyTypes, networkingv1.PolicyTypeIngress)
+		for i := 0; i < f.rand.Intn(3); i++ {
+			netpol.Spec.Ingress = append(netpol.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{From: f.netpolPeers(), Ports: f.netpolPorts()})
+		}
+	}
+	if egress {
+		netpol.Spec.PolicyTypes = append(netpol.Spec.PolicyTypes, networkingv1.PolicyTypeEgress)
+		for i := 0; i < f.rand.Intn(3); i++ {
+			netpol.Spec.Egress = append(netpol.Spec.Egress, networkingv1.NetworkPolicyEgressRule{To: f.netpolPeers(), Ports: f.netpolPorts()})
+		}
+	}
+	return netpol
+}
+
+func (f *Fuzzer) netpolPeers() []networkingv1.NetworkPolicyPeer {
+	var peers []networkingv1.NetworkPolicyPeer
+	for i := 0; i < f.rand.Intn(3); i++ {
+		switch f.rand.Intn(4) {
+		case 0:
+			peers = append(peers, networkingv1.NetworkPolicyPeer{PodSelector: f.podSelector()})
+		case 1:
+			peers = append(peers, networkingv1.NetworkPolicyPeer{NamespaceSelector: f.namespaceSelector()})
+		case 2:
+			peers = append(peers, networkingv1.NetworkPolicyPeer{PodSelector: f.podSelector(), NamespaceSelector: f.namespaceSelector()})
+		default:
+			peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: f.pick(allNetworks)}})
+		}
+	}
+	return peers
+}
+
+func (f *Fuzzer) netpolPorts() []networkingv1.NetworkPolicyPort {
+	var ports []networkingv1.NetworkPolicyPort
+	for i := 0; i < f.rand.Intn(3); i++ {
+		protocol := f.protocol()
+		port := networkingv1.NetworkPolicyPort{Protocol: &protocol}
+		switch f.rand.Intn(4) {
+		case 0:
+		case 1:
+			number := intstr.FromInt(f.portNumber())
+			port.Port = &number
+		case 2:
+			name := intstr.FromString(namedPort(f.portNumber(), protocol))
+			port.Port = &name
+		default:
+			start := intstr.FromInt(f.portNumber())
+			end := start.IntVal + int32(f.rand.Intn(2))
+			port.Port, port.EndPort = &start, &end
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+func (f *Fuzzer) subject() v1alpha1.AdminNetworkPolicySubject {
+	if f.chance(50) {
+		return v1alpha1.AdminNetworkPolicySubject{Namespaces: f.namespaceSelector()}
+	}
+	return v1alpha1.AdminNetworkPolicySubject{Pods: &v1alpha1.NamespacedPod{NamespaceSelector: *f.namespaceSelector(), PodSelector: *f.podSelector()}}
+}
+
+func (f *Fuzzer) adminPeer() (*metav1.LabelSelector, *v1alpha1.NamespacedPod) {
+	if f.chance(50) {
+		return f.namespaceSelector(), nil
+	}
+	return nil, &v1alpha1.NamespacedPod{NamespaceSelector: *f.namespaceSelector(), PodSelector: *f.podSelector()}
+}
+
+func (f *Fuzzer) adminPorts() *[]v1alpha1.AdminNetworkPolicyPort {
+	if f.chance(40) {
+		return nil
+	}
+	var ports []v1alpha1.AdminNetworkPolicyPort
+	for i := 0; i < 1+f.rand.Intn(2); i++ {
+		protocol, number := f.protocol(), f.portNumber()
+		switch f.rand.Intn(3) {
+		case 0:
+			ports = append(ports, v1alpha1.AdminNetworkPolicyPort{PortNumber: &v1alpha1.Port{Protocol: protocol, Port: int32(number)}})
+		case 1:
+			name := namedPort(number, protocol)
+			ports = append(ports, v1alpha1.AdminNetworkPolicyPort{NamedPort: &name})
+		default:
+			ports = append(ports, v1alpha1.AdminNetworkPolicyPort{PortRange: &v1alpha1.PortRange{Protocol: protocol, Start: int32(number), End: int32(number + 1 + f.rand.Intn(2))}})
+		}
+	}
+	return &ports
+}
+
+func hasNamedPort(ports *[]v1alpha1.AdminNetworkPolicyPort) bool {
+	if ports == nil {
+		return false
+	}
+	for _, port := range *ports {
+		if port.NamedPort != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Fuzzer) adminIngressPeers() []v1alpha1.AdminNetworkPolicyIngressPeer {
+	var peers []v1alpha1.AdminNetworkPolicyIngressPeer
+	for i := 0; i < 1+f.rand.Intn(2); i++ {
+		namespaces, pods := f.adminPeer()
+		peers = append(peers, v1alpha1.AdminNetworkPolicyIngressPeer{Namespaces: namespaces, Pods: pods})
+	}
+	return peers
+}
+
+// adminEgressPeers may include networks, which can't be used along with named ports.
+func (f *Fuzzer) adminEgressPeers(ports *[]v1alpha1.AdminNetworkPolicyPort) []v1alpha1.AdminNetworkPolicyEgressPeer {
+	var peers []v1alpha1.AdminNetworkPolicyEgressPeer
+	for i := 0; i < 1+f.rand.Intn(2); i++ {
+		if !hasNamedPort(ports) && f.chance(20) {
+			peers = append(peers, v1alpha1.AdminNetworkPolicyEgressPeer{Networks: []v1alpha1.CIDR{v1alpha1.CIDR(f.pick(allNetworks))}})
+			continue
+		}
+		namespaces, pods := f.adminPeer()
+		peers = append(peers, v1alpha1.AdminNetworkPolicyEgressPeer{Namespaces: namespaces, Pods: pods})
+	}
+	return peers
+}
+
+func (f *Fuzzer) adminNetworkPolicy(name string, priority int32) *v1alpha1.AdminNetworkPolicy {
+	actions := []v1alpha1.AdminNetworkPolicyRuleAction{
+		v1alpha1.AdminNetworkPolicyRuleActionAllow,
+		v1alpha1.AdminNetworkPolicyRuleActionDeny,
+		v1alpha1.AdminNetworkPolicyRuleActionPass,
+	}
+	anp := &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v1alpha1.AdminNetworkPolicySpec{Priority: priority, Subject: f.subject()},
+	}
+	for i := 0; i < 1+f.rand.Intn(2); i++ {
+		if f.chance(50) {
+			anp.Spec.Ingress = append(anp.Spec.Ingress, v1alpha1.AdminNetworkPolicyIngressRule{
+				Name:   fmt.Sprintf("ingress-%d", i),
+				Action: actions[f.rand.Intn(len(actions))],
+				From:   f.adminIngressPeers(),
+				Ports:  f.adminPorts(),
+			})
+		} else {
+			ports := f.adminPorts()
+			anp.Spec.Egress = append(anp.Spec.Egress, v1alpha1.AdminNetworkPolicyEgressRule{
+				Name:   fmt.Sprintf("egress-%d", i),
+				Action: actions[f.rand.Intn(len(actions))],
+				To:     f.adminEgressPeers(ports),
+				Ports:  ports,
+			})
+		}
+	}
+	return anp
+}
+
+func (f *Fuzzer) baselineAdminNetworkPolicy() *v1alpha1.BaselineAdminNetworkPolicy {
+	actions := []v1alpha1.BaselineAdminNetworkPolicyRuleAction{
+		v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow,
+		v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+	}
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       v1alpha1.BaselineAdminNetworkPolicySpec{Subject: f.subject()},
+	}
+	for i := 0; i < 1+f.rand.Intn(2); i++ {
+		if f.chance(50) {
+			banp.Spec.Ingress = append(banp.Spec.Ingress, v1alpha1.BaselineAdminNetworkPolicyIngressRule{
+				Name:   fmt.Sprintf("ingress-%d", i),
+				Action: actions[f.rand.Intn(len(actions))],
+				From:   f.adminIngressPeers(),
+				Ports:  f.adminPorts(),
+			})
+		} else {
+			ports := f.adminPorts()
+			var peers []v1alpha1.BaselineAdminNetworkPolicyEgressPeer
+			for _, peer := range f.adminEgressPeers(ports) {
+				peers = append(peers, v1alpha1.BaselineAdminNetworkPolicyEgressPeer{Namespaces: peer.Namespaces, Pods: peer.Pods, Networks: peer.Networks})
+			}
+			banp.Spec.Egress = append(banp.Spec.Egress, v1alpha1.BaselineAdminNetworkPolicyEgressRule{
+				Name:   fmt.Sprintf("egress-%d", i),
+				Action: actions[f.rand.Intn(len(actions))],
+				To:     peers,
+				Ports:  ports,
+			})
+		}
+	}
+	return banp
+}
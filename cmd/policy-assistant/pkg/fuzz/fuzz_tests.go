@@ -0,0 +1,107 @@
+package fuzz
+
+import (
+	"github.com/mattfenwick/cyclonus/pkg/generator"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+var testConfig = &Config{
+	Namespaces:           []string{"x", "y", "z"},
+	Pods:                 []string{"a", "b", "c"},
+	Ports:                []int{80, 81},
+	Protocols:            []v1.Protocol{v1.ProtocolTCP, v1.ProtocolUDP},
+	AdminNetworkPolicies: true,
+	MaxSteps:             3,
+	MaxActionsPerStep:    4,
+}
+
+func countActions(testCase *generator.TestCase) int {
+	count := 0
+	for _, step := range testCase.Steps {
+		count += len(step.Actions)
+	}
+	return count
+}
+
+func RunFuzzTests() {
+	Describe("Fuzz", func() {
+		It("Generates the same test case from the same seed", func() {
+			first, err := yaml.Marshal(TestCase(testConfig, 13))
+			Expect(err).To(BeNil())
+			second, err := yaml.Marshal(TestCase(testConfig, 13))
+			Expect(err).To(BeNil())
+			Expect(string(first)).To(Equal(string(second)))
+
+			other, err := yaml.Marshal(TestCase(testConfig, 14))
+			Expect(err).To(BeNil())
+			Expect(string(first)).ToNot(Equal(string(other)))
+		})
+
+		It("Generates valid policies", func() {
+			for seed := int64(0); seed < 200; seed++ {
+				testCase := TestCase(testConfig, seed)
+				Expect(generator.ValidateTags(testCase.Tags.Keys())).To(Succeed())
+				for _, step := range testCase.Steps {
+					for _, action := range step.Actions {
+						var netpols []*networkingv1.NetworkPolicy
+						var anps []*v1alpha1.AdminNetworkPolicy
+						var banp *v1alpha1.BaselineAdminNetworkPolicy
+						switch {
+						case action.CreatePolicy != nil:
+							netpols = append(netpols, action.CreatePolicy.Policy)
+						case action.UpdatePolicy != nil:
+							netpols = append(netpols, action.UpdatePolicy.Policy)
+						case action.CreateAdminNetworkPolicy != nil:
+							anps = append(anps, action.CreateAdminNetworkPolicy.Policy)
+						case action.UpdateAdminNetworkPolicy != nil:
+							anps = append(anps, action.UpdateAdminNetworkPolicy.Policy)
+						case action.CreateBaselineAdminNetworkPolicy != nil:
+							banp = action.CreateBaselineAdminNetworkPolicy.Policy
+						}
+						_, errs := matcher.BuildV1AndV2NetPols(false, netpols, anps, banp)
+						Expect(errs).To(BeEmpty(), testCase.Description)
+					}
+				}
+			}
+		})
+
+		It("Doesn't generate ANPs or BANPs unless enabled", func() {
+			config := *testConfig
+			config.AdminNetworkPolicies = false
+			for seed := int64(0); seed < 50; seed++ {
+				testCase := TestCase(&config, seed)
+				Expect(testCase.Tags.ContainsAny([]string{generator.TagCreateANP, generator.TagCreateBANP})).To(BeFalse())
+			}
+		})
+
+		It("Shrinks a failing test case", func() {
+			testCase := TestCase(testConfig, 11)
+			Expect(countActions(testCase)).To(BeNumerically(">", 1))
+
+			// "fails" as long as the test case creates any policy
+			fails := func(tc *generator.TestCase) bool {
+				for _, step := range tc.Steps {
+					for _, action := range step.Actions {
+						if action.CreatePolicy != nil || action.CreateAdminNetworkPolicy != nil || action.CreateBaselineAdminNetworkPolicy != nil {
+							return true
+						}
+					}
+				}
+				return false
+			}
+			Expect(fails(testCase)).To(BeTrue())
+
+			shrunk := Shrink(testCase, fails)
+			Expect(shrunk.Steps).To(HaveLen(1))
+			Expect(countActions(shrunk)).To(Equal(1))
+			Expect(removers(shrunk.Steps[0].Actions[0])).To(BeEmpty())
+			Expect(countActions(testCase)).To(BeNumerically(">", 1))
+		})
+	})
+}
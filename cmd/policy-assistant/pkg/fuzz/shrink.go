@@ -0,0 +1,188 @@
+package fuzz
+
+import (
+	"github.com/mattfenwick/cyclonus/pkg/generator"
+	"github.com/sirupsen/logrus"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// Shrink greedily removes steps, actions, rules, peers and ports from a failing test case, keeping each removal
+// for which the test case still fails, until nothing more can be removed.  The result is a minimal reproducer.
+func Shrink(testCase *generator.TestCase, fails func(*generator.TestCase) bool) *generator.TestCase {
+	current := testCase
+	for {
+		shrunk := false
+		for _, candidate := range candidates(current) {
+			if fails(candidate) {
+				logrus.Infof("shrunk test case to %d steps", len(candidate.Steps))
+				current, shrunk = candidate, true
+				break
+			}
+		}
+		if !shrunk {
+			return current
+		}
+	}
+}
+
+// copyTestCase deep copies a test case by a round trip through yaml, which is also how reproducers are saved.
+func copyTestCase(testCase *generator.TestCase) *generator.TestCase {
+	bytes, err := yaml.Marshal(testCase)
+	if err != nil {
+		panic(err)
+	}
+	var out generator.TestCase
+	if err := yaml.UnmarshalStrict(bytes, &out); err != nil {
+		panic(err)
+	}
+	return &out
+}
+
+// candidates are copies of the test case, each with one thing removed.  Larger removals come first.
+func candidates(testCase *generator.TestCase) []*generator.TestCase {
+	var out []*generator.TestCase
+	edit := func(f func(tc *generator.TestCase)) {
+		tc := copyTestCase(testCase)
+		f(tc)
+		out = append(out, tc)
+	}
+
+	for i := range testCase.Steps {
+		if len(testCase.Steps) > 1 {
+			edit(func(tc *generator.TestCase) {
+				tc.Steps = without(tc.Steps, i)
+			})
+		}
+	}
+	for i, step := range testCase.Steps {
+		for j := range step.Actions {
+			edit(func(tc *generator.TestCase) {
+				tc.Steps[i].Actions = without(tc.Steps[i].Actions, j)
+			})
+		}
+	}
+	for i, step := range testCase.Steps {
+		for j, action := range step.Actions {
+			for k := range removers(action) {
+				edit(func(tc *generator.TestCase) {
+					removers(tc.Steps[i].Actions[j])[k]()
+				})
+			}
+		}
+	}
+	return out
+}
+
+func without[T any](items []T, i int) []T {
+	return append(items[:i:i], items[i+1:]...)
+}
+
+// removers are functions which each remove one rule, peer, port or fuzz label from an action.
+func removers(action *generator.Action) []func() {
+	var out []func()
+	switch {
+	case action.CreatePolicy != nil:
+		out = networkPolicyRemovers(action.CreatePolicy.Policy)
+	case action.UpdatePolicy != nil:
+		out = networkPolicyRemovers(action.UpdatePolicy.Policy)
+	case action.CreateAdminNetworkPolicy != nil:
+		out = adminNetworkPolicyRemovers(action.CreateAdminNetworkPolicy.Policy)
+	case action.UpdateAdminNetworkPolicy != nil:
+		out = adminNetworkPolicyRemovers(action.UpdateAdminNetworkPolicy.Policy)
+	case action.CreateBaselineAdminNetworkPolicy != nil:
+		out = baselineAdminNetworkPolicyRemovers(action.CreateBaselineAdminNetworkPolicy.Policy)
+	case action.UpdateBaselineAdminNetworkPolicy != nil:
+		out = baselineAdminNetworkPolicyRemovers(action.UpdateBaselineAdminNetworkPolicy.Policy)
+	case action.SetPodLabels != nil:
+		out = labelRemovers(action.SetPodLabels.Labels)
+	case action.SetNamespaceLabels != nil:
+		out = labelRemovers(action.SetNamespaceLabels.Labels)
+	}
+	return out
+}
+
+func labelRemovers(labels map[string]string) []func() {
+	if _, ok := labels[fuzzLabel]; !ok {
+		return nil
+	}
+	return []func(){func() { delete(labels, fuzzLabel) }}
+}
+
+func networkPolicyRemovers(netpol *networkingv1.NetworkPolicy) []func() {
+	var out []func()
+	spec := &netpol.Spec
+	for i, rule := range spec.Ingress {
+		out = append(out, func() { spec.Ingress = without(spec.Ingress, i) })
+		for j := range rule.From {
+			out = append(out, func() { spec.Ingress[i].From = without(spec.Ingress[i].From, j) })
+		}
+		for j := range rule.Ports {
+			out = append(out, func() { spec.Ingress[i].Ports = without(spec.Ingress[i].Ports, j) })
+		}
+	}
+	for i, rule := range spec.Egress {
+		out = append(out, func() { spec.Egress = without(spec.Egress, i) })
+		for j := range rule.To {
+			out = append(out, func() { spec.Egress[i].To = without(spec.Egress[i].To, j) })
+		}
+		for j := range rule.Ports {
+			out = append(out, func() { spec.Egress[i].Ports = without(spec.Egress[i].Ports, j) })
+		}
+	}
+	return out
+}
+
+// adminPortRemovers removes ports, but not the last one: no ports and an empty list of ports mean different things.
+func adminPortRemovers(ports *[]v1alpha1.AdminNetworkPolicyPort) []func() {
+	var out []func()
+	if ports == nil || len(*ports) < 2 {
+		return nil
+	}
+	for j := range *ports {
+		out = append(out, func() { *ports = without(*ports, j) })
+	}
+	return out
+}
+
+// adminNetworkPolicyRemovers removes rules and peers, but not a rule's last peer, since peers are required.
+func adminNetworkPolicyRemovers(anp *v1alpha1.AdminNetworkPolicy) []func() {
+	var out []func()
+	spec := &anp.Spec
+	for i, rule := range spec.Ingress {
+		out = append(out, func() { spec.Ingress = without(spec.Ingress, i) })
+		for j := 0; len(rule.From) > 1 && j < len(rule.From); j++ {
+			out = append(out, func() { spec.Ingress[i].From = without(spec.Ingress[i].From, j) })
+		}
+		out = append(out, adminPortRemovers(spec.Ingress[i].Ports)...)
+	}
+	for i, rule := range spec.Egress {
+		out = append(out, func() { spec.Egress = without(spec.Egress, i) })
+		for j := 0; len(rule.To) > 1 && j < len(rule.To); j++ {
+			out = append(out, func() { spec.Egress[i].To = without(spec.Egress[i].To, j) })
+		}
+		out = append(out, adminPortRemovers(spec.Egress[i].Ports)...)
+	}
+	return out
+}
+
+func baselineAdminNetworkPolicyRemovers(banp *v1alpha1.BaselineAdminNetworkPolicy) []func() {
+	var out []func()
+	spec := &banp.Spec
+	for i, rule := range spec.Ingress {
+		out = append(out, func() { spec.Ingress = without(spec.Ingress, i) })
+		for j := 0; len(rule.From) > 1 && j < len(rule.From); j++ {
+			out = append(out, func() { spec.Ingress[i].From = without(spec.Ingress[i].From, j) })
+		}
+		out = append(out, adminPortRemovers(spec.Ingress[i].Ports)...)
+	}
+	for i, rule := range spec.Egress {
+		out = append(out, func() { spec.Egress = without(spec.Egress, i) })
+		for j := 0; len(rule.To) > 1 && j < len(rule.To); j++ {
+			out = append(out, func() { spec.Egress[i].To = without(spec.Egress[i].To, j) })
+		}
+		out = append(out, adminPortRemovers(spec.Egress[i].Ports)...)
+	}
+	return out
+}
@@ -0,0 +1,14 @@
+package fuzz
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFuzz(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunFuzzTests()
+	RunSpecs(t, "fuzz suite")
+}
@@ -1,26 +1,37 @@
 package generator
 
-import networkingv1 "k8s.io/api/networking/v1"
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
 
 // Action models a sum type (discriminated union): exactly one field must be non-null.
 type Action struct {
-	CreatePolicy *CreatePolicyAction
-	UpdatePolicy *UpdatePolicyAction
-	DeletePolicy *DeletePolicyAction
+	CreatePolicy *CreatePolicyAction `json:"createPolicy,omitempty"`
+	UpdatePolicy *UpdatePolicyAction `json:"updatePolicy,omitempty"`
+	DeletePolicy *DeletePolicyAction `json:"deletePolicy,omitempty"`
 
-	CreateNamespace    *CreateNamespaceAction
-	SetNamespaceLabels *SetNamespaceLabelsAction
-	DeleteNamespace    *DeleteNamespaceAction
+	CreateAdminNetworkPolicy *CreateAdminNetworkPolicyAction `json:"createAdminNetworkPolicy,omitempty"`
+	UpdateAdminNetworkPolicy *UpdateAdminNetworkPolicyAction `json:"updateAdminNetworkPolicy,omitempty"`
+	DeleteAdminNetworkPolicy *DeleteAdminNetworkPolicyAction `json:"deleteAdminNetworkPolicy,omitempty"`
 
-	ReadNetworkPolicies *ReadNetworkPoliciesAction
+	CreateBaselineAdminNetworkPolicy *CreateBaselineAdminNetworkPolicyAction `json:"createBaselineAdminNetworkPolicy,omitempty"`
+	UpdateBaselineAdminNetworkPolicy *UpdateBaselineAdminNetworkPolicyAction `json:"updateBaselineAdminNetworkPolicy,omitempty"`
+	DeleteBaselineAdminNetworkPolicy *DeleteBaselineAdminNetworkPolicyAction `json:"deleteBaselineAdminNetworkPolicy,omitempty"`
 
-	CreatePod    *CreatePodAction
-	SetPodLabels *SetPodLabelsAction
-	DeletePod    *DeletePodAction
+	CreateNamespace    *CreateNamespaceAction    `json:"createNamespace,omitempty"`
+	SetNamespaceLabels *SetNamespaceLabelsAction `json:"setNamespaceLabels,omitempty"`
+	DeleteNamespace    *DeleteNamespaceAction    `json:"deleteNamespace,omitempty"`
+
+	ReadNetworkPolicies *ReadNetworkPoliciesAction `json:"readNetworkPolicies,omitempty"`
+
+	CreatePod    *CreatePodAction    `json:"createPod,omitempty"`
+	SetPodLabels *SetPodLabelsAction `json:"setPodLabels,omitempty"`
+	DeletePod    *DeletePodAction    `json:"deletePod,omitempty"`
 }
 
 type CreatePolicyAction struct {
-	Policy *networkingv1.NetworkPolicy
+	Policy *networkingv1.NetworkPolicy `json:"policy"`
 }
 
 func CreatePolicy(policy *networkingv1.NetworkPolicy) *Action {
@@ -28,7 +39,7 @@ func CreatePolicy(policy *networkingv1.NetworkPolicy) *Action {
 }
 
 type UpdatePolicyAction struct {
-	Policy *networkingv1.NetworkPolicy
+	Policy *networkingv1.NetworkPolicy `json:"policy"`
 }
 
 func UpdatePolicy(policy *networkingv1.NetworkPolicy) *Action {
@@ -36,17 +47,65 @@ func UpdatePolicy(policy *networkingv1.NetworkPolicy) *Action {
 }
 
 type DeletePolicyAction struct {
-	Namespace string
-	Name      string
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
 }
 
 func DeletePolicy(ns string, name string) *Action {
 	return &Action{DeletePolicy: &DeletePolicyAction{Namespace: ns, Name: name}}
 }
 
+type CreateAdminNetworkPolicyAction struct {
+	Policy *v1alpha1.AdminNetworkPolicy `json:"policy"`
+}
+
+func CreateAdminNetworkPolicy(policy *v1alpha1.AdminNetworkPolicy) *Action {
+	return &Action{CreateAdminNetworkPolicy: &CreateAdminNetworkPolicyAction{Policy: policy}}
+}
+
+type UpdateAdminNetworkPolicyAction struct {
+	Policy *v1alpha1.AdminNetworkPolicy `json:"policy"`
+}
+
+func UpdateAdminNetworkPolicy(policy *v1alpha1.AdminNetworkPolicy) *Action {
+	return &Action{UpdateAdminNetworkPolicy: &UpdateAdminNetworkPolicyAction{Policy: policy}}
+}
+
+type DeleteAdminNetworkPolicyAction struct {
+	Name string `json:"name"`
+}
+
+func DeleteAdminNetworkPolicy(name string) *Action {
+	return &Action{DeleteAdminNetworkPolicy: &DeleteAdminNetworkPolicyAction{Name: name}}
+}
+
+type CreateBaselineAdminNetworkPolicyAction struct {
+	Policy *v1alpha1.BaselineAdminNetworkPolicy `json:"policy"`
+}
+
+func CreateBaselineAdminNetworkPolicy(policy *v1alpha1.BaselineAdminNetworkPolicy) *Action {
+	return &Action{CreateBaselineAdminNetworkPolicy: &CreateBaselineAdminNetworkPolicyAction{Policy: policy}}
+}
+
+type UpdateBaselineAdminNetworkPolicyAction struct {
+	Policy *v1alpha1.BaselineAdminNetworkPolicy `json:"policy"`
+}
+
+func UpdateBaselineAdminNetworkPolicy(policy *v1alpha1.BaselineAdminNetworkPolicy) *Action {
+	return &Action{UpdateBaselineAdminNetworkPolicy: &UpdateBaselineAdminNetworkPolicyAction{Policy: policy}}
+}
+
+type DeleteBaselineAdminNetworkPolicyAction struct {
+	Name string `json:"name"`
+}
+
+func DeleteBaselineAdminNetworkPolicy(name string) *Action {
+	return &Action{DeleteBaselineAdminNetworkPolicy: &DeleteBaselineAdminNetworkPolicyAction{Name: name}}
+}
+
 type CreateNamespaceAction struct {
-	Namespace string
-	Labels    map[string]string
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels"`
 }
 
 func CreateNamespace(ns string, labels map[string]string) *Action {
@@ -54,8 +113,8 @@ func CreateNamespace(ns string, labels map[string]string) *Action {
 }
 
 type SetNamespaceLabelsAction struct {
-	Namespace string
-	Labels    map[string]string
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels"`
 }
 
 func SetNamespaceLabels(ns string, labels map[string]string) *Action {
@@ -63,7 +122,7 @@ func SetNamespaceLabels(ns string, labels map[string]string) *Action {
 }
 
 type DeleteNamespaceAction struct {
-	Namespace string
+	Namespace string `json:"namespace"`
 }
 
 func DeleteNamespace(ns string) *Action {
@@ -71,7 +130,7 @@ func DeleteNamespace(ns string) *Action {
 }
 
 type ReadNetworkPoliciesAction struct {
-	Namespaces []string
+	Namespaces []string `json:"namespaces"`
 }
 
 func ReadNetworkPolicies(namespaces []string) *Action {
@@ -79,9 +138,9 @@ func ReadNetworkPolicies(namespaces []string) *Action {
 }
 
 type CreatePodAction struct {
-	Namespace string
-	Pod       string
-	Labels    map[string]string
+	Namespace string            `json:"namespace"`
+	Pod       string            `json:"pod"`
+	Labels    map[string]string `json:"labels"`
 }
 
 func CreatePod(namespace string, pod string, labels map[string]string) *Action {
@@ -93,9 +152,9 @@ func CreatePod(namespace string, pod string, labels map[string]string) *Action {
 }
 
 type SetPodLabelsAction struct {
-	Namespace string
-	Pod       string
-	Labels    map[string]string
+	Namespace string            `json:"namespace"`
+	Pod       string            `json:"pod"`
+	Labels    map[string]string `json:"labels"`
 }
 
 func SetPodLabels(namespace string, pod string, labels map[string]string) *Action {
@@ -107,8 +166,8 @@ func SetPodLabels(namespace string, pod string, labels map[string]string) *Actio
 }
 
 type DeletePodAction struct {
-	Namespace string
-	Pod       string
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
 }
 
 func DeletePod(namespace string, pod string) *Action {
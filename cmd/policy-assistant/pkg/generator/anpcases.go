@@ -0,0 +1,271 @@
+package generator
+
+import (
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+/*
+ANPs and BANPs:
+ - priority: the lowest priority number wins, regardless of the order of creation
+ - pass: skips lower priority ANPs, and delegates to network policies and then the BANP
+ - conflicts with network policies: ANPs are evaluated before network policies
+ - BANP: applies to traffic which no ANP or network policy decides
+ - ports: ranges and named ports
+
+Peers only select the test namespaces, by their 'ns' label, so that egress denials don't break DNS.
+*/
+
+var (
+	anpAllow = v1alpha1.AdminNetworkPolicyRuleActionAllow
+	anpDeny  = v1alpha1.AdminNetworkPolicyRuleActionDeny
+	anpPass  = v1alpha1.AdminNetworkPolicyRuleActionPass
+
+	nsXYZMatchExpressionsSelector = &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      "ns",
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   []string{"x", "y", "z"},
+			},
+		},
+	}
+	nsYMatchLabelsSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"ns": "y"}}
+)
+
+func anpNamespaceSubject(ns string) v1alpha1.AdminNetworkPolicySubject {
+	return v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"ns": ns}}}
+}
+
+func anpPodSubject(ns string, pod string) v1alpha1.AdminNetworkPolicySubject {
+	return v1alpha1.AdminNetworkPolicySubject{Pods: &v1alpha1.NamespacedPod{
+		NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"ns": ns}},
+		PodSelector:       metav1.LabelSelector{MatchLabels: map[string]string{"pod": pod}},
+	}}
+}
+
+func anpPortNumber(protocol v1.Protocol, port int32) v1alpha1.AdminNetworkPolicyPort {
+	return v1alpha1.AdminNetworkPolicyPort{PortNumber: &v1alpha1.Port{Protocol: protocol, Port: port}}
+}
+
+func anpPortRange(protocol v1.Protocol, start int32, end int32) v1alpha1.AdminNetworkPolicyPort {
+	return v1alpha1.AdminNetworkPolicyPort{PortRange: &v1alpha1.PortRange{Protocol: protocol, Start: start, End: end}}
+}
+
+func anpNamedPort(name string) v1alpha1.AdminNetworkPolicyPort {
+	return v1alpha1.AdminNetworkPolicyPort{NamedPort: &name}
+}
+
+func anpPorts(ports []v1alpha1.AdminNetworkPolicyPort) *[]v1alpha1.AdminNetworkPolicyPort {
+	if len(ports) == 0 {
+		return nil
+	}
+	return &ports
+}
+
+func anpIngress(action v1alpha1.AdminNetworkPolicyRuleAction, from *metav1.LabelSelector, ports ...v1alpha1.AdminNetworkPolicyPort) v1alpha1.AdminNetworkPolicyIngressRule {
+	return v1alpha1.AdminNetworkPolicyIngressRule{
+		Name:   "ingress-" + string(action),
+		Action: action,
+		From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: from}},
+		Ports:  anpPorts(ports),
+	}
+}
+
+func anpEgress(action v1alpha1.AdminNetworkPolicyRuleAction, to *metav1.LabelSelector, ports ...v1alpha1.AdminNetworkPolicyPort) v1alpha1.AdminNetworkPolicyEgressRule {
+	return v1alpha1.AdminNetworkPolicyEgressRule{
+		Name:   "egress-" + string(action),
+		Action: action,
+		To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Namespaces: to}},
+		Ports:  anpPorts(ports),
+	}
+}
+
+func BuildANP(name string, priority int32, subject v1alpha1.AdminNetworkPolicySubject, ingress []v1alpha1.AdminNetworkPolicyIngressRule, egress []v1alpha1.AdminNetworkPolicyEgressRule) *v1alpha1.AdminNetworkPolicy {
+	return &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: priority,
+			Subject:  subject,
+			Ingress:  ingress,
+			Egress:   egress,
+		},
+	}
+}
+
+func ingressANP(name string, priority int32, subject v1alpha1.AdminNetworkPolicySubject, rules ...v1alpha1.AdminNetworkPolicyIngressRule) *v1alpha1.AdminNetworkPolicy {
+	return BuildANP(name, priority, subject, rules, nil)
+}
+
+func egressANP(name string, priority int32, subject v1alpha1.AdminNetworkPolicySubject, rules ...v1alpha1.AdminNetworkPolicyEgressRule) *v1alpha1.AdminNetworkPolicy {
+	return BuildANP(name, priority, subject, nil, rules)
+}
+
+// BuildBANP builds the cluster's BANP, which must be named 'default'.
+func BuildBANP(subject v1alpha1.AdminNetworkPolicySubject, ingress []v1alpha1.BaselineAdminNetworkPolicyIngressRule, egress []v1alpha1.BaselineAdminNetworkPolicyEgressRule) *v1alpha1.BaselineAdminNetworkPolicy {
+	return &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: subject,
+			Ingress: ingress,
+			Egress:  egress,
+		},
+	}
+}
+
+func banpIngress(action v1alpha1.BaselineAdminNetworkPolicyRuleAction, from *metav1.LabelSelector) []v1alpha1.BaselineAdminNetworkPolicyIngressRule {
+	return []v1alpha1.BaselineAdminNetworkPolicyIngressRule{{
+		Name:   "ingress-" + string(action),
+		Action: action,
+		From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: from}},
+	}}
+}
+
+func banpEgress(action v1alpha1.BaselineAdminNetworkPolicyRuleAction, to *metav1.LabelSelector) []v1alpha1.BaselineAdminNetworkPolicyEgressRule {
+	return []v1alpha1.BaselineAdminNetworkPolicyEgressRule{{
+		Name:   "egress-" + string(action),
+		Action: action,
+		To:     []v1alpha1.BaselineAdminNetworkPolicyEgressPeer{{Namespaces: to}},
+	}}
+}
+
+// allowIngressFromPodA is a network policy in namespace y, allowing ingress only from pods 'a' of the test namespaces.
+func allowIngressFromPodA() *networkingv1.NetworkPolicy {
+	return (&Netpol{
+		Name:   "allow-ingress-from-pod-a",
+		Target: NewNetpolTarget("y", nil, nil),
+		Ingress: &NetpolPeers{Rules: []*Rule{{
+			Peers: []networkingv1.NetworkPolicyPeer{{PodSelector: podAMatchLabelsSelector, NamespaceSelector: nsXYZMatchExpressionsSelector}},
+		}}},
+	}).NetworkPolicy()
+}
+
+func (t *TestCaseGenerator) AdminNetworkPolicyTestCases() []*TestCase {
+	return flatten(
+		t.anpPriorityTestCases(),
+		t.anpPassTestCases(),
+		t.anpNetpolConflictTestCases(),
+		t.banpDefaultTestCases(),
+		t.anpPortTestCases())
+}
+
+func (t *TestCaseGenerator) anpPriorityTestCases() []*TestCase {
+	return []*TestCase{
+		NewSingleStepTestCase("ANP: higher priority deny beats lower priority allow",
+			NewStringSet(TagANPPriority, TagIngress, TagCreateANP),
+			ProbeAllAvailable,
+			// create the lower priority policy first, to check that order of creation doesn't matter
+			CreateAdminNetworkPolicy(ingressANP("allow-from-x", 20, anpNamespaceSubject("y"), anpIngress(anpAllow, nsXMatchLabelsSelector))),
+			CreateAdminNetworkPolicy(ingressANP("deny-from-x", 10, anpNamespaceSubject("y"), anpIngress(anpDeny, nsXMatchLabelsSelector)))),
+		NewSingleStepTestCase("ANP: higher priority allow beats lower priority deny",
+			NewStringSet(TagANPPriority, TagEgress, TagCreateANP),
+			ProbeAllAvailable,
+			CreateAdminNetworkPolicy(egressANP("allow-to-y", 10, anpNamespaceSubject("x"), anpEgress(anpAllow, nsYMatchLabelsSelector))),
+			CreateAdminNetworkPolicy(egressANP("deny-to-test-namespaces", 20, anpNamespaceSubject("x"), anpEgress(anpDeny, nsXYZMatchExpressionsSelector)))),
+		NewTestCase("ANP: update priorities to swap precedence, then delete",
+			NewStringSet(TagANPPriority, TagIngress, TagCreateANP, TagUpdateANP, TagDeleteANP),
+			NewTestStep(ProbeAllAvailable,
+				CreateAdminNetworkPolicy(ingressANP("deny-from-x", 10, anpNamespaceSubject("y"), anpIngress(anpDeny, nsXMatchLabelsSelector))),
+				CreateAdminNetworkPolicy(ingressANP("allow-from-x", 20, anpNamespaceSubject("y"), anpIngress(anpAllow, nsXMatchLabelsSelector)))),
+			NewTestStep(ProbeAllAvailable,
+				UpdateAdminNetworkPolicy(ingressANP("deny-from-x", 30, anpNamespaceSubject("y"), anpIngress(anpDeny, nsXMatchLabelsSelector)))),
+			NewTestStep(ProbeAllAvailable,
+				DeleteAdminNetworkPolicy("allow-from-x"))),
+	}
+}
+
+func (t *TestCaseGenerator) anpPassTestCases() []*TestCase {
+	return []*TestCase{
+		NewTestCase("ANP: pass skips lower priority ANPs, delegating to network policies",
+			NewStringSet(TagANPPass, TagIngress, TagCreateANP, TagCreatePolicy),
+			NewTestStep(ProbeAllAvailable,
+				CreateAdminNetworkPolicy(ingressANP("pass-from-x", 10, anpNamespaceSubject("y"), anpIngress(anpPass, nsXMatchLabelsSelector))),
+				CreateAdminNetworkPolicy(ingressANP("deny-from-x", 20, anpNamespaceSubject("y"), anpIngress(anpDeny, nsXMatchLabelsSelector)))),
+			NewTestStep(ProbeAllAvailable,
+				CreatePolicy(allowIngressFromPodA()))),
+		NewTestCase("ANP: pass delegates to the BANP when no network policy applies",
+			NewStringSet(TagANPPass, TagBANPDefault, TagEgress, TagCreateANP, TagCreateBANP, TagCreatePolicy),
+			NewTestStep(ProbeAllAvailable,
+				CreateAdminNetworkPolicy(egressANP("pass-to-y", 10, anpNamespaceSubject("x"), anpEgress(anpPass, nsYMatchLabelsSelector))),
+				CreateBaselineAdminNetworkPolicy(BuildBANP(anpNamespaceSubject("x"), nil, banpEgress(v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny, nsYMatchLabelsSelector)))),
+			NewTestStep(ProbeAllAvailable,
+				t.withDNS(NewNetpolTarget("x", nil, nil),
+					CreatePolicy((&Netpol{
+						Name:   "allow-egress-to-y",
+						Target: NewNetpolTarget("x", nil, nil),
+						Egress: &NetpolPeers{Rules: []*Rule{{Peers: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: nsYMatchLabelsSelector}}}}},
+					}).NetworkPolicy()))...)),
+	}
+}
+
+func (t *TestCaseGenerator) anpNetpolConflictTestCases() []*TestCase {
+	return []*TestCase{
+		NewSingleStepTestCase("ANP deny overrides network policy allow",
+			NewStringSet(TagANPNetpolConflict, TagIngress, TagAllowAll, TagCreateANP, TagCreatePolicy),
+			ProbeAllAvailable,
+			CreatePolicy((&Netpol{Name: "allow-all-ingress", Target: NewNetpolTarget("y", nil, nil), Ingress: ExplicitAllowAll}).NetworkPolicy()),
+			CreateAdminNetworkPolicy(ingressANP("deny-from-x", 10, anpNamespaceSubject("y"), anpIngress(anpDeny, nsXMatchLabelsSelector)))),
+		NewSingleStepTestCase("ANP allow overrides network policy deny",
+			NewStringSet(TagANPNetpolConflict, TagIngress, TagDenyAll, TagCreateANP, TagCreatePolicy),
+			ProbeAllAvailable,
+			CreatePolicy((&Netpol{Name: "deny-all-ingress", Target: NewNetpolTarget("y", nil, nil), Ingress: DenyAll}).NetworkPolicy()),
+			CreateAdminNetworkPolicy(ingressANP("allow-from-x", 10, anpNamespaceSubject("y"), anpIngress(anpAllow, nsXMatchLabelsSelector)))),
+		NewSingleStepTestCase("ANP egress allow overrides network policy deny",
+			NewStringSet(TagANPNetpolConflict, TagEgress, TagDenyAll, TagCreateANP, TagCreatePolicy),
+			ProbeAllAvailable,
+			t.withDNS(NewNetpolTarget("x", nil, nil),
+				CreatePolicy((&Netpol{Name: "deny-all-egress", Target: NewNetpolTarget("x", nil, nil), Egress: DenyAll}).NetworkPolicy()),
+				CreateAdminNetworkPolicy(egressANP("allow-to-y", 10, anpPodSubject("x", "a"), anpEgress(anpAllow, nsYMatchLabelsSelector))))...),
+	}
+}
+
+func (t *TestCaseGenerator) banpDefaultTestCases() []*TestCase {
+	return []*TestCase{
+		NewTestCase("BANP denies traffic which network policies don't decide",
+			NewStringSet(TagBANPDefault, TagIngress, TagCreateBANP, TagDeleteBANP, TagCreatePolicy),
+			NewTestStep(ProbeAllAvailable,
+				CreateBaselineAdminNetworkPolicy(BuildBANP(anpNamespaceSubject("y"), banpIngress(v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny, nsXYZMatchExpressionsSelector), nil))),
+			NewTestStep(ProbeAllAvailable,
+				CreatePolicy(allowIngressFromPodA())),
+			NewTestStep(ProbeAllAvailable,
+				DeleteBaselineAdminNetworkPolicy("default"))),
+		NewSingleStepTestCase("BANP egress deny from a pod",
+			NewStringSet(TagBANPDefault, TagEgress, TagCreateBANP),
+			ProbeAllAvailable,
+			CreateBaselineAdminNetworkPolicy(BuildBANP(anpPodSubject("x", "a"), nil, banpEgress(v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny, nsXYZMatchExpressionsSelector)))),
+	}
+}
+
+func (t *TestCaseGenerator) anpPortTestCases() []*TestCase {
+	return []*TestCase{
+		NewSingleStepTestCase("ANP: deny a port range, except for a port allowed at a higher priority",
+			NewStringSet(TagANPPortRange, TagTCPProtocol, TagIngress, TagCreateANP),
+			ProbeAllAvailable,
+			CreateAdminNetworkPolicy(ingressANP("allow-81-tcp", 10, anpNamespaceSubject("y"), anpIngress(anpAllow, nsXYZMatchExpressionsSelector, anpPortNumber(tcp, 81)))),
+			CreateAdminNetworkPolicy(ingressANP("deny-80-81-tcp", 20, anpNamespaceSubject("y"), anpIngress(anpDeny, nsXYZMatchExpressionsSelector, anpPortRange(tcp, 80, 81))))),
+		NewSingleStepTestCase("ANP: deny egress on a udp port range",
+			NewStringSet(TagANPPortRange, TagUDPProtocol, TagEgress, TagCreateANP),
+			ProbeAllAvailable,
+			CreateAdminNetworkPolicy(egressANP("deny-79-80-udp", 10, anpNamespaceSubject("x"), anpEgress(anpDeny, nsXYZMatchExpressionsSelector, anpPortRange(udp, 79, 80))))),
+		NewSingleStepTestCase("ANP: deny a named port",
+			NewStringSet(TagANPNamedPort, TagIngress, TagCreateANP),
+			ProbeAllAvailable,
+			CreateAdminNetworkPolicy(ingressANP("deny-serve-80-tcp", 10, anpNamespaceSubject("y"), anpIngress(anpDeny, nsXYZMatchExpressionsSelector, anpNamedPort("serve-80-tcp"))))),
+		NewSingleStepTestCase("ANP: allow a named port over a lower priority deny",
+			NewStringSet(TagANPNamedPort, TagEgress, TagCreateANP),
+			ProbeAllAvailable,
+			CreateAdminNetworkPolicy(egressANP("allow-serve-81-udp", 10, anpNamespaceSubject("x"), anpEgress(anpAllow, nsYMatchLabelsSelector, anpNamedPort("serve-81-udp")))),
+			CreateAdminNetworkPolicy(egressANP("deny-to-y", 20, anpNamespaceSubject("x"), anpEgress(anpDeny, nsYMatchLabelsSelector)))),
+	}
+}
+
+// withDNS adds a network policy allowing DNS from the source, if the generator allows DNS: network policies
+// isolating egress otherwise deny it.
+func (t *TestCaseGenerator) withDNS(source *NetpolTarget, actions ...*Action) []*Action {
+	if t.AllowDNS {
+		actions = append(actions, CreatePolicy(AllowDNSPolicy(source).NetworkPolicy()))
+	}
+	return actions
+}
@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	expectedAllowed    = "."
+	expectedBlocked    = "X"
+	expectedNotChecked = "#"
+)
+
+// ExpectedConnectivity is hand-written connectivity which a step is expected to have.  It's either a truth table,
+// or lists of allowed and denied flows.
+type ExpectedConnectivity struct {
+	// TruthTable is a whitespace-separated grid: the first row is a placeholder followed by destination pods, and each
+	// following row is a source pod followed by '.' (allowed), 'X' (blocked) or '#' (not checked) for each
+	// destination.  Pods are written as namespace/pod, and don't need to cover every pod.  It applies to every port
+	// and protocol which the step probes.
+	TruthTable string          `json:"truthTable,omitempty"`
+	Allowed    []*ExpectedFlow `json:"allowed,omitempty"`
+	Denied     []*ExpectedFlow `json:"denied,omitempty"`
+}
+
+// ExpectedFlow is traffic from one pod to another, both written as namespace/pod.  Without a port and protocol, it
+// applies to every port and protocol which the step probes.
+type ExpectedFlow struct {
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Port     *intstr.IntOrString `json:"port,omitempty"`
+	Protocol v1.Protocol         `json:"protocol,omitempty"`
+}
+
+// Matches checks whether the flow applies to a port -- by number or by name -- and protocol.
+func (f *ExpectedFlow) Matches(port int, portName string, protocol v1.Protocol) bool {
+	if f.Protocol != "" && f.Protocol != protocol {
+		return false
+	}
+	if f.Port == nil {
+		return true
+	}
+	if f.Port.Type == intstr.Int {
+		return f.Port.IntValue() == port
+	}
+	return f.Port.StrVal == portName
+}
+
+func validatePodName(pod string) error {
+	parts := strings.Split(pod, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return errors.Errorf("invalid pod '%s': expected namespace/pod", pod)
+	}
+	return nil
+}
+
+// Flows returns the flows which are expected to be allowed, and those expected to be denied.
+func (e *ExpectedConnectivity) Flows() ([]*ExpectedFlow, []*ExpectedFlow, error) {
+	hasFlows := len(e.Allowed) > 0 || len(e.Denied) > 0
+	if (e.TruthTable != "") == hasFlows {
+		return nil, nil, errors.Errorf("expected connectivity must be either a truth table, or allowed and denied flows")
+	}
+	if e.TruthTable != "" {
+		return e.truthTableFlows()
+	}
+	for _, flow := range append(append([]*ExpectedFlow{}, e.Allowed...), e.Denied...) {
+		for _, pod := range []string{flow.From, flow.To} {
+			if err := validatePodName(pod); err != nil {
+				return nil, nil, errors.WithMessagef(err, "flow %s -> %s", flow.From, flow.To)
+			}
+		}
+		if flow.Protocol != "" && flow.Protocol != v1.ProtocolTCP && flow.Protocol != v1.ProtocolUDP && flow.Protocol != v1.ProtocolSCTP {
+			return nil, nil, errors.Errorf("flow %s -> %s: invalid protocol %s", flow.From, flow.To, flow.Protocol)
+		}
+	}
+	return e.Allowed, e.Denied, nil
+}
+
+func (e *ExpectedConnectivity) truthTableFlows() ([]*ExpectedFlow, []*ExpectedFlow, error) {
+	var rows [][]string
+	for _, line := range strings.Split(e.TruthTable, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			rows = append(rows, fields)
+		}
+	}
+	if len(rows) < 2 {
+		return nil, nil, errors.Errorf("truth table needs a row of destinations and at least one source row")
+	}
+
+	tos := rows[0][1:]
+	for _, to := range tos {
+		if err := validatePodName(to); err != nil {
+			return nil, nil, errors.WithMessagef(err, "truth table destinations")
+		}
+	}
+	var allowed, denied []*ExpectedFlow
+	for _, row := range rows[1:] {
+		from := row[0]
+		if err := validatePodName(from); err != nil {
+			return nil, nil, errors.WithMessagef(err, "truth table sources")
+		}
+		if len(row)-1 != len(tos) {
+			return nil, nil, errors.Errorf("truth table row %s has %d values, expected %d", from, len(row)-1, len(tos))
+		}
+		for i, value := range row[1:] {
+			flow := &ExpectedFlow{From: from, To: tos[i]}
+			switch value {
+			case expectedAllowed:
+				allowed = append(allowed, flow)
+			case expectedBlocked:
+				denied = append(denied, flow)
+			case expectedNotChecked:
+			default:
+				return nil, nil, errors.Errorf("invalid truth table value '%s' for %s -> %s: expected one of '%s', '%s', '%s'",
+					value, from, tos[i], expectedAllowed, expectedBlocked, expectedNotChecked)
+			}
+		}
+	}
+	return allowed, denied, nil
+}
@@ -10,5 +10,6 @@ import (
 func TestGenerator(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunTestCaseGeneratorTests()
+	RunTestCaseFileTests()
 	RunSpecs(t, "generator suite")
 }
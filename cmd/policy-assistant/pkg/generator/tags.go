@@ -1,6 +1,8 @@
 package generator
 
 import (
+	"encoding/json"
+
 	"github.com/mattfenwick/collections/pkg/slice"
 	"github.com/pkg/errors"
 	"golang.org/x/exp/maps"
@@ -19,6 +21,7 @@ const (
 	TagPeerIPBlock   = "peer-ipblock"
 	TagPeerPods      = "peer-pods"
 	TagMiscellaneous = "miscellaneous"
+	TagANP           = "anp"
 )
 
 const (
@@ -31,6 +34,11 @@ const (
 	TagCreateNamespace    = "create-namespace"
 	TagDeleteNamespace    = "delete-namespace"
 	TagSetNamespaceLabels = "set-namespace-labels"
+	TagCreateANP          = "create-anp"
+	TagUpdateANP          = "update-anp"
+	TagDeleteANP          = "delete-anp"
+	TagCreateBANP         = "create-banp"
+	TagDeleteBANP         = "delete-banp"
 )
 
 const (
@@ -84,6 +92,16 @@ const (
 	TagConflict     = "conflict"
 	TagExample      = "example"
 	TagUpstreamE2E  = "upstream-e2e"
+	TagFuzz         = "fuzz"
+)
+
+const (
+	TagANPPriority       = "anp-priority"
+	TagANPPass           = "anp-pass"
+	TagANPNetpolConflict = "anp-netpol-conflict"
+	TagBANPDefault       = "banp-default"
+	TagANPPortRange      = "anp-port-range"
+	TagANPNamedPort      = "anp-named-port"
 )
 
 var AllTags = map[string][]string{
@@ -97,6 +115,11 @@ var AllTags = map[string][]string{
 		TagCreateNamespace,
 		TagDeleteNamespace,
 		TagSetNamespaceLabels,
+		TagCreateANP,
+		TagUpdateANP,
+		TagDeleteANP,
+		TagCreateBANP,
+		TagDeleteBANP,
 	},
 	TagTarget: {
 		TagTargetNamespace,
@@ -143,6 +166,15 @@ var AllTags = map[string][]string{
 		TagConflict,
 		TagExample,
 		TagUpstreamE2E,
+		TagFuzz,
+	},
+	TagANP: {
+		TagANPPriority,
+		TagANPPass,
+		TagANPNetpolConflict,
+		TagBANPDefault,
+		TagANPPortRange,
+		TagANPNamedPort,
 	},
 }
 
@@ -210,6 +242,31 @@ func NewStringSet(elems ...string) StringSet {
 	return dict
 }
 
+// MarshalJSON writes the set as a sorted list.
+func (s StringSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Keys())
+}
+
+// UnmarshalJSON reads a list of tags, adding their primary tags.
+func (s *StringSet) UnmarshalJSON(bs []byte) error {
+	var tags []string
+	if err := json.Unmarshal(bs, &tags); err != nil {
+		return errors.Wrapf(err, "unable to unmarshal tags")
+	}
+	if err := ValidateTags(tags); err != nil {
+		return err
+	}
+	*s = NewStringSet()
+	for _, tag := range tags {
+		if _, ok := AllTags[tag]; ok {
+			(*s)[tag] = true
+		} else {
+			s.Add(tag)
+		}
+	}
+	return nil
+}
+
 func (s StringSet) Add(key string) {
 	s[key] = true
 	s[MustGetPrimaryTag(key)] = true
@@ -10,9 +10,9 @@ import (
 )
 
 type TestCase struct {
-	Description string
-	Tags        StringSet
-	Steps       []*TestStep
+	Description string      `json:"description"`
+	Tags        StringSet   `json:"tags,omitempty"`
+	Steps       []*TestStep `json:"steps"`
 }
 
 func NewSingleStepTestCase(description string, tags StringSet, pp *ProbeConfig, actions ...*Action) *TestCase {
@@ -49,6 +49,18 @@ func (t *TestCase) collectActionsAndPolicies() (map[string]bool, []*networkingv1
 				policies = append(policies, action.UpdatePolicy.Policy)
 			} else if action.DeletePolicy != nil {
 				features[ActionFeatureDeletePolicy] = true
+			} else if action.CreateAdminNetworkPolicy != nil {
+				features[ActionFeatureCreateANP] = true
+			} else if action.UpdateAdminNetworkPolicy != nil {
+				features[ActionFeatureUpdateANP] = true
+			} else if action.DeleteAdminNetworkPolicy != nil {
+				features[ActionFeatureDeleteANP] = true
+			} else if action.CreateBaselineAdminNetworkPolicy != nil {
+				features[ActionFeatureCreateBANP] = true
+			} else if action.UpdateBaselineAdminNetworkPolicy != nil {
+				features[ActionFeatureUpdateBANP] = true
+			} else if action.DeleteBaselineAdminNetworkPolicy != nil {
+				features[ActionFeatureDeleteBANP] = true
 			} else if action.CreateNamespace != nil {
 				features[ActionFeatureCreateNamespace] = true
 			} else if action.SetNamespaceLabels != nil {
@@ -138,9 +150,9 @@ func ParseProbeMode(mode string) (ProbeMode, error) {
 //
 //	models a discriminated union (sum type).
 type ProbeConfig struct {
-	AllAvailable bool
-	PortProtocol *PortProtocol
-	Mode         ProbeMode
+	AllAvailable bool          `json:"allAvailable,omitempty"`
+	PortProtocol *PortProtocol `json:"portProtocol,omitempty"`
+	Mode         ProbeMode     `json:"mode"`
 }
 
 func NewAllAvailable(mode ProbeMode) *ProbeConfig {
@@ -152,13 +164,15 @@ func NewProbeConfig(port intstr.IntOrString, protocol v1.Protocol, mode ProbeMod
 }
 
 type PortProtocol struct {
-	Protocol v1.Protocol
-	Port     intstr.IntOrString
+	Protocol v1.Protocol        `json:"protocol"`
+	Port     intstr.IntOrString `json:"port"`
 }
 
 type TestStep struct {
-	Probe   *ProbeConfig
-	Actions []*Action
+	Probe   *ProbeConfig `json:"probe"`
+	Actions []*Action    `json:"actions"`
+	// Expected is optional, hand-written expected connectivity
+	Expected *ExpectedConnectivity `json:"expected,omitempty"`
 }
 
 func NewTestStep(pp *ProbeConfig, actions ...*Action) *TestStep {
@@ -0,0 +1,169 @@
+package generator
+
+import (
+	"os"
+	"reflect"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"sigs.k8s.io/yaml"
+)
+
+// TestCaseFileVersion is the version of the test case file schema.  Fields may be added to it, but not changed or
+// removed, without a new version.
+const TestCaseFileVersion = "v1"
+
+// TestCaseFile is a yaml file of test cases, which may be written by hand or exported from the built-in test cases.
+type TestCaseFile struct {
+	Version   string      `json:"version"`
+	TestCases []*TestCase `json:"testCases"`
+}
+
+// ReadTestCasesFromPath reads and validates a test case file.
+func ReadTestCasesFromPath(path string) ([]*TestCase, error) {
+	file, err := utils.ParseYamlFromFileStrict[TestCaseFile](path)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "unable to read test cases from %s", path)
+	}
+	if file.Version != TestCaseFileVersion {
+		return nil, errors.Errorf("unsupported test case file version '%s' in %s: expected '%s'", file.Version, path, TestCaseFileVersion)
+	}
+	for i, testCase := range file.TestCases {
+		if err := ValidateTestCase(testCase); err != nil {
+			return nil, errors.WithMessagef(err, "invalid test case %d in %s", i+1, path)
+		}
+	}
+	return file.TestCases, nil
+}
+
+func WriteTestCasesToPath(path string, testCases []*TestCase) error {
+	bytes, err := yaml.Marshal(&TestCaseFile{Version: TestCaseFileVersion, TestCases: testCases})
+	if err != nil {
+		return errors.Wrapf(err, "unable to marshal test cases")
+	}
+	return errors.Wrapf(os.WriteFile(path, bytes, 0644), "unable to write %s", path)
+}
+
+// ValidateTestCase checks what the yaml schema can't: that unions have exactly one field set, and that expected
+// connectivity parses.
+func ValidateTestCase(testCase *TestCase) error {
+	if testCase.Description == "" {
+		return errors.Errorf("missing description")
+	}
+	if len(testCase.Steps) == 0 {
+		return errors.Errorf("test case '%s' has no steps", testCase.Description)
+	}
+	for i, step := range testCase.Steps {
+		if err := validateTestStep(step); err != nil {
+			return errors.WithMessagef(err, "test case '%s' step %d", testCase.Description, i+1)
+		}
+	}
+	return nil
+}
+
+func validateTestStep(step *TestStep) error {
+	if step.Probe == nil {
+		return errors.Errorf("missing probe")
+	}
+	if step.Probe.AllAvailable == (step.Probe.PortProtocol != nil) {
+		return errors.Errorf("probe must set exactly one of allAvailable and portProtocol")
+	}
+	if _, err := ParseProbeMode(string(step.Probe.Mode)); err != nil {
+		return err
+	}
+	for j, action := range step.Actions {
+		if count := countSetFields(action); count != 1 {
+			return errors.Errorf("action %d must set exactly one field, found %d", j+1, count)
+		}
+		if err := validateAction(action); err != nil {
+			return errors.WithMessagef(err, "action %d", j+1)
+		}
+	}
+	if step.Expected != nil {
+		if _, _, err := step.Expected.Flows(); err != nil {
+			return errors.WithMessagef(err, "invalid expected connectivity")
+		}
+	}
+	return nil
+}
+
+// validateAction checks that the set field of an action has what executing it needs: a policy, or the names of
+// what it acts on.
+func validateAction(action *Action) error {
+	required := func(fields map[string]string) error {
+		for _, name := range slice.Sort(maps.Keys(fields)) {
+			if fields[name] == "" {
+				return errors.Errorf("missing %s", name)
+			}
+		}
+		return nil
+	}
+	switch {
+	case action.CreatePolicy != nil:
+		if action.CreatePolicy.Policy == nil {
+			return errors.Errorf("createPolicy: missing policy")
+		}
+		return errors.WithMessagef(required(map[string]string{"namespace": action.CreatePolicy.Policy.Namespace, "name": action.CreatePolicy.Policy.Name}), "createPolicy")
+	case action.UpdatePolicy != nil:
+		if action.UpdatePolicy.Policy == nil {
+			return errors.Errorf("updatePolicy: missing policy")
+		}
+		return errors.WithMessagef(required(map[string]string{"namespace": action.UpdatePolicy.Policy.Namespace, "name": action.UpdatePolicy.Policy.Name}), "updatePolicy")
+	case action.DeletePolicy != nil:
+		return errors.WithMessagef(required(map[string]string{"namespace": action.DeletePolicy.Namespace, "name": action.DeletePolicy.Name}), "deletePolicy")
+	case action.CreateAdminNetworkPolicy != nil:
+		if action.CreateAdminNetworkPolicy.Policy == nil {
+			return errors.Errorf("createAdminNetworkPolicy: missing policy")
+		}
+		return errors.WithMessagef(required(map[string]string{"name": action.CreateAdminNetworkPolicy.Policy.Name}), "createAdminNetworkPolicy")
+	case action.UpdateAdminNetworkPolicy != nil:
+		if action.UpdateAdminNetworkPolicy.Policy == nil {
+			return errors.Errorf("updateAdminNetworkPolicy: missing policy")
+		}
+		return errors.WithMessagef(required(map[string]string{"name": action.UpdateAdminNetworkPolicy.Policy.Name}), "updateAdminNetworkPolicy")
+	case action.DeleteAdminNetworkPolicy != nil:
+		return errors.WithMessagef(required(map[string]string{"name": action.DeleteAdminNetworkPolicy.Name}), "deleteAdminNetworkPolicy")
+	case action.CreateBaselineAdminNetworkPolicy != nil:
+		if action.CreateBaselineAdminNetworkPolicy.Policy == nil {
+			return errors.Errorf("createBaselineAdminNetworkPolicy: missing policy")
+		}
+		return errors.WithMessagef(required(map[string]string{"name": action.CreateBaselineAdminNetworkPolicy.Policy.Name}), "createBaselineAdminNetworkPolicy")
+	case action.UpdateBaselineAdminNetworkPolicy != nil:
+		if action.UpdateBaselineAdminNetworkPolicy.Policy == nil {
+			return errors.Errorf("updateBaselineAdminNetworkPolicy: missing policy")
+		}
+		return errors.WithMessagef(required(map[string]string{"name": action.UpdateBaselineAdminNetworkPolicy.Policy.Name}), "updateBaselineAdminNetworkPolicy")
+	case action.DeleteBaselineAdminNetworkPolicy != nil:
+		return errors.WithMessagef(required(map[string]string{"name": action.DeleteBaselineAdminNetworkPolicy.Name}), "deleteBaselineAdminNetworkPolicy")
+	case action.CreateNamespace != nil:
+		return errors.WithMessagef(required(map[string]string{"namespace": action.CreateNamespace.Namespace}), "createNamespace")
+	case action.SetNamespaceLabels != nil:
+		return errors.WithMessagef(required(map[string]string{"namespace": action.SetNamespaceLabels.Namespace}), "setNamespaceLabels")
+	case action.DeleteNamespace != nil:
+		return errors.WithMessagef(required(map[string]string{"namespace": action.DeleteNamespace.Namespace}), "deleteNamespace")
+	case action.CreatePod != nil:
+		return errors.WithMessagef(required(map[string]string{"namespace": action.CreatePod.Namespace, "pod": action.CreatePod.Pod}), "createPod")
+	case action.SetPodLabels != nil:
+		return errors.WithMessagef(required(map[string]string{"namespace": action.SetPodLabels.Namespace, "pod": action.SetPodLabels.Pod}), "setPodLabels")
+	case action.DeletePod != nil:
+		return errors.WithMessagef(required(map[string]string{"namespace": action.DeletePod.Namespace, "pod": action.DeletePod.Pod}), "deletePod")
+	}
+	return nil
+}
+
+// countSetFields counts the non-nil fields of an Action
+func countSetFields(action *Action) int {
+	if action == nil {
+		return 0
+	}
+	count := 0
+	value := reflect.ValueOf(action).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if !value.Field(i).IsNil() {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,205 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+)
+
+const userTestCaseFile = `version: v1
+testCases:
+- description: deny ingress to x/a
+  tags:
+  - create-policy
+  steps:
+  - probe:
+      portProtocol:
+        port: 80
+        protocol: TCP
+      mode: pod-ip
+    actions:
+    - createPolicy:
+        policy:
+          metadata:
+            name: deny-ingress
+            namespace: x
+          spec:
+            podSelector:
+              matchLabels:
+                pod: a
+            policyTypes:
+            - Ingress
+    expected:
+      truthTable: |
+        -    x/a  x/b
+        x/a  #    .
+        x/b  X    #
+- description: allow x/a to x/b on port 80
+  steps:
+  - probe:
+      allAvailable: true
+      mode: pod-ip
+    actions: []
+    expected:
+      allowed:
+      - from: x/a
+        to: x/b
+        port: 80
+        protocol: TCP
+      denied:
+      - from: x/a
+        to: x/b
+        port: serve-81-udp
+`
+
+func writeTestCaseFile(contents string) string {
+	path := filepath.Join(GinkgoT().TempDir(), "test-cases.yaml")
+	Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+	return path
+}
+
+func RunTestCaseFileTests() {
+	Describe("TestCaseFile", func() {
+		It("Writes and reads the built-in test cases", func() {
+			gen := NewTestCaseGenerator(true, "1.2.3.4", []string{"x", "y", "z"}, []string{}, []string{})
+			testCases := gen.GenerateAllTestCases()
+			path := filepath.Join(GinkgoT().TempDir(), "test-cases.yaml")
+			Expect(WriteTestCasesToPath(path, testCases)).To(Succeed())
+
+			read, err := ReadTestCasesFromPath(path)
+			Expect(err).To(BeNil())
+			Expect(read).To(HaveLen(len(testCases)))
+			Expect(utils.YamlString(read)).To(Equal(utils.YamlString(testCases)))
+			Expect(read[0].Tags.Keys()).To(Equal(testCases[0].Tags.Keys()))
+		})
+
+		It("Reads a hand-written test case", func() {
+			testCases, err := ReadTestCasesFromPath(writeTestCaseFile(userTestCaseFile))
+			Expect(err).To(BeNil())
+			Expect(testCases).To(HaveLen(2))
+			Expect(testCases[0].Tags.Keys()).To(ConsistOf(TagAction, TagCreatePolicy))
+
+			step := testCases[0].Steps[0]
+			Expect(step.Probe.PortProtocol.Port.IntValue()).To(Equal(80))
+			Expect(step.Actions[0].CreatePolicy.Policy.Name).To(Equal("deny-ingress"))
+
+			allowed, blocked, err := step.Expected.Flows()
+			Expect(err).To(BeNil())
+			Expect(allowed).To(Equal([]*ExpectedFlow{{From: "x/a", To: "x/b"}}))
+			Expect(blocked).To(Equal([]*ExpectedFlow{{From: "x/b", To: "x/a"}}))
+
+			allowed, denied, err := testCases[1].Steps[0].Expected.Flows()
+			Expect(err).To(BeNil())
+			Expect(allowed).To(HaveLen(1))
+			Expect(allowed[0].Matches(80, "serve-80-tcp", v1.ProtocolTCP)).To(BeTrue())
+			Expect(allowed[0].Matches(80, "serve-80-udp", v1.ProtocolUDP)).To(BeFalse())
+			Expect(denied).To(HaveLen(1))
+			Expect(denied[0].Matches(81, "serve-81-udp", v1.ProtocolUDP)).To(BeTrue())
+			Expect(denied[0].Matches(81, "serve-81-tcp", v1.ProtocolTCP)).To(BeFalse())
+		})
+
+		It("Rejects an action without its policy", func() {
+			_, err := ReadTestCasesFromPath(writeTestCaseFile(`version: v1
+testCases:
+- description: empty create policy
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: [{createPolicy: {}}]`))
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("action 1: createPolicy: missing policy"))
+		})
+
+		It("Rejects invalid test case files", func() {
+			for _, contents := range []string{
+				`version: v2
+testCases: []`,
+				`version: v1
+testCases:
+- description: unknown field
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: []
+    unknown: true`,
+				`version: v1
+testCases:
+- description: unknown tag
+  tags: [not-a-tag]
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: []`,
+				`version: v1
+testCases:
+- description: two probes
+  steps:
+  - probe: {allAvailable: true, portProtocol: {port: 80, protocol: TCP}, mode: pod-ip}
+    actions: []`,
+				`version: v1
+testCases:
+- description: empty action
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: [{}]`,
+				`version: v1
+testCases:
+- description: create policy without a policy
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: [{createPolicy: {}}]`,
+				`version: v1
+testCases:
+- description: update ANP without a policy
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: [{updateAdminNetworkPolicy: {}}]`,
+				`version: v1
+testCases:
+- description: create BANP without a name
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: [{createBaselineAdminNetworkPolicy: {policy: {spec: {subject: {namespaces: {}}}}}}]`,
+				`version: v1
+testCases:
+- description: delete policy without a name
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: [{deletePolicy: {namespace: x}}]`,
+				`version: v1
+testCases:
+- description: bad truth table
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: []
+    expected:
+      truthTable: |
+        -    x/a
+        x/a  Y`,
+				`version: v1
+testCases:
+- description: truth table and flows
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: []
+    expected:
+      truthTable: |
+        -    x/a
+        x/a  .
+      allowed: [{from: x/a, to: x/b}]`,
+				`version: v1
+testCases:
+- description: bad flow
+  steps:
+  - probe: {allAvailable: true, mode: pod-ip}
+    actions: []
+    expected:
+      denied: [{from: a, to: x/b}]`,
+			} {
+				_, err := ReadTestCasesFromPath(writeTestCaseFile(contents))
+				Expect(err).ToNot(BeNil(), contents)
+			}
+		})
+	})
+}
@@ -72,13 +72,30 @@ func (t *TestCaseGenerator) GenerateAllTestCases() []*TestCase {
 		t.ActionTestCases(),
 		t.ConflictTestCases(),
 		t.NamespaceTestCases(),
-		t.UpstreamE2ETestCases())
+		t.UpstreamE2ETestCases(),
+		t.AdminNetworkPolicyTestCases())
 }
 
+// GenerateTestCases returns the test cases with any of the tags, and none of the excluded tags.  Excluded tags
+// which are included, or are the primary tags of included tags, are ignored, so that tags excluded by default
+// can be selected.
 func (t *TestCaseGenerator) GenerateTestCases() []*TestCase {
+	included := NewStringSet()
+	for _, tag := range t.Tags {
+		included[tag] = true
+		if primary, ok := TagSubToPrimary[tag]; ok {
+			included[primary] = true
+		}
+	}
+	var excluded []string
+	for _, tag := range t.ExcludedTags {
+		if !included[tag] {
+			excluded = append(excluded, tag)
+		}
+	}
 	var cases []*TestCase
 	for _, testcase := range t.GenerateAllTestCases() {
-		if (len(t.Tags) == 0 || testcase.Tags.ContainsAny(t.Tags)) && !testcase.Tags.ContainsAny(t.ExcludedTags) {
+		if (len(t.Tags) == 0 || testcase.Tags.ContainsAny(t.Tags)) && !testcase.Tags.ContainsAny(excluded) {
 			cases = append(cases, testcase)
 		}
 	}
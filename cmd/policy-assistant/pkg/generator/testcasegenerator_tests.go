@@ -1,8 +1,10 @@
 package generator
 
 import (
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 )
 
 func RunTestCaseGeneratorTests() {
@@ -19,8 +21,40 @@ func RunTestCaseGeneratorTests() {
 			Expect(len(gen.PortProtocolTestCases())).To(Equal(70))
 			Expect(len(gen.ConflictTestCases())).To(Equal(16))
 			Expect(len(gen.NamespaceTestCases())).To(Equal(2))
+			Expect(len(gen.AdminNetworkPolicyTestCases())).To(Equal(14))
 
+			Expect(len(gen.GenerateTestCases())).To(Equal(244))
+		})
+
+		It("Selects ANP test cases by tag, even if excluded", func() {
+			gen := NewTestCaseGenerator(true, "1.2.3.4", []string{"x", "y", "z"}, []string{TagANP}, []string{TagExample, TagANP})
+			Expect(len(gen.GenerateTestCases())).To(Equal(14))
+
+			gen = NewTestCaseGenerator(true, "1.2.3.4", []string{"x", "y", "z"}, []string{TagBANPDefault}, []string{TagANP})
+			Expect(len(gen.GenerateTestCases())).To(Equal(3))
+
+			gen = NewTestCaseGenerator(true, "1.2.3.4", []string{"x", "y", "z"}, []string{}, []string{TagANP})
 			Expect(len(gen.GenerateTestCases())).To(Equal(230))
 		})
+
+		It("Generates valid ANPs and BANPs", func() {
+			gen := NewTestCaseGenerator(true, "1.2.3.4", []string{"x", "y", "z"}, []string{}, []string{})
+			for _, testCase := range gen.AdminNetworkPolicyTestCases() {
+				var anps []*v1alpha1.AdminNetworkPolicy
+				var banp *v1alpha1.BaselineAdminNetworkPolicy
+				for _, step := range testCase.Steps {
+					for _, action := range step.Actions {
+						if action.CreateAdminNetworkPolicy != nil {
+							anps = append(anps, action.CreateAdminNetworkPolicy.Policy)
+						} else if action.CreateBaselineAdminNetworkPolicy != nil {
+							banp = action.CreateBaselineAdminNetworkPolicy.Policy
+						}
+					}
+				}
+				_, errs := matcher.BuildV1AndV2NetPols(false, nil, anps, banp)
+				Expect(errs).To(BeEmpty(), testCase.Description)
+			}
+		})
+
 	})
 }
@@ -2,4 +2,7 @@ package kube
 
 const (
 	DefaultNamespaceLabel = "kubernetes.io/metadata.name"
+	// ManagedLabel marks the cluster-scoped ANPs and BANPs which cyclonus creates, so that it only cleans up its own
+	ManagedLabel      = "cyclonus.io/managed"
+	ManagedLabelValue = "true"
 )
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"github.com/mattfenwick/cyclonus/pkg/utils"
 	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"math/rand"
 	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"slices"
 )
 
 type IKubernetes interface {
@@ -19,6 +21,9 @@ type IKubernetes interface {
 	DeleteNamespace(namespace string) error
 	GetAllNamespaces() (*v1.NamespaceList, error)
 
+	GetNode(name string) (*v1.Node, error)
+	GetAllNodes() ([]v1.Node, error)
+
 	CreateNetworkPolicy(kubePolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error)
 	GetNetworkPoliciesInNamespace(ctx context.Context, namespace string) ([]networkingv1.NetworkPolicy, error)
 	UpdateNetworkPolicy(kubePolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error)
@@ -46,6 +51,12 @@ type IKubernetes interface {
 	SetPodLabels(namespace string, pod string, labels map[string]string) (*v1.Pod, error)
 	GetPodsInNamespace(namespace string) ([]v1.Pod, error)
 
+	GetDeploymentsInNamespace(namespace string) ([]appsv1.Deployment, error)
+	GetDaemonSetsInNamespace(namespace string) ([]appsv1.DaemonSet, error)
+	GetStatefulSetsInNamespace(namespace string) ([]appsv1.StatefulSet, error)
+	GetReplicaSetsInNamespace(namespace string) ([]appsv1.ReplicaSet, error)
+	GetReplicaSet(namespace string, name string) (*appsv1.ReplicaSet, error)
+
 	ExecuteRemoteCommand(namespace string, pod string, container string, command []string) (string, string, error, error)
 }
 
@@ -112,6 +123,7 @@ type MockNamespace struct {
 
 type MockKubernetes struct {
 	AdminNetworkPolicies        []v1alpha1.AdminNetworkPolicy
+	Nodes                       map[string]*v1.Node
 	AdminNetworkPolicyError     error
 	BaselineNetworkPolicy       *v1alpha1.BaselineAdminNetworkPolicy
 	BaseAdminNetworkPolicyError error
@@ -124,6 +136,7 @@ type MockKubernetes struct {
 func NewMockKubernetes(passRate float64) *MockKubernetes {
 	return &MockKubernetes{
 		Namespaces: map[string]*MockNamespace{},
+		Nodes:      map[string]*v1.Node{},
 		passRate:   passRate,
 		podID:      1,
 	}
@@ -195,6 +208,31 @@ func (m *MockKubernetes) CreateNamespace(ns *v1.Namespace) (*v1.Namespace, error
 	return ns, nil
 }
 
+func (m *MockKubernetes) GetNode(name string) (*v1.Node, error) {
+	if node, ok := m.Nodes[name]; ok {
+		return node, nil
+	}
+	return nil, errors.Errorf("node %s not found", name)
+}
+
+func (m *MockKubernetes) GetAllNodes() ([]v1.Node, error) {
+	var nodes []v1.Node
+	for _, node := range m.Nodes {
+		nodes = append(nodes, *node)
+	}
+	return nodes, nil
+}
+
+// CreateNode adds a node to the mock.  Nodes aren't part of IKubernetes since
+// cyclonus never creates them in a real cluster.
+func (m *MockKubernetes) CreateNode(node *v1.Node) (*v1.Node, error) {
+	if _, ok := m.Nodes[node.Name]; ok {
+		return nil, errors.Errorf("node %s already present", node.Name)
+	}
+	m.Nodes[node.Name] = node
+	return node, nil
+}
+
 func (m *MockKubernetes) DeleteAllNetworkPoliciesInNamespace(ns string) error {
 	nsObject, err := m.getNamespaceObject(ns)
 	if err != nil {
@@ -347,6 +385,7 @@ func (m *MockKubernetes) CreatePod(pod *v1.Pod) (*v1.Pod, error) {
 		panic(errors.Errorf("unable to handle more than 254 pods in mock"))
 	}
 	pod.Status.Phase = v1.PodRunning
+	pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
 	pod.Status.PodIP = fmt.Sprintf("192.168.1.%d", m.podID)
 	m.podID++
 	nsObject.Pods[pod.Name] = pod
@@ -365,6 +404,32 @@ func (m *MockKubernetes) DeletePod(namespace string, podName string) error {
 	return nil
 }
 
+// The mock doesn't model workloads: all its pods are unowned.
+
+func (m *MockKubernetes) GetDeploymentsInNamespace(namespace string) ([]appsv1.Deployment, error) {
+	_, err := m.getNamespaceObject(namespace)
+	return nil, err
+}
+
+func (m *MockKubernetes) GetDaemonSetsInNamespace(namespace string) ([]appsv1.DaemonSet, error) {
+	_, err := m.getNamespaceObject(namespace)
+	return nil, err
+}
+
+func (m *MockKubernetes) GetStatefulSetsInNamespace(namespace string) ([]appsv1.StatefulSet, error) {
+	_, err := m.getNamespaceObject(namespace)
+	return nil, err
+}
+
+func (m *MockKubernetes) GetReplicaSetsInNamespace(namespace string) ([]appsv1.ReplicaSet, error) {
+	_, err := m.getNamespaceObject(namespace)
+	return nil, err
+}
+
+func (m *MockKubernetes) GetReplicaSet(namespace string, name string) (*appsv1.ReplicaSet, error) {
+	return nil, errors.Errorf("replicaSet %s/%s not found", namespace, name)
+}
+
 func (m *MockKubernetes) ExecuteRemoteCommand(namespace string, pod string, container string, command []string) (string, string, error, error) {
 	nsObject, err := m.getNamespaceObject(namespace)
 	if err != nil {
@@ -394,35 +459,63 @@ func (m *MockKubernetes) ExecuteRemoteCommand(namespace string, pod string, cont
 }
 
 func (m *MockKubernetes) GetAdminNetworkPolicies(ctx context.Context) ([]v1alpha1.AdminNetworkPolicy, error) {
-	return m.AdminNetworkPolicies, m.AdminNetworkPolicyError
+	return slices.Clone(m.AdminNetworkPolicies), m.AdminNetworkPolicyError
 }
 
-func (k *MockKubernetes) CreateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+func (m *MockKubernetes) CreateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
+	for _, anp := range m.AdminNetworkPolicies {
+		if anp.Name == policy.Name {
+			return nil, errors.Errorf("admin network policy %s already present", policy.Name)
+		}
+	}
+	m.AdminNetworkPolicies = append(m.AdminNetworkPolicies, *policy)
+	return policy, nil
 }
 
-func (k *MockKubernetes) UpdateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+func (m *MockKubernetes) UpdateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
+	for i, anp := range m.AdminNetworkPolicies {
+		if anp.Name == policy.Name {
+			m.AdminNetworkPolicies[i] = *policy
+			return policy, nil
+		}
+	}
+	return nil, errors.Errorf("admin network policy %s not found", policy.Name)
 }
 
-func (k *MockKubernetes) DeleteAdminNetworkPolicy(ctx context.Context, name string) error {
-	//TODO: implement
-	return ErrNotImplemented
+func (m *MockKubernetes) DeleteAdminNetworkPolicy(ctx context.Context, name string) error {
+	for i, anp := range m.AdminNetworkPolicies {
+		if anp.Name == name {
+			m.AdminNetworkPolicies = append(m.AdminNetworkPolicies[:i], m.AdminNetworkPolicies[i+1:]...)
+			return nil
+		}
+	}
+	return errors.Errorf("admin network policy %s not found", name)
 }
 
 func (m *MockKubernetes) GetBaselineAdminNetworkPolicy(ctx context.Context) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
 	return m.BaselineNetworkPolicy, m.BaseAdminNetworkPolicyError
 }
 
-func (k *MockKubernetes) CreateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+func (m *MockKubernetes) CreateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
+	if m.BaselineNetworkPolicy != nil {
+		return nil, errors.Errorf("baseline admin network policy %s already present", m.BaselineNetworkPolicy.Name)
+	}
+	m.BaselineNetworkPolicy = policy
+	return policy, nil
 }
 
-func (k *MockKubernetes) UpdateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+func (m *MockKubernetes) UpdateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
+	if m.BaselineNetworkPolicy == nil || m.BaselineNetworkPolicy.Name != policy.Name {
+		return nil, errors.Errorf("baseline admin network policy %s not found", policy.Name)
+	}
+	m.BaselineNetworkPolicy = policy
+	return policy, nil
 }
 
-func (k *MockKubernetes) DeleteBaselineAdminNetworkPolicy(ctx context.Context, name string) error {
-	//TODO: implement
-	return ErrNotImplemented
+func (m *MockKubernetes) DeleteBaselineAdminNetworkPolicy(ctx context.Context, name string) error {
+	if m.BaselineNetworkPolicy == nil || m.BaselineNetworkPolicy.Name != name {
+		return errors.Errorf("baseline admin network policy %s not found", name)
+	}
+	m.BaselineNetworkPolicy = nil
+	return nil
 }
@@ -1,24 +1,69 @@
 package kube
 
 import (
-	"fmt"
+	"net"
+	"net/netip"
+
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
-	"net"
 )
 
+// IsIPInCIDR returns true if the IP is in the CIDR.  An IP is never in a CIDR of the other family: in particular,
+// IPv4-mapped IPv6 addresses such as ::ffff:1.2.3.4 are IPv6, and are only in IPv6 CIDRs such as ::ffff:0:0/96.
 func IsIPInCIDR(ip string, cidr string) (bool, error) {
-	_, cidrNet, err := net.ParseCIDR(cidr)
+	prefix, err := netip.ParsePrefix(cidr)
 	if err != nil {
 		return false, errors.Wrapf(err, "unable to parse CIDR '%s'", cidr)
 	}
-	trafficIP := net.ParseIP(ip)
-	if trafficIP == nil {
-		return false, errors.Errorf("unable to parse IP '%s'", ip)
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to parse IP '%s'", ip)
+	}
+	return prefix.Contains(addr), nil
+}
+
+// IPFamily returns the family of an IP address.  IPv4-mapped IPv6 addresses are IPv6.
+func IPFamily(ip string) (v1.IPFamily, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to parse IP '%s'", ip)
+	}
+	if addr.Is4() {
+		return v1.IPv4Protocol, nil
 	}
-	return cidrNet.Contains(trafficIP), nil
+	return v1.IPv6Protocol, nil
 }
 
+// CIDRFamily returns the family of a CIDR.
+func CIDRFamily(cidr string) (v1.IPFamily, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to parse CIDR '%s'", cidr)
+	}
+	if prefix.Addr().Is4() {
+		return v1.IPv4Protocol, nil
+	}
+	return v1.IPv6Protocol, nil
+}
+
+// IsCIDRSubset returns true if every IP in sub is also in super.
+func IsCIDRSubset(sub string, super string) (bool, error) {
+	_, subNet, err := net.ParseCIDR(sub)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to parse CIDR '%s'", sub)
+	}
+	_, superNet, err := net.ParseCIDR(super)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to parse CIDR '%s'", super)
+	}
+	subOnes, subBits := subNet.Mask.Size()
+	superOnes, superBits := superNet.Mask.Size()
+	return subBits == superBits && superOnes <= subOnes && superNet.Contains(subNet.IP), nil
+}
+
+// IsIPAddressMatchForIPBlock returns true if the IP is in the block's CIDR, but not in any of its excepts.
+// Excepts must be of the same family as the CIDR.
 func IsIPAddressMatchForIPBlock(ip string, ipBlock *networkingv1.IPBlock) (bool, error) {
 	isInCidr, err := IsIPInCIDR(ip, ipBlock.CIDR)
 	if err != nil {
@@ -27,7 +72,18 @@ func IsIPAddressMatchForIPBlock(ip string, ipBlock *networkingv1.IPBlock) (bool,
 	if !isInCidr {
 		return false, nil
 	}
+	family, err := CIDRFamily(ipBlock.CIDR)
+	if err != nil {
+		return false, err
+	}
 	for _, except := range ipBlock.Except {
+		exceptFamily, err := CIDRFamily(except)
+		if err != nil {
+			return false, err
+		}
+		if exceptFamily != family {
+			return false, errors.Errorf("except '%s' is not of the same IP family as CIDR '%s'", except, ipBlock.CIDR)
+		}
 		isInExcept, err := IsIPInCIDR(ip, except)
 		if err != nil {
 			return false, err
@@ -39,34 +95,73 @@ func IsIPAddressMatchForIPBlock(ip string, ipBlock *networkingv1.IPBlock) (bool,
 	return true, nil
 }
 
+// IsIPV4Address returns true for IPv4 addresses, and false for IPv6 addresses, including IPv4-mapped ones.
+// It panics if the address can't be parsed.
 func IsIPV4Address(s string) bool {
-	for i := 0; i < len(s); i++ {
-		switch s[i] {
-		case '.':
-			return true
-		case ':':
-			return false
-		}
+	family, err := IPFamily(s)
+	if err != nil {
+		panic(errors.Wrapf(err, "address %s is neither IPv4 nor IPv6", s))
 	}
-	panic(errors.Errorf("address %s is neither IPv4 nor IPv6", s))
+	return family == v1.IPv4Protocol
 }
 
 func MakeCIDRFromZeroes(ipString string, zeroes int) string {
 	if IsIPV4Address(ipString) {
-		return makeCidr(ipString, 32-zeroes, 32)
+		return makeCidr(ipString, 32-zeroes)
 	}
-	return makeCidr(ipString, 128-zeroes, 128)
+	return makeCidr(ipString, 128-zeroes)
 }
 
 func MakeCIDRFromOnes(ipString string, ones int) string {
-	if IsIPV4Address(ipString) {
-		return makeCidr(ipString, ones, 32)
+	return makeCidr(ipString, ones)
+}
+
+func makeCidr(ipString string, ones int) string {
+	addr, err := netip.ParseAddr(ipString)
+	if err != nil {
+		panic(errors.Wrapf(err, "unable to parse IP '%s'", ipString))
+	}
+	prefix, err := addr.Prefix(ones)
+	if err != nil {
+		panic(errors.Wrapf(err, "unable to make /%d CIDR from IP '%s'", ones, ipString))
 	}
-	return makeCidr(ipString, ones, 128)
+	return prefix.String()
 }
 
-func makeCidr(ipString string, ones int, bits int) string {
-	mask := net.CIDRMask(ones, bits)
-	ip := net.ParseIP(ipString)
-	return fmt.Sprintf("%s/%d", ip.Mask(mask).String(), ones)
+// PodIPs returns the IPs of a pod, one per family on dual-stack clusters.  The first is the pod's primary IP.
+func PodIPs(pod *v1.Pod) []string {
+	var ips []string
+	for _, podIP := range pod.Status.PodIPs {
+		ips = append(ips, podIP.IP)
+	}
+	if len(ips) == 0 && pod.Status.PodIP != "" {
+		ips = append(ips, pod.Status.PodIP)
+	}
+	return ips
+}
+
+// ServiceClusterIPs returns the cluster IPs of a service, one per family on dual-stack clusters.  The first is the
+// service's primary cluster IP.  Headless services have none.
+func ServiceClusterIPs(service *v1.Service) []string {
+	ips := service.Spec.ClusterIPs
+	if len(ips) == 0 && service.Spec.ClusterIP != "" {
+		ips = []string{service.Spec.ClusterIP}
+	}
+	var clusterIPs []string
+	for _, ip := range ips {
+		if ip != v1.ClusterIPNone {
+			clusterIPs = append(clusterIPs, ip)
+		}
+	}
+	return clusterIPs
+}
+
+// IPForFamily returns the first IP of the family, or "" if there isn't one.  Unparseable IPs are skipped.
+func IPForFamily(ips []string, family v1.IPFamily) string {
+	for _, ip := range ips {
+		if ipFamily, err := IPFamily(ip); err == nil && ipFamily == family {
+			return ip
+		}
+	}
+	return ""
 }
@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/networking/v1"
 )
 
@@ -49,6 +50,26 @@ func RunIPAddressTests() {
 			}
 		})
 
+		It("Determines whether a CIDR is contained in another CIDR", func() {
+			testCases := []struct {
+				Sub      string
+				Super    string
+				IsSubset bool
+			}{
+				{Sub: "10.1.0.0/16", Super: "10.0.0.0/8", IsSubset: true},
+				{Sub: "10.0.0.0/8", Super: "10.0.0.0/8", IsSubset: true},
+				{Sub: "10.0.0.0/8", Super: "10.1.0.0/16", IsSubset: false},
+				{Sub: "11.0.0.0/16", Super: "10.0.0.0/8", IsSubset: false},
+				{Sub: "fd00:10::/64", Super: "fd00::/8", IsSubset: true},
+				{Sub: "10.0.0.0/8", Super: "::/0", IsSubset: false},
+			}
+			for _, c := range testCases {
+				isSubset, err := IsCIDRSubset(c.Sub, c.Super)
+				Expect(err).To(BeNil())
+				Expect(isSubset).To(Equal(c.IsSubset), "%s in %s", c.Sub, c.Super)
+			}
+		})
+
 		It("Determines whether an IPv6 address is in a CIDR", func() {
 			testCases := []*ipCidrTestCase{
 				{
@@ -91,9 +112,69 @@ func RunIPAddressTests() {
 			}
 		})
 
-		It("Determines whether an IPv4-mapped IPv6 address id in a CIDR", func() {
-			// TODO
-			// ::ffff:192.0.2.1
+		It("Determines whether an IPv4-mapped IPv6 address is in a CIDR", func() {
+			testCases := []*ipCidrTestCase{
+				{IP: "::ffff:192.0.2.1", CIDR: "::ffff:0:0/96", IsMember: true},
+				{IP: "::ffff:192.0.2.1", CIDR: "::ffff:192.0.2.0/120", IsMember: true},
+				{IP: "::ffff:192.0.2.1", CIDR: "192.0.2.0/24", IsMember: false},
+				{IP: "::ffff:192.0.2.1", CIDR: "0.0.0.0/0", IsMember: false},
+				{IP: "::ffff:192.0.2.1", CIDR: "::/0", IsMember: true},
+			}
+			for _, c := range testCases {
+				isInCidr, err := IsIPInCIDR(c.IP, c.CIDR)
+				Expect(err).To(BeNil())
+				Expect(isInCidr).To(Equal(c.IsMember), "%s in %s", c.IP, c.CIDR)
+			}
+		})
+
+		It("Never puts an IP in a CIDR of the other family", func() {
+			testCases := []*ipCidrTestCase{
+				{IP: "1.2.3.4", CIDR: "::/0", IsMember: false},
+				{IP: "1.2.3.4", CIDR: "0.0.0.0/0", IsMember: true},
+				{IP: "fd00::1", CIDR: "0.0.0.0/0", IsMember: false},
+				{IP: "fd00::1", CIDR: "::/0", IsMember: true},
+			}
+			for _, c := range testCases {
+				isInCidr, err := IsIPInCIDR(c.IP, c.CIDR)
+				Expect(err).To(BeNil())
+				Expect(isInCidr).To(Equal(c.IsMember), "%s in %s", c.IP, c.CIDR)
+			}
+		})
+
+		It("Determines the family of IPs and CIDRs", func() {
+			Expect(IPFamily("1.2.3.4")).To(Equal(corev1.IPv4Protocol))
+			Expect(IPFamily("fd00:10:244::5")).To(Equal(corev1.IPv6Protocol))
+			Expect(IPFamily("::ffff:1.2.3.4")).To(Equal(corev1.IPv6Protocol))
+			_, err := IPFamily("TODO")
+			Expect(err).ToNot(BeNil())
+
+			Expect(CIDRFamily("10.0.0.0/8")).To(Equal(corev1.IPv4Protocol))
+			Expect(CIDRFamily("fd00::/8")).To(Equal(corev1.IPv6Protocol))
+
+			Expect(IsIPV4Address("1.2.3.4")).To(BeTrue())
+			Expect(IsIPV4Address("2001:db8::1.2.3.4")).To(BeFalse())
+			Expect(func() { IsIPV4Address("abc") }).To(Panic())
+		})
+
+		It("Reads the IPs of dual-stack pods and services", func() {
+			pod := &corev1.Pod{Status: corev1.PodStatus{
+				PodIP:  "10.244.0.5",
+				PodIPs: []corev1.PodIP{{IP: "10.244.0.5"}, {IP: "fd00:10:244::5"}},
+			}}
+			Expect(PodIPs(pod)).To(Equal([]string{"10.244.0.5", "fd00:10:244::5"}))
+			Expect(PodIPs(&corev1.Pod{Status: corev1.PodStatus{PodIP: "10.244.0.6"}})).To(Equal([]string{"10.244.0.6"}))
+			Expect(PodIPs(&corev1.Pod{})).To(BeEmpty())
+
+			service := &corev1.Service{Spec: corev1.ServiceSpec{
+				ClusterIP:  "fd00:10:96::a",
+				ClusterIPs: []string{"fd00:10:96::a", "10.96.0.10"},
+			}}
+			Expect(ServiceClusterIPs(service)).To(Equal([]string{"fd00:10:96::a", "10.96.0.10"}))
+			Expect(ServiceClusterIPs(&corev1.Service{Spec: corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone}})).To(BeEmpty())
+
+			Expect(IPForFamily(ServiceClusterIPs(service), corev1.IPv4Protocol)).To(Equal("10.96.0.10"))
+			Expect(IPForFamily(ServiceClusterIPs(service), corev1.IPv6Protocol)).To(Equal("fd00:10:96::a"))
+			Expect(IPForFamily([]string{"TODO"}, corev1.IPv4Protocol)).To(Equal(""))
 		})
 
 		It("reports an error for malformed IP addresses and CIDRs", func() {
@@ -199,6 +280,36 @@ func RunIPAddressTests() {
 		})
 	})
 
+	Describe("IPv6 IPBlocks", func() {
+		It("Handles IPv6 IPBlocks with exceptions", func() {
+			ipBlock := &v1.IPBlock{
+				CIDR:   "fd00:10:244::/48",
+				Except: []string{"fd00:10:244:1::/64", "fd00:10:244:2::/64"},
+			}
+			testCases := []struct {
+				IP      string
+				IsMatch bool
+			}{
+				{IP: "fd00:10:244::5", IsMatch: true},
+				{IP: "fd00:10:244:1::5", IsMatch: false},
+				{IP: "fd00:10:244:2:ffff::5", IsMatch: false},
+				{IP: "fd00:10:244:3::5", IsMatch: true},
+				{IP: "fd00:10:245::5", IsMatch: false},
+				{IP: "10.244.1.5", IsMatch: false},
+			}
+			for _, c := range testCases {
+				isMatch, err := IsIPAddressMatchForIPBlock(c.IP, ipBlock)
+				Expect(err).To(BeNil())
+				Expect(isMatch).To(Equal(c.IsMatch), c.IP)
+			}
+		})
+
+		It("Reports an error for excepts of the other family", func() {
+			_, err := IsIPAddressMatchForIPBlock("fd00::1", &v1.IPBlock{CIDR: "::/0", Except: []string{"10.0.0.0/8"}})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
 	Describe("Make CIDR from IPAddress", func() {
 		It("should build normalized IPV4 CIDRs correctly", func() {
 			testCases := []struct {
@@ -317,5 +428,11 @@ func RunIPAddressTests() {
 				Expect(actual).To(Equal(tc.Expected))
 			}
 		})
+
+		It("should build IPV6 CIDRs from zeroes, and keep IPv4-mapped addresses IPv6", func() {
+			Expect(MakeCIDRFromZeroes("fd00:10:244:a8:96fd:be93:52d8:6b85", 8)).To(Equal("fd00:10:244:a8:96fd:be93:52d8:6b00/120"))
+			Expect(MakeCIDRFromZeroes("fd00:10:244:a8:96fd:be93:52d8:6b85", 4)).To(Equal("fd00:10:244:a8:96fd:be93:52d8:6b80/124"))
+			Expect(MakeCIDRFromZeroes("::ffff:192.0.2.1", 8)).To(Equal("::ffff:192.0.2.0/120"))
+		})
 	})
 }
@@ -25,7 +25,7 @@ var ErrNotImplemented = errors.New("Not implemented")
 
 type Kubernetes struct {
 	ClientSet      *kubernetes.Clientset
-	alphaClientSet *v1alpha1.PolicyV1alpha1Client
+	alphaClientSet v1alpha1.PolicyV1alpha1Interface
 	RestConfig     *rest.Config
 }
 
@@ -63,6 +63,19 @@ func (k *Kubernetes) GetAllNamespaces() (*v1.NamespaceList, error) {
 	return nsList, errors.Wrapf(err, "unable to list namespaces")
 }
 
+func (k *Kubernetes) GetNode(name string) (*v1.Node, error) {
+	node, err := k.ClientSet.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+	return node, errors.Wrapf(err, "unable to get node %s", name)
+}
+
+func (k *Kubernetes) GetAllNodes() ([]v1.Node, error) {
+	nodeList, err := k.ClientSet.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list nodes")
+	}
+	return nodeList.Items, nil
+}
+
 func (k *Kubernetes) SetNamespaceLabels(namespace string, labels map[string]string) (*v1.Namespace, error) {
 	ns, err := k.GetNamespace(namespace)
 	if err != nil {
@@ -121,16 +134,28 @@ func (k *Kubernetes) GetAdminNetworkPolicies(ctx context.Context) ([]v1alpha12.A
 }
 
 func (k *Kubernetes) CreateAdminNetworkPolicy(ctx context.Context, policy *v1alpha12.AdminNetworkPolicy) (*v1alpha12.AdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+	logrus.Debugf("creating admin network policy %s", policy.Name)
+	createdPolicy, err := k.alphaClientSet.AdminNetworkPolicies().Create(ctx, policy, metav1.CreateOptions{})
+	return createdPolicy, errors.Wrapf(err, "unable to create admin network policy %s", policy.Name)
 }
 
+// UpdateAdminNetworkPolicy replaces an ANP.  ANPs are CRDs, which don't allow unconditional updates, so it updates
+// the current resource version.
 func (k *Kubernetes) UpdateAdminNetworkPolicy(ctx context.Context, policy *v1alpha12.AdminNetworkPolicy) (*v1alpha12.AdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+	logrus.Debugf("updating admin network policy %s", policy.Name)
+	current, err := k.alphaClientSet.AdminNetworkPolicies().Get(ctx, policy.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get admin network policy %s", policy.Name)
+	}
+	policy = policy.DeepCopy()
+	policy.ResourceVersion = current.ResourceVersion
+	updatedPolicy, err := k.alphaClientSet.AdminNetworkPolicies().Update(ctx, policy, metav1.UpdateOptions{})
+	return updatedPolicy, errors.Wrapf(err, "unable to update admin network policy %s", policy.Name)
 }
 
 func (k *Kubernetes) DeleteAdminNetworkPolicy(ctx context.Context, name string) error {
-	//TODO: implement
-	return ErrNotImplemented
+	logrus.Debugf("deleting admin network policy %s", name)
+	return errors.Wrapf(k.alphaClientSet.AdminNetworkPolicies().Delete(ctx, name, metav1.DeleteOptions{}), "unable to delete admin network policy %s", name)
 }
 
 func (k *Kubernetes) GetBaselineAdminNetworkPolicy(ctx context.Context) (*v1alpha12.BaselineAdminNetworkPolicy, error) {
@@ -146,16 +171,27 @@ func (k *Kubernetes) GetBaselineAdminNetworkPolicy(ctx context.Context) (*v1alph
 }
 
 func (k *Kubernetes) CreateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha12.BaselineAdminNetworkPolicy) (*v1alpha12.BaselineAdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+	logrus.Debugf("creating baseline admin network policy %s", policy.Name)
+	createdPolicy, err := k.alphaClientSet.BaselineAdminNetworkPolicies().Create(ctx, policy, metav1.CreateOptions{})
+	return createdPolicy, errors.Wrapf(err, "unable to create baseline admin network policy %s", policy.Name)
 }
 
+// UpdateBaselineAdminNetworkPolicy replaces the BANP, at its current resource version like UpdateAdminNetworkPolicy.
 func (k *Kubernetes) UpdateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha12.BaselineAdminNetworkPolicy) (*v1alpha12.BaselineAdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+	logrus.Debugf("updating baseline admin network policy %s", policy.Name)
+	current, err := k.alphaClientSet.BaselineAdminNetworkPolicies().Get(ctx, policy.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get baseline admin network policy %s", policy.Name)
+	}
+	policy = policy.DeepCopy()
+	policy.ResourceVersion = current.ResourceVersion
+	updatedPolicy, err := k.alphaClientSet.BaselineAdminNetworkPolicies().Update(ctx, policy, metav1.UpdateOptions{})
+	return updatedPolicy, errors.Wrapf(err, "unable to update baseline admin network policy %s", policy.Name)
 }
 
 func (k *Kubernetes) DeleteBaselineAdminNetworkPolicy(ctx context.Context, name string) error {
-	//TODO: implement
-	return ErrNotImplemented
+	logrus.Debugf("deleting baseline admin network policy %s", name)
+	return errors.Wrapf(k.alphaClientSet.BaselineAdminNetworkPolicies().Delete(ctx, name, metav1.DeleteOptions{}), "unable to delete baseline admin network policy %s", name)
 }
 
 func (k *Kubernetes) UpdateNetworkPolicy(policy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
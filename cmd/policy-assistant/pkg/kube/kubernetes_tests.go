@@ -0,0 +1,57 @@
+package kube
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned/fake"
+)
+
+func RunKubernetesTests() {
+	Describe("Kubernetes admin network policy updates", func() {
+		// captures the objects sent to update, since the fake clientset doesn't require a resource version
+		newKubernetes := func(objects ...runtime.Object) (*Kubernetes, *[]runtime.Object) {
+			clientset := fake.NewSimpleClientset(objects...)
+			var updated []runtime.Object
+			clientset.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				updated = append(updated, action.(k8stesting.UpdateAction).GetObject())
+				return false, nil, nil
+			})
+			return &Kubernetes{alphaClientSet: clientset.PolicyV1alpha1()}, &updated
+		}
+
+		It("Updates an ANP at its current resource version", func() {
+			k, updated := newKubernetes(&v1alpha1.AdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "a", ResourceVersion: "7"}})
+			policy := &v1alpha1.AdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "a"}, Spec: v1alpha1.AdminNetworkPolicySpec{Priority: 5}}
+
+			_, err := k.UpdateAdminNetworkPolicy(context.TODO(), policy)
+			Expect(err).To(Succeed())
+			Expect(*updated).To(HaveLen(1))
+			Expect((*updated)[0].(*v1alpha1.AdminNetworkPolicy).ResourceVersion).To(Equal("7"))
+			Expect((*updated)[0].(*v1alpha1.AdminNetworkPolicy).Spec.Priority).To(Equal(int32(5)))
+			Expect(policy.ResourceVersion).To(BeEmpty())
+		})
+
+		It("Updates the BANP at its current resource version", func() {
+			k, updated := newKubernetes(&v1alpha1.BaselineAdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "default", ResourceVersion: "3"}})
+
+			_, err := k.UpdateBaselineAdminNetworkPolicy(context.TODO(), &v1alpha1.BaselineAdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+			Expect(err).To(Succeed())
+			Expect(*updated).To(HaveLen(1))
+			Expect((*updated)[0].(*v1alpha1.BaselineAdminNetworkPolicy).ResourceVersion).To(Equal("3"))
+		})
+
+		It("Fails to update an ANP which doesn't exist", func() {
+			k, updated := newKubernetes()
+
+			_, err := k.UpdateAdminNetworkPolicy(context.TODO(), &v1alpha1.AdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "a"}})
+			Expect(err).ToNot(Succeed())
+			Expect(*updated).To(BeEmpty())
+		})
+	})
+}
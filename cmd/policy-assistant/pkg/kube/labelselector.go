@@ -7,6 +7,7 @@ import (
 	"github.com/mattfenwick/collections/pkg/slice"
 	"github.com/mattfenwick/cyclonus/pkg/utils"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -122,3 +123,79 @@ func LabelSelectorTableLines(selector metav1.LabelSelector) string {
 	}
 	return strings.Join(lines, "\n")
 }
+
+// IsLabelSelectorSubset returns true if every set of labels matched by sub is also matched by super.
+// It is conservative: it returns true only if every requirement of super is implied by a requirement of sub,
+// so it may return false for selectors that are subsets in ways it can't prove.
+func IsLabelSelectorSubset(sub metav1.LabelSelector, super metav1.LabelSelector) bool {
+	for key, val := range super.MatchLabels {
+		if !isRequirementImplied(sub, metav1.LabelSelectorRequirement{Key: key, Operator: metav1.LabelSelectorOpIn, Values: []string{val}}) {
+			return false
+		}
+	}
+	for _, exp := range super.MatchExpressions {
+		if !isRequirementImplied(sub, exp) {
+			return false
+		}
+	}
+	return true
+}
+
+// isRequirementImplied returns true if every set of labels matched by selector also satisfies req.
+func isRequirementImplied(selector metav1.LabelSelector, req metav1.LabelSelectorRequirement) bool {
+	if val, ok := selector.MatchLabels[req.Key]; ok {
+		switch req.Operator {
+		case metav1.LabelSelectorOpIn:
+			return slices.Contains(req.Values, val)
+		case metav1.LabelSelectorOpNotIn:
+			return !slices.Contains(req.Values, val)
+		case metav1.LabelSelectorOpExists:
+			return true
+		}
+	}
+	for _, exp := range selector.MatchExpressions {
+		if exp.Key != req.Key {
+			continue
+		}
+		switch req.Operator {
+		case metav1.LabelSelectorOpIn:
+			if exp.Operator == metav1.LabelSelectorOpIn && isSubsetOf(exp.Values, req.Values) {
+				return true
+			}
+		case metav1.LabelSelectorOpNotIn:
+			if exp.Operator == metav1.LabelSelectorOpIn && isDisjoint(exp.Values, req.Values) {
+				return true
+			}
+			if exp.Operator == metav1.LabelSelectorOpNotIn && isSubsetOf(req.Values, exp.Values) {
+				return true
+			}
+		case metav1.LabelSelectorOpExists:
+			if exp.Operator == metav1.LabelSelectorOpIn || exp.Operator == metav1.LabelSelectorOpExists {
+				return true
+			}
+		case metav1.LabelSelectorOpDoesNotExist:
+			if exp.Operator == metav1.LabelSelectorOpDoesNotExist {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isSubsetOf(sub []string, super []string) bool {
+	for _, s := range sub {
+		if !slices.Contains(super, s) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDisjoint(a []string, b []string) bool {
+	for _, s := range a {
+		if slices.Contains(b, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -13,5 +13,32 @@ func RunLabelSelectorTests() {
 				MatchLabels: map[string]string{"pod": "b"},
 			})).To(BeFalse())
 		})
+
+		It("Should determine whether a label selector is a subset of another", func() {
+			all := metav1.LabelSelector{}
+			app := metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}}
+			appTier := metav1.LabelSelector{MatchLabels: map[string]string{"app": "a", "tier": "web"}}
+			appIn := metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+			}}
+			appExists := metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpExists},
+			}}
+			appNotC := metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"c"}},
+			}}
+
+			Expect(IsLabelSelectorSubset(app, all)).To(BeTrue())
+			Expect(IsLabelSelectorSubset(all, app)).To(BeFalse())
+			Expect(IsLabelSelectorSubset(appTier, app)).To(BeTrue())
+			Expect(IsLabelSelectorSubset(app, appTier)).To(BeFalse())
+			Expect(IsLabelSelectorSubset(app, appIn)).To(BeTrue())
+			Expect(IsLabelSelectorSubset(appIn, app)).To(BeFalse())
+			Expect(IsLabelSelectorSubset(appIn, appExists)).To(BeTrue())
+			Expect(IsLabelSelectorSubset(appIn, appNotC)).To(BeTrue())
+			Expect(IsLabelSelectorSubset(appExists, appNotC)).To(BeFalse())
+			// NotIn matches labels without the key
+			Expect(IsLabelSelectorSubset(appNotC, appExists)).To(BeFalse())
+		})
 	})
 }
@@ -9,6 +9,18 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// PolicyTypes returns the policy types of a NetworkPolicy.  If spec.policyTypes isn't set, they're defaulted as kube
+// does: Ingress, plus Egress if there are egress rules.
+func PolicyTypes(policy *NetworkPolicy) []PolicyType {
+	if len(policy.Spec.PolicyTypes) > 0 {
+		return policy.Spec.PolicyTypes
+	}
+	if len(policy.Spec.Egress) > 0 {
+		return []PolicyType{PolicyTypeIngress, PolicyTypeEgress}
+	}
+	return []PolicyType{PolicyTypeIngress}
+}
+
 func NetworkPoliciesToTable(policies []*NetworkPolicy) string {
 	tableString := &strings.Builder{}
 	table := tablewriter.NewWriter(tableString)
@@ -25,7 +37,7 @@ func NetworkPoliciesToTable(policies []*NetworkPolicy) string {
 			target = "all pods"
 		}
 
-		for _, policyType := range policy.Spec.PolicyTypes {
+		for _, policyType := range PolicyTypes(policy) {
 			if policyType == PolicyTypeIngress {
 				if len(policy.Spec.Ingress) == 0 {
 					table.Append([]string{name, target, "ingress", "none", "none"})
@@ -0,0 +1,27 @@
+package kube
+
+import (
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// NodeInternalIPs returns the InternalIP addresses of a node, in the order they're reported.
+// Dual-stack nodes report one InternalIP per IP family.
+func NodeInternalIPs(node *v1.Node) []string {
+	var ips []string
+	for _, address := range node.Status.Addresses {
+		if address.Type == v1.NodeInternalIP {
+			ips = append(ips, address.Address)
+		}
+	}
+	return ips
+}
+
+// NodeInternalIP returns the first InternalIP address of a node.
+func NodeInternalIP(node *v1.Node) (string, error) {
+	ips := NodeInternalIPs(node)
+	if len(ips) == 0 {
+		return "", errors.Errorf("node %s has no InternalIP address", node.Name)
+	}
+	return ips[0], nil
+}
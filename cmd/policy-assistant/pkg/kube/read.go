@@ -2,31 +2,183 @@ package kube
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
 	"github.com/mattfenwick/collections/pkg/builtin"
 	"github.com/mattfenwick/collections/pkg/file"
 	"github.com/mattfenwick/collections/pkg/slice"
-	"github.com/mattfenwick/cyclonus/pkg/utils"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
-	"os"
-	"path/filepath"
 	v1alpha12 "sigs.k8s.io/network-policy-api/apis/v1alpha1"
-	"sync"
 )
 
-// ReadNetworkPoliciesFromPath walks the folder and try to parse each file in
-// one of the supported types in the following manner:
-// 1. NetworkPolicyList
-// 2. NetworkPolicy
-// 3. BaselineAdminNetworkPolicy
-// 4. AdminNetworkPolicyList
-// 5. AdminNetworkPolicy
+const (
+	NetworkingAPIVersion = "networking.k8s.io/v1"
+	PolicyAPIVersion     = "policy.networking.k8s.io/v1alpha1"
+
+	NetworkPolicyKind              = "NetworkPolicy"
+	AdminNetworkPolicyKind         = "AdminNetworkPolicy"
+	BaselineAdminNetworkPolicyKind = "BaselineAdminNetworkPolicy"
+)
+
+// SourceLocation is the file and line a policy was read from.
+type SourceLocation struct {
+	Path string
+	// Line is 1-based
+	Line int
+	// doc is the document the policy was parsed from, for finding the lines of its fields
+	doc *YamlDocument
+}
+
+func (s *SourceLocation) String() string {
+	return fmt.Sprintf("%s:%d", s.Path, s.Line)
+}
+
+// FieldLine returns the line of a field of the policy, such as `spec.ingress[1].from`.  See YamlDocument.FieldLine.
+func (s *SourceLocation) FieldLine(path string) int {
+	if s.doc == nil {
+		return s.Line
+	}
+	return s.doc.FieldLine(path)
+}
+
+// PolicyRef identifies a policy.  Namespace is empty for ANPs and BANPs, which are cluster-scoped.
+type PolicyRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// PoliciesFromPath are the policies read from a file or directory, along with where each one was read from.
+type PoliciesFromPath struct {
+	NetworkPolicies            []*networkingv1.NetworkPolicy
+	AdminNetworkPolicies       []*v1alpha12.AdminNetworkPolicy
+	BaselineAdminNetworkPolicy *v1alpha12.BaselineAdminNetworkPolicy
+	Sources                    map[PolicyRef]*SourceLocation
+}
+
+func NewPoliciesFromPath() *PoliciesFromPath {
+	return &PoliciesFromPath{Sources: map[PolicyRef]*SourceLocation{}}
+}
+
+// policyRef identifies a NetworkPolicy without a namespace by the default namespace, as the matcher does.
+func policyRef(kind string, namespace string, name string) PolicyRef {
+	if kind == NetworkPolicyKind && namespace == "" {
+		namespace = v1.NamespaceDefault
+	}
+	return PolicyRef{Kind: kind, Namespace: namespace, Name: name}
+}
+
+// Source returns where a policy was read from, or nil if it wasn't read from a file.
+func (p *PoliciesFromPath) Source(kind string, namespace string, name string) *SourceLocation {
+	return p.Sources[policyRef(kind, namespace, name)]
+}
+
+// AddSource records the document a policy was read from.
+func (p *PoliciesFromPath) AddSource(kind string, namespace string, name string, doc *YamlDocument) {
+	ref := policyRef(kind, namespace, name)
+	source := &SourceLocation{Path: doc.Path, Line: doc.Line(), doc: doc}
+	if other, ok := p.Sources[ref]; ok {
+		logrus.Debugf("%s %s at %s has the same name as the one at %s", ref.Kind, ref.Name, source, other)
+	}
+	p.Sources[ref] = source
+}
+
+// PolicyDocument is a document holding a single policy of the given kind.
+type PolicyDocument struct {
+	*YamlDocument
+	Kind string
+}
+
+// PolicyDocuments returns the policies of a document, which are the document itself if it's a policy, or its items
+// if it's a plain yaml list, a v1 List or a list of policies.  Documents of other kinds are skipped.
+// It's an error if the document has no kind.
+func PolicyDocuments(doc *YamlDocument) ([]*PolicyDocument, error) {
+	if doc.Node.Kind == yaml.SequenceNode {
+		var policyDocs []*PolicyDocument
+		for _, item := range doc.Node.Content {
+			itemPolicyDocs, err := PolicyDocuments(&YamlDocument{Path: doc.Path, Node: item})
+			if err != nil {
+				return nil, err
+			}
+			policyDocs = append(policyDocs, itemPolicyDocs...)
+		}
+		return policyDocs, nil
+	}
+
+	apiVersion, kind := doc.TypeMeta()
+	switch {
+	case kind == "":
+		return nil, errors.Errorf("document at %s:%d has no kind", doc.Path, doc.Line())
+	case apiVersion == "v1" && kind == "List":
+		var policyDocs []*PolicyDocument
+		for _, item := range doc.Items() {
+			itemPolicyDocs, err := PolicyDocuments(item)
+			if err != nil {
+				return nil, err
+			}
+			policyDocs = append(policyDocs, itemPolicyDocs...)
+		}
+		return policyDocs, nil
+	case apiVersion == NetworkingAPIVersion && kind == NetworkPolicyKind,
+		apiVersion == PolicyAPIVersion && (kind == AdminNetworkPolicyKind || kind == BaselineAdminNetworkPolicyKind):
+		return []*PolicyDocument{{YamlDocument: doc, Kind: kind}}, nil
+	case apiVersion == NetworkingAPIVersion && kind == NetworkPolicyKind+"List",
+		apiVersion == PolicyAPIVersion && (kind == AdminNetworkPolicyKind+"List" || kind == BaselineAdminNetworkPolicyKind+"List"):
+		// items of typed lists don't need to repeat their kind
+		var policyDocs []*PolicyDocument
+		for _, item := range doc.Items() {
+			policyDocs = append(policyDocs, &PolicyDocument{YamlDocument: item, Kind: strings.TrimSuffix(kind, "List")})
+		}
+		return policyDocs, nil
+	default:
+		logrus.Debugf("skipping %s %s at %s:%d", apiVersion, kind, doc.Path, doc.Line())
+		return nil, nil
+	}
+}
+
+// ReadNetworkPoliciesFromPath walks the folder and reads the policies of each file.
+// See ReadPoliciesFromPath.
 func ReadNetworkPoliciesFromPath(policyPath string) ([]*networkingv1.NetworkPolicy, []*v1alpha12.AdminNetworkPolicy, *v1alpha12.BaselineAdminNetworkPolicy, error) {
-	var netPolicies []*networkingv1.NetworkPolicy
-	var adminNetworkPolicies []*v1alpha12.AdminNetworkPolicy
-	var baselineAdminNetworkPolicy *v1alpha12.BaselineAdminNetworkPolicy
+	policies, err := ReadPoliciesFromPath(policyPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return policies.NetworkPolicies, policies.AdminNetworkPolicies, policies.BaselineAdminNetworkPolicy, nil
+}
 
+// ReadPoliciesFromPath walks the folder and reads NetworkPolicies, AdminNetworkPolicies and a
+// BaselineAdminNetworkPolicy from each file.  Files may have several documents separated by '---' lines.
+// Each document is parsed according to its apiVersion and kind:
+// 1. a single policy
+// 2. a NetworkPolicyList, AdminNetworkPolicyList or BaselineAdminNetworkPolicyList
+// 3. a v1 List, whose items may be of different kinds
+// Documents of other kinds, such as Deployments and Services, are skipped.
+func ReadPoliciesFromPath(policyPath string) (*PoliciesFromPath, error) {
+	sources, err := ReadYamlSources(policyPath)
+	if err != nil {
+		return nil, err
+	}
+	return ReadPoliciesFromYaml(sources)
+}
+
+// YamlSource is yaml read from a file, or rendered by a tool such as kustomize or helm.
+type YamlSource struct {
+	// Path is the file the yaml was read or rendered from
+	Path string
+	Yaml []byte
+}
+
+// ReadYamlSources walks the folder and reads each file.
+func ReadYamlSources(policyPath string) ([]*YamlSource, error) {
+	var sources []*YamlSource
 	err := filepath.Walk(policyPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return errors.Wrapf(err, "unable to walk path %s", path)
@@ -40,76 +192,71 @@ func ReadNetworkPoliciesFromPath(policyPath string) ([]*networkingv1.NetworkPoli
 		if err != nil {
 			return err
 		}
+		sources = append(sources, &YamlSource{Path: path, Yaml: bytes})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
 
-		// TODO try parsing plain yaml list (that is: not a NetworkPolicyList)
-		// policies, err := utils.ParseYaml[[]*networkingv1.NetworkPolicy](bytes)
-
-		// TODO try parsing multiple policies separated by '---' lines
-		// policies, err := yaml.ParseMany[networkingv1.NetworkPolicy](bytes)
-		// if err == nil {
-		// 	logrus.Debugf("parsed %d policies from %s", len(policies), path)
-		// 	netPolicies = append(netPolicies, refNetpolList(policies)...)
-		// 	return nil
-		// }
-		// logrus.Errorf("unable to parse multiple policies separated by '---' lines: %+v", err)
-
-		// try parsing a NetworkPolicyList
-		policyList, err := utils.ParseYamlStrict[networkingv1.NetworkPolicyList](bytes)
-		if err == nil {
-			netPolicies = append(netPolicies, refList(policyList.Items)...)
-			return nil
-		}
-		logrus.Debugf("unable to parse list of network policies: %+v", err)
-
-		policy, err := utils.ParseYamlStrict[networkingv1.NetworkPolicy](bytes)
-		if err == nil {
-			netPolicies = append(netPolicies, policy)
-			return nil
+// ReadPoliciesFromYaml reads the policies of every document of the sources.  See ReadPoliciesFromPath.
+func ReadPoliciesFromYaml(sources []*YamlSource) (*PoliciesFromPath, error) {
+	policies := NewPoliciesFromPath()
+	for _, source := range sources {
+		docs, err := ParseYamlDocuments(source.Path, source.Yaml)
+		if err != nil {
+			return nil, err
 		}
-		logrus.Debugf("unable to parse network policy: %+v", err)
-
-		banp, err := utils.ParseYamlStrict[v1alpha12.BaselineAdminNetworkPolicy](bytes)
-		if err == nil {
-			if baselineAdminNetworkPolicy != nil {
-				return errors.New("baseline admin network policy already exists")
+		for _, doc := range docs {
+			policyDocs, err := PolicyDocuments(doc)
+			if err != nil {
+				return nil, err
+			}
+			for _, policyDoc := range policyDocs {
+				if err := policies.add(policyDoc); err != nil {
+					return nil, err
+				}
 			}
-			baselineAdminNetworkPolicy = banp
-			return nil
 		}
-		logrus.Debugf("unable to base admin network policies: %+v", err)
+	}
+	return policies, nil
+}
 
-		anpList, err := utils.ParseYamlStrict[v1alpha12.AdminNetworkPolicyList](bytes)
-		if err == nil {
-			adminNetworkPolicies = append(adminNetworkPolicies, refList(anpList.Items)...)
-			return nil
+func (p *PoliciesFromPath) add(doc *PolicyDocument) error {
+	source := &SourceLocation{Path: doc.Path, Line: doc.Line()}
+	var namespace, name string
+	switch doc.Kind {
+	case NetworkPolicyKind:
+		netpol, err := ParseYamlDocument[networkingv1.NetworkPolicy](doc.YamlDocument, true)
+		if err != nil {
+			return errors.WithMessagef(err, "unable to parse network policy at %s", source)
 		}
-		logrus.Debugf("unable to parse list of admin network policies: %+v", err)
-
-		anp, err := utils.ParseYamlStrict[v1alpha12.AdminNetworkPolicy](bytes)
-		if err == nil {
-			adminNetworkPolicies = append(adminNetworkPolicies, anp)
-			return nil
+		p.NetworkPolicies = append(p.NetworkPolicies, netpol)
+		namespace, name = netpol.Namespace, netpol.Name
+	case AdminNetworkPolicyKind:
+		anp, err := ParseYamlDocument[v1alpha12.AdminNetworkPolicy](doc.YamlDocument, true)
+		if err != nil {
+			return errors.WithMessagef(err, "unable to parse admin network policy at %s", source)
 		}
-		logrus.Debugf("unable to single admin network policies: %+v", err)
-
-		if len(netPolicies) == 0 && len(adminNetworkPolicies) == 0 && baselineAdminNetworkPolicy == nil {
-			return errors.WithMessagef(err, "unable to parse any policies from yaml at %s", path)
+		p.AdminNetworkPolicies = append(p.AdminNetworkPolicies, anp)
+		name = anp.Name
+	case BaselineAdminNetworkPolicyKind:
+		banp, err := ParseYamlDocument[v1alpha12.BaselineAdminNetworkPolicy](doc.YamlDocument, true)
+		if err != nil {
+			return errors.WithMessagef(err, "unable to parse baseline admin network policy at %s", source)
 		}
-
-		return nil
-	})
-	if err != nil {
-		return nil, nil, nil, err
-		//return nil, errors.Wrapf(err, "unable to walk filesystem from %s", policyPath)
-	}
-	if len(netPolicies) > 0 {
-		for _, p := range netPolicies {
-			if len(p.Spec.PolicyTypes) == 0 {
-				return nil, nil, nil, errors.Errorf("missing spec.policyTypes from network policy %s/%s", p.Namespace, p.Name)
-			}
+		if p.BaselineAdminNetworkPolicy != nil {
+			return errors.Errorf("baseline admin network policy already exists: %s at %s", p.BaselineAdminNetworkPolicy.Name, p.Source(BaselineAdminNetworkPolicyKind, "", p.BaselineAdminNetworkPolicy.Name))
 		}
+		p.BaselineAdminNetworkPolicy = banp
+		name = banp.Name
+	default:
+		return errors.Errorf("unsupported policy kind %s at %s", doc.Kind, source)
 	}
-	return netPolicies, adminNetworkPolicies, baselineAdminNetworkPolicy, nil
+	p.AddSource(doc.Kind, namespace, name, doc.YamlDocument)
+	return nil
 }
 
 func refList[T any](refs []T) []*T {
@@ -3,6 +3,7 @@ package kube
 import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
 )
 
 func RunReadNetworkPolicyTests() {
@@ -18,14 +19,32 @@ func RunReadNetworkPolicyTests() {
 			Expect(len(policies)).To(Equal(3))
 		})
 
-		// TODO test case to read multiple policies from plain yaml list
+		It("Should read multiple policies from a plain yaml list", func() {
+			policies, _, _, err := ReadNetworkPoliciesFromPath("../../test/example-policies/networkpolicies/yaml-syntax/plain-list.yaml")
+			Expect(err).To(BeNil())
+			Expect(len(policies)).To(Equal(2))
+		})
+
+		It("Should read multiple policies separated by '---' lines from a single file, skipping other kinds", func() {
+			policies, _, _, err := ReadNetworkPoliciesFromPath("../../test/example-policies/networkpolicies/yaml-syntax/triple-dash-separated.yaml")
+			Expect(err).To(BeNil())
+			Expect(len(policies)).To(Equal(3))
+		})
 
-		// TODO
-		// It("Should read multiple policies separated by '---' lines from a single file", func() {
-		// 	policies, err := ReadNetworkPoliciesFromPath("../../networkpolicies/yaml-syntax/triple-dash-separated.yaml")
-		// 	Expect(err).To(BeNil())
-		// 	Expect(len(policies)).To(Equal(3))
-		// })
+		It("Should read policies of every kind from a single file, along with their sources", func() {
+			path := "../../test/mixed-policies/mixed.yaml"
+			policies, err := ReadPoliciesFromPath(path)
+			Expect(err).To(BeNil())
+			Expect(policies.NetworkPolicies).To(HaveLen(1))
+			Expect(policies.AdminNetworkPolicies).To(HaveLen(1))
+			Expect(policies.BaselineAdminNetworkPolicy).ToNot(BeNil())
+
+			Expect(policies.Source(AdminNetworkPolicyKind, "", "allow-monitoring").String()).To(Equal(path + ":1"))
+			Expect(policies.Source(BaselineAdminNetworkPolicyKind, "", "default").String()).To(Equal(path + ":17"))
+			Expect(policies.Source(NetworkPolicyKind, "x", "allow-web").String()).To(Equal(path + ":47"))
+			Expect(policies.Source(NetworkPolicyKind, "x", "allow-web").FieldLine("spec.ingress[0]")).To(Equal(57))
+			Expect(policies.Source(NetworkPolicyKind, "x", "settings")).To(BeNil())
+		})
 
 		It("Should read multiple policies from all files in a directory", func() {
 			policies, _, _, err := ReadNetworkPoliciesFromPath("../../test/example-policies/networkpolicies/simple-example")
@@ -34,7 +53,7 @@ func RunReadNetworkPolicyTests() {
 
 			policies, _, _, err = ReadNetworkPoliciesFromPath("../../test/example-policies/networkpolicies/")
 			Expect(err).To(BeNil())
-			Expect(len(policies)).To(Equal(14))
+			Expect(len(policies)).To(Equal(19))
 		})
 
 		It("Should read multiple admin network policies", func() {
@@ -52,11 +71,37 @@ func RunReadNetworkPolicyTests() {
 		It("Should parse multiple types from folder", func() {
 			policiies, anps, bapn, err := ReadNetworkPoliciesFromPath("../../test/example-policies/")
 			Expect(err).To(BeNil())
-			Expect(len(policiies)).To(Equal(14))
+			Expect(len(policiies)).To(Equal(19))
 			Expect(len(anps)).To(Equal(3))
 			Expect(bapn).ToNot(BeNil())
 		})
 
+		It("Should read a network policy without spec.policyTypes along with valid ones", func() {
+			yaml := `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: good
+  namespace: x
+spec:
+  podSelector: {}
+  policyTypes: [Ingress]
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: no-types
+  namespace: x
+spec:
+  podSelector: {}
+`
+			policies, err := ReadPoliciesFromYaml([]*YamlSource{{Path: "policies.yaml", Yaml: []byte(yaml)}})
+			Expect(err).To(BeNil())
+			Expect(policies.NetworkPolicies).To(HaveLen(2))
+			Expect(policies.NetworkPolicies[1].Spec.PolicyTypes).To(BeEmpty())
+			Expect(PolicyTypes(policies.NetworkPolicies[1])).To(Equal([]networkingv1.PolicyType{networkingv1.PolicyTypeIngress}))
+			Expect(policies.Source(NetworkPolicyKind, "x", "no-types").String()).To(Equal("policies.yaml:10"))
+		})
+
 		// TODO test to show what happens for duplicate names
 	})
 }
@@ -0,0 +1,442 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+// SnapshotVersion is the version of the snapshot format.  It must be bumped on incompatible changes.
+const SnapshotVersion = "v1"
+
+var ErrReadOnlySnapshot = errors.New("snapshot is read-only")
+
+// Snapshot is a point-in-time copy of the cluster resources that cyclonus reads,
+// so that analysis can be reproduced without a cluster.
+type Snapshot struct {
+	Version string
+
+	Namespaces   []v1.Namespace
+	Pods         []v1.Pod
+	Services     []v1.Service
+	Nodes        []v1.Node
+	Deployments  []appsv1.Deployment
+	DaemonSets   []appsv1.DaemonSet
+	StatefulSets []appsv1.StatefulSet
+	ReplicaSets  []appsv1.ReplicaSet
+
+	NetworkPolicies            []networkingv1.NetworkPolicy
+	AdminNetworkPolicies       []v1alpha1.AdminNetworkPolicy
+	BaselineAdminNetworkPolicy *v1alpha1.BaselineAdminNetworkPolicy
+}
+
+// TakeSnapshot reads resources from kube.  Namespaced resources are only read from the given namespaces,
+// or from all namespaces if namespaces is empty or includes v1.NamespaceAll.
+func TakeSnapshot(ctx context.Context, kubernetes IKubernetes, namespaces []string, includeANPs, includeBANP bool) (*Snapshot, error) {
+	s := &Snapshot{Version: SnapshotVersion}
+
+	allNamespaces := len(namespaces) == 0
+	for _, ns := range namespaces {
+		if ns == v1.NamespaceAll {
+			allNamespaces = true
+		}
+	}
+	if allNamespaces {
+		nsList, err := kubernetes.GetAllNamespaces()
+		if err != nil {
+			return nil, err
+		}
+		s.Namespaces = nsList.Items
+	} else {
+		for _, name := range namespaces {
+			ns, err := kubernetes.GetNamespace(name)
+			if err != nil {
+				return nil, err
+			}
+			s.Namespaces = append(s.Namespaces, *ns)
+		}
+	}
+
+	for _, ns := range s.Namespaces {
+		pods, err := kubernetes.GetPodsInNamespace(ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		s.Pods = append(s.Pods, pods...)
+
+		services, err := kubernetes.GetServicesInNamespace(ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		s.Services = append(s.Services, services...)
+
+		deployments, err := kubernetes.GetDeploymentsInNamespace(ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		s.Deployments = append(s.Deployments, deployments...)
+
+		daemonSets, err := kubernetes.GetDaemonSetsInNamespace(ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		s.DaemonSets = append(s.DaemonSets, daemonSets...)
+
+		statefulSets, err := kubernetes.GetStatefulSetsInNamespace(ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		s.StatefulSets = append(s.StatefulSets, statefulSets...)
+
+		replicaSets, err := kubernetes.GetReplicaSetsInNamespace(ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		s.ReplicaSets = append(s.ReplicaSets, replicaSets...)
+
+		netpols, err := kubernetes.GetNetworkPoliciesInNamespace(ctx, ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		s.NetworkPolicies = append(s.NetworkPolicies, netpols...)
+	}
+
+	nodes, err := kubernetes.GetAllNodes()
+	if err != nil {
+		return nil, err
+	}
+	s.Nodes = nodes
+
+	if includeANPs {
+		s.AdminNetworkPolicies, err = kubernetes.GetAdminNetworkPolicies(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get admin network policies")
+		}
+	}
+	if includeBANP {
+		s.BaselineAdminNetworkPolicy, err = kubernetes.GetBaselineAdminNetworkPolicy(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get baseline admin network policy")
+		}
+	}
+
+	s.clearManagedFields()
+	return s, nil
+}
+
+// clearManagedFields drops server-side apply bookkeeping, which makes up much of a snapshot and isn't used.
+func (s *Snapshot) clearManagedFields() {
+	for i := range s.Namespaces {
+		s.Namespaces[i].ManagedFields = nil
+	}
+	for i := range s.Pods {
+		s.Pods[i].ManagedFields = nil
+	}
+	for i := range s.Services {
+		s.Services[i].ManagedFields = nil
+	}
+	for i := range s.Nodes {
+		s.Nodes[i].ManagedFields = nil
+	}
+	for i := range s.Deployments {
+		s.Deployments[i].ManagedFields = nil
+	}
+	for i := range s.DaemonSets {
+		s.DaemonSets[i].ManagedFields = nil
+	}
+	for i := range s.StatefulSets {
+		s.StatefulSets[i].ManagedFields = nil
+	}
+	for i := range s.ReplicaSets {
+		s.ReplicaSets[i].ManagedFields = nil
+	}
+	for i := range s.NetworkPolicies {
+		s.NetworkPolicies[i].ManagedFields = nil
+	}
+	for i := range s.AdminNetworkPolicies {
+		s.AdminNetworkPolicies[i].ManagedFields = nil
+	}
+	if s.BaselineAdminNetworkPolicy != nil {
+		s.BaselineAdminNetworkPolicy.ManagedFields = nil
+	}
+}
+
+func (s *Snapshot) WriteFile(path string) error {
+	bytes, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "unable to marshal snapshot")
+	}
+	return errors.Wrapf(os.WriteFile(path, bytes, 0644), "unable to write snapshot to %s", path)
+}
+
+func ReadSnapshotFile(path string) (*Snapshot, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read snapshot from %s", path)
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(bytes, s); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse snapshot from %s", path)
+	}
+	if s.Version != SnapshotVersion {
+		return nil, errors.Errorf("unsupported snapshot version %q in %s: expected %q", s.Version, path, SnapshotVersion)
+	}
+	return s, nil
+}
+
+// SnapshotKubernetes is a read-only IKubernetes backed by a Snapshot.
+type SnapshotKubernetes struct {
+	Snapshot *Snapshot
+}
+
+func NewSnapshotKubernetes(snapshot *Snapshot) *SnapshotKubernetes {
+	return &SnapshotKubernetes{Snapshot: snapshot}
+}
+
+// checkNamespace returns an error if a namespace isn't in the snapshot, so that reads from a namespace
+// which wasn't captured fail instead of silently returning nothing.
+func (s *SnapshotKubernetes) checkNamespace(namespace string) error {
+	if namespace == v1.NamespaceAll {
+		return nil
+	}
+	_, err := s.GetNamespace(namespace)
+	return err
+}
+
+func inNamespace(namespace string, objectNamespace string) bool {
+	return namespace == v1.NamespaceAll || namespace == objectNamespace
+}
+
+func (s *SnapshotKubernetes) CreateNamespace(kubeNamespace *v1.Namespace) (*v1.Namespace, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) GetNamespace(namespace string) (*v1.Namespace, error) {
+	for i, ns := range s.Snapshot.Namespaces {
+		if ns.Name == namespace {
+			return &s.Snapshot.Namespaces[i], nil
+		}
+	}
+	return nil, errors.Errorf("namespace %s not found in snapshot", namespace)
+}
+
+func (s *SnapshotKubernetes) SetNamespaceLabels(namespace string, labels map[string]string) (*v1.Namespace, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) DeleteNamespace(namespace string) error {
+	return ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) GetAllNamespaces() (*v1.NamespaceList, error) {
+	return &v1.NamespaceList{Items: s.Snapshot.Namespaces}, nil
+}
+
+func (s *SnapshotKubernetes) GetNode(name string) (*v1.Node, error) {
+	for i, node := range s.Snapshot.Nodes {
+		if node.Name == name {
+			return &s.Snapshot.Nodes[i], nil
+		}
+	}
+	return nil, errors.Errorf("node %s not found in snapshot", name)
+}
+
+func (s *SnapshotKubernetes) GetAllNodes() ([]v1.Node, error) {
+	return s.Snapshot.Nodes, nil
+}
+
+func (s *SnapshotKubernetes) CreateNetworkPolicy(kubePolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) GetNetworkPoliciesInNamespace(ctx context.Context, namespace string) ([]networkingv1.NetworkPolicy, error) {
+	if err := s.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	var netpols []networkingv1.NetworkPolicy
+	for _, netpol := range s.Snapshot.NetworkPolicies {
+		if inNamespace(namespace, netpol.Namespace) {
+			netpols = append(netpols, netpol)
+		}
+	}
+	return netpols, nil
+}
+
+func (s *SnapshotKubernetes) UpdateNetworkPolicy(kubePolicy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) DeleteNetworkPolicy(namespace string, name string) error {
+	return ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) DeleteAllNetworkPoliciesInNamespace(namespace string) error {
+	return ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) CreateService(kubeService *v1.Service) (*v1.Service, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) GetService(namespace string, name string) (*v1.Service, error) {
+	for i, svc := range s.Snapshot.Services {
+		if svc.Namespace == namespace && svc.Name == name {
+			return &s.Snapshot.Services[i], nil
+		}
+	}
+	return nil, errors.Errorf("service %s/%s not found in snapshot", namespace, name)
+}
+
+func (s *SnapshotKubernetes) DeleteService(namespace string, name string) error {
+	return ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) GetServicesInNamespace(namespace string) ([]v1.Service, error) {
+	if err := s.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	var services []v1.Service
+	for _, svc := range s.Snapshot.Services {
+		if inNamespace(namespace, svc.Namespace) {
+			services = append(services, svc)
+		}
+	}
+	return services, nil
+}
+
+func (s *SnapshotKubernetes) GetAdminNetworkPolicies(ctx context.Context) ([]v1alpha1.AdminNetworkPolicy, error) {
+	return s.Snapshot.AdminNetworkPolicies, nil
+}
+
+func (s *SnapshotKubernetes) CreateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) UpdateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) DeleteAdminNetworkPolicy(ctx context.Context, name string) error {
+	return ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) GetBaselineAdminNetworkPolicy(ctx context.Context) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
+	return s.Snapshot.BaselineAdminNetworkPolicy, nil
+}
+
+func (s *SnapshotKubernetes) CreateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) UpdateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) DeleteBaselineAdminNetworkPolicy(ctx context.Context, name string) error {
+	return ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) CreatePod(kubePod *v1.Pod) (*v1.Pod, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) GetPod(namespace string, pod string) (*v1.Pod, error) {
+	for i, p := range s.Snapshot.Pods {
+		if p.Namespace == namespace && p.Name == pod {
+			return &s.Snapshot.Pods[i], nil
+		}
+	}
+	return nil, errors.Errorf("pod %s/%s not found in snapshot", namespace, pod)
+}
+
+func (s *SnapshotKubernetes) DeletePod(namespace string, pod string) error {
+	return ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) SetPodLabels(namespace string, pod string, labels map[string]string) (*v1.Pod, error) {
+	return nil, ErrReadOnlySnapshot
+}
+
+func (s *SnapshotKubernetes) GetPodsInNamespace(namespace string) ([]v1.Pod, error) {
+	if err := s.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	var pods []v1.Pod
+	for _, pod := range s.Snapshot.Pods {
+		if inNamespace(namespace, pod.Namespace) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func (s *SnapshotKubernetes) GetDeploymentsInNamespace(namespace string) ([]appsv1.Deployment, error) {
+	if err := s.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	var deployments []appsv1.Deployment
+	for _, deployment := range s.Snapshot.Deployments {
+		if inNamespace(namespace, deployment.Namespace) {
+			deployments = append(deployments, deployment)
+		}
+	}
+	return deployments, nil
+}
+
+func (s *SnapshotKubernetes) GetDaemonSetsInNamespace(namespace string) ([]appsv1.DaemonSet, error) {
+	if err := s.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	var daemonSets []appsv1.DaemonSet
+	for _, daemonSet := range s.Snapshot.DaemonSets {
+		if inNamespace(namespace, daemonSet.Namespace) {
+			daemonSets = append(daemonSets, daemonSet)
+		}
+	}
+	return daemonSets, nil
+}
+
+func (s *SnapshotKubernetes) GetStatefulSetsInNamespace(namespace string) ([]appsv1.StatefulSet, error) {
+	if err := s.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	var statefulSets []appsv1.StatefulSet
+	for _, statefulSet := range s.Snapshot.StatefulSets {
+		if inNamespace(namespace, statefulSet.Namespace) {
+			statefulSets = append(statefulSets, statefulSet)
+		}
+	}
+	return statefulSets, nil
+}
+
+func (s *SnapshotKubernetes) GetReplicaSetsInNamespace(namespace string) ([]appsv1.ReplicaSet, error) {
+	if err := s.checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	var replicaSets []appsv1.ReplicaSet
+	for _, replicaSet := range s.Snapshot.ReplicaSets {
+		if inNamespace(namespace, replicaSet.Namespace) {
+			replicaSets = append(replicaSets, replicaSet)
+		}
+	}
+	return replicaSets, nil
+}
+
+func (s *SnapshotKubernetes) GetReplicaSet(namespace string, name string) (*appsv1.ReplicaSet, error) {
+	for i, replicaSet := range s.Snapshot.ReplicaSets {
+		if replicaSet.Namespace == namespace && replicaSet.Name == name {
+			return &s.Snapshot.ReplicaSets[i], nil
+		}
+	}
+	return nil, errors.Errorf("replicaSet %s/%s not found in snapshot", namespace, name)
+}
+
+func (s *SnapshotKubernetes) ExecuteRemoteCommand(namespace string, pod string, container string, command []string) (string, string, error, error) {
+	return "", "", nil, errors.Errorf("unable to execute commands in pod %s/%s: %s", namespace, pod, ErrReadOnlySnapshot)
+}
@@ -0,0 +1,93 @@
+package kube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+func RunSnapshotTests() {
+	Describe("Snapshot", func() {
+		newMock := func() *MockKubernetes {
+			mock := NewMockKubernetes(1)
+			for _, ns := range []string{"x", "y"} {
+				_, err := mock.CreateNamespace(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns, Labels: map[string]string{"ns": ns}}})
+				Expect(err).To(Succeed())
+				_, err = mock.CreatePod(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "a", Labels: map[string]string{"pod": "a"}}})
+				Expect(err).To(Succeed())
+				_, err = mock.CreateNetworkPolicy(&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "deny-all"}})
+				Expect(err).To(Succeed())
+			}
+			_, err := mock.CreateNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})
+			Expect(err).To(Succeed())
+			mock.AdminNetworkPolicies = []v1alpha1.AdminNetworkPolicy{{ObjectMeta: metav1.ObjectMeta{Name: "anp"}}}
+			return mock
+		}
+
+		It("Should read resources from a file written from kube", func() {
+			snapshot, err := TakeSnapshot(context.TODO(), newMock(), nil, true, true)
+			Expect(err).To(Succeed())
+			path := filepath.Join(GinkgoT().TempDir(), "snapshot.json")
+			Expect(snapshot.WriteFile(path)).To(Succeed())
+
+			read, err := ReadSnapshotFile(path)
+			Expect(err).To(Succeed())
+			Expect(read.Version).To(Equal(SnapshotVersion))
+			snapshotKube := NewSnapshotKubernetes(read)
+
+			nsList, err := snapshotKube.GetAllNamespaces()
+			Expect(err).To(Succeed())
+			Expect(nsList.Items).To(HaveLen(2))
+
+			pods, err := GetPodsInNamespaces(snapshotKube, []string{v1.NamespaceAll})
+			Expect(err).To(Succeed())
+			Expect(pods).To(HaveLen(2))
+			pod, err := snapshotKube.GetPod("y", "a")
+			Expect(err).To(Succeed())
+			Expect(pod.Status.PodIP).ToNot(BeEmpty())
+
+			netpols, anps, banp, netpolErr, anpErr, banpErr := ReadNetworkPoliciesFromKube(context.TODO(), snapshotKube, []string{"x"}, true, true)
+			Expect(netpolErr).To(Succeed())
+			Expect(anpErr).To(Succeed())
+			Expect(banpErr).To(Succeed())
+			Expect(netpols).To(HaveLen(1))
+			Expect(netpols[0].Namespace).To(Equal("x"))
+			Expect(anps).To(HaveLen(1))
+			Expect(banp).To(BeNil())
+
+			_, err = snapshotKube.GetNode("node-1")
+			Expect(err).To(Succeed())
+		})
+
+		It("Should only capture the given namespaces", func() {
+			snapshot, err := TakeSnapshot(context.TODO(), newMock(), []string{"x"}, false, false)
+			Expect(err).To(Succeed())
+			Expect(snapshot.Namespaces).To(HaveLen(1))
+			Expect(snapshot.Pods).To(HaveLen(1))
+			Expect(snapshot.AdminNetworkPolicies).To(BeEmpty())
+
+			_, err = NewSnapshotKubernetes(snapshot).GetPodsInNamespace("y")
+			Expect(err).To(MatchError("namespace y not found in snapshot"))
+		})
+
+		It("Should be read-only", func() {
+			snapshotKube := NewSnapshotKubernetes(&Snapshot{Version: SnapshotVersion})
+			_, err := snapshotKube.CreateNamespace(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "z"}})
+			Expect(err).To(MatchError(ErrReadOnlySnapshot))
+		})
+
+		It("Should reject snapshots of another version", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "snapshot.json")
+			Expect(os.WriteFile(path, []byte(`{"Version": "v0"}`), 0644)).To(Succeed())
+			_, err := ReadSnapshotFile(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+}
@@ -10,7 +10,11 @@ import (
 func TestModel(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunIPAddressTests()
+	RunKubernetesTests()
 	RunLabelSelectorTests()
 	RunReadNetworkPolicyTests()
+	RunSnapshotTests()
+	RunWorkloadTests()
+	RunYamlDocumentTests()
 	RunSpecs(t, "network policy matcher suite")
 }
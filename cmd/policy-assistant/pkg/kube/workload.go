@@ -0,0 +1,54 @@
+package kube
+
+import (
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// controllerGeneratedLabels are the pod labels which controllers set per revision, pod or job, rather than per workload.
+var controllerGeneratedLabels = map[string]bool{
+	appsv1.DefaultDeploymentUniqueLabelKey: true,
+	appsv1.ControllerRevisionHashLabelKey:  true,
+	appsv1.StatefulSetPodNameLabel:         true,
+	appsv1.PodIndexLabel:                   true,
+	"pod-template-generation":              true,
+	batchv1.JobNameLabel:                   true,
+	batchv1.ControllerUidLabel:             true,
+	batchv1.JobCompletionIndexAnnotation:   true,
+	"job-name":                             true,
+	"controller-uid":                       true,
+}
+
+// WorkloadLabels returns the labels of a pod without those generated by its controller, so that selectors built from
+// them keep selecting the workload's pods across rollouts and restarts.
+func WorkloadLabels(labels map[string]string) map[string]string {
+	workloadLabels := map[string]string{}
+	for key, value := range labels {
+		if !controllerGeneratedLabels[key] {
+			workloadLabels[key] = value
+		}
+	}
+	return workloadLabels
+}
+
+// PodWorkload returns the kind and name of the workload that manages a pod, in the lower-case form
+// used by workload strings such as `namespace/deployment/name`.
+// A pod of a ReplicaSet is attributed to its Deployment through the pod-template-hash label, so that
+// no ReplicaSets need to be read.  A pod without a controller is its own workload, of kind `pod`.
+func PodWorkload(pod *v1.Pod) (string, string) {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+		if owner.Kind == "ReplicaSet" {
+			if hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok && strings.HasSuffix(owner.Name, "-"+hash) {
+				return "deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+			}
+		}
+		return strings.ToLower(owner.Kind), owner.Name
+	}
+	return "pod", pod.Name
+}
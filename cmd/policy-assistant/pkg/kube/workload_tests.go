@@ -0,0 +1,55 @@
+package kube
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func RunWorkloadTests() {
+	Describe("PodWorkload", func() {
+		isController := true
+		pod := func(name string, labels map[string]string, owners ...metav1.OwnerReference) *v1.Pod {
+			return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, OwnerReferences: owners}}
+		}
+		owner := func(kind string, name string) metav1.OwnerReference {
+			return metav1.OwnerReference{Kind: kind, Name: name, Controller: &isController}
+		}
+
+		It("Should attribute pods of a ReplicaSet to its Deployment", func() {
+			kind, name := PodWorkload(pod("web-5d4f8c-abcde", map[string]string{"pod-template-hash": "5d4f8c"}, owner("ReplicaSet", "web-5d4f8c")))
+			Expect(kind).To(Equal("deployment"))
+			Expect(name).To(Equal("web"))
+		})
+
+		It("Should use the controller of other pods", func() {
+			kind, name := PodWorkload(pod("db-0", nil, owner("StatefulSet", "db")))
+			Expect(kind).To(Equal("statefulset"))
+			Expect(name).To(Equal("db"))
+
+			kind, name = PodWorkload(pod("rs-abcde", nil, owner("ReplicaSet", "rs")))
+			Expect(kind).To(Equal("replicaset"))
+			Expect(name).To(Equal("rs"))
+		})
+
+		It("Should treat pods without a controller as their own workload", func() {
+			kind, name := PodWorkload(pod("a", nil, metav1.OwnerReference{Kind: "ConfigMap", Name: "config"}))
+			Expect(kind).To(Equal("pod"))
+			Expect(name).To(Equal("a"))
+		})
+	})
+
+	Describe("WorkloadLabels", func() {
+		It("Should leave out labels generated by controllers", func() {
+			Expect(WorkloadLabels(map[string]string{
+				"app":                                "web",
+				"pod-template-hash":                  "5d4f8c",
+				"controller-revision-hash":           "web-6c8f",
+				"statefulset.kubernetes.io/pod-name": "web-0",
+				"batch.kubernetes.io/job-name":       "migrate",
+				"job-name":                           "migrate",
+			})).To(Equal(map[string]string{"app": "web"}))
+		})
+	})
+}
@@ -0,0 +1,184 @@
+package kube
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// YamlDocument is a single document of a yaml file, which keeps track of the lines its fields are on.
+type YamlDocument struct {
+	Path string
+	Node *yaml.Node
+}
+
+// Line is the 1-based line the document starts on.
+func (d *YamlDocument) Line() int {
+	return d.Node.Line
+}
+
+// TypeMeta returns the apiVersion and kind of the document, which are empty if not set.
+func (d *YamlDocument) TypeMeta() (string, string) {
+	var apiVersion, kind string
+	if node := mappingValue(d.Node, "apiVersion"); node != nil {
+		apiVersion = node.Value
+	}
+	if node := mappingValue(d.Node, "kind"); node != nil {
+		kind = node.Value
+	}
+	return apiVersion, kind
+}
+
+// Items returns a document for each element of the document's items field, as found in lists.
+// As when parsing json, the field name is case-insensitive.
+func (d *YamlDocument) Items() []*YamlDocument {
+	var items *yaml.Node
+	if d.Node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(d.Node.Content); i += 2 {
+			if strings.EqualFold(d.Node.Content[i].Value, "items") {
+				items = d.Node.Content[i+1]
+			}
+		}
+	}
+	if items == nil || items.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var docs []*YamlDocument
+	for _, item := range items.Content {
+		docs = append(docs, &YamlDocument{Path: d.Path, Node: item})
+	}
+	return docs
+}
+
+var fieldPathSegmentRegex = regexp.MustCompile(`^([^\[]*)((?:\[\d+\])*)$`)
+var fieldPathIndexRegex = regexp.MustCompile(`\[(\d+)\]`)
+
+// FieldLine returns the line of a field, given a path such as spec.egress[0].to[1].networks[0].
+// If the field isn't found, the line of its closest ancestor is returned.
+func (d *YamlDocument) FieldLine(path string) int {
+	node := d.Node
+	line := node.Line
+	if path == "" {
+		return line
+	}
+	for _, segment := range strings.Split(path, ".") {
+		match := fieldPathSegmentRegex.FindStringSubmatch(segment)
+		if match == nil {
+			return line
+		}
+		key, value := mappingEntry(node, match[1])
+		if value == nil {
+			return line
+		}
+		node, line = value, key.Line
+		for _, index := range fieldPathIndexRegex.FindAllStringSubmatch(match[2], -1) {
+			i, err := strconv.Atoi(index[1])
+			if err != nil || node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+				return line
+			}
+			node = node.Content[i]
+			line = node.Line
+		}
+	}
+	return line
+}
+
+// FindKeys returns the mapping keys with the given name, at any depth of the document.
+func (d *YamlDocument) FindKeys(name string) []*yaml.Node {
+	var keys []*yaml.Node
+	var find func(node *yaml.Node)
+	find = func(node *yaml.Node) {
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == name {
+					keys = append(keys, node.Content[i])
+				}
+			}
+		}
+		for _, child := range node.Content {
+			find(child)
+		}
+	}
+	find(d.Node)
+	return keys
+}
+
+// ParseYamlDocument decodes a document into T.  If strict, unknown fields are an error.
+func ParseYamlDocument[T any](doc *YamlDocument, strict bool) (*T, error) {
+	bs, err := yaml.Marshal(doc.Node)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to marshal yaml document from %s", doc.Path)
+	}
+	if strict {
+		return utils.ParseYamlStrict[T](bs)
+	}
+	return utils.ParseYaml[T](bs)
+}
+
+var yamlErrorLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// YamlErrorLine returns the line of a yaml syntax error, or 0 if the error doesn't have one.
+func YamlErrorLine(err error) int {
+	match := yamlErrorLineRegex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+	line, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+	return line
+}
+
+// ReadYamlDocuments reads every document of a yaml file, where documents are separated by '---' lines.
+// Empty documents are skipped.
+func ReadYamlDocuments(path string) ([]*YamlDocument, error) {
+	bs, err := file.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseYamlDocuments(path, bs)
+}
+
+// ParseYamlDocuments parses every document of yaml read from path.  Empty documents are skipped.
+func ParseYamlDocuments(path string, bs []byte) ([]*YamlDocument, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(bs))
+	var docs []*YamlDocument
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			return docs, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse yaml from %s", path)
+		}
+		if node.Kind != yaml.DocumentNode || len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue
+		}
+		docs = append(docs, &YamlDocument{Path: path, Node: node.Content[0]})
+	}
+}
+
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	_, value := mappingEntry(node, key)
+	return value
+}
@@ -0,0 +1,91 @@
+package kube
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func RunYamlDocumentTests() {
+	Describe("YamlDocument", func() {
+		writeFile := func(contents string) string {
+			path := filepath.Join(GinkgoT().TempDir(), "policies.yaml")
+			Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+			return path
+		}
+
+		It("reads each document of a file, skipping empty ones", func() {
+			path := writeFile(`apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny-all
+  namespace: x
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - ipBlock:
+        cidr: 10.0.0.0/8
+`)
+			docs, err := ReadYamlDocuments(path)
+			Expect(err).To(Succeed())
+			Expect(docs).To(HaveLen(2))
+
+			apiVersion, kind := docs[0].TypeMeta()
+			Expect(apiVersion).To(Equal("v1"))
+			Expect(kind).To(Equal("Service"))
+			Expect(docs[0].Line()).To(Equal(1))
+
+			apiVersion, kind = docs[1].TypeMeta()
+			Expect(apiVersion).To(Equal("networking.k8s.io/v1"))
+			Expect(kind).To(Equal("NetworkPolicy"))
+			Expect(docs[1].Line()).To(Equal(7))
+			Expect(docs[1].FieldLine("spec.policyTypes")).To(Equal(14))
+			Expect(docs[1].FieldLine("spec.ingress[0].from[0].ipBlock.cidr")).To(Equal(19))
+			// missing fields resolve to their closest ancestor
+			Expect(docs[1].FieldLine("spec.egress[0]")).To(Equal(12))
+			Expect(docs[1].FieldLine("spec.ingress[3]")).To(Equal(16))
+
+			netpol, err := ParseYamlDocument[networkingv1.NetworkPolicy](docs[1], true)
+			Expect(err).To(Succeed())
+			Expect(netpol.Name).To(Equal("deny-all"))
+			Expect(netpol.Spec.Ingress[0].From[0].IPBlock.CIDR).To(Equal("10.0.0.0/8"))
+		})
+
+		It("returns the items of a list", func() {
+			path := writeFile(`apiVersion: v1
+kind: List
+items:
+- kind: NetworkPolicy
+  metadata:
+    name: a
+- kind: NetworkPolicy
+  metadata:
+    name: b
+`)
+			docs, err := ReadYamlDocuments(path)
+			Expect(err).To(Succeed())
+			Expect(docs).To(HaveLen(1))
+			items := docs[0].Items()
+			Expect(items).To(HaveLen(2))
+			Expect(items[1].Line()).To(Equal(7))
+		})
+
+		It("reports the line of syntax errors", func() {
+			_, err := ReadYamlDocuments(writeFile("kind: NetworkPolicy\nmetadata:\n  name: [a\n"))
+			Expect(err).ToNot(Succeed())
+			Expect(YamlErrorLine(err)).To(BeNumerically(">", 0))
+		})
+	})
+}
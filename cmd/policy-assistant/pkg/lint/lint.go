@@ -0,0 +1,267 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1/validation"
+)
+
+type Level string
+
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+)
+
+type Rule struct {
+	ID          string
+	Level       Level
+	Description string
+}
+
+var (
+	ParseErrorRule = &Rule{
+		ID:          "parse-error",
+		Level:       LevelError,
+		Description: "a file or document can't be parsed as a policy",
+	}
+	InvalidPolicyRule = &Rule{
+		ID:          "invalid-policy",
+		Level:       LevelError,
+		Description: "a policy fails validation and is skipped by analysis",
+	}
+	MissingPolicyTypesRule = &Rule{
+		ID:          "missing-policy-types",
+		Level:       LevelWarning,
+		Description: "a NetworkPolicy doesn't set spec.policyTypes, which is defaulted to Ingress, plus Egress if there are egress rules",
+	}
+	ShadowedRuleRule = &Rule{
+		ID:          "shadowed-rule",
+		Level:       LevelWarning,
+		Description: "an ANP or BANP rule can never take effect",
+	}
+	DeprecatedSameLabelsRule = &Rule{
+		ID:          "deprecated-same-labels",
+		Level:       LevelWarning,
+		Description: "sameLabels and notSameLabels were removed from the AdminNetworkPolicy API and are ignored",
+	}
+)
+
+var AllRules = []*Rule{
+	ParseErrorRule,
+	InvalidPolicyRule,
+	MissingPolicyTypesRule,
+	ShadowedRuleRule,
+	DeprecatedSameLabelsRule,
+}
+
+// Finding is a problem with a policy file, at a line of the file.
+type Finding struct {
+	Rule    *Rule
+	Message string
+	Path    string
+	// Line is 1-based, or 0 if unknown
+	Line int
+}
+
+func (f *Finding) Location() string {
+	if f.Line == 0 {
+		return f.Path
+	}
+	return fmt.Sprintf("%s:%d", f.Path, f.Line)
+}
+
+// sourceKinds are the kinds policies are read as, by the kind the matcher builds them as
+var sourceKinds = map[matcher.PolicyKind]string{
+	matcher.NetworkPolicyV1:            kube.NetworkPolicyKind,
+	matcher.AdminNetworkPolicy:         kube.AdminNetworkPolicyKind,
+	matcher.BaselineAdminNetworkPolicy: kube.BaselineAdminNetworkPolicyKind,
+}
+
+type linter struct {
+	findings []*Finding
+	policies *kube.PoliciesFromPath
+}
+
+// LintPath reads policies from a file or a directory, and reports parse errors, invalid policies -- including ANPs and
+// BANPs which their CRDs would reject -- missing spec.policyTypes, shadowed rules and the use of removed ANP fields,
+// along with the lines they're on.
+// Documents of kinds other than policies are skipped.
+func LintPath(policyPath string) ([]*Finding, error) {
+	l := &linter{policies: kube.NewPoliciesFromPath()}
+	err := filepath.Walk(policyPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "unable to walk path %s", path)
+		}
+		if info.IsDir() {
+			return nil
+		}
+		docs, err := kube.ReadYamlDocuments(path)
+		if err != nil {
+			l.add(ParseErrorRule, path, kube.YamlErrorLine(err), err.Error())
+			return nil
+		}
+		for _, doc := range docs {
+			l.lintDocument(doc)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	policies, policyErrors := matcher.BuildV1AndV2NetPols(false, l.policies.NetworkPolicies, l.policies.AdminNetworkPolicies, l.policies.BaselineAdminNetworkPolicy)
+	for _, policyError := range policyErrors {
+		l.addPolicyError(policyError)
+	}
+	for _, shadowed := range policies.ShadowedRules() {
+		l.addShadowedRule(shadowed)
+	}
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		if l.findings[i].Path != l.findings[j].Path {
+			return l.findings[i].Path < l.findings[j].Path
+		}
+		return l.findings[i].Line < l.findings[j].Line
+	})
+	return l.findings, nil
+}
+
+func (l *linter) add(rule *Rule, path string, line int, message string) {
+	l.findings = append(l.findings, &Finding{Rule: rule, Message: message, Path: path, Line: line})
+}
+
+func (l *linter) lintDocument(doc *kube.YamlDocument) {
+	policyDocs, err := kube.PolicyDocuments(doc)
+	if err != nil {
+		l.add(ParseErrorRule, doc.Path, doc.Line(), err.Error())
+		return
+	}
+	for _, policyDoc := range policyDocs {
+		l.lintPolicyDocument(policyDoc)
+	}
+}
+
+func (l *linter) lintPolicyDocument(doc *kube.PolicyDocument) {
+	switch doc.Kind {
+	case kube.NetworkPolicyKind:
+		netpol, err := kube.ParseYamlDocument[networkingv1.NetworkPolicy](doc.YamlDocument, true)
+		if err != nil {
+			l.add(ParseErrorRule, doc.Path, doc.Line(), err.Error())
+			return
+		}
+		if len(netpol.Spec.PolicyTypes) == 0 {
+			l.lintMissingPolicyTypes(doc.YamlDocument, netpol)
+		}
+		l.policies.NetworkPolicies = append(l.policies.NetworkPolicies, netpol)
+		l.policies.AddSource(doc.Kind, netpol.Namespace, netpol.Name, doc.YamlDocument)
+	case kube.AdminNetworkPolicyKind:
+		anp, err := kube.ParseYamlDocument[v1alpha1.AdminNetworkPolicy](doc.YamlDocument, !l.lintSameLabels(doc.YamlDocument))
+		if err != nil {
+			l.add(ParseErrorRule, doc.Path, doc.Line(), err.Error())
+			return
+		}
+		if errs := validation.ValidateAdminNetworkPolicy(anp); len(errs) > 0 {
+			l.addValidationErrors(doc.YamlDocument, matcher.NewPolicyErrors(matcher.AdminNetworkPolicy, "", anp.Name, errs))
+			return
+		}
+		l.policies.AdminNetworkPolicies = append(l.policies.AdminNetworkPolicies, anp)
+		l.policies.AddSource(doc.Kind, "", anp.Name, doc.YamlDocument)
+	case kube.BaselineAdminNetworkPolicyKind:
+		banp, err := kube.ParseYamlDocument[v1alpha1.BaselineAdminNetworkPolicy](doc.YamlDocument, !l.lintSameLabels(doc.YamlDocument))
+		if err != nil {
+			l.add(ParseErrorRule, doc.Path, doc.Line(), err.Error())
+			return
+		}
+		if errs := validation.ValidateBaselineAdminNetworkPolicy(banp); len(errs) > 0 {
+			l.addValidationErrors(doc.YamlDocument, matcher.NewPolicyErrors(matcher.BaselineAdminNetworkPolicy, "", banp.Name, errs))
+			return
+		}
+		if existing := l.policies.BaselineAdminNetworkPolicy; existing != nil {
+			l.add(InvalidPolicyRule, doc.Path, doc.Line(), fmt.Sprintf("more than one BaselineAdminNetworkPolicy: %s is skipped in favor of %s", banp.Name, existing.Name))
+			return
+		}
+		l.policies.BaselineAdminNetworkPolicy = banp
+		l.policies.AddSource(doc.Kind, "", banp.Name, doc.YamlDocument)
+	}
+}
+
+// lintMissingPolicyTypes reports a network policy without spec.policyTypes, and what it's defaulted to.
+func (l *linter) lintMissingPolicyTypes(doc *kube.YamlDocument, netpol *networkingv1.NetworkPolicy) {
+	namespace := netpol.Namespace
+	if namespace == "" {
+		namespace = v1.NamespaceDefault
+	}
+	var types []string
+	for _, policyType := range kube.PolicyTypes(netpol) {
+		types = append(types, string(policyType))
+	}
+	message := fmt.Sprintf("network policy %s/%s has no spec.policyTypes; like kube, analysis defaults it to %s", namespace, netpol.Name, strings.Join(types, " and "))
+	l.add(MissingPolicyTypesRule, doc.Path, doc.FieldLine("spec.policyTypes"), message)
+}
+
+// lintSameLabels reports sameLabels and notSameLabels fields, and returns true if there are any.
+// Such policies are parsed leniently, so that the rest of the policy is still analyzed.
+func (l *linter) lintSameLabels(doc *kube.YamlDocument) bool {
+	found := false
+	for _, name := range []string{"sameLabels", "notSameLabels"} {
+		for _, key := range doc.FindKeys(name) {
+			l.add(DeprecatedSameLabelsRule, doc.Path, key.Line, fmt.Sprintf("%s was removed from the AdminNetworkPolicy API and is ignored", name))
+			found = true
+		}
+	}
+	return found
+}
+
+// addValidationErrors reports the errors from validating an ANP or BANP like its CRD does.  Such a policy would be
+// rejected by kube, so it's skipped by analysis.
+func (l *linter) addValidationErrors(doc *kube.YamlDocument, policyErrors []*matcher.PolicyError) {
+	for _, policyError := range policyErrors {
+		l.add(InvalidPolicyRule, doc.Path, doc.FieldLine(policyError.Field()), policyError.Error())
+	}
+}
+
+func (l *linter) addPolicyError(policyError *matcher.PolicyError) {
+	source := l.policies.Source(sourceKinds[policyError.Kind], policyError.Namespace, policyError.Name)
+	if source == nil {
+		// shouldn't happen: every policy that is built has a source
+		logrus.Errorf("no source found for %s %s", policyError.Kind, policyError.PolicyName())
+		return
+	}
+	l.add(InvalidPolicyRule, source.Path, source.FieldLine(policyError.Field()), policyError.Error())
+}
+
+func (l *linter) addShadowedRule(shadowed *matcher.ShadowedRule) {
+	source := l.policies.Source(sourceKinds[shadowed.Rule.Kind], "", shadowed.Rule.PolicyName)
+	if source == nil {
+		logrus.Errorf("no source found for %s %s", shadowed.Rule.Kind, shadowed.Rule.PolicyName)
+		return
+	}
+	direction := "egress"
+	if shadowed.IsIngress {
+		direction = "ingress"
+	}
+	shadowedBy := ""
+	if shadowed.ShadowedBy != nil {
+		shadowedBy = shadowed.ShadowedBy.String()
+	} else {
+		var netpols []string
+		for _, id := range shadowed.NetworkPolicies {
+			netpols = append(netpols, string(id))
+		}
+		shadowedBy = "network policies " + strings.Join(netpols, ", ")
+	}
+	message := fmt.Sprintf("%s %s is shadowed by %s (%s)", direction, shadowed.Rule.String(), shadowedBy, shadowed.Reason)
+	l.add(ShadowedRuleRule, source.Path, source.FieldLine(fmt.Sprintf("spec.%s[%d]", direction, shadowed.Rule.RuleIndex)), message)
+}
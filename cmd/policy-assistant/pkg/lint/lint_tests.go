@@ -0,0 +1,116 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func RunLintTests() {
+	Describe("LintPath", func() {
+		It("reports findings with their file and line", func() {
+			findings, err := LintPath("../../test/lint-policies")
+			Expect(err).To(Succeed())
+
+			type location struct {
+				ruleID string
+				path   string
+				line   int
+			}
+			var locations []location
+			for _, finding := range findings {
+				locations = append(locations, location{ruleID: finding.Rule.ID, path: finding.Path, line: finding.Line})
+			}
+			Expect(locations).To(Equal([]location{
+				{ruleID: ParseErrorRule.ID, path: "../../test/lint-policies/broken.yaml", line: 1},
+				{ruleID: MissingPolicyTypesRule.ID, path: "../../test/lint-policies/findings.yaml", line: 11},
+				{ruleID: ShadowedRuleRule.ID, path: "../../test/lint-policies/findings.yaml", line: 27},
+				{ruleID: DeprecatedSameLabelsRule.ID, path: "../../test/lint-policies/findings.yaml", line: 36},
+				{ruleID: InvalidPolicyRule.ID, path: "../../test/lint-policies/findings.yaml", line: 50},
+			}))
+		})
+
+		It("doesn't report anything for valid policies", func() {
+			findings, err := LintPath("../../test/example-policies/networkpolicies/simple-example")
+			Expect(err).To(Succeed())
+			Expect(findings).To(BeEmpty())
+		})
+
+		Describe("ANP and BANP validation", func() {
+			lintYaml := func(policy string) []*Finding {
+				path := filepath.Join(GinkgoT().TempDir(), "policy.yaml")
+				Expect(os.WriteFile(path, []byte(policy), 0644)).To(Succeed())
+				findings, err := LintPath(path)
+				Expect(err).To(Succeed())
+				return findings
+			}
+			anp := func(priority int, egressRule string) string {
+				return fmt.Sprintf(`apiVersion: policy.networking.k8s.io/v1alpha1
+kind: AdminNetworkPolicy
+metadata:
+  name: anp
+spec:
+  priority: %d
+  subject:
+    namespaces: {}
+  egress:
+%s`, priority, egressRule)
+			}
+			expectInvalid := func(findings []*Finding, line int, message string) {
+				Expect(findings).To(HaveLen(1))
+				Expect(findings[0].Rule).To(Equal(InvalidPolicyRule))
+				Expect(findings[0].Line).To(Equal(line))
+				Expect(findings[0].Message).To(ContainSubstring(message))
+			}
+			rule := func(name string, peer string) string {
+				return fmt.Sprintf(`  - name: %s
+    action: Allow
+    to:
+    - %s
+`, name, peer)
+			}
+
+			It("reports a priority out of range", func() {
+				expectInvalid(lintYaml(anp(1001, rule("r", "namespaces: {}"))), 6, "spec.priority")
+			})
+
+			It("reports a rule name over 100 characters", func() {
+				expectInvalid(lintYaml(anp(10, rule(strings.Repeat("a", 101), "namespaces: {}"))), 10, "spec.egress[0].name")
+			})
+
+			It("reports too many items", func() {
+				var domainNames []string
+				for i := 0; i < 26; i++ {
+					domainNames = append(domainNames, fmt.Sprintf("d%d.example.com", i))
+				}
+				findings := lintYaml(anp(10, rule("r", fmt.Sprintf("domainNames: [%s]", strings.Join(domainNames, ", ")))))
+				expectInvalid(findings, 13, "spec.egress[0].to[0].domainNames: Too many")
+			})
+
+			It("reports a bad domain name", func() {
+				expectInvalid(lintYaml(anp(10, rule("r", `domainNames: ["*.*.example.com"]`))), 13, "spec.egress[0].to[0].domainNames[0]")
+			})
+
+			It("reports a BANP not named default", func() {
+				findings := lintYaml(`apiVersion: policy.networking.k8s.io/v1alpha1
+kind: BaselineAdminNetworkPolicy
+metadata:
+  name: baseline
+spec:
+  subject:
+    namespaces: {}
+`)
+				expectInvalid(findings, 4, "metadata.name")
+			})
+		})
+
+		It("fails if the path doesn't exist", func() {
+			_, err := LintPath("../../test/example-policies/does-not-exist")
+			Expect(err).ToNot(Succeed())
+		})
+	})
+}
@@ -0,0 +1,120 @@
+package lint
+
+import (
+	"path/filepath"
+)
+
+const (
+	SarifVersion = "2.1.0"
+	SarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	toolName           = "policy-assistant"
+	toolInformationURI = "https://github.com/kubernetes-sigs/network-policy-api/tree/main/cmd/policy-assistant"
+)
+
+// SarifLog is a SARIF 2.1.0 log, with the subset of the format needed to report findings.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type SarifLog struct {
+	Version string      `json:"version"`
+	Schema  string      `json:"$schema"`
+	Runs    []*SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool    *SarifTool     `json:"tool"`
+	Results []*SarifResult `json:"results"`
+}
+
+type SarifTool struct {
+	Driver *SarifDriver `json:"driver"`
+}
+
+type SarifDriver struct {
+	Name           string       `json:"name"`
+	Version        string       `json:"version,omitempty"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*SarifRule `json:"rules"`
+}
+
+type SarifRule struct {
+	ID                   string                  `json:"id"`
+	ShortDescription     *SarifMessage           `json:"shortDescription"`
+	DefaultConfiguration *SarifRuleConfiguration `json:"defaultConfiguration"`
+}
+
+type SarifRuleConfiguration struct {
+	Level Level `json:"level"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+type SarifResult struct {
+	RuleID    string           `json:"ruleId"`
+	RuleIndex int              `json:"ruleIndex"`
+	Level     Level            `json:"level"`
+	Message   *SarifMessage    `json:"message"`
+	Locations []*SarifLocation `json:"locations"`
+}
+
+type SarifLocation struct {
+	PhysicalLocation *SarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type SarifPhysicalLocation struct {
+	ArtifactLocation *SarifArtifactLocation `json:"artifactLocation"`
+	Region           *SarifRegion           `json:"region,omitempty"`
+}
+
+type SarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// NewSarifLog builds a log with a single run holding the findings.  Paths are used as given, so that relative
+// paths are resolved against the root of the repository by code scanning tools.
+func NewSarifLog(findings []*Finding, toolVersion string) *SarifLog {
+	driver := &SarifDriver{Name: toolName, Version: toolVersion, InformationURI: toolInformationURI}
+	ruleIndexes := map[*Rule]int{}
+	for i, rule := range AllRules {
+		ruleIndexes[rule] = i
+		driver.Rules = append(driver.Rules, &SarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     &SarifMessage{Text: rule.Description},
+			DefaultConfiguration: &SarifRuleConfiguration{Level: rule.Level},
+		})
+	}
+
+	results := []*SarifResult{}
+	for _, finding := range findings {
+		location := &SarifPhysicalLocation{ArtifactLocation: &SarifArtifactLocation{URI: sarifURI(finding.Path)}}
+		if finding.Line > 0 {
+			location.Region = &SarifRegion{StartLine: finding.Line}
+		}
+		results = append(results, &SarifResult{
+			RuleID:    finding.Rule.ID,
+			RuleIndex: ruleIndexes[finding.Rule],
+			Level:     finding.Rule.Level,
+			Message:   &SarifMessage{Text: finding.Message},
+			Locations: []*SarifLocation{{PhysicalLocation: location}},
+		})
+	}
+
+	return &SarifLog{
+		Version: SarifVersion,
+		Schema:  SarifSchema,
+		Runs:    []*SarifRun{{Tool: &SarifTool{Driver: driver}, Results: results}},
+	}
+}
+
+func sarifURI(path string) string {
+	uri := filepath.ToSlash(filepath.Clean(path))
+	if filepath.IsAbs(path) {
+		return "file://" + uri
+	}
+	return uri
+}
@@ -0,0 +1,33 @@
+package lint
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func RunSarifTests() {
+	Describe("NewSarifLog", func() {
+		It("reports each finding with its rule and location", func() {
+			log := NewSarifLog([]*Finding{
+				{Rule: ShadowedRuleRule, Message: "shadowed", Path: "policies/anp.yaml", Line: 12},
+				{Rule: ParseErrorRule, Message: "unable to parse", Path: "policies/broken.yaml"},
+			}, "v1.2.3")
+
+			Expect(log.Version).To(Equal("2.1.0"))
+			Expect(log.Runs).To(HaveLen(1))
+			run := log.Runs[0]
+			Expect(run.Tool.Driver.Version).To(Equal("v1.2.3"))
+			Expect(run.Tool.Driver.Rules).To(HaveLen(len(AllRules)))
+
+			Expect(run.Results).To(HaveLen(2))
+			Expect(run.Results[0].RuleID).To(Equal("shadowed-rule"))
+			Expect(run.Tool.Driver.Rules[run.Results[0].RuleIndex].ID).To(Equal("shadowed-rule"))
+			Expect(run.Results[0].Level).To(Equal(LevelWarning))
+			Expect(run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI).To(Equal("policies/anp.yaml"))
+			Expect(run.Results[0].Locations[0].PhysicalLocation.Region.StartLine).To(Equal(12))
+
+			Expect(run.Results[1].Level).To(Equal(LevelError))
+			Expect(run.Results[1].Locations[0].PhysicalLocation.Region).To(BeNil())
+		})
+	})
+}
@@ -0,0 +1,15 @@
+package lint
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLint(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunLintTests()
+	RunSarifTests()
+	RunSpecs(t, "lint suite")
+}
@@ -2,51 +2,73 @@ package matcher
 
 import (
 	"fmt"
+	"net"
+	"strings"
 
 	"github.com/mattfenwick/cyclonus/pkg/kube"
-	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1/validation"
 )
 
-func BuildNetworkPolicies(simplify bool, netpols []*networkingv1.NetworkPolicy) *Policy {
+// BuildNetworkPolicies builds a Policy from v1 NetPols.  Invalid policies are skipped and returned as errors.
+func BuildNetworkPolicies(simplify bool, netpols []*networkingv1.NetworkPolicy) (*Policy, []*PolicyError) {
 	return BuildV1AndV2NetPols(simplify, netpols, nil, nil)
 }
 
-func BuildV1AndV2NetPols(simplify bool, netpols []*networkingv1.NetworkPolicy, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) *Policy {
+// BuildV1AndV2NetPols builds a Policy from v1 NetPols, ANPs and a BANP.
+// Invalid policies are skipped and returned as errors, so that the valid ones can still be analyzed.
+func BuildV1AndV2NetPols(simplify bool, netpols []*networkingv1.NetworkPolicy, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) (*Policy, []*PolicyError) {
 	np := NewPolicy()
+	var policyErrors []*PolicyError
 	for _, p := range netpols {
-		ingress, egress := BuildTarget(p)
+		ingress, egress, errs := BuildTarget(p)
+		if len(errs) > 0 {
+			policyErrors = append(policyErrors, errs...)
+			continue
+		}
 		np.AddTarget(true, ingress)
 		np.AddTarget(false, egress)
 	}
 
-	priorities := make(map[int32]struct{})
+	priorities := make(map[int32]string)
 	for _, p := range anps {
-		if _, ok := priorities[p.Spec.Priority]; ok {
-			panic(errors.Errorf("duplicate priorities are undefined. priority: %d", p.Spec.Priority))
+		if other, ok := priorities[p.Spec.Priority]; ok {
+			err := field.Invalid(field.NewPath("spec", "priority"), p.Spec.Priority, fmt.Sprintf("same priority as %s: duplicate priorities are undefined", other))
+			policyErrors = append(policyErrors, NewPolicyErrors(AdminNetworkPolicy, "", p.Name, field.ErrorList{err})...)
+			continue
 		}
-		priorities[p.Spec.Priority] = struct{}{}
 
-		ingress, egress := BuildTargetANP(p)
+		ingress, egress, errs := BuildTargetANP(p)
+		if len(errs) > 0 {
+			policyErrors = append(policyErrors, errs...)
+			continue
+		}
+		priorities[p.Spec.Priority] = p.Name
 		np.AddTarget(true, ingress)
 		np.AddTarget(false, egress)
 	}
 
 	if banp != nil {
 		// there can only be one BANP by definition
-		ingress, egress := BuildTargetBANP(banp)
-		np.AddTarget(true, ingress)
-		np.AddTarget(false, egress)
+		ingress, egress, errs := BuildTargetBANP(banp)
+		if len(errs) > 0 {
+			policyErrors = append(policyErrors, errs...)
+		} else {
+			np.AddTarget(true, ingress)
+			np.AddTarget(false, egress)
+		}
 	}
 
 	if simplify {
 		np.Simplify()
 	}
 
-	return np
+	return np, policyErrors
 }
 
 func getPolicyNamespace(policy *networkingv1.NetworkPolicy) string {
@@ -56,79 +78,99 @@ func getPolicyNamespace(policy *networkingv1.NetworkPolicy) string {
 	return policy.Namespace
 }
 
-func BuildTarget(netpol *networkingv1.NetworkPolicy) (*Target, *Target) {
+func BuildTarget(netpol *networkingv1.NetworkPolicy) (*Target, *Target, []*PolicyError) {
 	var ingress *Target
 	var egress *Target
-	if len(netpol.Spec.PolicyTypes) == 0 {
-		panic(errors.Errorf("invalid NetworkPolicy: need at least 1 type"))
-	}
 	policyNamespace := getPolicyNamespace(netpol)
-	for _, pType := range netpol.Spec.PolicyTypes {
+	var errs field.ErrorList
+	for _, pType := range kube.PolicyTypes(netpol) {
 		switch pType {
 		case networkingv1.PolicyTypeIngress:
+			peers, ingressErrs := BuildIngressMatcher(policyNamespace, netpol.Spec.Ingress)
+			errs = append(errs, ingressErrs...)
 			ingress = &Target{
 				SubjectMatcher: NewSubjectV1(policyNamespace, netpol.Spec.PodSelector),
 				SourceRules:    []NetPolID{netPolID(netpol)},
-				Peers:          BuildIngressMatcher(policyNamespace, netpol.Spec.Ingress),
+				Peers:          peers,
 			}
 		case networkingv1.PolicyTypeEgress:
+			peers, egressErrs := BuildEgressMatcher(policyNamespace, netpol.Spec.Egress)
+			errs = append(errs, egressErrs...)
 			egress = &Target{
 				SubjectMatcher: NewSubjectV1(policyNamespace, netpol.Spec.PodSelector),
 				SourceRules:    []NetPolID{netPolID(netpol)},
-				Peers:          BuildEgressMatcher(policyNamespace, netpol.Spec.Egress),
+				Peers:          peers,
 			}
 		}
 	}
-	return ingress, egress
+	if len(errs) > 0 {
+		return nil, nil, NewPolicyErrors(NetworkPolicyV1, policyNamespace, netpol.Name, errs)
+	}
+	return ingress, egress, nil
 }
 
-func BuildIngressMatcher(policyNamespace string, ingresses []networkingv1.NetworkPolicyIngressRule) []PeerMatcher {
+func BuildIngressMatcher(policyNamespace string, ingresses []networkingv1.NetworkPolicyIngressRule) ([]PeerMatcher, field.ErrorList) {
 	if len(ingresses) == 0 {
-		return []PeerMatcher{&NoMatcher{}}
+		return []PeerMatcher{&NoMatcher{}}, nil
 	}
 
 	var matchers []PeerMatcher
-	for _, ingress := range ingresses {
-		matchers = append(matchers, BuildPeerMatcher(policyNamespace, ingress.Ports, ingress.From)...)
-	}
-	return matchers
+	var errs field.ErrorList
+	for i, ingress := range ingresses {
+		rulePath := field.NewPath("spec", "ingress").Index(i)
+		peers, peerErrs := BuildPeerMatcher(rulePath.Child("from"), rulePath.Child("ports"), policyNamespace, ingress.Ports, ingress.From)
+		matchers = append(matchers, peers...)
+		errs = append(errs, peerErrs...)
+	}
+	return matchers, errs
 }
 
-func BuildEgressMatcher(policyNamespace string, egresses []networkingv1.NetworkPolicyEgressRule) []PeerMatcher {
+func BuildEgressMatcher(policyNamespace string, egresses []networkingv1.NetworkPolicyEgressRule) ([]PeerMatcher, field.ErrorList) {
 	if len(egresses) == 0 {
-		return []PeerMatcher{&NoMatcher{}}
+		return []PeerMatcher{&NoMatcher{}}, nil
 	}
 
 	var matchers []PeerMatcher
-	for _, egress := range egresses {
-		matchers = append(matchers, BuildPeerMatcher(policyNamespace, egress.Ports, egress.To)...)
-	}
-	return matchers
+	var errs field.ErrorList
+	for i, egress := range egresses {
+		rulePath := field.NewPath("spec", "egress").Index(i)
+		peers, peerErrs := BuildPeerMatcher(rulePath.Child("to"), rulePath.Child("ports"), policyNamespace, egress.Ports, egress.To)
+		matchers = append(matchers, peers...)
+		errs = append(errs, peerErrs...)
+	}
+	return matchers, errs
 }
 
-func BuildPeerMatcher(policyNamespace string, npPorts []networkingv1.NetworkPolicyPort, peers []networkingv1.NetworkPolicyPeer) []PeerMatcher {
+func BuildPeerMatcher(peersPath *field.Path, portsPath *field.Path, policyNamespace string, npPorts []networkingv1.NetworkPolicyPort, peers []networkingv1.NetworkPolicyPeer) ([]PeerMatcher, field.ErrorList) {
 	if len(npPorts) == 0 && len(peers) == 0 {
-		return []PeerMatcher{AllPeersPorts}
+		return []PeerMatcher{AllPeersPorts}, nil
 	}
 	// 1. build port matcher
-	port := BuildPortMatcher(npPorts)
+	port, errs := BuildPortMatcher(portsPath, npPorts)
 	// 2. build Peers
 	if len(peers) == 0 {
-		return []PeerMatcher{&PortsForAllPeersMatcher{Port: port}}
+		return []PeerMatcher{&PortsForAllPeersMatcher{Port: port}}, errs
 	}
 
 	var matchers []PeerMatcher
-	for _, from := range peers {
+	for i, from := range peers {
+		peerPath := peersPath.Index(i)
 		ip, ns, pod := BuildIPBlockNamespacePodMatcher(policyNamespace, from)
 		// invalid netpol guards
 		if ip == nil && ns == nil && pod == nil {
-			panic(errors.Errorf("invalid NetworkPolicyPeer: all of IPBlock, NamespaceSelector, and PodSelector are nil"))
+			errs = append(errs, field.Required(peerPath, "all of IPBlock, NamespaceSelector, and PodSelector are nil"))
+			continue
 		}
-		if ip != nil && (ns != nil || pod != nil) {
-			panic(errors.Errorf("invalid NetworkPolicyPeer: if NamespaceSelector or PodSelector is non-nil, IPBlock must be nil"))
+		if ip != nil && (from.NamespaceSelector != nil || from.PodSelector != nil) {
+			errs = append(errs, field.Forbidden(peerPath.Child("ipBlock"), "if NamespaceSelector or PodSelector is non-nil, IPBlock must be nil"))
+			continue
 		}
 		// process a valid netpol
 		if ip != nil {
+			if ipErrs := validateIPBlock(peerPath.Child("ipBlock"), ip.IPBlock); len(ipErrs) > 0 {
+				errs = append(errs, ipErrs...)
+				continue
+			}
 			ip.Port = port
 			matchers = append(matchers, ip)
 		} else {
@@ -139,7 +181,27 @@ func BuildPeerMatcher(policyNamespace string, npPorts []networkingv1.NetworkPoli
 			})
 		}
 	}
-	return matchers
+	return matchers, errs
+}
+
+// validateIPBlock checks that the CIDR and excepts parse, and that, as kube requires, each except is within the CIDR,
+// and so of the same IP family.
+func validateIPBlock(fldPath *field.Path, ipBlock *networkingv1.IPBlock) field.ErrorList {
+	var errs field.ErrorList
+	_, _, cidrErr := net.ParseCIDR(ipBlock.CIDR)
+	if cidrErr != nil {
+		errs = append(errs, field.Invalid(fldPath.Child("cidr"), ipBlock.CIDR, "unable to parse CIDR"))
+	}
+	for i, except := range ipBlock.Except {
+		if _, _, err := net.ParseCIDR(except); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("except").Index(i), except, "unable to parse CIDR"))
+		} else if cidrErr == nil {
+			if isSubset, _ := kube.IsCIDRSubset(except, ipBlock.CIDR); !isSubset {
+				errs = append(errs, field.Invalid(fldPath.Child("except").Index(i), except, "must be within the CIDR "+ipBlock.CIDR))
+			}
+		}
+	}
+	return errs
 }
 
 func BuildIPBlockNamespacePodMatcher(policyNamespace string, peer networkingv1.NetworkPolicyPeer) (*IPPeerMatcher, NamespaceMatcher, PodMatcher) {
@@ -171,24 +233,27 @@ func BuildIPBlockNamespacePodMatcher(policyNamespace string, peer networkingv1.N
 	return nil, nsMatcher, podMatcher
 }
 
-func BuildPortMatcher(npPorts []networkingv1.NetworkPolicyPort) PortMatcher {
+func BuildPortMatcher(fldPath *field.Path, npPorts []networkingv1.NetworkPolicyPort) (PortMatcher, field.ErrorList) {
 	if len(npPorts) == 0 {
-		return &AllPortMatcher{}
+		return &AllPortMatcher{}, nil
 	} else {
 		matcher := &SpecificPortMatcher{}
-		for _, p := range npPorts {
-			singlePort, portRange := BuildSinglePortMatcher(p)
-			if singlePort != nil {
+		var errs field.ErrorList
+		for i, p := range npPorts {
+			singlePort, portRange, err := BuildSinglePortMatcher(fldPath.Index(i), p)
+			if err != nil {
+				errs = append(errs, err)
+			} else if singlePort != nil {
 				matcher.Ports = append(matcher.Ports, singlePort)
 			} else {
 				matcher.PortRanges = append(matcher.PortRanges, portRange)
 			}
 		}
-		return matcher
+		return matcher, errs
 	}
 }
 
-func BuildSinglePortMatcher(npPort networkingv1.NetworkPolicyPort) (*PortProtocolMatcher, *PortRangeMatcher) {
+func BuildSinglePortMatcher(fldPath *field.Path, npPort networkingv1.NetworkPolicyPort) (*PortProtocolMatcher, *PortRangeMatcher, *field.Error) {
 	protocol := v1.ProtocolTCP
 	if npPort.Protocol != nil {
 		protocol = *npPort.Protocol
@@ -197,32 +262,35 @@ func BuildSinglePortMatcher(npPort networkingv1.NetworkPolicyPort) (*PortProtoco
 		return &PortProtocolMatcher{
 			Port:     npPort.Port,
 			Protocol: protocol,
-		}, nil
+		}, nil, nil
 	}
 	// we have a port range: make sure it's valid
 	if npPort.Port == nil {
-		panic(errors.Errorf("invalid port range: start port is nil"))
+		return nil, nil, field.Required(fldPath.Child("port"), "invalid port range: start port is nil")
 	}
 	if npPort.Port.Type == intstr.String {
-		panic(errors.Errorf("invalid port range: start port is string"))
+		return nil, nil, field.Invalid(fldPath.Child("port"), npPort.Port.StrVal, "invalid port range: start port is string")
 	}
 	if *npPort.EndPort < npPort.Port.IntVal {
-		panic(errors.Errorf("invalid port range: end port < start port"))
+		return nil, nil, field.Invalid(fldPath.Child("endPort"), *npPort.EndPort, "invalid port range: end port < start port")
 	}
 	return nil, &PortRangeMatcher{
 		From:     int(npPort.Port.IntVal),
 		To:       int(*npPort.EndPort),
 		Protocol: protocol,
-	}
+	}, nil
 }
 
-func BuildTargetANP(anp *v1alpha1.AdminNetworkPolicy) (*Target, *Target) {
+func BuildTargetANP(anp *v1alpha1.AdminNetworkPolicy) (*Target, *Target, []*PolicyError) {
+	specPath := field.NewPath("spec")
 	if len(anp.Spec.Ingress) == 0 && len(anp.Spec.Egress) == 0 {
-		panic(errors.Errorf("invalid AdminNetworkPolicy: need at least one egress or ingress rule"))
+		err := field.Required(specPath, "need at least one egress or ingress rule")
+		return nil, nil, NewPolicyErrors(AdminNetworkPolicy, "", anp.Name, field.ErrorList{err})
 	}
 
 	var ingress *Target
 	var egress *Target
+	errs := validateSubjectAdmin(specPath.Child("subject"), &anp.Spec.Subject)
 
 	if len(anp.Spec.Ingress) > 0 {
 		ingress = &Target{
@@ -230,11 +298,16 @@ func BuildTargetANP(anp *v1alpha1.AdminNetworkPolicy) (*Target, *Target) {
 			SourceRules:    []NetPolID{netPolID(anp)},
 		}
 
-		for _, r := range anp.Spec.Ingress {
-			v := AdminActionToVerdict(r.Action)
-			matchers := BuildPeerMatcherAdmin(r.From, r.Ports)
+		for i, r := range anp.Spec.Ingress {
+			rulePath := specPath.Child("ingress").Index(i)
+			v, err := AdminActionToVerdict(r.Action)
+			if err != nil {
+				errs = append(errs, field.NotSupported(rulePath.Child("action"), r.Action, []string{string(v1alpha1.AdminNetworkPolicyRuleActionAllow), string(v1alpha1.AdminNetworkPolicyRuleActionDeny), string(v1alpha1.AdminNetworkPolicyRuleActionPass)}))
+			}
+			matchers, ruleErrs := buildRuleMatchersAdmin(rulePath.Child("from"), rulePath.Child("ports"), ingressPeersToEgressPeers(r.From), r.Ports)
+			errs = append(errs, ruleErrs...)
 			for _, m := range matchers {
-				matcherAdmin := NewPeerMatcherANP(m, v, int(anp.Spec.Priority), anp.Name, r.Name)
+				matcherAdmin := NewPeerMatcherANP(m, v, int(anp.Spec.Priority), anp.Name, i, r.Name)
 				ingress.Peers = append(ingress.Peers, matcherAdmin)
 			}
 		}
@@ -246,26 +319,44 @@ func BuildTargetANP(anp *v1alpha1.AdminNetworkPolicy) (*Target, *Target) {
 			SourceRules:    []NetPolID{netPolID(anp)},
 		}
 
-		for _, r := range anp.Spec.Egress {
-			v := AdminActionToVerdict(r.Action)
-			matchers := BuildPeerMatcherAdmin(r.To, r.Ports)
+		for i, r := range anp.Spec.Egress {
+			rulePath := specPath.Child("egress").Index(i)
+			v, err := AdminActionToVerdict(r.Action)
+			if err != nil {
+				errs = append(errs, field.NotSupported(rulePath.Child("action"), r.Action, []string{string(v1alpha1.AdminNetworkPolicyRuleActionAllow), string(v1alpha1.AdminNetworkPolicyRuleActionDeny), string(v1alpha1.AdminNetworkPolicyRuleActionPass)}))
+			}
+			if r.Action != v1alpha1.AdminNetworkPolicyRuleActionAllow {
+				for j, peer := range r.To {
+					if peer.DomainNames != nil {
+						errs = append(errs, field.Forbidden(rulePath.Child("to").Index(j).Child("domainNames"), "only allowed in rules with action Allow"))
+					}
+				}
+			}
+			matchers, ruleErrs := buildRuleMatchersAdmin(rulePath.Child("to"), rulePath.Child("ports"), r.To, r.Ports)
+			errs = append(errs, ruleErrs...)
 			for _, m := range matchers {
-				matcherAdmin := NewPeerMatcherANP(m, v, int(anp.Spec.Priority), anp.Name, r.Name)
+				matcherAdmin := NewPeerMatcherANP(m, v, int(anp.Spec.Priority), anp.Name, i, r.Name)
 				egress.Peers = append(egress.Peers, matcherAdmin)
 			}
 		}
 	}
 
-	return ingress, egress
+	if len(errs) > 0 {
+		return nil, nil, NewPolicyErrors(AdminNetworkPolicy, "", anp.Name, errs)
+	}
+	return ingress, egress, nil
 }
 
-func BuildTargetBANP(banp *v1alpha1.BaselineAdminNetworkPolicy) (*Target, *Target) {
+func BuildTargetBANP(banp *v1alpha1.BaselineAdminNetworkPolicy) (*Target, *Target, []*PolicyError) {
+	specPath := field.NewPath("spec")
 	if len(banp.Spec.Ingress) == 0 && len(banp.Spec.Egress) == 0 {
-		panic(errors.Errorf("invalid BaselineAdminNetworkPolicy: need at least one egress or ingress rule"))
+		err := field.Required(specPath, "need at least one egress or ingress rule")
+		return nil, nil, NewPolicyErrors(BaselineAdminNetworkPolicy, "", banp.Name, field.ErrorList{err})
 	}
 
 	var ingress *Target
 	var egress *Target
+	errs := validateSubjectAdmin(specPath.Child("subject"), &banp.Spec.Subject)
 
 	if len(banp.Spec.Ingress) > 0 {
 		ingress = &Target{
@@ -273,11 +364,16 @@ func BuildTargetBANP(banp *v1alpha1.BaselineAdminNetworkPolicy) (*Target, *Targe
 			SourceRules:    []NetPolID{netPolID(banp)},
 		}
 
-		for _, r := range banp.Spec.Ingress {
-			v := BaselineAdminActionToVerdict(r.Action)
-			matchers := BuildPeerMatcherAdmin(r.From, r.Ports)
+		for i, r := range banp.Spec.Ingress {
+			rulePath := specPath.Child("ingress").Index(i)
+			v, err := BaselineAdminActionToVerdict(r.Action)
+			if err != nil {
+				errs = append(errs, field.NotSupported(rulePath.Child("action"), r.Action, []string{string(v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow), string(v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny)}))
+			}
+			matchers, ruleErrs := buildRuleMatchersAdmin(rulePath.Child("from"), rulePath.Child("ports"), ingressPeersToEgressPeers(r.From), r.Ports)
+			errs = append(errs, ruleErrs...)
 			for _, m := range matchers {
-				matcherAdmin := NewPeerMatcherBANP(m, v, banp.Name, r.Name)
+				matcherAdmin := NewPeerMatcherBANP(m, v, banp.Name, i, r.Name)
 				ingress.Peers = append(ingress.Peers, matcherAdmin)
 			}
 		}
@@ -289,91 +385,157 @@ func BuildTargetBANP(banp *v1alpha1.BaselineAdminNetworkPolicy) (*Target, *Targe
 			SourceRules:    []NetPolID{netPolID(banp)},
 		}
 
-		for _, r := range banp.Spec.Egress {
-			v := BaselineAdminActionToVerdict(r.Action)
-			matchers := BuildPeerMatcherAdmin(r.To, r.Ports)
+		for i, r := range banp.Spec.Egress {
+			rulePath := specPath.Child("egress").Index(i)
+			v, err := BaselineAdminActionToVerdict(r.Action)
+			if err != nil {
+				errs = append(errs, field.NotSupported(rulePath.Child("action"), r.Action, []string{string(v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow), string(v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny)}))
+			}
+			matchers, ruleErrs := buildRuleMatchersAdmin(rulePath.Child("to"), rulePath.Child("ports"), baselineEgressPeersToEgressPeers(r.To), r.Ports)
+			errs = append(errs, ruleErrs...)
 			for _, m := range matchers {
-				matcherAdmin := NewPeerMatcherBANP(m, v, banp.Name, r.Name)
+				matcherAdmin := NewPeerMatcherBANP(m, v, banp.Name, i, r.Name)
 				egress.Peers = append(egress.Peers, matcherAdmin)
 			}
 		}
 	}
 
-	return ingress, egress
+	if len(errs) > 0 {
+		return nil, nil, NewPolicyErrors(BaselineAdminNetworkPolicy, "", banp.Name, errs)
+	}
+	return ingress, egress, nil
 }
 
-func BuildPeerMatcherAdmin(peers []v1alpha1.AdminNetworkPolicyPeer, ports *[]v1alpha1.AdminNetworkPolicyPort) []*PodPeerMatcher {
-	if len(peers) == 0 {
-		panic(errors.Errorf("invalid admin to/from field: must have at least one peer"))
+func validateSubjectAdmin(fldPath *field.Path, subject *v1alpha1.AdminNetworkPolicySubject) field.ErrorList {
+	if (subject.Namespaces == nil) == (subject.Pods == nil) {
+		return field.ErrorList{field.Invalid(fldPath, subject, "must have exactly one of Namespaces or Pods")}
 	}
+	return nil
+}
 
-	// 1. build port matcher
+// buildRuleMatchersAdmin builds the matchers for the peers and ports of an ANP/BANP rule.
+func buildRuleMatchersAdmin(peersPath *field.Path, portsPath *field.Path, peers []v1alpha1.AdminNetworkPolicyEgressPeer, ports *[]v1alpha1.AdminNetworkPolicyPort) ([]PeerMatcher, field.ErrorList) {
 	var portMatcher PortMatcher
+	var errs field.ErrorList
 	if ports == nil {
-		portMatcher = BuildPortMatcherAdmin(nil)
+		portMatcher, errs = BuildPortMatcherAdmin(portsPath, nil)
 	} else {
-		portMatcher = BuildPortMatcherAdmin(*ports)
+		portMatcher, errs = BuildPortMatcherAdmin(portsPath, *ports)
 	}
+	matchers, peerErrs := BuildPeerMatcherAdmin(peersPath, peers, portMatcher)
+	return matchers, append(errs, peerErrs...)
+}
 
-	// 2. build Peers
-	var peerMatchers []*PodPeerMatcher
-	for _, peer := range peers {
-		if (peer.Namespaces == nil && peer.Pods == nil) || (peer.Namespaces != nil && peer.Pods != nil) {
-			panic(errors.Errorf("invalid admin peer: must have exactly one of Namespaces or Pods"))
+// ingressPeersToEgressPeers converts ingress peers to egress peers, since the
+// egress peer is a superset of the ingress peer.
+func ingressPeersToEgressPeers(peers []v1alpha1.AdminNetworkPolicyIngressPeer) []v1alpha1.AdminNetworkPolicyEgressPeer {
+	egressPeers := make([]v1alpha1.AdminNetworkPolicyEgressPeer, len(peers))
+	for i, p := range peers {
+		egressPeers[i] = v1alpha1.AdminNetworkPolicyEgressPeer{
+			Namespaces: p.Namespaces,
+			Pods:       p.Pods,
 		}
+	}
+	return egressPeers
+}
 
-		var ns v1alpha1.NamespacedPeer
-		var podMatcher PodMatcher
-		if peer.Pods != nil {
-			ns = peer.Pods.Namespaces
-
-			// TODO account for Tenancy or Pod same/not-same labels when these become features
-			podSel := peer.Pods.PodSelector
-			if kube.IsLabelSelectorEmpty(podSel) {
-				podMatcher = &AllPodMatcher{}
-			} else {
-				podMatcher = &LabelSelectorPodMatcher{Selector: podSel}
-			}
-		} else {
-			// peer.Namespaces is non-nil
-			ns = *peer.Namespaces
-			podMatcher = &AllPodMatcher{}
+// baselineEgressPeersToEgressPeers converts BANP egress peers to ANP egress peers.
+// The ANP egress peer is a superset of the BANP egress peer.
+func baselineEgressPeersToEgressPeers(peers []v1alpha1.BaselineAdminNetworkPolicyEgressPeer) []v1alpha1.AdminNetworkPolicyEgressPeer {
+	egressPeers := make([]v1alpha1.AdminNetworkPolicyEgressPeer, len(peers))
+	for i, p := range peers {
+		egressPeers[i] = v1alpha1.AdminNetworkPolicyEgressPeer{
+			Namespaces: p.Namespaces,
+			Pods:       p.Pods,
+			Nodes:      p.Nodes,
+			Networks:   p.Networks,
 		}
+	}
+	return egressPeers
+}
+
+// BuildPeerMatcherAdmin builds the matchers for the to/from peers of an ANP/BANP rule.
+// fldPath is the path of the to/from field.
+func BuildPeerMatcherAdmin(fldPath *field.Path, peers []v1alpha1.AdminNetworkPolicyEgressPeer, portMatcher PortMatcher) ([]PeerMatcher, field.ErrorList) {
+	if len(peers) == 0 {
+		return nil, field.ErrorList{field.Required(fldPath, "must have at least one peer")}
+	}
 
+	var peerMatchers []PeerMatcher
+	var errs field.ErrorList
+	for i, peer := range peers {
+		peerPath := fldPath.Index(i)
 		nonNilCount := 0
-		if ns.NamespaceSelector != nil {
+		if peer.Namespaces != nil {
+			nonNilCount++
+		}
+		if peer.Pods != nil {
+			nonNilCount++
+		}
+		if peer.Nodes != nil {
 			nonNilCount++
 		}
-		if ns.SameLabels != nil {
-			fmt.Println("WARN: SameLabels is deprecated and will be removed after v0.1.1 (alpha) of sigs.k8s.io/network-policy-api. Tenancy will replace this concept.")
+		if peer.Networks != nil {
 			nonNilCount++
 		}
-		if ns.NotSameLabels != nil {
-			fmt.Println("WARN: NotSameLabels is deprecated and will be removed after v0.1.1 (alpha) of sigs.k8s.io/network-policy-api. Tenancy will replace this concept.")
+		if peer.DomainNames != nil {
 			nonNilCount++
 		}
 		if nonNilCount != 1 {
-			panic(errors.Errorf("invalid admin peer: must have exactly one of NamespaceSelector, SameLabels, or NotSameLabels"))
+			errs = append(errs, field.Invalid(peerPath, peer, "must have exactly one of Namespaces, Pods, Nodes, Networks, or DomainNames"))
+			continue
 		}
 
-		var nsMatcher NamespaceMatcher
-		if ns.NamespaceSelector != nil {
-			if kube.IsLabelSelectorEmpty(*ns.NamespaceSelector) {
-				nsMatcher = &AllNamespaceMatcher{}
+		if peer.Networks != nil {
+			m, peerErrs := BuildNetworksPeerMatcherAdmin(peerPath.Child("networks"), peer.Networks, portMatcher)
+			if len(peerErrs) > 0 {
+				errs = append(errs, peerErrs...)
 			} else {
-				nsMatcher = &LabelSelectorNamespaceMatcher{
-					Selector: *ns.NamespaceSelector,
-				}
+				peerMatchers = append(peerMatchers, m)
 			}
-		} else if ns.SameLabels != nil {
-			nsMatcher = &SameLabelsNamespaceMatcher{
-				labels: ns.SameLabels,
+			continue
+		}
+		if peer.Nodes != nil {
+			m, peerErrs := BuildNodePeerMatcherAdmin(peerPath.Child("nodes"), *peer.Nodes, portMatcher)
+			if len(peerErrs) > 0 {
+				errs = append(errs, peerErrs...)
+			} else {
+				peerMatchers = append(peerMatchers, m)
 			}
-		} else {
-			// ns.NotSameLabels is non-nil
-			nsMatcher = &NotSameLabelsNamespaceMatcher{
-				labels: ns.NotSameLabels,
+			continue
+		}
+		if peer.DomainNames != nil {
+			m, peerErrs := BuildDomainNamesPeerMatcherAdmin(peerPath.Child("domainNames"), peer.DomainNames, portMatcher)
+			if len(peerErrs) > 0 {
+				errs = append(errs, peerErrs...)
+			} else {
+				peerMatchers = append(peerMatchers, m)
 			}
+			continue
+		}
+
+		var nsSel metav1.LabelSelector
+		var podMatcher PodMatcher
+		if peer.Pods != nil {
+			nsSel = peer.Pods.NamespaceSelector
+			podSel := peer.Pods.PodSelector
+			if kube.IsLabelSelectorEmpty(podSel) {
+				podMatcher = &AllPodMatcher{}
+			} else {
+				podMatcher = &LabelSelectorPodMatcher{Selector: podSel}
+			}
+		} else {
+			// peer.Namespaces is non-nil.  It's a plain label selector: sameLabels and notSameLabels, and their
+			// matchers, went away with the NamespacedPeer type of older API versions, and lint reports them instead.
+			nsSel = *peer.Namespaces
+			podMatcher = &AllPodMatcher{}
+		}
+
+		var nsMatcher NamespaceMatcher
+		if kube.IsLabelSelectorEmpty(nsSel) {
+			nsMatcher = &AllNamespaceMatcher{}
+		} else {
+			nsMatcher = &LabelSelectorNamespaceMatcher{Selector: nsSel}
 		}
 
 		m := &PodPeerMatcher{
@@ -384,27 +546,105 @@ func BuildPeerMatcherAdmin(peers []v1alpha1.AdminNetworkPolicyPeer, ports *[]v1a
 		peerMatchers = append(peerMatchers, m)
 	}
 
-	return peerMatchers
+	return peerMatchers, errs
 }
 
-func BuildPortMatcherAdmin(ports []v1alpha1.AdminNetworkPolicyPort) PortMatcher {
+// BuildNetworksPeerMatcherAdmin builds a matcher for the networks peer of an ANP/BANP egress rule.
+func BuildNetworksPeerMatcherAdmin(fldPath *field.Path, networks []v1alpha1.CIDR, portMatcher PortMatcher) (*NetworksPeerMatcher, field.ErrorList) {
+	if len(networks) == 0 {
+		return nil, field.ErrorList{field.Required(fldPath, "must have at least one CIDR")}
+	}
+	var errs field.ErrorList
+	if hasNamedPort(portMatcher) {
+		errs = append(errs, field.Forbidden(fldPath, "cannot be used with a NamedPort"))
+	}
+
+	cidrs := make([]string, len(networks))
+	for i, n := range networks {
+		cidr := string(n)
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Index(i), cidr, "unable to parse CIDR"))
+		}
+		cidrs[i] = cidr
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return &NetworksPeerMatcher{
+		Networks: cidrs,
+		Port:     portMatcher,
+	}, nil
+}
+
+// BuildNodePeerMatcherAdmin builds a matcher for the nodes peer of an ANP/BANP egress rule.
+func BuildNodePeerMatcherAdmin(fldPath *field.Path, nodes metav1.LabelSelector, portMatcher PortMatcher) (*NodePeerMatcher, field.ErrorList) {
+	if hasNamedPort(portMatcher) {
+		return nil, field.ErrorList{field.Forbidden(fldPath, "cannot be used with a NamedPort")}
+	}
+	return &NodePeerMatcher{
+		Selector: nodes,
+		Port:     portMatcher,
+	}, nil
+}
+
+// BuildDomainNamesPeerMatcherAdmin builds a matcher for the domainNames peer of an ANP egress rule.  Domain names must
+// match the CRD's pattern, and be lowercase.
+func BuildDomainNamesPeerMatcherAdmin(fldPath *field.Path, domainNames []v1alpha1.DomainName, portMatcher PortMatcher) (*DomainNamesPeerMatcher, field.ErrorList) {
+	if len(domainNames) == 0 {
+		return nil, field.ErrorList{field.Required(fldPath, "must have at least one domain name")}
+	}
+	var errs field.ErrorList
+	names := make([]string, len(domainNames))
+	for i, d := range domainNames {
+		domainErrs := validation.ValidateDomainName(d, fldPath.Index(i))
+		if len(domainErrs) == 0 && strings.ToLower(string(d)) != string(d) {
+			domainErrs = field.ErrorList{field.Invalid(fldPath.Index(i), d, "must be lowercase")}
+		}
+		errs = append(errs, domainErrs...)
+		names[i] = string(d)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &DomainNamesPeerMatcher{
+		DomainNames: names,
+		Port:        portMatcher,
+	}, nil
+}
+
+func hasNamedPort(portMatcher PortMatcher) bool {
+	if specific, ok := portMatcher.(*SpecificPortMatcher); ok {
+		for _, p := range specific.Ports {
+			if p.Port != nil && p.Port.Type == intstr.String {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func BuildPortMatcherAdmin(fldPath *field.Path, ports []v1alpha1.AdminNetworkPolicyPort) (PortMatcher, field.ErrorList) {
 	if len(ports) == 0 {
-		return &AllPortMatcher{}
+		return &AllPortMatcher{}, nil
 	} else {
 		matcher := &SpecificPortMatcher{}
-		for _, p := range ports {
-			singlePort, portRange := BuildSinglePortMatcherAdmin(p)
-			if singlePort != nil {
+		var errs field.ErrorList
+		for i, p := range ports {
+			singlePort, portRange, err := BuildSinglePortMatcherAdmin(fldPath.Index(i), p)
+			if err != nil {
+				errs = append(errs, err)
+			} else if singlePort != nil {
 				matcher.Ports = append(matcher.Ports, singlePort)
 			} else {
 				matcher.PortRanges = append(matcher.PortRanges, portRange)
 			}
 		}
-		return matcher
+		return matcher, errs
 	}
 }
 
-func BuildSinglePortMatcherAdmin(port v1alpha1.AdminNetworkPolicyPort) (*PortProtocolMatcher, *PortRangeMatcher) {
+func BuildSinglePortMatcherAdmin(fldPath *field.Path, port v1alpha1.AdminNetworkPolicyPort) (*PortProtocolMatcher, *PortRangeMatcher, *field.Error) {
 	nonNilCount := 0
 	if port.PortNumber != nil {
 		nonNilCount++
@@ -416,7 +656,7 @@ func BuildSinglePortMatcherAdmin(port v1alpha1.AdminNetworkPolicyPort) (*PortPro
 		nonNilCount++
 	}
 	if nonNilCount != 1 {
-		panic(errors.Errorf("invalid port: must have exactly one of PortNumber, NamedPort, or PortRange"))
+		return nil, nil, field.Invalid(fldPath, port, "must have exactly one of PortNumber, NamedPort, or PortRange")
 	}
 
 	if port.PortNumber != nil {
@@ -431,7 +671,7 @@ func BuildSinglePortMatcherAdmin(port v1alpha1.AdminNetworkPolicyPort) (*PortPro
 			Protocol: proto,
 		}
 
-		return m, nil
+		return m, nil, nil
 	}
 
 	if port.NamedPort != nil {
@@ -451,7 +691,7 @@ func BuildSinglePortMatcherAdmin(port v1alpha1.AdminNetworkPolicyPort) (*PortPro
 			Protocol: proto,
 		}
 
-		return m, nil
+		return m, nil, nil
 	}
 
 	// port.PortRange is non-nil
@@ -462,14 +702,14 @@ func BuildSinglePortMatcherAdmin(port v1alpha1.AdminNetworkPolicyPort) (*PortPro
 	}
 
 	if port.PortRange.Start >= port.PortRange.End {
-		panic(errors.Errorf("invalid port range: start >= end"))
+		return nil, nil, field.Invalid(fldPath.Child("portRange"), port.PortRange, "start must be less than end")
 	}
 
 	return nil, &PortRangeMatcher{
 		From:     int(port.PortRange.Start),
 		To:       int(port.PortRange.End),
 		Protocol: proto,
-	}
+	}, nil
 }
 
 func endsIn(s string, suffix string) bool {
@@ -10,6 +10,8 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 )
 
 var (
@@ -25,7 +27,8 @@ var (
 func RunBuilderTests() {
 	Describe("BuildTarget: Allow none -- nil egress/ingress", func() {
 		It("allow-no-ingress", func() {
-			ingress, egress := BuildTarget(netpol.AllowNoIngress)
+			ingress, egress, errs := BuildTarget(netpol.AllowNoIngress)
+			Expect(errs).To(BeEmpty())
 
 			Expect(ingress).ToNot(BeNil())
 			Expect(ingress.Peers).To(Equal([]PeerMatcher{&NoMatcher{}}))
@@ -34,7 +37,8 @@ func RunBuilderTests() {
 		})
 
 		It("allow-no-egress", func() {
-			ingress, egress := BuildTarget(netpol.AllowNoEgress)
+			ingress, egress, errs := BuildTarget(netpol.AllowNoEgress)
+			Expect(errs).To(BeEmpty())
 
 			Expect(egress).ToNot(BeNil())
 			Expect(egress.Peers).To(Equal([]PeerMatcher{&NoMatcher{}}))
@@ -43,7 +47,8 @@ func RunBuilderTests() {
 		})
 
 		It("allow-neither", func() {
-			ingress, egress := BuildTarget(netpol.AllowNoIngressAllowNoEgress)
+			ingress, egress, errs := BuildTarget(netpol.AllowNoIngressAllowNoEgress)
+			Expect(errs).To(BeEmpty())
 
 			Expect(egress).ToNot(BeNil())
 			Expect(egress.Peers).To(Equal([]PeerMatcher{&NoMatcher{}}))
@@ -55,7 +60,7 @@ func RunBuilderTests() {
 
 	Describe("BuildTarget: missing namespace gets treated as default namespace", func() {
 		It("missing namespace", func() {
-			ingress, egress := BuildTarget(&networkingv1.NetworkPolicy{
+			ingress, egress, errs := BuildTarget(&networkingv1.NetworkPolicy{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: "abc",
 				},
@@ -64,6 +69,7 @@ func RunBuilderTests() {
 					Ingress:     []networkingv1.NetworkPolicyIngressRule{},
 					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
 				}})
+			Expect(errs).To(BeEmpty())
 
 			Expect(ingress.SubjectMatcher.(*SubjectV1).namespace).To(Equal("default"))
 			Expect(egress.SubjectMatcher.(*SubjectV1).namespace).To(Equal("default"))
@@ -72,7 +78,8 @@ func RunBuilderTests() {
 
 	Describe("BuildTarget: Allow none -- empty ingress/egress", func() {
 		It("allow-no-ingress", func() {
-			ingress, egress := BuildTarget(netpol.AllowNoIngress_EmptyIngress)
+			ingress, egress, errs := BuildTarget(netpol.AllowNoIngress_EmptyIngress)
+			Expect(errs).To(BeEmpty())
 
 			Expect(ingress).ToNot(BeNil())
 			Expect(ingress.Peers).To(Equal([]PeerMatcher{&NoMatcher{}}))
@@ -81,7 +88,8 @@ func RunBuilderTests() {
 		})
 
 		It("allow-no-egress", func() {
-			ingress, egress := BuildTarget(netpol.AllowNoEgress_EmptyEgress)
+			ingress, egress, errs := BuildTarget(netpol.AllowNoEgress_EmptyEgress)
+			Expect(errs).To(BeEmpty())
 
 			Expect(egress).ToNot(BeNil())
 			Expect(egress.Peers).To(Equal([]PeerMatcher{&NoMatcher{}}))
@@ -90,7 +98,8 @@ func RunBuilderTests() {
 		})
 
 		It("allow-neither", func() {
-			ingress, egress := BuildTarget(netpol.AllowNoIngressAllowNoEgress_EmptyEgressEmptyIngress)
+			ingress, egress, errs := BuildTarget(netpol.AllowNoIngressAllowNoEgress_EmptyEgressEmptyIngress)
+			Expect(errs).To(BeEmpty())
 
 			Expect(egress).ToNot(BeNil())
 			Expect(egress.Peers).To(Equal([]PeerMatcher{&NoMatcher{}}))
@@ -102,21 +111,24 @@ func RunBuilderTests() {
 
 	Describe("BuildTarget: Allow all", func() {
 		It("allow-all-ingress", func() {
-			ingress, egress := BuildTarget(netpol.AllowAllIngress)
+			ingress, egress, errs := BuildTarget(netpol.AllowAllIngress)
+			Expect(errs).To(BeEmpty())
 
 			Expect(egress).To(BeNil())
 			Expect(ingress.Peers).To(Equal([]PeerMatcher{AllPeersPorts}))
 		})
 
 		It("allow-all-egress", func() {
-			ingress, egress := BuildTarget(netpol.AllowAllEgress)
+			ingress, egress, errs := BuildTarget(netpol.AllowAllEgress)
+			Expect(errs).To(BeEmpty())
 
 			Expect(egress.Peers).To(Equal([]PeerMatcher{AllPeersPorts}))
 			Expect(ingress).To(BeNil())
 		})
 
 		It("allow-all-both", func() {
-			ingress, egress := BuildTarget(netpol.AllowAllIngressAllowAllEgress)
+			ingress, egress, errs := BuildTarget(netpol.AllowAllIngressAllowAllEgress)
+			Expect(errs).To(BeEmpty())
 
 			Expect(egress.Peers).To(Equal([]PeerMatcher{AllPeersPorts}))
 			Expect(ingress.Peers).To(Equal([]PeerMatcher{AllPeersPorts}))
@@ -127,31 +139,35 @@ func RunBuilderTests() {
 
 	Describe("PeerMatcher from slice of ingress/egress rules", func() {
 		It("allows no ingress from an empty slice of ingress rules", func() {
-			peers := BuildIngressMatcher("abc", []networkingv1.NetworkPolicyIngressRule{})
+			peers, errs := BuildIngressMatcher("abc", []networkingv1.NetworkPolicyIngressRule{})
+			Expect(errs).To(BeEmpty())
 			Expect(peers).To(Equal([]PeerMatcher{&NoMatcher{}}))
 		})
 
 		It("allows no egress from an empty slice of egress rules", func() {
-			peers := BuildEgressMatcher("abc", []networkingv1.NetworkPolicyEgressRule{})
+			peers, errs := BuildEgressMatcher("abc", []networkingv1.NetworkPolicyEgressRule{})
+			Expect(errs).To(BeEmpty())
 			Expect(peers).To(Equal([]PeerMatcher{&NoMatcher{}}))
 		})
 
 		It("allows all ingress from an ingress containing a single empty rule", func() {
-			peers := BuildIngressMatcher("abc", []networkingv1.NetworkPolicyIngressRule{
+			peers, errs := BuildIngressMatcher("abc", []networkingv1.NetworkPolicyIngressRule{
 				{Ports: nil, From: nil},
 			})
+			Expect(errs).To(BeEmpty())
 			Expect(peers).To(Equal([]PeerMatcher{AllPeersPorts}))
 		})
 
 		It("allows all egress from an ingress containing a single empty rule", func() {
-			peers := BuildEgressMatcher("abc", []networkingv1.NetworkPolicyEgressRule{
+			peers, errs := BuildEgressMatcher("abc", []networkingv1.NetworkPolicyEgressRule{
 				{Ports: nil, To: nil},
 			})
+			Expect(errs).To(BeEmpty())
 			Expect(peers).To(Equal([]PeerMatcher{AllPeersPorts}))
 		})
 
 		It("allows to ips in IPBlock range and also to all pods/ips for DNS", func() {
-			peers := BuildEgressMatcher("abc", []networkingv1.NetworkPolicyEgressRule{
+			peers, errs := BuildEgressMatcher("abc", []networkingv1.NetworkPolicyEgressRule{
 				{
 					Ports: []networkingv1.NetworkPolicyPort{{Port: &port80, Protocol: &tcp}},
 					To: []networkingv1.NetworkPolicyPeer{
@@ -163,6 +179,7 @@ func RunBuilderTests() {
 					Ports: []networkingv1.NetworkPolicyPort{{Port: &port53, Protocol: &udp}},
 				},
 			})
+			Expect(errs).To(BeEmpty())
 			port53UDPMatcher := &SpecificPortMatcher{Ports: []*PortProtocolMatcher{{Port: &port53, Protocol: v1.ProtocolUDP}}}
 			port80TCPMatcher := &SpecificPortMatcher{Ports: []*PortProtocolMatcher{{Port: &port80, Protocol: v1.ProtocolTCP}}}
 			ip := &IPPeerMatcher{
@@ -186,15 +203,17 @@ func RunBuilderTests() {
 
 	Describe("PeerMatcher from slice of NetworkPolicyPeer", func() {
 		It("allows all source/destination from an empty slice", func() {
-			sds := BuildPeerMatcher("abc", []networkingv1.NetworkPolicyPort{}, []networkingv1.NetworkPolicyPeer{})
+			sds, errs := BuildPeerMatcher(field.NewPath("from"), field.NewPath("ports"), "abc", []networkingv1.NetworkPolicyPort{}, []networkingv1.NetworkPolicyPeer{})
+			Expect(errs).To(BeEmpty())
 			Expect(sds).To(Equal([]PeerMatcher{AllPeersPorts}))
 		})
 
 		It("allows all ips and all pods over a specific port from an empty peer slice", func() {
-			sds := BuildPeerMatcher("abc", []networkingv1.NetworkPolicyPort{{
+			sds, errs := BuildPeerMatcher(field.NewPath("from"), field.NewPath("ports"), "abc", []networkingv1.NetworkPolicyPort{{
 				Protocol: &sctp,
 				Port:     &port103,
 			}}, []networkingv1.NetworkPolicyPeer{})
+			Expect(errs).To(BeEmpty())
 			portMatcher := &SpecificPortMatcher{Ports: []*PortProtocolMatcher{
 				{Port: &port103, Protocol: v1.ProtocolSCTP},
 			}}
@@ -203,9 +222,10 @@ func RunBuilderTests() {
 		})
 
 		It("allows ips, but no pods from a single IPBlock", func() {
-			peers := BuildPeerMatcher("abc", []networkingv1.NetworkPolicyPort{}, []networkingv1.NetworkPolicyPeer{
+			peers, errs := BuildPeerMatcher(field.NewPath("from"), field.NewPath("ports"), "abc", []networkingv1.NetworkPolicyPort{}, []networkingv1.NetworkPolicyPeer{
 				{IPBlock: netpol.IPBlock_10_0_0_1_24},
 			})
+			Expect(errs).To(BeEmpty())
 			ip := &IPPeerMatcher{
 				IPBlock: netpol.IPBlock_10_0_0_1_24,
 				Port:    &AllPortMatcher{},
@@ -214,20 +234,22 @@ func RunBuilderTests() {
 		})
 
 		It("allows all ns/pods/ports, but no ips from a single peer with empty pod/ns selectors", func() {
-			peers := BuildPeerMatcher("abc", []networkingv1.NetworkPolicyPort{}, []networkingv1.NetworkPolicyPeer{
+			peers, errs := BuildPeerMatcher(field.NewPath("from"), field.NewPath("ports"), "abc", []networkingv1.NetworkPolicyPort{}, []networkingv1.NetworkPolicyPeer{
 				{
 					PodSelector:       netpol.SelectorEmpty,
 					NamespaceSelector: netpol.SelectorEmpty,
 				},
 			})
+			Expect(errs).To(BeEmpty())
 			Expect(peers).To(Equal([]PeerMatcher{
 				&PodPeerMatcher{Namespace: &AllNamespaceMatcher{}, Pod: &AllPodMatcher{}, Port: &AllPortMatcher{}}}))
 		})
 
 		It("allows ns/pods, but no ips from a single namespace/pod", func() {
-			peers := BuildPeerMatcher("abc", []networkingv1.NetworkPolicyPort{}, []networkingv1.NetworkPolicyPeer{
+			peers, errs := BuildPeerMatcher(field.NewPath("from"), field.NewPath("ports"), "abc", []networkingv1.NetworkPolicyPort{}, []networkingv1.NetworkPolicyPeer{
 				{PodSelector: netpol.SelectorEmpty},
 			})
+			Expect(errs).To(BeEmpty())
 			matcher := &PodPeerMatcher{
 				Namespace: &ExactNamespaceMatcher{Namespace: "abc"},
 				Pod:       &AllPodMatcher{},
@@ -314,18 +336,21 @@ func RunBuilderTests() {
 
 	Describe("Port from NetworkPolicyPort", func() {
 		It("allows all ports and all protocols from an empty slice", func() {
-			pm := BuildPortMatcher([]networkingv1.NetworkPolicyPort{})
+			pm, errs := BuildPortMatcher(field.NewPath("ports"), []networkingv1.NetworkPolicyPort{})
+			Expect(errs).To(BeEmpty())
 			Expect(pm).To(Equal(&AllPortMatcher{}))
 		})
 
 		It("allow all ports on protocol", func() {
-			pm := BuildPortMatcher([]networkingv1.NetworkPolicyPort{netpol.AllowAllPortsOnProtocol})
+			pm, errs := BuildPortMatcher(field.NewPath("ports"), []networkingv1.NetworkPolicyPort{netpol.AllowAllPortsOnProtocol})
+			Expect(errs).To(BeEmpty())
 			Expect(pm).To(Equal(&SpecificPortMatcher{Ports: []*PortProtocolMatcher{{Port: nil, Protocol: v1.ProtocolSCTP}}}))
 		})
 
 		It("allow numbered port on protocol", func() {
 			portNumber := intstr.FromInt(9001)
-			pm := BuildPortMatcher([]networkingv1.NetworkPolicyPort{netpol.AllowNumberedPortOnProtocol})
+			pm, errs := BuildPortMatcher(field.NewPath("ports"), []networkingv1.NetworkPolicyPort{netpol.AllowNumberedPortOnProtocol})
+			Expect(errs).To(BeEmpty())
 			Expect(pm).To(Equal(&SpecificPortMatcher{Ports: []*PortProtocolMatcher{{
 				Protocol: v1.ProtocolTCP,
 				Port:     &portNumber,
@@ -334,7 +359,8 @@ func RunBuilderTests() {
 
 		It("allow named port on protocol", func() {
 			portName := intstr.FromString("hello")
-			pm := BuildPortMatcher([]networkingv1.NetworkPolicyPort{netpol.AllowNamedPortOnProtocol})
+			pm, errs := BuildPortMatcher(field.NewPath("ports"), []networkingv1.NetworkPolicyPort{netpol.AllowNamedPortOnProtocol})
+			Expect(errs).To(BeEmpty())
 			Expect(pm).To(Equal(&SpecificPortMatcher{Ports: []*PortProtocolMatcher{{
 				Protocol: v1.ProtocolUDP,
 				Port:     &portName,
@@ -342,9 +368,182 @@ func RunBuilderTests() {
 		})
 	})
 
+	Describe("NetworksPeerMatcher from AdminNetworkPolicyEgressPeer", func() {
+		It("builds a networks matcher from a networks peer", func() {
+			peers := []v1alpha1.AdminNetworkPolicyEgressPeer{
+				{Networks: []v1alpha1.CIDR{"10.0.0.0/8", "192.168.0.0/16"}},
+			}
+			matchers, errs := BuildPeerMatcherAdmin(field.NewPath("to"), peers, &AllPortMatcher{})
+			Expect(errs).To(BeEmpty())
+			Expect(matchers).To(Equal([]PeerMatcher{&NetworksPeerMatcher{
+				Networks: []string{"10.0.0.0/8", "192.168.0.0/16"},
+				Port:     &AllPortMatcher{},
+			}}))
+		})
+
+		It("matches ips inside any of the networks", func() {
+			m := &NetworksPeerMatcher{Networks: []string{"10.0.0.0/8", "fd00::/8"}, Port: &AllPortMatcher{}}
+			Expect(m.MatchesIP("10.20.30.40")).To(BeTrue())
+			Expect(m.MatchesIP("fd00::1")).To(BeTrue())
+			Expect(m.MatchesIP("11.0.0.1")).To(BeFalse())
+			Expect(m.MatchesIP("")).To(BeFalse())
+		})
+	})
+
+	Describe("NodePeerMatcher from AdminNetworkPolicyEgressPeer", func() {
+		It("builds a node matcher from a nodes peer", func() {
+			selector := metav1.LabelSelector{MatchLabels: map[string]string{"node-role.kubernetes.io/control-plane": ""}}
+			peers := []v1alpha1.AdminNetworkPolicyEgressPeer{{Nodes: &selector}}
+			ports := []v1alpha1.AdminNetworkPolicyPort{{PortNumber: &v1alpha1.Port{Protocol: v1.ProtocolTCP, Port: 6443}}}
+			portMatcher, errs := BuildPortMatcherAdmin(field.NewPath("ports"), ports)
+			Expect(errs).To(BeEmpty())
+			matchers, errs := BuildPeerMatcherAdmin(field.NewPath("to"), peers, portMatcher)
+			Expect(errs).To(BeEmpty())
+			Expect(matchers).To(Equal([]PeerMatcher{&NodePeerMatcher{
+				Selector: selector,
+				Port:     portMatcher,
+			}}))
+		})
+
+		It("rejects a nodes peer with a named port", func() {
+			selector := metav1.LabelSelector{}
+			peers := []v1alpha1.AdminNetworkPolicyEgressPeer{{Nodes: &selector}}
+			namedPort := "https"
+			portMatcher, errs := BuildPortMatcherAdmin(field.NewPath("ports"), []v1alpha1.AdminNetworkPolicyPort{{NamedPort: &namedPort}})
+			Expect(errs).To(BeEmpty())
+			matchers, errs := BuildPeerMatcherAdmin(field.NewPath("to"), peers, portMatcher)
+			Expect(matchers).To(BeEmpty())
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("to[0].nodes"))
+		})
+
+		It("rejects invalid domain names", func() {
+			for _, domainName := range []v1alpha1.DomainName{"*.*.kubernetes.io", "kubernetes.", "Kubernetes.io", "*.KUBERNETES.io"} {
+				peers := []v1alpha1.AdminNetworkPolicyEgressPeer{{DomainNames: []v1alpha1.DomainName{"kubernetes.io", domainName}}}
+				matchers, errs := BuildPeerMatcherAdmin(field.NewPath("to"), peers, &AllPortMatcher{})
+				Expect(matchers).To(BeEmpty())
+				Expect(errs).To(HaveLen(1), string(domainName))
+				Expect(errs[0].Type).To(Equal(field.ErrorTypeInvalid))
+				Expect(errs[0].Field).To(Equal("to[0].domainNames[1]"))
+			}
+		})
+
+		It("accepts valid domain names", func() {
+			peers := []v1alpha1.AdminNetworkPolicyEgressPeer{{DomainNames: []v1alpha1.DomainName{"kubernetes.io", "*.kubernetes.io", "kubernetes.io."}}}
+			matchers, errs := BuildPeerMatcherAdmin(field.NewPath("to"), peers, &AllPortMatcher{})
+			Expect(errs).To(BeEmpty())
+			Expect(matchers).To(HaveLen(1))
+		})
+
+		It("rejects domainNames peers in Deny and Pass rules", func() {
+			for _, action := range []v1alpha1.AdminNetworkPolicyRuleAction{v1alpha1.AdminNetworkPolicyRuleActionDeny, v1alpha1.AdminNetworkPolicyRuleActionPass} {
+				anp := &v1alpha1.AdminNetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "domains"},
+					Spec: v1alpha1.AdminNetworkPolicySpec{
+						Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+						Egress: []v1alpha1.AdminNetworkPolicyEgressRule{{
+							Action: action,
+							To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{DomainNames: []v1alpha1.DomainName{"kubernetes.io"}}},
+						}},
+					},
+				}
+				_, _, errs := BuildTargetANP(anp)
+				Expect(errs).To(HaveLen(1))
+				Expect(errs[0].Err.Type).To(Equal(field.ErrorTypeForbidden))
+				Expect(errs[0].Field()).To(Equal("spec.egress[0].to[0].domainNames"))
+
+				anp.Spec.Egress[0].Action = v1alpha1.AdminNetworkPolicyRuleActionAllow
+				_, _, errs = BuildTargetANP(anp)
+				Expect(errs).To(BeEmpty())
+			}
+		})
+	})
+
+	Describe("BuildV1AndV2NetPols: invalid policies", func() {
+		namedPort := "serve-80-tcp"
+		invalidANP := &v1alpha1.AdminNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid-anp"},
+			Spec: v1alpha1.AdminNetworkPolicySpec{
+				Priority: 30,
+				Subject:  v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+				Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
+					{
+						Name:   "valid",
+						Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+						To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Namespaces: &metav1.LabelSelector{}}},
+					},
+					{
+						Name:   "bad-cidr-and-named-port",
+						Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+						To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Networks: []v1alpha1.CIDR{"10.0.0.0/8", "not-a-cidr"}}},
+						Ports:  &[]v1alpha1.AdminNetworkPolicyPort{{NamedPort: &namedPort}},
+					},
+				},
+			},
+		}
+		invalidNetpol := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "invalid-netpol"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{
+					{From: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/33"}}}},
+				},
+			},
+		}
+
+		It("returns errors with policy, rule index and field path", func() {
+			_, errs := BuildV1AndV2NetPols(false, []*networkingv1.NetworkPolicy{invalidNetpol}, []*v1alpha1.AdminNetworkPolicy{invalidANP}, nil)
+			Expect(errs).To(HaveLen(3))
+
+			Expect(errs[0].Kind).To(Equal(NetworkPolicyV1))
+			Expect(errs[0].PolicyName()).To(Equal("x/invalid-netpol"))
+			Expect(errs[0].RuleIndex).To(Equal(0))
+			Expect(errs[0].Field()).To(Equal("spec.ingress[0].from[0].ipBlock.cidr"))
+
+			Expect(errs[1].Kind).To(Equal(AdminNetworkPolicy))
+			Expect(errs[1].PolicyName()).To(Equal("invalid-anp"))
+			Expect(errs[1].RuleIndex).To(Equal(1))
+			Expect(errs[1].Field()).To(Equal("spec.egress[1].to[0].networks"))
+			Expect(errs[2].Field()).To(Equal("spec.egress[1].to[0].networks[1]"))
+		})
+
+		It("skips invalid policies and keeps the valid ones", func() {
+			policy, errs := BuildV1AndV2NetPols(false, nil, append([]*v1alpha1.AdminNetworkPolicy{invalidANP}, examples.SimpleANPs...), nil)
+			Expect(errs).To(HaveLen(2))
+			Expect(policy.Egress).To(HaveLen(1))
+		})
+
+		It("defaults the policy types of a network policy without spec.policyTypes like kube", func() {
+			noTypes := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "no-types"}}
+			noTypesWithEgress := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "no-types-with-egress"},
+				Spec:       networkingv1.NetworkPolicySpec{Egress: []networkingv1.NetworkPolicyEgressRule{{}}},
+			}
+			policy, errs := BuildV1AndV2NetPols(false, []*networkingv1.NetworkPolicy{noTypes, noTypesWithEgress, invalidNetpol}, nil, nil)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].PolicyName()).To(Equal("x/invalid-netpol"))
+			Expect(policy.Ingress).To(HaveLen(1))
+			Expect(policy.Egress).To(HaveLen(1))
+			for _, target := range policy.Ingress {
+				Expect(target.SourceRules).To(ConsistOf(netPolID(noTypes), netPolID(noTypesWithEgress)))
+			}
+			for _, target := range policy.Egress {
+				Expect(target.SourceRules).To(ConsistOf(netPolID(noTypesWithEgress)))
+			}
+		})
+
+		It("reports a missing rule as not specific to a rule", func() {
+			_, _, errs := BuildTargetBANP(&v1alpha1.BaselineAdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].RuleIndex).To(Equal(NoRuleIndex))
+			Expect(errs[0].Error()).To(Equal("invalid BANP default: spec: Required value: need at least one egress or ingress rule"))
+		})
+	})
+
 	Describe("BuildV1AndV2NetPols", func() {
 		It("it combines ANPs with same subject", func() {
-			result := BuildV1AndV2NetPols(true, nil, examples.SimpleANPs, nil)
+			result, errs := BuildV1AndV2NetPols(true, nil, examples.SimpleANPs, nil)
+			Expect(errs).To(BeEmpty())
 			Expect(result.Egress).To(HaveLen(1))
 			k := maps.Keys(result.Egress)
 			firstRule := result.Egress[k[0]]
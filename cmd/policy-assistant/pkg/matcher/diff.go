@@ -0,0 +1,25 @@
+package matcher
+
+// TrafficDiff is the result of evaluating the same traffic against two sets of policies.
+type TrafficDiff struct {
+	Traffic *Traffic
+	Before  *AllowedResult
+	After   *AllowedResult
+}
+
+// IsChanged returns true if the traffic is allowed by one set of policies and denied by the other.
+func (d *TrafficDiff) IsChanged() bool {
+	return d.Before.IsAllowed() != d.After.IsAllowed()
+}
+
+// DiffTraffic evaluates traffic against two sets of policies, and returns the traffic whose verdict changes.
+func DiffTraffic(before *Policy, after *Policy, traffic []*Traffic) []*TrafficDiff {
+	var diffs []*TrafficDiff
+	for _, t := range traffic {
+		diff := &TrafficDiff{Traffic: t, Before: before.IsTrafficAllowed(t), After: after.IsTrafficAllowed(t)}
+		if diff.IsChanged() {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
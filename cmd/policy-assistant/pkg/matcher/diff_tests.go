@@ -0,0 +1,38 @@
+package matcher
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func RunDiffTests() {
+	Describe("DiffTraffic", func() {
+		peer := func(ns string, ip string) *TrafficPeer {
+			return &TrafficPeer{Internal: &InternalPeer{Namespace: ns, NamespaceLabels: map[string]string{v1.LabelMetadataName: ns}}, IP: ip}
+		}
+		toX := &Traffic{Source: peer("y", "1.2.3.4"), Destination: peer("x", "1.2.3.5"), ResolvedPort: 80, Protocol: v1.ProtocolTCP}
+		toY := &Traffic{Source: peer("x", "1.2.3.5"), Destination: peer("y", "1.2.3.4"), ResolvedPort: 80, Protocol: v1.ProtocolTCP}
+
+		It("returns traffic whose verdict changes", func() {
+			before, errs := BuildV1AndV2NetPols(false, nil, nil, nil)
+			Expect(errs).To(BeEmpty())
+			after, errs := BuildV1AndV2NetPols(false, []*networkingv1.NetworkPolicy{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "deny-all"},
+				Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}},
+			}}, nil, nil)
+			Expect(errs).To(BeEmpty())
+
+			diffs := DiffTraffic(before, after, []*Traffic{toX, toY})
+			Expect(diffs).To(HaveLen(1))
+			Expect(diffs[0].Traffic).To(Equal(toX))
+			Expect(diffs[0].Before.IsAllowed()).To(BeTrue())
+			Expect(diffs[0].After.IsAllowed()).To(BeFalse())
+			Expect(diffs[0].After.Ingress.Flow()).To(Equal("[NPv1] Dropped (x/deny-all)"))
+
+			Expect(DiffTraffic(after, after, []*Traffic{toX, toY})).To(BeEmpty())
+		})
+	})
+}
@@ -0,0 +1,75 @@
+package matcher
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	v1 "k8s.io/api/core/v1"
+)
+
+// DomainNamesPeerMatcher matches traffic to external hosts by domain name.
+// It is only relevant to the domainNames peer of ANP egress rules.
+// Traffic only matches if its peer has a Hostname; see ResolveHostname.
+type DomainNamesPeerMatcher struct {
+	DomainNames []string
+	Port        PortMatcher
+}
+
+// PrimaryKey returns a content-based, deterministic key based on the domain names.
+func (d *DomainNamesPeerMatcher) PrimaryKey() string {
+	return "[" + strings.Join(slice.Sort(d.DomainNames), ", ") + "]"
+}
+
+func (d *DomainNamesPeerMatcher) MarshalJSON() (b []byte, e error) {
+	return json.Marshal(map[string]interface{}{
+		"Type":        "domain names",
+		"DomainNames": d.DomainNames,
+		"Port":        d.Port,
+	})
+}
+
+func (d *DomainNamesPeerMatcher) Matches(_, peer *TrafficPeer, portInt int, portName string, protocol v1.Protocol) bool {
+	return d.matchesAnyHostname(append([]string{peer.Hostname}, peer.HostnameAliases...)) && d.Port.Matches(portInt, portName, protocol)
+}
+
+func (d *DomainNamesPeerMatcher) matchesAnyHostname(hostnames []string) bool {
+	for _, hostname := range hostnames {
+		if d.MatchesHostname(hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesHostname returns true if the hostname matches at least one of the domain names.
+func (d *DomainNamesPeerMatcher) MatchesHostname(hostname string) bool {
+	if hostname == "" {
+		return false
+	}
+	for _, domainName := range d.DomainNames {
+		if IsDomainNameMatch(domainName, hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDomainNameMatch implements the DomainName matching rules of the ANP API:
+//   - `kubernetes.io` matches only `kubernetes.io`
+//   - `*.kubernetes.io` matches `www.kubernetes.io` and `latest.blog.kubernetes.io`, but not `kubernetes.io`
+//
+// Comparison is case-insensitive, and a trailing dot (fully-qualified form) is ignored.
+func IsDomainNameMatch(domainName string, hostname string) bool {
+	pattern := normalizeDomainName(domainName)
+	host := normalizeDomainName(hostname)
+	if suffix, isWildcard := strings.CutPrefix(pattern, "*"); isWildcard {
+		// suffix starts with '.', so '*' has to match at least one entire label
+		return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+	}
+	return pattern == host
+}
+
+func normalizeDomainName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
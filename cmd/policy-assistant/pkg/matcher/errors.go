@@ -0,0 +1,76 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// NoRuleIndex is the RuleIndex of a PolicyError that isn't specific to an ingress or egress rule.
+const NoRuleIndex = -1
+
+// PolicyError is a problem with a single NPv1, ANP or BANP, found while building matchers from it.
+// Policies with errors are left out of the built Policy, so that the valid ones can still be analyzed.
+type PolicyError struct {
+	Kind PolicyKind
+	// Namespace is empty for ANPs and BANPs, which are cluster-scoped
+	Namespace string
+	Name      string
+	// RuleIndex is the index of the offending rule in spec.ingress or spec.egress, or NoRuleIndex
+	RuleIndex int
+	// Err holds the path to the offending field, such as spec.egress[0].to[1].networks[0]
+	Err *field.Error
+}
+
+var rulePathRegex = regexp.MustCompile(`^spec\.(?:ingress|egress)\[(\d+)\]`)
+
+// NewPolicyErrors wraps field errors from validating a policy, extracting the rule index from each field path.
+func NewPolicyErrors(kind PolicyKind, namespace string, name string, errs field.ErrorList) []*PolicyError {
+	var policyErrors []*PolicyError
+	for _, err := range errs {
+		policyErrors = append(policyErrors, &PolicyError{
+			Kind:      kind,
+			Namespace: namespace,
+			Name:      name,
+			RuleIndex: ruleIndexFromPath(err.Field),
+			Err:       err,
+		})
+	}
+	return policyErrors
+}
+
+// ruleIndexFromPath extracts the rule index from paths such as spec.egress[2].to[0].
+func ruleIndexFromPath(path string) int {
+	match := rulePathRegex.FindStringSubmatch(path)
+	if match == nil {
+		return NoRuleIndex
+	}
+	index, err := strconv.Atoi(match[1])
+	if err != nil {
+		return NoRuleIndex
+	}
+	return index
+}
+
+// PolicyName is the namespace/name of an NPv1, or the name of an ANP or BANP.
+func (e *PolicyError) PolicyName() string {
+	if e.Namespace == "" {
+		return e.Name
+	}
+	return e.Namespace + "/" + e.Name
+}
+
+// Field returns the path to the offending field.
+func (e *PolicyError) Field() string {
+	return e.Err.Field
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("invalid %s %s: %s", e.Kind, e.PolicyName(), e.Err.Error())
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
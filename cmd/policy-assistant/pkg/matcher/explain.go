@@ -43,6 +43,12 @@ func (s *SliceBuilder) Append(items ...string) {
 }
 
 func (p *Policy) ExplainTable() string {
+	ingresses, egresses := p.SortedTargets()
+	return ExplainTargetsTable(ingresses, egresses)
+}
+
+// ExplainTargetsTable explains the targets in the order given.
+func ExplainTargetsTable(ingresses []*Target, egresses []*Target) string {
 	tableString := &strings.Builder{}
 	table := tablewriter.NewWriter(tableString)
 	table.SetAutoWrapText(false)
@@ -52,7 +58,6 @@ func (p *Policy) ExplainTable() string {
 	table.SetHeader([]string{"Type", "Subject", "Source rules", "Peer", "Action", "Port/Protocol"})
 
 	builder := &SliceBuilder{}
-	ingresses, egresses := p.SortedTargets()
 	builder.TargetsTableLines(ingresses, true)
 
 	if len(egresses) > 0 {
@@ -196,11 +201,12 @@ func groupAnbAndBanp(p []PeerMatcher) []PeerMatcher {
 	for _, v := range p {
 		switch t := v.(type) {
 		case *PeerMatcherAdmin:
-			k := t.Port.GetPrimaryKey() + t.Pod.PrimaryKey() + t.Namespace.PrimaryKey()
+			subject, port := resolveAdminPeer(t.PeerMatcher)
+			k := port.GetPrimaryKey() + subject
 			if _, ok := groups[k]; !ok {
 				groups[k] = &peerProtocolGroup{
-					port:     strings.Join(PortMatcherTableLines(t.PodPeerMatcher.Port, t.effectFromMatch.PolicyKind), "\n"),
-					subject:  resolveSubject(t.PodPeerMatcher),
+					port:     strings.Join(PortMatcherTableLines(port, t.effectFromMatch.PolicyKind), "\n"),
+					subject:  subject,
 					policies: map[string]*anpGroup{},
 				}
 			}
@@ -237,6 +243,34 @@ func groupAnbAndBanp(p []PeerMatcher) []PeerMatcher {
 	return result
 }
 
+// resolveAdminPeer returns the peer description and port matcher of the PeerMatcher wrapped by a PeerMatcherAdmin
+func resolveAdminPeer(m PeerMatcher) (string, PortMatcher) {
+	switch t := m.(type) {
+	case *PodPeerMatcher:
+		return resolveSubject(t), t.Port
+	case *NetworksPeerMatcher:
+		return resolveNetworks(t), t.Port
+	case *NodePeerMatcher:
+		return resolveNodes(t), t.Port
+	case *DomainNamesPeerMatcher:
+		return resolveDomainNames(t), t.Port
+	default:
+		panic(errors.Errorf("invalid admin PeerMatcher type %T", m))
+	}
+}
+
+func resolveNetworks(n *NetworksPeerMatcher) string {
+	return fmt.Sprintf("Networks:\n   %s", strings.Join(slice.Sort(n.Networks), "\n   "))
+}
+
+func resolveNodes(n *NodePeerMatcher) string {
+	return fmt.Sprintf("Nodes:\n   %s", strings.TrimSpace(kube.LabelSelectorTableLines(n.Selector)))
+}
+
+func resolveDomainNames(d *DomainNamesPeerMatcher) string {
+	return fmt.Sprintf("Domain names:\n   %s", strings.Join(slice.Sort(d.DomainNames), "\n   "))
+}
+
 func resolveSubject(nsPodMatcher *PodPeerMatcher) string {
 	var namespaces string
 	var pods string
@@ -245,10 +279,6 @@ func resolveSubject(nsPodMatcher *PodPeerMatcher) string {
 		namespaces = "all"
 	case *LabelSelectorNamespaceMatcher:
 		namespaces = kube.LabelSelectorTableLines(ns.Selector)
-	case *SameLabelsNamespaceMatcher:
-		namespaces = fmt.Sprintf("Same labels - %s", strings.Join(ns.labels, ", "))
-	case *NotSameLabelsNamespaceMatcher:
-		namespaces = fmt.Sprintf("Not Same labels - %s", strings.Join(ns.labels, ", "))
 	case *ExactNamespaceMatcher:
 		namespaces = ns.Namespace
 	default:
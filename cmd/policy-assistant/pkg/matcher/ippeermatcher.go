@@ -35,7 +35,12 @@ func (i *IPPeerMatcher) MarshalJSON() (b []byte, e error) {
 	})
 }
 
+// Matches returns true if the peer's IP is in the block, which is never the case for an IP of the other family or a
+// peer without an IP.
 func (i *IPPeerMatcher) Matches(_, peer *TrafficPeer, portInt int, portName string, protocol v1.Protocol) bool {
+	if peer.IP == "" {
+		return false
+	}
 	isIpMatch, err := kube.IsIPAddressMatchForIPBlock(peer.IP, i.IPBlock)
 	// TODO propagate this error instead of panic
 	if err != nil {
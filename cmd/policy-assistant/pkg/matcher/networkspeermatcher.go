@@ -0,0 +1,54 @@
+package matcher
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	v1 "k8s.io/api/core/v1"
+)
+
+// NetworksPeerMatcher matches traffic to CIDR blocks.
+// It is only relevant to the networks peer of ANP and BANP egress rules.
+// Unlike IPPeerMatcher, there are no except blocks, and cluster-internal peers
+// are matched as well if their IP falls within one of the CIDRs.
+type NetworksPeerMatcher struct {
+	Networks []string
+	Port     PortMatcher
+}
+
+// PrimaryKey returns a content-based, deterministic key based on the CIDRs.
+func (n *NetworksPeerMatcher) PrimaryKey() string {
+	return "[" + strings.Join(slice.Sort(n.Networks), ", ") + "]"
+}
+
+func (n *NetworksPeerMatcher) MarshalJSON() (b []byte, e error) {
+	return json.Marshal(map[string]interface{}{
+		"Type":     "networks",
+		"Networks": n.Networks,
+		"Port":     n.Port,
+	})
+}
+
+func (n *NetworksPeerMatcher) Matches(_, peer *TrafficPeer, portInt int, portName string, protocol v1.Protocol) bool {
+	return n.MatchesIP(peer.IP) && n.Port.Matches(portInt, portName, protocol)
+}
+
+// MatchesIP returns true if the IP is in at least one of the CIDRs.
+func (n *NetworksPeerMatcher) MatchesIP(ip string) bool {
+	if ip == "" {
+		return false
+	}
+	for _, cidr := range n.Networks {
+		isIPMatch, err := kube.IsIPInCIDR(ip, cidr)
+		// TODO propagate this error instead of panic
+		if err != nil {
+			panic(err)
+		}
+		if isIPMatch {
+			return true
+		}
+	}
+	return false
+}
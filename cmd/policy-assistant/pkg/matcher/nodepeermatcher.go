@@ -0,0 +1,36 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodePeerMatcher matches traffic to cluster nodes by their labels.
+// It is only relevant to the nodes peer of ANP and BANP egress rules.
+type NodePeerMatcher struct {
+	Selector metav1.LabelSelector
+	Port     PortMatcher
+}
+
+// PrimaryKey returns a content-based, deterministic key based on the node selector.
+func (n *NodePeerMatcher) PrimaryKey() string {
+	return fmt.Sprintf(`{"type": "nodes", "selector": "%s"}`, kube.SerializeLabelSelector(n.Selector))
+}
+
+func (n *NodePeerMatcher) MarshalJSON() (b []byte, e error) {
+	return json.Marshal(map[string]interface{}{
+		"Type":     "nodes",
+		"Selector": n.Selector,
+		"Port":     n.Port,
+	})
+}
+
+func (n *NodePeerMatcher) Matches(_, peer *TrafficPeer, portInt int, portName string, protocol v1.Protocol) bool {
+	return peer.IsNode() &&
+		kube.IsLabelsMatchLabelSelector(peer.Node.Labels, n.Selector) &&
+		n.Port.Matches(portInt, portName, protocol)
+}
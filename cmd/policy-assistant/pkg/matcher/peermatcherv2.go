@@ -9,21 +9,27 @@ import (
 )
 
 // PeerMatcherAdmin models an ANP or BANP rule, incorporating an ANP/BANP action and an ANP priority.
-// NOTE: we only use the PodPeerMatcher out of all the PeerMatcher imlementations.
-// This is because ANP and BANP only deal with Pod to Pod traffic, and do not deal with external IPs.
+// The wrapped PeerMatcher is one of:
+// - a PodPeerMatcher, for namespaces and pods peers
+// - a NetworksPeerMatcher, for egress networks peers
+// - a NodePeerMatcher, for egress nodes peers
+// - a DomainNamesPeerMatcher, for egress domainNames peers
 type PeerMatcherAdmin struct {
-	*PodPeerMatcher
-	PolicyName      string
+	PeerMatcher
+	PolicyName string
+	// RuleIndex is the index of the rule in the ingress or egress rules of the policy
+	RuleIndex       int
 	RuleName        string
 	effectFromMatch Effect
 }
 
 // NewPeerMatcherANP creates a PeerMatcherAdmin for an ANP rule
-func NewPeerMatcherANP(peer *PodPeerMatcher, v Verdict, priority int, policyName, ruleName string) *PeerMatcherAdmin {
+func NewPeerMatcherANP(peer PeerMatcher, v Verdict, priority int, policyName string, ruleIndex int, ruleName string) *PeerMatcherAdmin {
 	return &PeerMatcherAdmin{
-		PodPeerMatcher: peer,
-		PolicyName:     policyName,
-		RuleName:       ruleName,
+		PeerMatcher: peer,
+		PolicyName:  policyName,
+		RuleIndex:   ruleIndex,
+		RuleName:    ruleName,
 		effectFromMatch: Effect{
 			RuleName:   ruleName,
 			PolicyKind: AdminNetworkPolicy,
@@ -34,11 +40,12 @@ func NewPeerMatcherANP(peer *PodPeerMatcher, v Verdict, priority int, policyName
 }
 
 // NewPeerMatcherBANP creates a new PeerMatcherAdmin for a BANP rule
-func NewPeerMatcherBANP(peer *PodPeerMatcher, v Verdict, policyName, ruleName string) *PeerMatcherAdmin {
+func NewPeerMatcherBANP(peer PeerMatcher, v Verdict, policyName string, ruleIndex int, ruleName string) *PeerMatcherAdmin {
 	return &PeerMatcherAdmin{
-		PodPeerMatcher: peer,
-		PolicyName:     policyName,
-		RuleName:       ruleName,
+		PeerMatcher: peer,
+		PolicyName:  policyName,
+		RuleIndex:   ruleIndex,
+		RuleName:    ruleName,
 		effectFromMatch: Effect{
 			RuleName:   ruleName,
 			PolicyKind: BaselineAdminNetworkPolicy,
@@ -94,26 +101,26 @@ const (
 	Pass Verdict = "Pass"
 )
 
-func AdminActionToVerdict(action v1alpha1.AdminNetworkPolicyRuleAction) Verdict {
+func AdminActionToVerdict(action v1alpha1.AdminNetworkPolicyRuleAction) (Verdict, error) {
 	switch action {
 	case v1alpha1.AdminNetworkPolicyRuleActionAllow:
-		return Allow
+		return Allow, nil
 	case v1alpha1.AdminNetworkPolicyRuleActionDeny:
-		return Deny
+		return Deny, nil
 	case v1alpha1.AdminNetworkPolicyRuleActionPass:
-		return Pass
+		return Pass, nil
 	default:
-		panic(errors.Errorf("unsupported ANP action %s", action))
+		return None, errors.Errorf("unsupported ANP action %s", action)
 	}
 }
 
-func BaselineAdminActionToVerdict(action v1alpha1.BaselineAdminNetworkPolicyRuleAction) Verdict {
+func BaselineAdminActionToVerdict(action v1alpha1.BaselineAdminNetworkPolicyRuleAction) (Verdict, error) {
 	switch action {
 	case v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow:
-		return Allow
+		return Allow, nil
 	case v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny:
-		return Deny
+		return Deny, nil
 	default:
-		panic(errors.Errorf("unsupported BANP action %s", action))
+		return None, errors.Errorf("unsupported BANP action %s", action)
 	}
 }
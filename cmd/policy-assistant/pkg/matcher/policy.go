@@ -239,25 +239,46 @@ type AllowedResult struct {
 	Egress  DirectionResult
 }
 
+// Table shows the effect of each rule on the traffic, in the order rules are evaluated, and the resulting verdict
+// of each direction.
 func (ar *AllowedResult) Table() string {
 	tableString := &strings.Builder{}
 	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
 	table.SetRowLine(true)
 	table.SetAutoMergeCells(true)
-	table.SetHeader([]string{"Type", "Action", "Target"})
-	// NOTE: broken. This function only used by 1) "cyclonus analyze explain --mode=query-target" and 2) a trace-level log in "cyclonus generate"
-	// Broken since we're changing the DirectionResult struct to use Effects instead of Allowing/DenyingTargets
-	// addTargetsToTable(table, "Ingress", "Allow", ar.Ingress.AllowingTargets)
-	// addTargetsToTable(table, "Ingress", "Deny", ar.Ingress.DenyingTargets)
-	table.Append([]string{"", "", ""})
-	// addTargetsToTable(table, "Egress", "Allow", ar.Egress.AllowingTargets)
-	// addTargetsToTable(table, "Egress", "Deny", ar.Egress.DenyingTargets)
-	table.SetFooter([]string{"Is allowed?", fmt.Sprintf("%t", ar.IsAllowed()), ""})
+	table.SetHeader([]string{"Type", "Policy", "Rule", "Effect"})
+	addDirectionToTable(table, "Ingress", ar.Ingress)
+	addDirectionToTable(table, "Egress", ar.Egress)
+	table.SetFooter([]string{"", "", "Is allowed?", ar.Verdict()})
 
 	table.Render()
 	return tableString.String()
 }
 
+func addDirectionToTable(table *tablewriter.Table, direction string, d DirectionResult) {
+	if len(d) == 0 {
+		table.Append([]string{direction, "no policies", "", string(Allow)})
+		return
+	}
+	for _, e := range d.SortedByPrecedence() {
+		kind := string(e.PolicyKind)
+		if e.PolicyKind == AdminNetworkPolicy {
+			kind = fmt.Sprintf("%s (priority %d)", e.PolicyKind, e.Priority)
+		}
+		effect := string(e.Verdict)
+		if e.Verdict == None {
+			effect = "no match"
+		}
+		table.Append([]string{direction, kind, e.RuleName, effect})
+	}
+	verdict := "Denied"
+	if d.IsAllowed() {
+		verdict = "Allowed"
+	}
+	table.Append([]string{direction, "result", d.Flow(), verdict})
+}
+
 func (ar *AllowedResult) IsAllowed() bool {
 	return ar.Ingress.IsAllowed() && ar.Egress.IsAllowed()
 }
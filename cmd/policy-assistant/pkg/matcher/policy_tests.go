@@ -1,11 +1,14 @@
 package matcher
 
 import (
+	"github.com/mattfenwick/cyclonus/pkg/kube"
 	"github.com/mattfenwick/cyclonus/pkg/utils"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 )
 
 func RunPolicyTests() {
@@ -24,7 +27,8 @@ spec:
   - Ingress`
 	allowAllOnSCTPSerializedPolicy, err := utils.ParseYaml[networkingv1.NetworkPolicy]([]byte(allowAllOnSCTPSerializedYaml))
 	utils.DoOrDie(err)
-	allowAllOnSCTP := BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{allowAllOnSCTPSerializedPolicy})
+	allowAllOnSCTP, errs := BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{allowAllOnSCTPSerializedPolicy})
+	Expect(errs).To(BeEmpty())
 
 	Describe("Allowing a protocol should implicitly deny other protocols from pods", func() {
 		It("should not allow TCP", func() {
@@ -149,7 +153,8 @@ spec:
   - Egress`
 		kubePolicy, err := utils.ParseYaml[networkingv1.NetworkPolicy]([]byte(policyYaml))
 		utils.DoOrDie(err)
-		policy := BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{kubePolicy})
+		policy, errs := BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{kubePolicy})
+		Expect(errs).To(BeEmpty())
 
 		It("Should allow ips in cidr", func() {
 			Expect(policy.IsTrafficAllowed(&Traffic{
@@ -176,6 +181,73 @@ spec:
 		})
 	})
 
+	Describe("Policy allowing egress to IPv4 and IPv6 ips", func() {
+		policyYaml := `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-dual-stack-egress
+  namespace: x
+spec:
+  egress:
+  - to:
+    - ipBlock:
+        cidr: 10.0.0.0/8
+    - ipBlock:
+        cidr: fd00:10::/32
+        except:
+        - fd00:10:244::/48
+  podSelector: {}
+  policyTypes:
+  - Egress`
+		kubePolicy, err := utils.ParseYaml[networkingv1.NetworkPolicy]([]byte(policyYaml))
+		utils.DoOrDie(err)
+		policy, errs := BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{kubePolicy})
+		Expect(errs).To(BeEmpty())
+
+		egressTo := func(sourceIP string, destinationIP string) *Traffic {
+			return &Traffic{
+				Source: &TrafficPeer{
+					Internal: &InternalPeer{
+						PodLabels:       map[string]string{"pod": "a"},
+						NamespaceLabels: map[string]string{"ns": "x"},
+						Namespace:       "x",
+					},
+					IP: sourceIP,
+				},
+				Destination:  &TrafficPeer{IP: destinationIP},
+				ResolvedPort: 80,
+				Protocol:     v1.ProtocolTCP,
+			}
+		}
+
+		It("Should allow ips in the cidr of their family", func() {
+			Expect(policy.IsTrafficAllowed(egressTo("10.244.0.5", "10.1.2.3")).IsAllowed()).To(BeTrue())
+			Expect(policy.IsTrafficAllowed(egressTo("fd00:10:244::5", "fd00:10:1::3")).IsAllowed()).To(BeTrue())
+		})
+
+		It("Should deny ips in an IPv6 except block", func() {
+			Expect(policy.IsTrafficAllowed(egressTo("fd00:10:244::5", "fd00:10:244:1::3")).IsAllowed()).To(BeFalse())
+		})
+
+		It("Should deny ips outside every cidr, including IPv4-mapped IPv6 ips", func() {
+			Expect(policy.IsTrafficAllowed(egressTo("10.244.0.5", "192.168.1.1")).IsAllowed()).To(BeFalse())
+			Expect(policy.IsTrafficAllowed(egressTo("fd00:10:244::5", "::ffff:10.1.2.3")).IsAllowed()).To(BeFalse())
+		})
+
+		It("Should deny peers without an ip", func() {
+			Expect(policy.IsTrafficAllowed(egressTo("10.244.0.5", "")).IsAllowed()).To(BeFalse())
+		})
+
+		It("Should reject except blocks outside the cidr", func() {
+			invalid := kubePolicy.DeepCopy()
+			invalid.Spec.Egress[0].To[1].IPBlock.Except = []string{"10.0.0.0/16"}
+			_, errs := BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{invalid})
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Error()).To(ContainSubstring("must be within the CIDR fd00:10::/32"))
+		})
+	})
+
 	Describe("Policy allowing ingress to named port", func() {
 		policyYaml := `
 apiVersion: networking.k8s.io/v1
@@ -195,7 +267,8 @@ spec:
   - Ingress`
 		kubePolicy, err := utils.ParseYaml[networkingv1.NetworkPolicy]([]byte(policyYaml))
 		utils.DoOrDie(err)
-		policy := BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{kubePolicy})
+		policy, errs := BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{kubePolicy})
+		Expect(errs).To(BeEmpty())
 
 		It("Should allow access to named port", func() {
 			Expect(policy.IsTrafficAllowed(&Traffic{
@@ -216,4 +289,247 @@ spec:
 			}).IsAllowed()).To(BeTrue())
 		})
 	})
+
+	Describe("AdminNetworkPolicy denying egress to networks", func() {
+		anpYaml := `
+apiVersion: policy.networking.k8s.io/v1alpha1
+kind: AdminNetworkPolicy
+metadata:
+  name: deny-egress-to-cidr
+spec:
+  priority: 10
+  subject:
+    namespaces:
+      matchLabels:
+        ns: x
+  egress:
+  - name: deny-to-10-0-0-0-8
+    action: Deny
+    to:
+    - networks:
+      - 10.0.0.0/8
+    ports:
+    - portNumber:
+        protocol: TCP
+        port: 80`
+		anp, err := utils.ParseYaml[v1alpha1.AdminNetworkPolicy]([]byte(anpYaml))
+		utils.DoOrDie(err)
+		policy, errs := BuildV1AndV2NetPols(true, nil, []*v1alpha1.AdminNetworkPolicy{anp}, nil)
+		Expect(errs).To(BeEmpty())
+
+		trafficTo := func(ip string, port int) *Traffic {
+			return &Traffic{
+				Source: &TrafficPeer{
+					Internal: &InternalPeer{
+						PodLabels:       map[string]string{"pod": "a"},
+						NamespaceLabels: map[string]string{"ns": "x"},
+						Namespace:       "x",
+					},
+					IP: "192.168.1.1",
+				},
+				Destination: &TrafficPeer{
+					IP: ip,
+				},
+				ResolvedPort: port,
+				Protocol:     v1.ProtocolTCP,
+			}
+		}
+
+		It("Should deny ips in cidr on matching port", func() {
+			Expect(policy.IsTrafficAllowed(trafficTo("10.1.2.3", 80)).IsAllowed()).To(BeFalse())
+		})
+
+		It("Should allow ips in cidr on other ports", func() {
+			Expect(policy.IsTrafficAllowed(trafficTo("10.1.2.3", 443)).IsAllowed()).To(BeTrue())
+		})
+
+		It("Should allow ips outside cidr", func() {
+			Expect(policy.IsTrafficAllowed(trafficTo("11.1.2.3", 80)).IsAllowed()).To(BeTrue())
+		})
+	})
+
+	Describe("AdminNetworkPolicy denying egress to nodes", func() {
+		anpYaml := `
+apiVersion: policy.networking.k8s.io/v1alpha1
+kind: AdminNetworkPolicy
+metadata:
+  name: deny-egress-to-control-plane
+spec:
+  priority: 10
+  subject:
+    namespaces: {}
+  egress:
+  - name: deny-to-kube-apiserver
+    action: Deny
+    to:
+    - nodes:
+        matchExpressions:
+        - key: node-role.kubernetes.io/control-plane
+          operator: Exists
+    ports:
+    - portNumber:
+        protocol: TCP
+        port: 6443`
+		anp, err := utils.ParseYaml[v1alpha1.AdminNetworkPolicy]([]byte(anpYaml))
+		utils.DoOrDie(err)
+		policy, errs := BuildV1AndV2NetPols(true, nil, []*v1alpha1.AdminNetworkPolicy{anp}, nil)
+		Expect(errs).To(BeEmpty())
+
+		kubeClient := kube.NewMockKubernetes(1.0)
+		_, err = kubeClient.CreateNode(&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "control-plane-1",
+				Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+			},
+			Status: v1.NodeStatus{Addresses: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "control-plane-1"},
+				{Type: v1.NodeInternalIP, Address: "172.18.0.2"},
+			}},
+		})
+		utils.DoOrDie(err)
+
+		trafficTo := func(destination *TrafficPeer, port int) *Traffic {
+			return &Traffic{
+				Source: &TrafficPeer{
+					Internal: &InternalPeer{
+						PodLabels:       map[string]string{"pod": "a"},
+						NamespaceLabels: map[string]string{"ns": "x"},
+						Namespace:       "x",
+					},
+					IP: "192.168.1.1",
+				},
+				Destination:  destination,
+				ResolvedPort: port,
+				Protocol:     v1.ProtocolTCP,
+			}
+		}
+
+		It("Should resolve node labels and InternalIP by name", func() {
+			node, err := ResolveNodePeer(kubeClient, &NodePeer{Name: "control-plane-1"}, "")
+			Expect(err).To(BeNil())
+			Expect(node.IP).To(Equal("172.18.0.2"))
+			Expect(node.Node.Labels).To(HaveKey("node-role.kubernetes.io/control-plane"))
+		})
+
+		It("Should deny traffic to matching nodes on matching port", func() {
+			node, err := ResolveNodePeer(kubeClient, &NodePeer{Name: "control-plane-1"}, "")
+			Expect(err).To(BeNil())
+			Expect(policy.IsTrafficAllowed(trafficTo(node, 6443)).IsAllowed()).To(BeFalse())
+			Expect(policy.IsTrafficAllowed(trafficTo(node, 10250)).IsAllowed()).To(BeTrue())
+		})
+
+		It("Should allow traffic to other nodes and to the same IP as an external host", func() {
+			worker := &TrafficPeer{Node: &NodePeer{Name: "worker-1", Labels: map[string]string{}}, IP: "172.18.0.3"}
+			Expect(policy.IsTrafficAllowed(trafficTo(worker, 6443)).IsAllowed()).To(BeTrue())
+			Expect(policy.IsTrafficAllowed(trafficTo(&TrafficPeer{IP: "172.18.0.2"}, 6443)).IsAllowed()).To(BeTrue())
+		})
+
+		It("Should fail to resolve unknown nodes", func() {
+			_, err := ResolveNodePeer(kubeClient, &NodePeer{Name: "missing"}, "")
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("AdminNetworkPolicy allowing egress to domain names", func() {
+		anpYaml := `
+apiVersion: policy.networking.k8s.io/v1alpha1
+kind: AdminNetworkPolicy
+metadata:
+  name: allow-egress-to-github
+spec:
+  priority: 10
+  subject:
+    namespaces: {}
+  egress:
+  - name: allow-to-github
+    action: Allow
+    to:
+    - domainNames:
+      - github.com
+      - "*.github.com"
+  - name: deny-to-everything-else
+    action: Deny
+    to:
+    - networks:
+      - 0.0.0.0/0`
+		anp, err := utils.ParseYaml[v1alpha1.AdminNetworkPolicy]([]byte(anpYaml))
+		utils.DoOrDie(err)
+		policy, errs := BuildV1AndV2NetPols(true, nil, []*v1alpha1.AdminNetworkPolicy{anp}, nil)
+		Expect(errs).To(BeEmpty())
+
+		resolver, err := ReadHostsFile("../../examples/hosts")
+		utils.DoOrDie(err)
+
+		trafficTo := func(hostname string, ip string) *Traffic {
+			destination := &TrafficPeer{Hostname: hostname, IP: ip}
+			utils.DoOrDie(ResolveHostname(resolver, destination))
+			return &Traffic{
+				Source: &TrafficPeer{
+					Internal: &InternalPeer{
+						PodLabels:       map[string]string{"pod": "a"},
+						NamespaceLabels: map[string]string{"ns": "x"},
+						Namespace:       "x",
+					},
+					IP: "192.168.1.1",
+				},
+				Destination:  destination,
+				ResolvedPort: 443,
+				Protocol:     v1.ProtocolTCP,
+			}
+		}
+
+		It("Should allow exact and wildcard domain name matches", func() {
+			Expect(policy.IsTrafficAllowed(trafficTo("github.com", "")).IsAllowed()).To(BeTrue())
+			Expect(policy.IsTrafficAllowed(trafficTo("API.GitHub.com.", "")).IsAllowed()).To(BeTrue())
+		})
+
+		It("Should match IP-only traffic by the hostname it resolves to", func() {
+			Expect(policy.IsTrafficAllowed(trafficTo("", "140.82.112.6")).IsAllowed()).To(BeTrue())
+		})
+
+		It("Should match IP-only traffic by any of the hostnames it resolves to", func() {
+			multiResolver := NewStaticResolver()
+			multiResolver.Add("10.0.0.1", "cdn.example.net", "www.github.com")
+			destination := &TrafficPeer{IP: "10.0.0.1"}
+			utils.DoOrDie(ResolveHostname(multiResolver, destination))
+			Expect(destination.Hostname).To(Equal("cdn.example.net"))
+			Expect(destination.HostnameAliases).To(Equal([]string{"www.github.com"}))
+
+			traffic := trafficTo("github.com", "")
+			traffic.Destination = destination
+			Expect(policy.IsTrafficAllowed(traffic).IsAllowed()).To(BeTrue())
+		})
+
+		It("Should deny other domain names", func() {
+			Expect(policy.IsTrafficAllowed(trafficTo("raw.githubusercontent.com", "")).IsAllowed()).To(BeFalse())
+			Expect(policy.IsTrafficAllowed(trafficTo("", "93.184.216.34")).IsAllowed()).To(BeFalse())
+		})
+
+		It("Should fail to resolve unknown hostnames", func() {
+			Expect(ResolveHostname(resolver, &TrafficPeer{Hostname: "unknown.example.org"})).ToNot(Succeed())
+			Expect(ResolveHostname(nil, &TrafficPeer{Hostname: "github.com"})).ToNot(Succeed())
+		})
+	})
+
+	Describe("Domain name matching", func() {
+		It("Should only match exact domain names without a wildcard", func() {
+			Expect(IsDomainNameMatch("kubernetes.io", "kubernetes.io")).To(BeTrue())
+			Expect(IsDomainNameMatch("kubernetes.io", "www.kubernetes.io")).To(BeFalse())
+			Expect(IsDomainNameMatch("kubernetes.io", "my-kubernetes.io")).To(BeFalse())
+			Expect(IsDomainNameMatch("blog.kubernetes.io", "kubernetes.io")).To(BeFalse())
+		})
+
+		It("Should match one or more entire labels with a wildcard", func() {
+			Expect(IsDomainNameMatch("*.kubernetes.io", "www.kubernetes.io")).To(BeTrue())
+			Expect(IsDomainNameMatch("*.kubernetes.io", "latest.blog.kubernetes.io")).To(BeTrue())
+			Expect(IsDomainNameMatch("*.kubernetes.io", "kubernetes.io")).To(BeFalse())
+			Expect(IsDomainNameMatch("*.kubernetes.io", "my-kubernetes.io")).To(BeFalse())
+			Expect(IsDomainNameMatch("*.kubernetes.io", "wikipedia.org")).To(BeFalse())
+		})
+
+		It("Should ignore case and a trailing dot", func() {
+			Expect(IsDomainNameMatch("Kubernetes.IO.", "kubernetes.io")).To(BeTrue())
+			Expect(IsDomainNameMatch("*.kubernetes.io", "WWW.Kubernetes.io.")).To(BeTrue())
+		})
+	})
 }
@@ -0,0 +1,72 @@
+package matcher
+
+import (
+	"sort"
+)
+
+// kindPrecedence is the order in which policies of each kind take effect: ANP, then v1 NetPol, then BANP.
+var kindPrecedence = map[PolicyKind]int{
+	AdminNetworkPolicy:         0,
+	NetworkPolicyV1:            1,
+	BaselineAdminNetworkPolicy: 2,
+}
+
+// precedence returns the kind and priority of a target's first rule to take effect.
+// A target combines the rules of all policies sharing its subject, so it may hold ANP and BANP rules.
+func (t *Target) precedence() (PolicyKind, int) {
+	if _, ok := t.SubjectMatcher.(*SubjectV1); ok {
+		return NetworkPolicyV1, 0
+	}
+	kind, priority := BaselineAdminNetworkPolicy, maxInt
+	for _, peer := range t.Peers {
+		admin, ok := peer.(*PeerMatcherAdmin)
+		if !ok || admin.effectFromMatch.PolicyKind != AdminNetworkPolicy {
+			continue
+		}
+		kind = AdminNetworkPolicy
+		if admin.effectFromMatch.Priority < priority {
+			priority = admin.effectFromMatch.Priority
+		}
+	}
+	if kind == BaselineAdminNetworkPolicy {
+		priority = 0
+	}
+	return kind, priority
+}
+
+// TargetsApplyingToPodByPrecedence returns the targets applying to a pod in the order their rules take effect:
+// targets with ANP rules by priority, then v1 NetPol targets, then BANP targets.
+func (p *Policy) TargetsApplyingToPodByPrecedence(isIngress bool, subject *InternalPeer) []*Target {
+	targets := p.TargetsApplyingToPod(isIngress, subject)
+	sort.Slice(targets, func(i, j int) bool {
+		iKind, iPriority := targets[i].precedence()
+		jKind, jPriority := targets[j].precedence()
+		if iKind != jKind {
+			return kindPrecedence[iKind] < kindPrecedence[jKind]
+		}
+		if iPriority != jPriority {
+			return iPriority < jPriority
+		}
+		return targets[i].GetPrimaryKey() < targets[j].GetPrimaryKey()
+	})
+	return targets
+}
+
+// SortedByPrecedence returns the effects in the order they're evaluated: ANP rules by priority, then v1 NetPols,
+// then BANP rules.  Rules of the same ANP or BANP keep their order; v1 NetPols, which are unordered, are sorted by name.
+func (d DirectionResult) SortedByPrecedence() DirectionResult {
+	if d == nil {
+		return nil
+	}
+	sorted := append(DirectionResult{}, d...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].PolicyKind != sorted[j].PolicyKind {
+			return kindPrecedence[sorted[i].PolicyKind] < kindPrecedence[sorted[j].PolicyKind]
+		}
+		if sorted[i].PolicyKind == NetworkPolicyV1 {
+			return sorted[i].RuleName < sorted[j].RuleName
+		}
+		return sorted[i].Priority < sorted[j].Priority
+	})
+	return sorted
+}
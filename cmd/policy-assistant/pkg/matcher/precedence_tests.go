@@ -0,0 +1,125 @@
+package matcher
+
+import (
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+func RunPrecedenceTests() {
+	Describe("Precedence", func() {
+		policyYamls := []string{`
+apiVersion: policy.networking.k8s.io/v1alpha1
+kind: AdminNetworkPolicy
+metadata:
+  name: low-priority
+spec:
+  priority: 20
+  subject:
+    namespaces:
+      matchLabels:
+        ns: x
+  ingress:
+  - name: allow-all
+    action: Allow
+    from:
+    - namespaces: {}`, `
+apiVersion: policy.networking.k8s.io/v1alpha1
+kind: AdminNetworkPolicy
+metadata:
+  name: high-priority
+spec:
+  priority: 5
+  subject:
+    pods:
+      namespaceSelector:
+        matchLabels:
+          ns: x
+      podSelector:
+        matchLabels:
+          pod: a
+  ingress:
+  - name: deny-from-y
+    action: Deny
+    from:
+    - namespaces:
+        matchLabels:
+          ns: "y"`}
+		var anps []*v1alpha1.AdminNetworkPolicy
+		for _, anpYaml := range policyYamls {
+			anp, err := utils.ParseYaml[v1alpha1.AdminNetworkPolicy]([]byte(anpYaml))
+			utils.DoOrDie(err)
+			anps = append(anps, anp)
+		}
+		banp, err := utils.ParseYaml[v1alpha1.BaselineAdminNetworkPolicy]([]byte(`
+apiVersion: policy.networking.k8s.io/v1alpha1
+kind: BaselineAdminNetworkPolicy
+metadata:
+  name: default
+spec:
+  subject:
+    namespaces: {}
+  ingress:
+  - name: deny-all
+    action: Deny
+    from:
+    - namespaces: {}`))
+		utils.DoOrDie(err)
+		netpol, err := utils.ParseYaml[networkingv1.NetworkPolicy]([]byte(`
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-from-z
+  namespace: x
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - namespaceSelector:
+        matchLabels:
+          ns: z`))
+		utils.DoOrDie(err)
+
+		policy, errs := BuildV1AndV2NetPols(false, []*networkingv1.NetworkPolicy{netpol}, anps, banp)
+		Expect(errs).To(BeEmpty())
+
+		It("Should order targets by kind, then by ANP priority", func() {
+			targets := policy.TargetsApplyingToPodByPrecedence(true, &InternalPeer{
+				PodLabels:       map[string]string{"pod": "a"},
+				NamespaceLabels: map[string]string{"ns": "x"},
+				Namespace:       "x",
+			})
+			var sourceRules [][]NetPolID
+			for _, target := range targets {
+				sourceRules = append(sourceRules, target.SourceRules)
+			}
+			Expect(sourceRules).To(Equal([][]NetPolID{
+				{"[ANP] default/high-priority"},
+				{"[ANP] default/low-priority"},
+				{"[NPv1] x/allow-from-z"},
+				{"[BANP] default/default"},
+			}))
+			Expect(policy.TargetsApplyingToPodByPrecedence(false, &InternalPeer{Namespace: "x"})).To(BeEmpty())
+		})
+
+		It("Should order effects in the order they're evaluated", func() {
+			effects := DirectionResult{
+				{RuleName: "default", PolicyKind: BaselineAdminNetworkPolicy, Verdict: Deny},
+				{RuleName: "x/b", PolicyKind: NetworkPolicyV1, Verdict: None},
+				{RuleName: "low-priority", PolicyKind: AdminNetworkPolicy, Priority: 20, Verdict: Allow},
+				{RuleName: "x/a", PolicyKind: NetworkPolicyV1, Verdict: Allow},
+				{RuleName: "high-priority", PolicyKind: AdminNetworkPolicy, Priority: 5, Verdict: Pass},
+			}
+			var names []string
+			for _, effect := range effects.SortedByPrecedence() {
+				names = append(names, effect.RuleName)
+			}
+			Expect(names).To(Equal([]string{"high-priority", "low-priority", "x/a", "x/b", "default"}))
+			Expect(effects[0].RuleName).To(Equal("default"))
+		})
+	})
+}
@@ -0,0 +1,188 @@
+package matcher
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+// PodPort is a port that a pod serves traffic on.
+type PodPort struct {
+	Port     int
+	Name     string
+	Protocol v1.Protocol
+}
+
+// PodResource is a pod, along with the labels of its namespace, that policies are evaluated against.
+type PodResource struct {
+	Namespace       string
+	Name            string
+	Labels          map[string]string
+	NamespaceLabels map[string]string
+	IP              string
+	Ports           []*PodPort
+}
+
+func (p *PodResource) String() string {
+	return fmt.Sprintf("%s/%s", p.Namespace, p.Name)
+}
+
+func (p *PodResource) trafficPeer() *TrafficPeer {
+	return &TrafficPeer{
+		Internal: &InternalPeer{
+			PodLabels:       p.Labels,
+			NamespaceLabels: p.NamespaceLabels,
+			Namespace:       p.Namespace,
+		},
+		IP: p.IP,
+	}
+}
+
+// VerdictConflict is a pair of rules, one from each ANP of a PriorityConflict, which match the same traffic
+// with different verdicts.
+type VerdictConflict struct {
+	IsIngress bool
+	Rule      *AdminRule
+	OtherRule *AdminRule
+	// Example is one connection matched by both rules
+	Example string
+}
+
+// PriorityConflict is a pair of ANPs with the same priority whose subjects select at least one common pod.
+// The order in which such ANPs are evaluated is undefined.
+type PriorityConflict struct {
+	Priority    int
+	PolicyName  string
+	OtherPolicy string
+	// Pods are selected by the subjects of both ANPs
+	Pods []*PodResource
+	// Verdicts are the conflicting verdicts that the ANPs produce for traffic of Pods.
+	// If it's empty, the ANPs overlap but agree on all traffic between the given pods.
+	Verdicts []*VerdictConflict
+}
+
+// PriorityConflicts finds pairs of ANPs with the same priority whose subjects select a common pod,
+// and the conflicting verdicts they produce for traffic between the given pods.
+// Only pod-to-pod traffic on the ports of the given pods is evaluated: networks, nodes and domainNames
+// peers are not taken into account.
+// ANPs which can't be built are skipped and returned as errors.
+func PriorityConflicts(anps []*v1alpha1.AdminNetworkPolicy, pods []*PodResource) ([]*PriorityConflict, []*PolicyError) {
+	type builtANP struct {
+		anp     *v1alpha1.AdminNetworkPolicy
+		subject *SubjectAdmin
+		ingress []*AdminRule
+		egress  []*AdminRule
+	}
+
+	var policyErrors []*PolicyError
+	byPriority := map[int32][]*builtANP{}
+	for _, anp := range anps {
+		ingress, egress, errs := BuildTargetANP(anp)
+		if len(errs) > 0 {
+			policyErrors = append(policyErrors, errs...)
+			continue
+		}
+		built := &builtANP{anp: anp, subject: NewSubjectAdmin(&anp.Spec.Subject)}
+		if ingress != nil {
+			built.ingress = AdminRules([]*Target{ingress})
+		}
+		if egress != nil {
+			built.egress = AdminRules([]*Target{egress})
+		}
+		byPriority[anp.Spec.Priority] = append(byPriority[anp.Spec.Priority], built)
+	}
+
+	var priorities []int32
+	for priority := range byPriority {
+		priorities = append(priorities, priority)
+	}
+	sort.Slice(priorities, func(i, j int) bool { return priorities[i] < priorities[j] })
+
+	var conflicts []*PriorityConflict
+	for _, priority := range priorities {
+		built := byPriority[priority]
+		sort.Slice(built, func(i, j int) bool { return built[i].anp.Name < built[j].anp.Name })
+		for i, a := range built {
+			for _, b := range built[i+1:] {
+				var common []*PodResource
+				for _, pod := range pods {
+					peer := pod.trafficPeer().Internal
+					if a.subject.Matches(peer) && b.subject.Matches(peer) {
+						common = append(common, pod)
+					}
+				}
+				if len(common) == 0 {
+					continue
+				}
+				verdicts := conflictingVerdicts(true, a.ingress, b.ingress, common, pods)
+				verdicts = append(verdicts, conflictingVerdicts(false, a.egress, b.egress, common, pods)...)
+				conflicts = append(conflicts, &PriorityConflict{
+					Priority:    int(priority),
+					PolicyName:  a.anp.Name,
+					OtherPolicy: b.anp.Name,
+					Pods:        common,
+					Verdicts:    verdicts,
+				})
+			}
+		}
+	}
+	return conflicts, policyErrors
+}
+
+// conflictingVerdicts evaluates the rules of two policies against all traffic between the subject pods and the
+// given pods, and returns each pair of rules which decide some of that traffic differently.
+// Traffic is sent to the ports of the destination pod.
+func conflictingVerdicts(isIngress bool, rules []*AdminRule, otherRules []*AdminRule, subjects []*PodResource, pods []*PodResource) []*VerdictConflict {
+	if len(rules) == 0 || len(otherRules) == 0 {
+		return nil
+	}
+	type rulePair struct {
+		rule      int
+		otherRule int
+	}
+	seen := map[rulePair]bool{}
+	var conflicts []*VerdictConflict
+	for _, subject := range subjects {
+		subjectPeer := subject.trafficPeer()
+		for _, pod := range pods {
+			peer := pod.trafficPeer()
+			src, dst := pod, subject
+			if !isIngress {
+				src, dst = subject, pod
+			}
+			for _, port := range dst.Ports {
+				rule := firstMatchingRule(rules, subjectPeer, peer, port)
+				otherRule := firstMatchingRule(otherRules, subjectPeer, peer, port)
+				if rule == nil || otherRule == nil || rule.Verdict == otherRule.Verdict {
+					continue
+				}
+				key := rulePair{rule: rule.RuleIndex, otherRule: otherRule.RuleIndex}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				conflicts = append(conflicts, &VerdictConflict{
+					IsIngress: isIngress,
+					Rule:      rule,
+					OtherRule: otherRule,
+					Example:   fmt.Sprintf("%s -> %s on %d/%s", src, dst, port.Port, port.Protocol),
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// firstMatchingRule returns the first of a policy's rules which matches the traffic, or nil if none do.
+func firstMatchingRule(rules []*AdminRule, subject *TrafficPeer, peer *TrafficPeer, port *PodPort) *AdminRule {
+	for _, rule := range rules {
+		for _, m := range rule.Peers {
+			if m.Matches(subject, peer, port.Port, port.Name, port.Protocol) {
+				return rule
+			}
+		}
+	}
+	return nil
+}
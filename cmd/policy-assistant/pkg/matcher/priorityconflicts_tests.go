@@ -0,0 +1,110 @@
+package matcher
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+func RunPriorityConflictsTests() {
+	podsInNamespace := func(ns string, labels map[string]string) v1alpha1.AdminNetworkPolicySubject {
+		return v1alpha1.AdminNetworkPolicySubject{Pods: &v1alpha1.NamespacedPod{
+			NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{v1.LabelMetadataName: ns}},
+			PodSelector:       metav1.LabelSelector{MatchLabels: labels},
+		}}
+	}
+	allPods := v1alpha1.AdminNetworkPolicyIngressPeer{Namespaces: &metav1.LabelSelector{}}
+	ingressANP := func(name string, priority int32, subject v1alpha1.AdminNetworkPolicySubject, actions ...v1alpha1.AdminNetworkPolicyRuleAction) *v1alpha1.AdminNetworkPolicy {
+		var rules []v1alpha1.AdminNetworkPolicyIngressRule
+		for _, action := range actions {
+			rules = append(rules, v1alpha1.AdminNetworkPolicyIngressRule{
+				Name:   string(action),
+				Action: action,
+				From:   []v1alpha1.AdminNetworkPolicyIngressPeer{allPods},
+			})
+		}
+		return &v1alpha1.AdminNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1alpha1.AdminNetworkPolicySpec{Priority: priority, Subject: subject, Ingress: rules},
+		}
+	}
+	pod := func(ns string, name string, labels map[string]string) *PodResource {
+		return &PodResource{
+			Namespace:       ns,
+			Name:            name,
+			Labels:          labels,
+			NamespaceLabels: map[string]string{v1.LabelMetadataName: ns},
+			Ports:           []*PodPort{{Port: 80, Name: "serve-80-tcp", Protocol: v1.ProtocolTCP}},
+		}
+	}
+	podXA := pod("x", "a", map[string]string{"pod": "a"})
+	podXB := pod("x", "b", map[string]string{"pod": "b"})
+	podYA := pod("y", "a", map[string]string{"pod": "a"})
+	pods := []*PodResource{podXA, podXB, podYA}
+
+	Describe("PriorityConflicts", func() {
+		It("reports conflicting verdicts of ANPs with the same priority and overlapping subjects", func() {
+			conflicts, errs := PriorityConflicts([]*v1alpha1.AdminNetworkPolicy{
+				ingressANP("deny-x", 10, podsInNamespace("x", nil), v1alpha1.AdminNetworkPolicyRuleActionDeny),
+				ingressANP("allow-a", 10, v1alpha1.AdminNetworkPolicySubject{Pods: &v1alpha1.NamespacedPod{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"pod": "a"}},
+				}}, v1alpha1.AdminNetworkPolicyRuleActionAllow),
+			}, pods)
+			Expect(errs).To(BeEmpty())
+			Expect(conflicts).To(HaveLen(1))
+			conflict := conflicts[0]
+			Expect(conflict.Priority).To(Equal(10))
+			Expect(conflict.PolicyName).To(Equal("allow-a"))
+			Expect(conflict.OtherPolicy).To(Equal("deny-x"))
+			Expect(conflict.Pods).To(Equal([]*PodResource{podXA}))
+			Expect(conflict.Verdicts).To(HaveLen(1))
+			Expect(conflict.Verdicts[0].IsIngress).To(BeTrue())
+			Expect(conflict.Verdicts[0].Rule.Verdict).To(Equal(Allow))
+			Expect(conflict.Verdicts[0].OtherRule.Verdict).To(Equal(Deny))
+			Expect(conflict.Verdicts[0].Example).To(Equal("x/a -> x/a on 80/TCP"))
+		})
+
+		It("reports overlapping ANPs that agree on all traffic without verdicts", func() {
+			conflicts, errs := PriorityConflicts([]*v1alpha1.AdminNetworkPolicy{
+				ingressANP("deny-x", 10, podsInNamespace("x", nil), v1alpha1.AdminNetworkPolicyRuleActionDeny),
+				ingressANP("deny-x-a", 10, podsInNamespace("x", map[string]string{"pod": "a"}), v1alpha1.AdminNetworkPolicyRuleActionDeny),
+			}, pods)
+			Expect(errs).To(BeEmpty())
+			Expect(conflicts).To(HaveLen(1))
+			Expect(conflicts[0].Pods).To(Equal([]*PodResource{podXA}))
+			Expect(conflicts[0].Verdicts).To(BeEmpty())
+		})
+
+		It("uses the first matching rule of each policy", func() {
+			conflicts, _ := PriorityConflicts([]*v1alpha1.AdminNetworkPolicy{
+				ingressANP("pass-then-deny", 10, podsInNamespace("y", nil), v1alpha1.AdminNetworkPolicyRuleActionPass, v1alpha1.AdminNetworkPolicyRuleActionDeny),
+				ingressANP("deny", 10, podsInNamespace("y", nil), v1alpha1.AdminNetworkPolicyRuleActionDeny),
+			}, pods)
+			Expect(conflicts).To(HaveLen(1))
+			Expect(conflicts[0].Verdicts).To(HaveLen(1))
+			Expect(conflicts[0].Verdicts[0].Rule.PolicyName).To(Equal("deny"))
+			Expect(conflicts[0].Verdicts[0].OtherRule.RuleIndex).To(Equal(0))
+			Expect(conflicts[0].Verdicts[0].OtherRule.Verdict).To(Equal(Pass))
+		})
+
+		It("ignores ANPs with different priorities or disjoint subjects", func() {
+			conflicts, errs := PriorityConflicts([]*v1alpha1.AdminNetworkPolicy{
+				ingressANP("deny-x", 10, podsInNamespace("x", nil), v1alpha1.AdminNetworkPolicyRuleActionDeny),
+				ingressANP("allow-x", 20, podsInNamespace("x", nil), v1alpha1.AdminNetworkPolicyRuleActionAllow),
+				ingressANP("allow-y", 10, podsInNamespace("y", nil), v1alpha1.AdminNetworkPolicyRuleActionAllow),
+			}, pods)
+			Expect(errs).To(BeEmpty())
+			Expect(conflicts).To(BeEmpty())
+		})
+
+		It("returns errors for ANPs that can't be built", func() {
+			_, errs := PriorityConflicts([]*v1alpha1.AdminNetworkPolicy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "empty"}, Spec: v1alpha1.AdminNetworkPolicySpec{Priority: 10, Subject: podsInNamespace("x", nil)}},
+			}, pods)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Name).To(Equal("empty"))
+		})
+	})
+}
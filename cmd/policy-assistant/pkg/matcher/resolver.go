@@ -0,0 +1,116 @@
+package matcher
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DomainNameResolver maps between hostnames and IPs for external traffic peers.
+type DomainNameResolver interface {
+	// LookupHost returns the IPs of a hostname.
+	LookupHost(hostname string) ([]string, error)
+	// LookupAddr returns the hostnames of an IP, canonical name first.
+	LookupAddr(ip string) ([]string, error)
+}
+
+// StaticResolver is a DomainNameResolver backed by fixed entries, for offline analysis.
+type StaticResolver struct {
+	hostToIPs map[string][]string
+	ipToHosts map[string][]string
+}
+
+func NewStaticResolver() *StaticResolver {
+	return &StaticResolver{
+		hostToIPs: map[string][]string{},
+		ipToHosts: map[string][]string{},
+	}
+}
+
+// Add maps an IP to one or more hostnames; the first is the canonical name.
+func (s *StaticResolver) Add(ip string, hostnames ...string) {
+	for _, hostname := range hostnames {
+		host := normalizeDomainName(hostname)
+		s.hostToIPs[host] = append(s.hostToIPs[host], ip)
+		s.ipToHosts[ip] = append(s.ipToHosts[ip], host)
+	}
+}
+
+func (s *StaticResolver) LookupHost(hostname string) ([]string, error) {
+	ips, ok := s.hostToIPs[normalizeDomainName(hostname)]
+	if !ok {
+		return nil, errors.Errorf("no IPs found for host %s", hostname)
+	}
+	return ips, nil
+}
+
+func (s *StaticResolver) LookupAddr(ip string) ([]string, error) {
+	return s.ipToHosts[ip], nil
+}
+
+// ReadHostsFile builds a StaticResolver from a file in /etc/hosts format:
+// each line is an IP followed by its hostnames, and '#' starts a comment.
+func ReadHostsFile(path string) (*StaticResolver, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open hosts file %s", path)
+	}
+	defer file.Close()
+
+	resolver := NewStaticResolver()
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+			return nil, errors.Errorf("invalid hosts file entry at %s:%d: expected an IP followed by hostnames", path, lineNumber)
+		}
+		resolver.Add(fields[0], fields[1:]...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read hosts file %s", path)
+	}
+	return resolver, nil
+}
+
+// ResolveHostname fills in whichever of an external peer's Hostname and IP is missing.  An IP may have several
+// hostnames: the first is the Hostname, and the others are kept as HostnameAliases.
+// Pods and nodes are left alone.
+func ResolveHostname(resolver DomainNameResolver, peer *TrafficPeer) error {
+	if !peer.IsExternal() || peer.IsNode() || (peer.Hostname != "" && peer.IP != "") {
+		return nil
+	}
+	if peer.Hostname != "" {
+		if resolver == nil {
+			return errors.Errorf("unable to resolve host %s: no resolver configured, set its IP instead", peer.Hostname)
+		}
+		ips, err := resolver.LookupHost(peer.Hostname)
+		if err != nil {
+			return err
+		}
+		if len(ips) == 0 {
+			return errors.Errorf("no IPs found for host %s", peer.Hostname)
+		}
+		peer.IP = ips[0]
+		return nil
+	}
+	if peer.IP != "" && resolver != nil {
+		hostnames, err := resolver.LookupAddr(peer.IP)
+		if err != nil {
+			return err
+		}
+		if len(hostnames) > 0 {
+			peer.Hostname = hostnames[0]
+			peer.HostnameAliases = hostnames[1:]
+		}
+	}
+	return nil
+}
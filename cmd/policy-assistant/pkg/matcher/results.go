@@ -0,0 +1,119 @@
+package matcher
+
+import (
+	"strings"
+)
+
+// ExplanationResult is one row of a policy explanation: the action taken by policies on traffic between
+// a target's subject and a peer.
+type ExplanationResult struct {
+	Type         string   `json:"type"`
+	Subject      string   `json:"subject"`
+	SourceRules  []string `json:"sourceRules,omitempty"`
+	Peer         string   `json:"peer"`
+	Action       []string `json:"action"`
+	PortProtocol []string `json:"portProtocol"`
+}
+
+// Explain returns the same information as ExplainTable, one result per table row.
+func (p *Policy) Explain() []*ExplanationResult {
+	ingresses, egresses := p.SortedTargets()
+	return ExplainTargets(ingresses, egresses)
+}
+
+// ExplainTargets returns the same information as ExplainTargetsTable, one result per table row.
+func ExplainTargets(ingresses []*Target, egresses []*Target) []*ExplanationResult {
+	builder := &SliceBuilder{}
+	builder.TargetsTableLines(ingresses, true)
+	builder.TargetsTableLines(egresses, false)
+
+	var results []*ExplanationResult
+	for _, row := range builder.Elements {
+		results = append(results, &ExplanationResult{
+			Type:         row[0],
+			Subject:      row[1],
+			SourceRules:  splitLines(row[2]),
+			Peer:         row[3],
+			Action:       splitLines(row[4]),
+			PortProtocol: splitLines(row[5]),
+		})
+	}
+	return results
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return lines
+}
+
+// EffectResult is an Effect of a single policy rule on traffic.
+type EffectResult struct {
+	PolicyKind PolicyKind `json:"policyKind"`
+	RuleName   string     `json:"ruleName"`
+	// Priority is only set for ANPs
+	Priority int     `json:"priority,omitempty"`
+	Verdict  Verdict `json:"verdict"`
+}
+
+// DirectionVerdictResult is the outcome of evaluating policies for one direction of traffic.
+type DirectionVerdictResult struct {
+	Allowed bool `json:"allowed"`
+	// Flow is empty if no policies target the traffic in this direction
+	Flow    string          `json:"flow,omitempty"`
+	Effects []*EffectResult `json:"effects,omitempty"`
+}
+
+func NewDirectionVerdictResult(d DirectionResult) *DirectionVerdictResult {
+	result := &DirectionVerdictResult{Allowed: d.IsAllowed(), Flow: d.Flow()}
+	for _, effect := range d {
+		result.Effects = append(result.Effects, &EffectResult{
+			PolicyKind: effect.PolicyKind,
+			RuleName:   effect.RuleName,
+			Priority:   effect.Priority,
+			Verdict:    effect.Verdict,
+		})
+	}
+	return result
+}
+
+// VerdictResult is the outcome of evaluating policies for both directions of traffic.
+type VerdictResult struct {
+	Verdict string                  `json:"verdict"`
+	Ingress *DirectionVerdictResult `json:"ingress"`
+	Egress  *DirectionVerdictResult `json:"egress"`
+}
+
+func NewVerdictResult(ar *AllowedResult) *VerdictResult {
+	return &VerdictResult{
+		Verdict: ar.Verdict(),
+		Ingress: NewDirectionVerdictResult(ar.Ingress),
+		Egress:  NewDirectionVerdictResult(ar.Egress),
+	}
+}
+
+// TrafficVerdictResult is the verdict for a single piece of traffic.  Traffic to a service has no verdict of its
+// own: instead, it has a verdict for each backend, and whether all, some or none of them are reachable.
+type TrafficVerdictResult struct {
+	Traffic string `json:"traffic"`
+	*VerdictResult
+	Reachable ServiceReachability     `json:"reachable,omitempty"`
+	Backends  []*TrafficVerdictResult `json:"backends,omitempty"`
+}
+
+func NewTrafficVerdictResult(traffic *Traffic, ar *AllowedResult) *TrafficVerdictResult {
+	return &TrafficVerdictResult{Traffic: traffic.PrettyString(), VerdictResult: NewVerdictResult(ar)}
+}
+
+func NewServiceTrafficVerdictResult(traffic *Traffic, reachable ServiceReachability, backends []*ServiceBackend, results []*AllowedResult) *TrafficVerdictResult {
+	result := &TrafficVerdictResult{Traffic: traffic.PrettyString(), Reachable: reachable}
+	for i, backend := range backends {
+		result.Backends = append(result.Backends, NewTrafficVerdictResult(backend.Traffic, results[i]))
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package matcher
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func RunResultsTests() {
+	Describe("Results", func() {
+		denyAll := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "deny-all"},
+			Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}},
+		}
+
+		It("explains policies one row per target and peer", func() {
+			policy, errs := BuildV1AndV2NetPols(false, []*networkingv1.NetworkPolicy{denyAll}, nil, nil)
+			Expect(errs).To(BeEmpty())
+
+			explanation := policy.Explain()
+			Expect(explanation).To(HaveLen(1))
+			Expect(explanation[0].Type).To(Equal("Ingress"))
+			Expect(explanation[0].SourceRules).To(Equal([]string{"[NPv1] x/deny-all"}))
+			Expect(explanation[0].Peer).To(Equal("no peers"))
+			Expect(explanation[0].PortProtocol).To(Equal([]string{"none"}))
+		})
+
+		It("reports the verdict and effects of each direction", func() {
+			policy, errs := BuildV1AndV2NetPols(false, []*networkingv1.NetworkPolicy{denyAll}, nil, nil)
+			Expect(errs).To(BeEmpty())
+
+			peer := func(ns string, ip string) *TrafficPeer {
+				return &TrafficPeer{Internal: &InternalPeer{Namespace: ns, NamespaceLabels: map[string]string{v1.LabelMetadataName: ns}}, IP: ip}
+			}
+			traffic := &Traffic{Source: peer("y", "1.2.3.4"), Destination: peer("x", "1.2.3.5"), ResolvedPort: 80, Protocol: v1.ProtocolTCP}
+
+			result := NewTrafficVerdictResult(traffic, policy.IsTrafficAllowed(traffic))
+			Expect(result.Verdict).To(Equal("Denied"))
+			Expect(result.Ingress.Allowed).To(BeFalse())
+			Expect(result.Ingress.Flow).To(Equal("[NPv1] Dropped (x/deny-all)"))
+			Expect(result.Ingress.Effects).To(Equal([]*EffectResult{{PolicyKind: NetworkPolicyV1, RuleName: "x/deny-all", Verdict: None}}))
+			Expect(result.Egress.Allowed).To(BeTrue())
+			Expect(result.Egress.Flow).To(BeEmpty())
+			Expect(result.Egress.Effects).To(BeEmpty())
+		})
+	})
+}
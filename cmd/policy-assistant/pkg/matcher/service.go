@@ -0,0 +1,209 @@
+package matcher
+
+import (
+	"sort"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ServicePeer is a Service in the cluster.  Traffic to a service is evaluated for each of the pods backing it.
+type ServicePeer struct {
+	Namespace string
+	Name      string
+}
+
+type ServiceReachability string
+
+const (
+	ServiceReachableAll  ServiceReachability = "all"
+	ServiceReachableSome ServiceReachability = "some"
+	ServiceReachableNone ServiceReachability = "none"
+)
+
+// ServiceBackend is traffic to one of the pods backing a service.
+type ServiceBackend struct {
+	Pod     string
+	Traffic *Traffic
+}
+
+// ResolveServiceBackends resolves traffic to a service into traffic to each of the pods selected by the service.
+// The traffic's port is a port of the service, found by number, or by name if the number is 0; it is sent to the
+// port's targetPort.  Named targetPorts are resolved on each pod, and pods that don't declare the named port aren't
+// backends, as for kube endpoints.  Likewise, only ready pods are backends, unless the service publishes not-ready
+// addresses.  Traffic goes to the pod IP of the service's primary IP family.
+func ResolveServiceBackends(kubeClient kube.IKubernetes, traffic *Traffic) ([]*ServiceBackend, error) {
+	servicePeer := traffic.Destination.Service
+	if servicePeer == nil {
+		return nil, errors.Errorf("traffic destination is not a service")
+	}
+	services, err := kubeClient.GetServicesInNamespace(servicePeer.Namespace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read services from kube, ns '%s'", servicePeer.Namespace)
+	}
+	var service *v1.Service
+	for i := range services {
+		if services[i].Name == servicePeer.Name {
+			service = &services[i]
+		}
+	}
+	if service == nil {
+		return nil, errors.Errorf("service %s/%s not found", servicePeer.Namespace, servicePeer.Name)
+	}
+	if len(service.Spec.Selector) == 0 {
+		return nil, errors.Errorf("service %s/%s has no selector: its endpoints aren't pods selected by labels", servicePeer.Namespace, servicePeer.Name)
+	}
+
+	protocol := traffic.Protocol
+	if protocol == "" {
+		protocol = v1.ProtocolTCP
+	}
+	servicePort, err := findServicePort(service, traffic.ResolvedPort, traffic.ResolvedPortName, protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := kubeClient.GetNamespace(servicePeer.Namespace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read namespace '%s' from kube", servicePeer.Namespace)
+	}
+	pods, err := kubeClient.GetPodsInNamespace(servicePeer.Namespace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read pods from kube, ns '%s'", servicePeer.Namespace)
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+	selector := labels.SelectorFromSet(service.Spec.Selector)
+	var backends []*ServiceBackend
+	for _, pod := range pods {
+		if !selector.Matches(labels.Set(pod.Labels)) || !isServiceBackend(&pod, service) {
+			continue
+		}
+		port, portName, ok := resolveTargetPort(&pod, servicePort, protocol)
+		if !ok {
+			continue
+		}
+		workload := pod.Namespace + "/pod/" + pod.Name
+		backends = append(backends, &ServiceBackend{
+			Pod: pod.Namespace + "/" + pod.Name,
+			Traffic: &Traffic{
+				Source: traffic.Source,
+				Destination: &TrafficPeer{
+					Internal: &InternalPeer{
+						Workload:        workload,
+						PodLabels:       pod.Labels,
+						NamespaceLabels: ns.Labels,
+						Namespace:       pod.Namespace,
+					},
+					IP: backendIP(&pod, service),
+				},
+				ResolvedPort:     port,
+				ResolvedPortName: portName,
+				Protocol:         protocol,
+			},
+		})
+	}
+	return backends, nil
+}
+
+// isServiceBackend returns whether kube endpoints would route traffic of the service to the selected pod: it must
+// have an IP and not be done, and be ready and not terminating unless the service publishes not-ready addresses.
+func isServiceBackend(pod *v1.Pod, service *v1.Service) bool {
+	if len(kube.PodIPs(pod)) == 0 || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return false
+	}
+	if service.Spec.PublishNotReadyAddresses {
+		return true
+	}
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// backendIP returns the pod IP of the service's primary IP family, or the pod's primary IP.
+func backendIP(pod *v1.Pod, service *v1.Service) string {
+	ips := kube.PodIPs(pod)
+	if len(service.Spec.IPFamilies) > 0 {
+		if ip := kube.IPForFamily(ips, service.Spec.IPFamilies[0]); ip != "" {
+			return ip
+		}
+	}
+	return ips[0]
+}
+
+func findServicePort(service *v1.Service, port int, portName string, protocol v1.Protocol) (*v1.ServicePort, error) {
+	for i, servicePort := range service.Spec.Ports {
+		servicePortProtocol := servicePort.Protocol
+		if servicePortProtocol == "" {
+			servicePortProtocol = v1.ProtocolTCP
+		}
+		if servicePortProtocol != protocol {
+			continue
+		}
+		if (port != 0 && int(servicePort.Port) == port) || (port == 0 && portName != "" && servicePort.Name == portName) {
+			return &service.Spec.Ports[i], nil
+		}
+	}
+	return nil, errors.Errorf("service %s/%s has no port %d (%s) on %s", service.Namespace, service.Name, port, portName, protocol)
+}
+
+// resolveTargetPort returns the number and name of the container port of the pod that the service port sends
+// traffic to.  A numbered targetPort needn't be declared by the pod; a named one must be.
+func resolveTargetPort(pod *v1.Pod, servicePort *v1.ServicePort, protocol v1.Protocol) (int, string, bool) {
+	targetPort := servicePort.TargetPort
+	if targetPort.Type == intstr.Int && targetPort.IntVal == 0 {
+		targetPort = intstr.FromInt32(servicePort.Port)
+	}
+	for _, cont := range pod.Spec.Containers {
+		for _, port := range cont.Ports {
+			portProtocol := port.Protocol
+			if portProtocol == "" {
+				portProtocol = v1.ProtocolTCP
+			}
+			if portProtocol != protocol {
+				continue
+			}
+			if targetPort.Type == intstr.String && port.Name == targetPort.StrVal {
+				return int(port.ContainerPort), port.Name, true
+			}
+			if targetPort.Type == intstr.Int && port.ContainerPort == targetPort.IntVal {
+				return int(port.ContainerPort), port.Name, true
+			}
+		}
+	}
+	if targetPort.Type == intstr.Int {
+		return int(targetPort.IntVal), "", true
+	}
+	return 0, "", false
+}
+
+// IsServiceTrafficAllowed evaluates the traffic to each backend of a service, and whether all, some or none of
+// them are reachable.  A service without backends isn't reachable.
+func (p *Policy) IsServiceTrafficAllowed(backends []*ServiceBackend) (ServiceReachability, []*AllowedResult) {
+	var results []*AllowedResult
+	allowed := 0
+	for _, backend := range backends {
+		result := p.IsTrafficAllowed(backend.Traffic)
+		results = append(results, result)
+		if result.IsAllowed() {
+			allowed++
+		}
+	}
+	switch {
+	case allowed == 0:
+		return ServiceReachableNone, results
+	case allowed == len(backends):
+		return ServiceReachableAll, results
+	default:
+		return ServiceReachableSome, results
+	}
+}
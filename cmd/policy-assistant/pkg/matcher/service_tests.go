@@ -0,0 +1,169 @@
+package matcher
+
+import (
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func RunServiceTests() {
+	Describe("Service traffic", func() {
+		kubeClient := kube.NewMockKubernetes(1.0)
+		_, err := kubeClient.CreateNamespace(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "y", Labels: map[string]string{"ns": "y"}}})
+		utils.DoOrDie(err)
+		createPod := func(name string, labels map[string]string, ports ...v1.ContainerPort) *v1.Pod {
+			pod, err := kubeClient.CreatePod(&v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "y", Name: name, Labels: labels},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "cont", Ports: ports}}},
+			})
+			utils.DoOrDie(err)
+			return pod
+		}
+		createPod("web-1", map[string]string{"app": "web", "version": "v1"}, v1.ContainerPort{Name: "http", ContainerPort: 8080})
+		createPod("web-2", map[string]string{"app": "web", "version": "v2"}, v1.ContainerPort{Name: "http", ContainerPort: 9090})
+		createPod("web-3", map[string]string{"app": "web", "version": "v3"})
+		createPod("db", map[string]string{"app": "db"}, v1.ContainerPort{Name: "http", ContainerPort: 8080})
+
+		apiReady := createPod("api-ready", map[string]string{"app": "api"})
+		apiReady.Status.PodIPs = []v1.PodIP{{IP: apiReady.Status.PodIP}, {IP: "fd00::1"}}
+		createPod("api-not-ready", map[string]string{"app": "api"}).Status.Conditions[0].Status = v1.ConditionFalse
+		createPod("api-terminating", map[string]string{"app": "api"}).DeletionTimestamp = &metav1.Time{}
+		createPod("api-succeeded", map[string]string{"app": "api"}).Status.Phase = v1.PodSucceeded
+
+		createService := func(name string, selector map[string]string, targetPort intstr.IntOrString) {
+			_, err := kubeClient.CreateService(&v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "y", Name: name},
+				Spec: v1.ServiceSpec{
+					Selector: selector,
+					Ports:    []v1.ServicePort{{Name: "web", Port: 443, Protocol: v1.ProtocolTCP, TargetPort: targetPort}},
+				},
+			})
+			utils.DoOrDie(err)
+		}
+		createService("web-named", map[string]string{"app": "web"}, intstr.FromString("http"))
+		createService("web-numbered", map[string]string{"app": "web"}, intstr.FromInt32(8080))
+		createService("web-default", map[string]string{"app": "web"}, intstr.IntOrString{})
+		createService("nothing", map[string]string{"app": "nothing"}, intstr.FromString("http"))
+		createService("api", map[string]string{"app": "api"}, intstr.FromInt32(8080))
+		_, err = kubeClient.CreateService(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "y", Name: "api-ipv6-not-ready"},
+			Spec: v1.ServiceSpec{
+				Selector:                 map[string]string{"app": "api"},
+				Ports:                    []v1.ServicePort{{Name: "web", Port: 443, Protocol: v1.ProtocolTCP, TargetPort: intstr.FromInt32(8080)}},
+				IPFamilies:               []v1.IPFamily{v1.IPv6Protocol, v1.IPv4Protocol},
+				PublishNotReadyAddresses: true,
+			},
+		})
+		utils.DoOrDie(err)
+		_, err = kubeClient.CreateService(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "y", Name: "external"},
+			Spec:       v1.ServiceSpec{Type: v1.ServiceTypeExternalName, ExternalName: "example.com"},
+		})
+		utils.DoOrDie(err)
+
+		trafficTo := func(service string, port int, portName string) *Traffic {
+			return &Traffic{
+				Source: &TrafficPeer{
+					Internal: &InternalPeer{
+						PodLabels:       map[string]string{"pod": "a"},
+						NamespaceLabels: map[string]string{"ns": "x"},
+						Namespace:       "x",
+					},
+					IP: "192.168.2.1",
+				},
+				Destination:      &TrafficPeer{Service: &ServicePeer{Namespace: "y", Name: service}},
+				ResolvedPort:     port,
+				ResolvedPortName: portName,
+				Protocol:         v1.ProtocolTCP,
+			}
+		}
+		backendPorts := func(backends []*ServiceBackend) map[string]int {
+			ports := map[string]int{}
+			for _, backend := range backends {
+				ports[backend.Pod] = backend.Traffic.ResolvedPort
+			}
+			return ports
+		}
+
+		It("Should resolve named targetPorts on each pod, skipping pods without the named port", func() {
+			backends, err := ResolveServiceBackends(kubeClient, trafficTo("web-named", 443, ""))
+			Expect(err).To(BeNil())
+			Expect(backendPorts(backends)).To(Equal(map[string]int{"y/web-1": 8080, "y/web-2": 9090}))
+			Expect(backends[0].Traffic.ResolvedPortName).To(Equal("http"))
+			Expect(backends[0].Traffic.Destination.Internal.NamespaceLabels).To(HaveKeyWithValue("ns", "y"))
+			Expect(backends[0].Traffic.Destination.IP).ToNot(BeEmpty())
+		})
+
+		It("Should resolve numbered and unset targetPorts on every selected pod", func() {
+			backends, err := ResolveServiceBackends(kubeClient, trafficTo("web-numbered", 443, ""))
+			Expect(err).To(BeNil())
+			Expect(backendPorts(backends)).To(Equal(map[string]int{"y/web-1": 8080, "y/web-2": 8080, "y/web-3": 8080}))
+			Expect(backends[0].Traffic.ResolvedPortName).To(Equal("http"))
+			Expect(backends[1].Traffic.ResolvedPortName).To(Equal(""))
+
+			backends, err = ResolveServiceBackends(kubeClient, trafficTo("web-default", 0, "web"))
+			Expect(err).To(BeNil())
+			Expect(backendPorts(backends)).To(Equal(map[string]int{"y/web-1": 443, "y/web-2": 443, "y/web-3": 443}))
+		})
+
+		It("Should only send traffic to ready pods, on the IP of the service's family", func() {
+			backends, err := ResolveServiceBackends(kubeClient, trafficTo("api", 443, ""))
+			Expect(err).To(BeNil())
+			Expect(backendPorts(backends)).To(Equal(map[string]int{"y/api-ready": 8080}))
+			Expect(backends[0].Traffic.Destination.IP).To(Equal(apiReady.Status.PodIP))
+
+			backends, err = ResolveServiceBackends(kubeClient, trafficTo("api-ipv6-not-ready", 443, ""))
+			Expect(err).To(BeNil())
+			Expect(backendPorts(backends)).To(Equal(map[string]int{"y/api-not-ready": 8080, "y/api-ready": 8080, "y/api-terminating": 8080}))
+			Expect(backends[1].Pod).To(Equal("y/api-ready"))
+			Expect(backends[1].Traffic.Destination.IP).To(Equal("fd00::1"))
+		})
+
+		It("Should reject unknown services and ports, and services without a selector", func() {
+			_, err := ResolveServiceBackends(kubeClient, trafficTo("missing", 443, ""))
+			Expect(err).ToNot(BeNil())
+			_, err = ResolveServiceBackends(kubeClient, trafficTo("web-named", 80, ""))
+			Expect(err).ToNot(BeNil())
+			_, err = ResolveServiceBackends(kubeClient, trafficTo("external", 443, ""))
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Should aggregate the verdicts of the backends", func() {
+			policy, errs := BuildV1AndV2NetPols(true, []*networkingv1.NetworkPolicy{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "y", Name: "deny-v1"},
+					Spec: networkingv1.NetworkPolicySpec{
+						PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"version": "v1"}},
+						PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+					},
+				},
+			}, nil, nil)
+			Expect(errs).To(BeEmpty())
+
+			backends, err := ResolveServiceBackends(kubeClient, trafficTo("web-named", 443, ""))
+			Expect(err).To(BeNil())
+			reachable, results := policy.IsServiceTrafficAllowed(backends)
+			Expect(reachable).To(Equal(ServiceReachableSome))
+			Expect(results[0].IsAllowed()).To(BeFalse())
+			Expect(results[1].IsAllowed()).To(BeTrue())
+
+			reachable, _ = NewPolicy().IsServiceTrafficAllowed(backends)
+			Expect(reachable).To(Equal(ServiceReachableAll))
+
+			backends, err = ResolveServiceBackends(kubeClient, trafficTo("nothing", 443, ""))
+			Expect(err).To(BeNil())
+			Expect(backends).To(BeEmpty())
+			reachable, _ = policy.IsServiceTrafficAllowed(backends)
+			Expect(reachable).To(Equal(ServiceReachableNone))
+
+			verdict := NewServiceTrafficVerdictResult(trafficTo("web-named", 443, ""), ServiceReachableSome, backends, nil)
+			Expect(verdict.Traffic).To(Equal("x/[pod=a] -> service/y/web-named:443 (TCP)"))
+			Expect(verdict.VerdictResult).To(BeNil())
+		})
+	})
+}
@@ -0,0 +1,425 @@
+package matcher
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// AdminRule is a single ingress or egress rule of an ANP or BANP.
+// It gathers the PeerMatcherAdmins that were built from the rule's peers.
+type AdminRule struct {
+	Subject    *SubjectAdmin
+	Kind       PolicyKind
+	PolicyName string
+	// Priority is only used for ANP
+	Priority  int
+	RuleIndex int
+	RuleName  string
+	Verdict   Verdict
+	// Peers are the matchers wrapped by the rule's PeerMatcherAdmins
+	Peers []PeerMatcher
+}
+
+func (r *AdminRule) String() string {
+	policy := fmt.Sprintf("[%s] %s", r.Kind, r.PolicyName)
+	if r.Kind == AdminNetworkPolicy {
+		policy = fmt.Sprintf("%s (priority %d)", policy, r.Priority)
+	}
+	rule := fmt.Sprintf("rule %d", r.RuleIndex)
+	if r.RuleName != "" {
+		rule = fmt.Sprintf("%s %q", rule, r.RuleName)
+	}
+	return fmt.Sprintf("%s %s: %s", policy, rule, r.Verdict)
+}
+
+// precedes returns true if the rule is always evaluated before the other rule.
+// ANP rules with the same priority in different policies don't have a defined order.
+func (r *AdminRule) precedes(other *AdminRule) bool {
+	if r.Kind != other.Kind {
+		return r.Kind == AdminNetworkPolicy
+	}
+	if r.PolicyName == other.PolicyName {
+		return r.RuleIndex < other.RuleIndex
+	}
+	return r.Kind == AdminNetworkPolicy && r.Priority < other.Priority
+}
+
+// covers returns true if the rule matches all traffic that the other rule matches.
+func (r *AdminRule) covers(other *AdminRule) bool {
+	if !subjectAdminCovers(r.Subject, other.Subject) {
+		return false
+	}
+	for _, otherPeer := range other.Peers {
+		covered := false
+		for _, peer := range r.Peers {
+			if peerMatcherCovers(peer, otherPeer) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+type ShadowReason string
+
+const (
+	ShadowedByHigherPriorityRule ShadowReason = "a rule of a higher-priority ANP matches all of its traffic"
+	ShadowedByEarlierRule        ShadowReason = "an earlier rule of the same policy matches all of its traffic"
+	DuplicateRule                ShadowReason = "duplicate of an earlier rule of the same policy"
+	ShadowedByANPRule            ShadowReason = "an ANP rule allows or denies all of its traffic before BANP is evaluated"
+	ShadowedByNetworkPolicies    ShadowReason = "NetworkPolicies select every pod of its subject, so BANP is never evaluated"
+)
+
+// ShadowedRule is an ANP or BANP rule that can never take effect, because all traffic
+// it matches is decided before it is evaluated.
+type ShadowedRule struct {
+	IsIngress bool
+	Rule      *AdminRule
+	// ShadowedBy is the rule that decides all traffic matched by Rule.
+	// It is nil if Rule is shadowed by NetworkPolicies.
+	ShadowedBy *AdminRule
+	// NetworkPolicies select every pod of a BANP rule's subject
+	NetworkPolicies []NetPolID
+	Reason          ShadowReason
+}
+
+// ShadowedRules finds ANP and BANP rules which can never take effect.
+// The analysis is conservative: a rule is only reported if it can be proven to be shadowed,
+// for example when label selectors are equal or strictly narrower.
+func (p *Policy) ShadowedRules() []*ShadowedRule {
+	ingress, egress := p.SortedTargets()
+	return append(shadowedRules(true, ingress), shadowedRules(false, egress)...)
+}
+
+func shadowedRules(isIngress bool, targets []*Target) []*ShadowedRule {
+	rules := AdminRules(targets)
+	var v1Subjects []*Target
+	for _, target := range targets {
+		if _, ok := target.SubjectMatcher.(*SubjectV1); ok {
+			v1Subjects = append(v1Subjects, target)
+		}
+	}
+
+	var shadowed []*ShadowedRule
+	for i, rule := range rules {
+		if s := findShadowingRule(rule, rules[:i]); s != nil {
+			s.IsIngress = isIngress
+			shadowed = append(shadowed, s)
+			continue
+		}
+		if rule.Kind == BaselineAdminNetworkPolicy {
+			if netpols := networkPoliciesSelectingSubject(rule.Subject, v1Subjects); len(netpols) > 0 {
+				shadowed = append(shadowed, &ShadowedRule{
+					IsIngress:       isIngress,
+					Rule:            rule,
+					NetworkPolicies: netpols,
+					Reason:          ShadowedByNetworkPolicies,
+				})
+			}
+		}
+	}
+	return shadowed
+}
+
+// findShadowingRule looks for a rule among the candidates, which are sorted by precedence,
+// that is evaluated before the rule and matches all of its traffic.
+func findShadowingRule(rule *AdminRule, candidates []*AdminRule) *ShadowedRule {
+	for _, candidate := range candidates {
+		if !candidate.precedes(rule) || !candidate.covers(rule) {
+			continue
+		}
+		var reason ShadowReason
+		switch {
+		case candidate.Kind != rule.Kind:
+			// a Pass continues to NetworkPolicies and BANP
+			if candidate.Verdict == Pass {
+				continue
+			}
+			reason = ShadowedByANPRule
+		case candidate.PolicyName != rule.PolicyName:
+			reason = ShadowedByHigherPriorityRule
+		case candidate.Verdict == rule.Verdict && rule.covers(candidate):
+			reason = DuplicateRule
+		default:
+			reason = ShadowedByEarlierRule
+		}
+		return &ShadowedRule{Rule: rule, ShadowedBy: candidate, Reason: reason}
+	}
+	return nil
+}
+
+// AdminRules gathers the ANP and BANP rules from targets, sorted by precedence:
+// ANPs by priority, then BANP.  Within a policy, rules are in order.
+func AdminRules(targets []*Target) []*AdminRule {
+	type ruleKey struct {
+		kind       PolicyKind
+		policyName string
+		ruleIndex  int
+	}
+	rulesByKey := map[ruleKey]*AdminRule{}
+	var rules []*AdminRule
+	for _, target := range targets {
+		subject, ok := target.SubjectMatcher.(*SubjectAdmin)
+		if !ok {
+			continue
+		}
+		for _, peer := range target.Peers {
+			matcherAdmin, ok := peer.(*PeerMatcherAdmin)
+			if !ok {
+				continue
+			}
+			effect := matcherAdmin.effectFromMatch
+			key := ruleKey{kind: effect.PolicyKind, policyName: matcherAdmin.PolicyName, ruleIndex: matcherAdmin.RuleIndex}
+			rule, ok := rulesByKey[key]
+			if !ok {
+				rule = &AdminRule{
+					Subject:    subject,
+					Kind:       effect.PolicyKind,
+					PolicyName: matcherAdmin.PolicyName,
+					Priority:   effect.Priority,
+					RuleIndex:  matcherAdmin.RuleIndex,
+					RuleName:   matcherAdmin.RuleName,
+					Verdict:    effect.Verdict,
+				}
+				rulesByKey[key] = rule
+				rules = append(rules, rule)
+			}
+			rule.Peers = append(rule.Peers, matcherAdmin.PeerMatcher)
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.Kind != b.Kind {
+			return a.Kind == AdminNetworkPolicy
+		}
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if a.PolicyName != b.PolicyName {
+			return a.PolicyName < b.PolicyName
+		}
+		return a.RuleIndex < b.RuleIndex
+	})
+	return rules
+}
+
+// networkPoliciesSelectingSubject returns the NetworkPolicies which together select every pod of the subject,
+// or nil if that can't be proven.
+// NetworkPolicies select pods by namespace name, so this is only possible if the subject's namespace selector
+// restricts the `kubernetes.io/metadata.name` label to a list of names.
+func networkPoliciesSelectingSubject(subject *SubjectAdmin, v1Targets []*Target) []NetPolID {
+	nsSelector, podSelector := subject.selectors()
+	namespaces := selectedNamespaceNames(nsSelector)
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	var netpols []NetPolID
+	for _, ns := range namespaces {
+		found := false
+		for _, target := range v1Targets {
+			v1Subject := target.SubjectMatcher.(*SubjectV1)
+			if v1Subject.namespace == ns && kube.IsLabelSelectorSubset(podSelector, v1Subject.podSelector) {
+				netpols = append(netpols, target.SourceRules...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	sort.Slice(netpols, func(i, j int) bool { return netpols[i] < netpols[j] })
+	return netpols
+}
+
+// selectedNamespaceNames returns the names that a namespace selector is restricted to, if any.
+func selectedNamespaceNames(selector metav1.LabelSelector) []string {
+	if name, ok := selector.MatchLabels[v1.LabelMetadataName]; ok {
+		return []string{name}
+	}
+	for _, exp := range selector.MatchExpressions {
+		if exp.Key == v1.LabelMetadataName && exp.Operator == metav1.LabelSelectorOpIn {
+			return exp.Values
+		}
+	}
+	return nil
+}
+
+// selectors returns the namespace and pod selectors of the subject.
+// A namespaces subject selects all pods in the selected namespaces.
+func (s *SubjectAdmin) selectors() (metav1.LabelSelector, metav1.LabelSelector) {
+	if s.subject.Namespaces != nil {
+		return *s.subject.Namespaces, metav1.LabelSelector{}
+	}
+	return s.subject.Pods.NamespaceSelector, s.subject.Pods.PodSelector
+}
+
+func subjectAdminCovers(s *SubjectAdmin, other *SubjectAdmin) bool {
+	if s.GetPrimaryKey() == other.GetPrimaryKey() {
+		return true
+	}
+	nsSelector, podSelector := s.selectors()
+	otherNsSelector, otherPodSelector := other.selectors()
+	return kube.IsLabelSelectorSubset(otherNsSelector, nsSelector) && kube.IsLabelSelectorSubset(otherPodSelector, podSelector)
+}
+
+// peerMatcherCovers returns true if m matches all traffic that other matches.
+// Peers of different types are never considered to cover each other:
+// for example, a networks peer may well include every pod IP, but that can't be known statically.
+func peerMatcherCovers(m PeerMatcher, other PeerMatcher) bool {
+	switch a := m.(type) {
+	case *PodPeerMatcher:
+		b, ok := other.(*PodPeerMatcher)
+		return ok && namespaceMatcherCovers(a.Namespace, b.Namespace) && podMatcherCovers(a.Pod, b.Pod) && portMatcherCovers(a.Port, b.Port)
+	case *NetworksPeerMatcher:
+		b, ok := other.(*NetworksPeerMatcher)
+		return ok && networksCover(a.Networks, b.Networks) && portMatcherCovers(a.Port, b.Port)
+	case *NodePeerMatcher:
+		b, ok := other.(*NodePeerMatcher)
+		return ok && kube.IsLabelSelectorSubset(b.Selector, a.Selector) && portMatcherCovers(a.Port, b.Port)
+	case *DomainNamesPeerMatcher:
+		b, ok := other.(*DomainNamesPeerMatcher)
+		return ok && domainNamesCover(a.DomainNames, b.DomainNames) && portMatcherCovers(a.Port, b.Port)
+	default:
+		return false
+	}
+}
+
+func namespaceMatcherCovers(m NamespaceMatcher, other NamespaceMatcher) bool {
+	switch a := m.(type) {
+	case *AllNamespaceMatcher:
+		return true
+	case *LabelSelectorNamespaceMatcher:
+		b, ok := other.(*LabelSelectorNamespaceMatcher)
+		return ok && kube.IsLabelSelectorSubset(b.Selector, a.Selector)
+	case *ExactNamespaceMatcher:
+		b, ok := other.(*ExactNamespaceMatcher)
+		return ok && a.Namespace == b.Namespace
+	default:
+		return false
+	}
+}
+
+func podMatcherCovers(m PodMatcher, other PodMatcher) bool {
+	switch a := m.(type) {
+	case *AllPodMatcher:
+		return true
+	case *LabelSelectorPodMatcher:
+		b, ok := other.(*LabelSelectorPodMatcher)
+		return ok && kube.IsLabelSelectorSubset(b.Selector, a.Selector)
+	default:
+		return false
+	}
+}
+
+func portMatcherCovers(m PortMatcher, other PortMatcher) bool {
+	if _, ok := m.(*AllPortMatcher); ok {
+		return true
+	}
+	a, ok := m.(*SpecificPortMatcher)
+	if !ok {
+		return false
+	}
+	b, ok := other.(*SpecificPortMatcher)
+	if !ok {
+		return false
+	}
+	for _, port := range b.Ports {
+		if !a.coversPort(port) {
+			return false
+		}
+	}
+	for _, portRange := range b.PortRanges {
+		if !a.coversPortRange(portRange) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *SpecificPortMatcher) coversPort(port *PortProtocolMatcher) bool {
+	for _, p := range s.Ports {
+		if p.Protocol == port.Protocol && (p.Port == nil || p.Equals(port)) {
+			return true
+		}
+	}
+	if port.Port == nil || port.Port.Type != intstr.Int {
+		return false
+	}
+	for _, r := range s.PortRanges {
+		if r.MatchesPortProtocol(int(port.Port.IntVal), port.Protocol) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SpecificPortMatcher) coversPortRange(portRange *PortRangeMatcher) bool {
+	for _, p := range s.Ports {
+		if p.Protocol == portRange.Protocol && p.Port == nil {
+			return true
+		}
+	}
+	for _, r := range s.PortRanges {
+		if r.Protocol == portRange.Protocol && r.From <= portRange.From && portRange.To <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+func networksCover(networks []string, others []string) bool {
+	for _, other := range others {
+		covered := false
+		for _, network := range networks {
+			// CIDRs were validated when building the matchers
+			if isSubset, err := kube.IsCIDRSubset(other, network); err == nil && isSubset {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func domainNamesCover(domainNames []string, others []string) bool {
+	for _, other := range others {
+		covered := false
+		for _, domainName := range domainNames {
+			if domainNameCovers(domainName, other) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// domainNameCovers returns true if every hostname matched by other is matched by domainName.
+func domainNameCovers(domainName string, other string) bool {
+	suffix, isWildcard := strings.CutPrefix(normalizeDomainName(other), "*.")
+	if !isWildcard {
+		return IsDomainNameMatch(domainName, other)
+	}
+	// *.example.com is covered by *.example.com and *.com, but not by example.com
+	pattern := normalizeDomainName(domainName)
+	return strings.HasPrefix(pattern, "*.") && (pattern == "*."+suffix || IsDomainNameMatch(pattern, suffix))
+}
@@ -0,0 +1,193 @@
+package matcher
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+func RunShadowTests() {
+	allNamespaces := v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}}
+	namespaceX := v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{v1.LabelMetadataName: "x"}}}
+	tcp80 := &[]v1alpha1.AdminNetworkPolicyPort{{PortNumber: &v1alpha1.Port{Protocol: v1.ProtocolTCP, Port: 80}}}
+	tcp80To90 := &[]v1alpha1.AdminNetworkPolicyPort{{PortRange: &v1alpha1.PortRange{Protocol: v1.ProtocolTCP, Start: 80, End: 90}}}
+
+	egressANP := func(name string, priority int32, subject v1alpha1.AdminNetworkPolicySubject, rules ...v1alpha1.AdminNetworkPolicyEgressRule) *v1alpha1.AdminNetworkPolicy {
+		return &v1alpha1.AdminNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1alpha1.AdminNetworkPolicySpec{Priority: priority, Subject: subject, Egress: rules},
+		}
+	}
+	networksRule := func(name string, action v1alpha1.AdminNetworkPolicyRuleAction, ports *[]v1alpha1.AdminNetworkPolicyPort, cidrs ...v1alpha1.CIDR) v1alpha1.AdminNetworkPolicyEgressRule {
+		return v1alpha1.AdminNetworkPolicyEgressRule{
+			Name:   name,
+			Action: action,
+			To:     []v1alpha1.AdminNetworkPolicyEgressPeer{{Networks: cidrs}},
+			Ports:  ports,
+		}
+	}
+	shadowed := func(netpols []*networkingv1.NetworkPolicy, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) []*ShadowedRule {
+		policy, errs := BuildV1AndV2NetPols(false, netpols, anps, banp)
+		Expect(errs).To(BeEmpty())
+		return policy.ShadowedRules()
+	}
+
+	Describe("ShadowedRules", func() {
+		It("finds a lower-priority ANP rule covered by a higher-priority rule", func() {
+			results := shadowed(nil, []*v1alpha1.AdminNetworkPolicy{
+				egressANP("deny-private", 10, allNamespaces, networksRule("deny-10", v1alpha1.AdminNetworkPolicyRuleActionDeny, tcp80To90, "10.0.0.0/8")),
+				egressANP("allow-subnet", 20, namespaceX, networksRule("allow-10-1", v1alpha1.AdminNetworkPolicyRuleActionAllow, tcp80, "10.1.0.0/16")),
+			}, nil)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].IsIngress).To(BeFalse())
+			Expect(results[0].Rule.PolicyName).To(Equal("allow-subnet"))
+			Expect(results[0].ShadowedBy.PolicyName).To(Equal("deny-private"))
+			Expect(results[0].ShadowedBy.RuleName).To(Equal("deny-10"))
+			Expect(results[0].Reason).To(Equal(ShadowedByHigherPriorityRule))
+			Expect(results[0].Rule.String()).To(Equal(`[ANP] allow-subnet (priority 20) rule 0 "allow-10-1": Allow`))
+		})
+
+		It("doesn't report rules that are only partly covered", func() {
+			Expect(shadowed(nil, []*v1alpha1.AdminNetworkPolicy{
+				// narrower subject
+				egressANP("deny-x", 10, namespaceX, networksRule("deny-10", v1alpha1.AdminNetworkPolicyRuleActionDeny, nil, "10.0.0.0/8")),
+				egressANP("allow-all", 20, allNamespaces, networksRule("allow-10-1", v1alpha1.AdminNetworkPolicyRuleActionAllow, nil, "10.1.0.0/16")),
+				// narrower ports
+				egressANP("deny-port-80", 30, allNamespaces, networksRule("deny-172", v1alpha1.AdminNetworkPolicyRuleActionDeny, tcp80, "172.16.0.0/12")),
+				egressANP("allow-range", 40, allNamespaces, networksRule("allow-172", v1alpha1.AdminNetworkPolicyRuleActionAllow, tcp80To90, "172.16.0.0/12")),
+			}, nil)).To(BeEmpty())
+		})
+
+		It("doesn't order rules of ANPs with the same priority", func() {
+			a := &AdminRule{Kind: AdminNetworkPolicy, PolicyName: "a", Priority: 10, RuleIndex: 0}
+			b := &AdminRule{Kind: AdminNetworkPolicy, PolicyName: "b", Priority: 10, RuleIndex: 1}
+			Expect(a.precedes(b)).To(BeFalse())
+			Expect(b.precedes(a)).To(BeFalse())
+		})
+
+		It("finds duplicate and covered rules within a policy", func() {
+			results := shadowed(nil, []*v1alpha1.AdminNetworkPolicy{
+				egressANP("anp", 10, allNamespaces,
+					networksRule("pass-10", v1alpha1.AdminNetworkPolicyRuleActionPass, nil, "10.0.0.0/8"),
+					networksRule("pass-10-again", v1alpha1.AdminNetworkPolicyRuleActionPass, nil, "10.0.0.0/8"),
+					networksRule("deny-10-1", v1alpha1.AdminNetworkPolicyRuleActionDeny, tcp80, "10.1.0.0/16"),
+				),
+			}, nil)
+			Expect(results).To(HaveLen(2))
+			Expect(results[0].Rule.RuleName).To(Equal("pass-10-again"))
+			Expect(results[0].ShadowedBy.RuleName).To(Equal("pass-10"))
+			Expect(results[0].Reason).To(Equal(DuplicateRule))
+			Expect(results[1].Rule.RuleName).To(Equal("deny-10-1"))
+			Expect(results[1].ShadowedBy.RuleName).To(Equal("pass-10"))
+			Expect(results[1].Reason).To(Equal(ShadowedByEarlierRule))
+		})
+
+		It("finds BANP rules covered by ANP Allow and Deny rules, but not Pass rules", func() {
+			banp := &v1alpha1.BaselineAdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+					Subject: allNamespaces,
+					Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{
+						{
+							Name:   "deny-from-y",
+							Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+							From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"ns": "y"}}}},
+						},
+						{
+							Name:   "deny-from-z",
+							Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+							From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"ns": "z"}}}},
+						},
+					},
+				},
+			}
+			anpRule := func(name string, action v1alpha1.AdminNetworkPolicyRuleAction, ns string) v1alpha1.AdminNetworkPolicyIngressRule {
+				return v1alpha1.AdminNetworkPolicyIngressRule{
+					Name:   name,
+					Action: action,
+					From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"ns": ns}}}},
+				}
+			}
+			anp := &v1alpha1.AdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "anp"},
+				Spec: v1alpha1.AdminNetworkPolicySpec{
+					Priority: 10,
+					Subject:  allNamespaces,
+					Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
+						anpRule("allow-from-y", v1alpha1.AdminNetworkPolicyRuleActionAllow, "y"),
+						anpRule("pass-from-z", v1alpha1.AdminNetworkPolicyRuleActionPass, "z"),
+					},
+				},
+			}
+
+			results := shadowed(nil, []*v1alpha1.AdminNetworkPolicy{anp}, banp)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].IsIngress).To(BeTrue())
+			Expect(results[0].Rule.Kind).To(Equal(BaselineAdminNetworkPolicy))
+			Expect(results[0].Rule.RuleName).To(Equal("deny-from-y"))
+			Expect(results[0].ShadowedBy.RuleName).To(Equal("allow-from-y"))
+			Expect(results[0].Reason).To(Equal(ShadowedByANPRule))
+		})
+
+		It("finds BANP rules whose subject is selected by NetworkPolicies", func() {
+			banp := &v1alpha1.BaselineAdminNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+					Subject: namespaceX,
+					Egress: []v1alpha1.BaselineAdminNetworkPolicyEgressRule{{
+						Name:   "deny-all",
+						Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+						To:     []v1alpha1.BaselineAdminNetworkPolicyEgressPeer{{Namespaces: &metav1.LabelSelector{}}},
+					}},
+					Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{{
+						Name:   "deny-all",
+						Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+						From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+					}},
+				},
+			}
+			netpol := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "default-deny-egress"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				},
+			}
+
+			results := shadowed([]*networkingv1.NetworkPolicy{netpol}, nil, banp)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].IsIngress).To(BeFalse())
+			Expect(results[0].Rule.RuleName).To(Equal("deny-all"))
+			Expect(results[0].ShadowedBy).To(BeNil())
+			Expect(results[0].NetworkPolicies).To(Equal([]NetPolID{"[NPv1] x/default-deny-egress"}))
+			Expect(results[0].Reason).To(Equal(ShadowedByNetworkPolicies))
+		})
+	})
+
+	Describe("Coverage of peers", func() {
+		It("compares domain names", func() {
+			Expect(domainNameCovers("*.kubernetes.io", "blog.kubernetes.io")).To(BeTrue())
+			Expect(domainNameCovers("*.kubernetes.io", "*.blog.kubernetes.io")).To(BeTrue())
+			Expect(domainNameCovers("*.kubernetes.io", "*.kubernetes.io.")).To(BeTrue())
+			Expect(domainNameCovers("*.kubernetes.io", "kubernetes.io")).To(BeFalse())
+			Expect(domainNameCovers("blog.kubernetes.io", "*.blog.kubernetes.io")).To(BeFalse())
+			Expect(domainNameCovers("*.blog.kubernetes.io", "*.kubernetes.io")).To(BeFalse())
+		})
+
+		It("compares ports", func() {
+			tcp80 := &SpecificPortMatcher{Ports: []*PortProtocolMatcher{{Port: &port80, Protocol: v1.ProtocolTCP}}}
+			allTCP := &SpecificPortMatcher{Ports: []*PortProtocolMatcher{{Protocol: v1.ProtocolTCP}}}
+			range80To90 := &SpecificPortMatcher{PortRanges: []*PortRangeMatcher{{From: 80, To: 90, Protocol: v1.ProtocolTCP}}}
+			udpRange := &SpecificPortMatcher{PortRanges: []*PortRangeMatcher{{From: 80, To: 90, Protocol: v1.ProtocolUDP}}}
+
+			Expect(portMatcherCovers(&AllPortMatcher{}, tcp80)).To(BeTrue())
+			Expect(portMatcherCovers(tcp80, &AllPortMatcher{})).To(BeFalse())
+			Expect(portMatcherCovers(range80To90, tcp80)).To(BeTrue())
+			Expect(portMatcherCovers(tcp80, range80To90)).To(BeFalse())
+			Expect(portMatcherCovers(allTCP, range80To90)).To(BeTrue())
+			Expect(portMatcherCovers(range80To90, udpRange)).To(BeFalse())
+		})
+	})
+}
@@ -125,7 +125,7 @@ func RunSimplifierTests() {
 
 		It("don't simplify (b)anp", func() {
 			anpDenyAll := &PeerMatcherAdmin{
-				PodPeerMatcher: &PodPeerMatcher{
+				PeerMatcher: &PodPeerMatcher{
 					Namespace: &AllNamespaceMatcher{},
 					Pod:       &AllPodMatcher{},
 					Port:      &AllPortMatcher{},
@@ -138,7 +138,7 @@ func RunSimplifierTests() {
 				RuleName: "anp",
 			}
 			banpAllowAll := &PeerMatcherAdmin{
-				PodPeerMatcher: &PodPeerMatcher{
+				PeerMatcher: &PodPeerMatcher{
 					Namespace: &AllNamespaceMatcher{},
 					Pod:       &AllPodMatcher{},
 					Port:      &AllPortMatcher{},
@@ -12,5 +12,12 @@ func TestMatcher(t *testing.T) {
 	RunBuilderTests()
 	RunPolicyTests()
 	RunSimplifierTests()
+	RunShadowTests()
+	RunPriorityConflictsTests()
+	RunDiffTests()
+	RunResultsTests()
+	RunServiceTests()
+	RunPrecedenceTests()
+	RunTrafficTests()
 	RunSpecs(t, "network policy matcher suite")
 }
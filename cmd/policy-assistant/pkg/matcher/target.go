@@ -39,6 +39,11 @@ func netPolID(p interface{}) NetPolID {
 	}
 }
 
+// NetworkPolicyID returns the ID of a v1 NetPol in the SourceRules of its targets.
+func NetworkPolicyID(netpol *networkingv1.NetworkPolicy) NetPolID {
+	return netPolID(netpol)
+}
+
 // Target represents ingress or egress for one or more NetworkPolicies.
 // It can represent either:
 // a) one or more v1 NetPols sharing the same Namespace and Pod Selector
@@ -6,13 +6,17 @@ import (
 
 	"github.com/mattfenwick/collections/pkg/slice"
 	"github.com/mattfenwick/cyclonus/pkg/kube"
-	"github.com/mattfenwick/cyclonus/pkg/utils"
 	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/exp/maps"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 )
 
+// ErrWorkloadNotFound is returned when translating a workload which has no pods on the cluster.
+var ErrWorkloadNotFound = errors.New("workload not found on the cluster")
+
 type Traffic struct {
 	Source      *TrafficPeer
 	Destination *TrafficPeer
@@ -35,6 +39,10 @@ func (t *Traffic) Table() string {
 	if t.Source.Internal != nil {
 		i := t.Source.Internal
 		source = append(source, i.Namespace, labelsToString(i.NamespaceLabels), labelsToString(i.PodLabels))
+	} else if t.Source.Node != nil {
+		source = append(source, "node: "+t.Source.Node.Name, "", labelsToString(t.Source.Node.Labels))
+	} else if t.Source.Hostname != "" {
+		source = append(source, "host: "+t.Source.Hostname, "", "")
 	} else {
 		source = append(source, "", "", "")
 	}
@@ -44,6 +52,12 @@ func (t *Traffic) Table() string {
 	if t.Destination.Internal != nil {
 		i := t.Destination.Internal
 		dest = append(dest, i.Namespace, labelsToString(i.NamespaceLabels), labelsToString(i.PodLabels))
+	} else if t.Destination.Node != nil {
+		dest = append(dest, "node: "+t.Destination.Node.Name, "", labelsToString(t.Destination.Node.Labels))
+	} else if t.Destination.Service != nil {
+		dest = append(dest, "service: "+t.Destination.Service.Namespace+"/"+t.Destination.Service.Name, "", "")
+	} else if t.Destination.Hostname != "" {
+		dest = append(dest, "host: "+t.Destination.Hostname, "", "")
 	} else {
 		dest = append(dest, "", "", "")
 	}
@@ -60,7 +74,16 @@ func labelsToString(labels map[string]string) string {
 
 // Helper function to generate the string for source or destination
 func (t *Traffic) formatPeer(peer *TrafficPeer) string {
+	if peer.Service != nil {
+		return fmt.Sprintf("service/%s/%s", peer.Service.Namespace, peer.Service.Name)
+	}
+	if peer.Node != nil {
+		return fmt.Sprintf("node/%s (%s)", peer.Node.Name, peer.IP)
+	}
 	if peer.Internal == nil {
+		if peer.Hostname != "" {
+			return fmt.Sprintf("%s (%s)", peer.Hostname, peer.IP)
+		}
 		return fmt.Sprintf("%s", peer.IP)
 	}
 
@@ -95,8 +118,16 @@ func labelsToStringSlim(labels map[string]string) string {
 
 type TrafficPeer struct {
 	Internal *InternalPeer
-	// IP external to cluster
+	// optional: set when the peer is a cluster node rather than a pod or an external host
+	Node *NodePeer
+	// IP external to cluster, or the node's InternalIP
 	IP string
+	// optional: name of a host external to cluster, used for matching domain names
+	Hostname string
+	// optional: other names of the host, from a reverse lookup of its IP; domain names match these too
+	HostnameAliases []string
+	// optional: set when the destination is a service; see ResolveServiceBackends
+	Service *ServicePeer
 }
 
 func (p *TrafficPeer) Namespace() string {
@@ -110,6 +141,10 @@ func (p *TrafficPeer) IsExternal() bool {
 	return p.Internal == nil
 }
 
+func (p *TrafficPeer) IsNode() bool {
+	return p.Node != nil
+}
+
 func CreateTrafficPeer(ip string, internal *InternalPeer) *TrafficPeer {
 	return &TrafficPeer{
 		IP:       ip,
@@ -128,11 +163,14 @@ func CreateTraffic(source, destination *TrafficPeer, resolvedPort int, protocol
 }
 
 // Helper function to get internal TrafficPeer info from workload string
-func GetInternalPeerInfo(workload string) *TrafficPeer {
+func GetInternalPeerInfo(kubeClient kube.IKubernetes, workload string) (*TrafficPeer, error) {
 	if workload == "" {
-		return nil
+		return nil, nil
+	}
+	workloadInfo, err := WorkloadStringToTrafficPeer(kubeClient, workload)
+	if err != nil {
+		return nil, err
 	}
-	workloadInfo := WorkloadStringToTrafficPeer(workload)
 	if workloadInfo.Internal.Pods == nil {
 		return &TrafficPeer{
 			Internal: &InternalPeer{
@@ -141,7 +179,7 @@ func GetInternalPeerInfo(workload string) *TrafficPeer {
 				Namespace:       workloadInfo.Internal.Namespace,
 				Workload:        workloadInfo.Internal.Workload,
 			},
-		}
+		}, nil
 	}
 	return &TrafficPeer{
 		Internal: &InternalPeer{
@@ -151,35 +189,34 @@ func GetInternalPeerInfo(workload string) *TrafficPeer {
 			Workload:        workloadInfo.Internal.Workload,
 		},
 		IP: workloadInfo.Internal.Pods[0].IP,
-	}
+	}, nil
 }
 
-func (p *TrafficPeer) Translate() TrafficPeer {
+func (p *TrafficPeer) Translate(kubeClient kube.IKubernetes) (TrafficPeer, error) {
 	//Translates kubernetes workload types to TrafficPeers.
 	var podsNetworking []*PodNetworking
 	var podLabels map[string]string
 	var namespaceLabels map[string]string
 	var workloadOwner string
 	var workloadKind string
-	var internalPeer InternalPeer
 	workloadOwnerExists := false
 	workloadMetadata := strings.Split(strings.ToLower(p.Internal.Workload), "/")
 	if len(workloadMetadata) != 3 || (workloadMetadata[0] == "" || workloadMetadata[1] == "" || workloadMetadata[2] == "") || (workloadMetadata[1] != "daemonset" && workloadMetadata[1] != "statefulset" && workloadMetadata[1] != "replicaset" && workloadMetadata[1] != "deployment" && workloadMetadata[1] != "pod") {
-		logrus.Fatalf("Bad Workload structure: Types supported are pod, replicaset, deployment, daemonset, statefulset, and 3 fields are required with this structure, <namespace>/<workloadType>/<workloadName>")
+		return TrafficPeer{}, errors.Errorf("Bad Workload structure: Types supported are pod, replicaset, deployment, daemonset, statefulset, and 3 fields are required with this structure, <namespace>/<workloadType>/<workloadName>")
 	}
-	kubeClient, err := kube.NewKubernetesForContext("")
-	utils.DoOrDie(err)
 	ns, err := kubeClient.GetNamespace(workloadMetadata[0])
-	utils.DoOrDie(err)
+	if err != nil {
+		return TrafficPeer{}, err
+	}
 	kubePods, err := kube.GetPodsInNamespaces(kubeClient, []string{workloadMetadata[0]})
 	if err != nil {
-		logrus.Fatalf("unable to read pods from kube, ns '%s': %+v", workloadMetadata[0], err)
+		return TrafficPeer{}, errors.Wrapf(err, "unable to read pods from kube, ns '%s'", workloadMetadata[0])
 	}
 	for _, pod := range kubePods {
 		if workloadMetadata[1] == "deployment" && pod.OwnerReferences != nil && pod.OwnerReferences[0].Kind == "ReplicaSet" {
 			kubeReplicaSets, err := kubeClient.GetReplicaSet(workloadMetadata[0], pod.OwnerReferences[0].Name)
 			if err != nil {
-				logrus.Fatalf("unable to read Replicaset from kube, rs '%s': %+v", pod.OwnerReferences[0].Name, err)
+				return TrafficPeer{}, errors.Wrapf(err, "unable to read Replicaset from kube, rs '%s'", pod.OwnerReferences[0].Name)
 			}
 			if kubeReplicaSets.OwnerReferences != nil {
 				workloadOwner = kubeReplicaSets.OwnerReferences[0].Name
@@ -206,218 +243,112 @@ func (p *TrafficPeer) Translate() TrafficPeer {
 	}
 
 	if !workloadOwnerExists {
-		logrus.Infof(workloadMetadata[0] + "/" + workloadMetadata[1] + "/" + workloadMetadata[2] + " workload not found on the cluster")
-		internalPeer = InternalPeer{
-			Workload: "",
-		}
-	} else {
-		internalPeer = InternalPeer{
-			Workload:        p.Internal.Workload,
-			PodLabels:       podLabels,
-			NamespaceLabels: namespaceLabels,
-			Namespace:       workloadMetadata[0],
-			Pods:            podsNetworking,
-		}
+		return TrafficPeer{}, errors.Wrapf(ErrWorkloadNotFound, "%s/%s/%s", workloadMetadata[0], workloadMetadata[1], workloadMetadata[2])
+	}
+	internalPeer := InternalPeer{
+		Workload:        p.Internal.Workload,
+		PodLabels:       podLabels,
+		NamespaceLabels: namespaceLabels,
+		Namespace:       workloadMetadata[0],
+		Pods:            podsNetworking,
 	}
 
 	TranslatedPeer := TrafficPeer{
 		Internal: &internalPeer,
 	}
-	return TranslatedPeer
+	return TranslatedPeer, nil
 }
 
-func WorkloadStringToTrafficPeer(workloadString string) TrafficPeer {
+func WorkloadStringToTrafficPeer(kubeClient kube.IKubernetes, workloadString string) (TrafficPeer, error) {
 	//Translates a Workload string to a TrafficPeer.
-	//var deploymentPeers []TrafficPeer
-
 	tmpInternalPeer := InternalPeer{
 		Workload: workloadString,
 	}
 	tmpPeer := TrafficPeer{
 		Internal: &tmpInternalPeer,
 	}
-	tmpPeerTranslated := tmpPeer.Translate()
-	//if tmpPeerTranslated.Internal.Workload != "" {
-	//	deploymentPeers = append(deploymentPeers, tmpPeerTranslated)
-	//}
-
-	return tmpPeerTranslated
+	return tmpPeer.Translate(kubeClient)
 }
 
-func DeploymentsToTrafficPeers() []TrafficPeer {
-	//Translates all pods associated with deployments to TrafficPeers.
-	var deploymentPeers []TrafficPeer
-	kubeClient, err := kube.NewKubernetesForContext("")
-	utils.DoOrDie(err)
+// workloadsToTrafficPeers translates the workloads named by getWorkloads in every namespace to TrafficPeers,
+// skipping workloads without pods.
+func workloadsToTrafficPeers(kubeClient kube.IKubernetes, kind string, getWorkloads func(namespace string) ([]string, error)) ([]TrafficPeer, error) {
+	var peers []TrafficPeer
 	kubeNamespaces, err := kubeClient.GetAllNamespaces()
 	if err != nil {
-		logrus.Fatalf("unable to read namespaces from kube: %+v", err)
+		return nil, errors.Wrapf(err, "unable to read namespaces from kube")
 	}
 
 	for _, namespace := range kubeNamespaces.Items {
-		kubeDeployments, err := kubeClient.GetDeploymentsInNamespace(namespace.Name)
+		names, err := getWorkloads(namespace.Name)
 		if err != nil {
-			logrus.Fatalf("unable to read deployments from kube, ns '%s': %+v", namespace.Name, err)
+			return nil, errors.Wrapf(err, "unable to read %ss from kube, ns '%s'", kind, namespace.Name)
 		}
-		for _, deployment := range kubeDeployments {
-			tmpInternalPeer := InternalPeer{
-				Workload: namespace.Name + "/deployment/" + deployment.Name,
-			}
-			tmpPeer := TrafficPeer{
-				Internal: &tmpInternalPeer,
-			}
-			tmpPeerTranslated := tmpPeer.Translate()
-			if tmpPeerTranslated.Internal.Workload != "" {
-				deploymentPeers = append(deploymentPeers, tmpPeerTranslated)
+		for _, name := range names {
+			peer, err := WorkloadStringToTrafficPeer(kubeClient, namespace.Name+"/"+kind+"/"+name)
+			if errors.Is(err, ErrWorkloadNotFound) {
+				logrus.Debugf("skipping %s: %s", name, err)
+				continue
+			} else if err != nil {
+				return nil, err
 			}
-
+			peers = append(peers, peer)
 		}
-
 	}
 
-	return deploymentPeers
+	return peers, nil
 }
 
-func DaemonSetsToTrafficPeers() []TrafficPeer {
-	//Translates all pods associated with daemonSets to TrafficPeers.
-	var daemonSetPeers []TrafficPeer
-	kubeClient, err := kube.NewKubernetesForContext("")
-	utils.DoOrDie(err)
-	kubeNamespaces, err := kubeClient.GetAllNamespaces()
-	if err != nil {
-		logrus.Fatalf("unable to read namespaces from kube: %+v", err)
-	}
-
-	for _, namespace := range kubeNamespaces.Items {
-		kubeDaemonSets, err := kubeClient.GetDaemonSetsInNamespace(namespace.Name)
-		if err != nil {
-			logrus.Fatalf("unable to read daemonSets from kube, ns '%s': %+v", namespace.Name, err)
-		}
-		for _, daemonSet := range kubeDaemonSets {
-			tmpInternalPeer := InternalPeer{
-				Workload: namespace.Name + "/daemonset/" + daemonSet.Name,
-			}
-			tmpPeer := TrafficPeer{
-				Internal: &tmpInternalPeer,
-			}
-			tmpPeerTranslated := tmpPeer.Translate()
-			if tmpPeerTranslated.Internal.Workload != "" {
-				daemonSetPeers = append(daemonSetPeers, tmpPeerTranslated)
-			}
-		}
-
-	}
+func DeploymentsToTrafficPeers(kubeClient kube.IKubernetes) ([]TrafficPeer, error) {
+	//Translates all pods associated with deployments to TrafficPeers.
+	return workloadsToTrafficPeers(kubeClient, "deployment", func(namespace string) ([]string, error) {
+		deployments, err := kubeClient.GetDeploymentsInNamespace(namespace)
+		return slice.Map(func(d appsv1.Deployment) string { return d.Name }, deployments), err
+	})
+}
 
-	return daemonSetPeers
+func DaemonSetsToTrafficPeers(kubeClient kube.IKubernetes) ([]TrafficPeer, error) {
+	//Translates all pods associated with daemonSets to TrafficPeers.
+	return workloadsToTrafficPeers(kubeClient, "daemonset", func(namespace string) ([]string, error) {
+		daemonSets, err := kubeClient.GetDaemonSetsInNamespace(namespace)
+		return slice.Map(func(d appsv1.DaemonSet) string { return d.Name }, daemonSets), err
+	})
 }
 
-func StatefulSetsToTrafficPeers() []TrafficPeer {
+func StatefulSetsToTrafficPeers(kubeClient kube.IKubernetes) ([]TrafficPeer, error) {
 	//Translates all pods associated with statefulSets to TrafficPeers.
-	var statefulSetPeers []TrafficPeer
-	kubeClient, err := kube.NewKubernetesForContext("")
-	utils.DoOrDie(err)
-	kubeNamespaces, err := kubeClient.GetAllNamespaces()
-	if err != nil {
-		logrus.Fatalf("unable to read namespaces from kube: %+v", err)
-	}
-
-	for _, namespace := range kubeNamespaces.Items {
-		kubeStatefulSets, err := kubeClient.GetStatefulSetsInNamespace(namespace.Name)
-		if err != nil {
-			logrus.Fatalf("unable to read statefulSets from kube, ns '%s': %+v", namespace.Name, err)
-		}
-		for _, statefulSet := range kubeStatefulSets {
-			tmpInternalPeer := InternalPeer{
-				Workload: namespace.Name + "/statefulset/" + statefulSet.Name,
-			}
-			tmpPeer := TrafficPeer{
-				Internal: &tmpInternalPeer,
-			}
-			tmpPeerTranslated := tmpPeer.Translate()
-			if tmpPeerTranslated.Internal.Workload != "" {
-				statefulSetPeers = append(statefulSetPeers, tmpPeerTranslated)
-			}
-		}
-
-	}
-
-	return statefulSetPeers
+	return workloadsToTrafficPeers(kubeClient, "statefulset", func(namespace string) ([]string, error) {
+		statefulSets, err := kubeClient.GetStatefulSetsInNamespace(namespace)
+		return slice.Map(func(s appsv1.StatefulSet) string { return s.Name }, statefulSets), err
+	})
 }
 
-func ReplicaSetsToTrafficPeers() []TrafficPeer {
+func ReplicaSetsToTrafficPeers(kubeClient kube.IKubernetes) ([]TrafficPeer, error) {
 	//Translates all pods associated with replicaSets that are not associated with deployments to TrafficPeers.
-	var replicaSetPeers []TrafficPeer
-	kubeClient, err := kube.NewKubernetesForContext("")
-	utils.DoOrDie(err)
-	kubeNamespaces, err := kubeClient.GetAllNamespaces()
-	if err != nil {
-		logrus.Fatalf("unable to read namespaces from kube: %+v", err)
-	}
-
-	for _, namespace := range kubeNamespaces.Items {
-		kubeReplicaSets, err := kubeClient.GetReplicaSetsInNamespace(namespace.Name)
-		if err != nil {
-			logrus.Fatalf("unable to read replicaSets from kube, ns '%s': %+v", namespace.Name, err)
-		}
-
-		for _, replicaSet := range kubeReplicaSets {
-			if replicaSet.OwnerReferences != nil {
-				continue
-			} else {
-				tmpInternalPeer := InternalPeer{
-					Workload: namespace.Name + "/replicaset/" + replicaSet.Name,
-				}
-				tmpPeer := TrafficPeer{
-					Internal: &tmpInternalPeer,
-				}
-				tmpPeerTranslated := tmpPeer.Translate()
-				if tmpPeerTranslated.Internal.Workload != "" {
-					replicaSetPeers = append(replicaSetPeers, tmpPeerTranslated)
-				}
-
+	return workloadsToTrafficPeers(kubeClient, "replicaset", func(namespace string) ([]string, error) {
+		replicaSets, err := kubeClient.GetReplicaSetsInNamespace(namespace)
+		var names []string
+		for _, replicaSet := range replicaSets {
+			if replicaSet.OwnerReferences == nil {
+				names = append(names, replicaSet.Name)
 			}
 		}
-
-	}
-
-	return replicaSetPeers
+		return names, err
+	})
 }
 
-func PodsToTrafficPeers() []TrafficPeer {
+func PodsToTrafficPeers(kubeClient kube.IKubernetes) ([]TrafficPeer, error) {
 	//Translates all pods that are not associated with other workload types (deployment, replicaSet, daemonSet, statefulSet.) to TrafficPeers.
-	var podPeers []TrafficPeer
-	kubeClient, err := kube.NewKubernetesForContext("")
-	utils.DoOrDie(err)
-	kubeNamespaces, err := kubeClient.GetAllNamespaces()
-	if err != nil {
-		logrus.Fatalf("unable to read namespaces from kube: %+v", err)
-	}
-
-	for _, namespace := range kubeNamespaces.Items {
-		kubePods, err := kube.GetPodsInNamespaces(kubeClient, []string{namespace.Name})
-		if err != nil {
-			logrus.Fatalf("unable to read pods from kube, ns '%s': %+v", namespace.Name, err)
-		}
-		for _, pod := range kubePods {
-			if pod.OwnerReferences != nil {
-				continue
-			} else {
-				tmpInternalPeer := InternalPeer{
-					Workload: namespace.Name + "/pod/" + pod.Name,
-				}
-				tmpPeer := TrafficPeer{
-					Internal: &tmpInternalPeer,
-				}
-				tmpPeerTranslated := tmpPeer.Translate()
-				if tmpPeerTranslated.Internal.Workload != "" {
-					podPeers = append(podPeers, tmpPeerTranslated)
-				}
+	return workloadsToTrafficPeers(kubeClient, "pod", func(namespace string) ([]string, error) {
+		pods, err := kubeClient.GetPodsInNamespace(namespace)
+		var names []string
+		for _, pod := range pods {
+			if pod.OwnerReferences == nil {
+				names = append(names, pod.Name)
 			}
 		}
-
-	}
-
-	return podPeers
+		return names, err
+	})
 }
 
 // Internal to cluster
@@ -431,6 +362,45 @@ type InternalPeer struct {
 	Pods []*PodNetworking
 }
 
+// Node in the cluster
+type NodePeer struct {
+	// optional: if labels or the peer IP aren't set, they will be filled in with information from cluster
+	Name   string
+	Labels map[string]string
+}
+
+// ResolveNodePeer builds a TrafficPeer for a node.  Missing labels and IP are looked up
+// by node name, with the IP taken from the node's first InternalIP.
+func ResolveNodePeer(kubeClient kube.IKubernetes, node *NodePeer, ip string) (*TrafficPeer, error) {
+	peer := &TrafficPeer{
+		Node: &NodePeer{Name: node.Name, Labels: node.Labels},
+		IP:   ip,
+	}
+	if node.Labels != nil && ip != "" {
+		return peer, nil
+	}
+	if node.Name == "" {
+		return nil, errors.Errorf("unable to resolve node peer: name is required if labels or IP are missing")
+	}
+	if kubeClient == nil {
+		return nil, errors.Errorf("unable to resolve node peer %s: no kube client to look up missing labels or IP", node.Name)
+	}
+	kubeNode, err := kubeClient.GetNode(node.Name)
+	if err != nil {
+		return nil, err
+	}
+	if peer.Node.Labels == nil {
+		peer.Node.Labels = kubeNode.Labels
+	}
+	if peer.IP == "" {
+		peer.IP, err = kube.NodeInternalIP(kubeNode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return peer, nil
+}
+
 type PodNetworking struct {
 	IP string
 	// don't worry about populating below fields right now
@@ -0,0 +1,35 @@
+package matcher
+
+import (
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func RunTrafficTests() {
+	Describe("Workload traffic peers", func() {
+		kubeClient := kube.NewMockKubernetes(1.0)
+		_, err := kubeClient.CreateNamespace(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "x", Labels: map[string]string{"ns": "x"}}})
+		utils.DoOrDie(err)
+		_, err = kubeClient.CreatePod(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "a", Labels: map[string]string{"pod": "a"}}})
+		utils.DoOrDie(err)
+
+		It("Translates a pod to a traffic peer", func() {
+			peer, err := WorkloadStringToTrafficPeer(kubeClient, "x/pod/a")
+			Expect(err).To(BeNil())
+			Expect(peer.Internal.Namespace).To(Equal("x"))
+			Expect(peer.Internal.PodLabels).To(Equal(map[string]string{"pod": "a"}))
+			Expect(peer.Internal.Pods).To(HaveLen(1))
+		})
+
+		It("Returns an error for a workload not found on the cluster", func() {
+			_, err := WorkloadStringToTrafficPeer(kubeClient, "x/deployment/missing")
+			Expect(errors.Is(err, ErrWorkloadNotFound)).To(BeTrue())
+			Expect(err.Error()).To(Equal("x/deployment/missing: workload not found on the cluster"))
+		})
+	})
+}
@@ -5,8 +5,8 @@ import (
 	v1 "k8s.io/api/core/v1"
 )
 
-var container = []*Container{{Port: 80, Protocol: v1.ProtocolTCP}}
-var cont5000 = []*Container{{Port: 5000, Protocol: v1.ProtocolTCP}}
+var container = []*Container{{Ports: []*ContainerPort{{Port: 80, Protocol: v1.ProtocolTCP}}}}
+var cont5000 = []*Container{{Ports: []*ContainerPort{{Port: 5000, Protocol: v1.ProtocolTCP}}}}
 
 const Recipe01 = `
 kind: NetworkPolicy
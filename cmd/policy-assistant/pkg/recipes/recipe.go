@@ -28,8 +28,17 @@ func (r *Recipe) Policies() []*networkingv1.NetworkPolicy {
 	return policies
 }
 
+// Policy builds the recipe's policies, which are expected to be valid.
+func (r *Recipe) Policy() *matcher.Policy {
+	policy, policyErrors := matcher.BuildNetworkPolicies(true, r.Policies())
+	for _, err := range policyErrors {
+		utils.DoOrDie(err)
+	}
+	return policy
+}
+
 func (r *Recipe) RunProbe() *probe.Table {
-	runner := probe.NewSimulatedRunner(matcher.BuildNetworkPolicies(true, r.Policies()), &probe.JobBuilder{TimeoutSeconds: 5})
+	runner := probe.NewSimulatedRunner(r.Policy(), &probe.JobBuilder{TimeoutSeconds: 5})
 	return runner.RunProbeForConfig(generator.NewProbeConfig(intstr.FromInt(r.Port), r.Protocol, generator.ProbeModeServiceName), r.Resources)
 }
 
@@ -55,7 +64,7 @@ func Run() {
 	for _, recipe := range AllRecipes {
 		table := recipe.RunProbe()
 
-		fmt.Printf("Policies:\n%s\n", matcher.BuildNetworkPolicies(true, recipe.Policies()).ExplainTable())
+		fmt.Printf("Policies:\n%s\n", recipe.Policy().ExplainTable())
 
 		fmt.Printf("resources:\n%s\n", recipe.Resources.RenderTable())
 
@@ -0,0 +1,92 @@
+package render
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const (
+	// HelmReleaseName and HelmReleaseNamespace are used to render charts, as 'helm template' does by default
+	HelmReleaseName      = "release-name"
+	HelmReleaseNamespace = "default"
+)
+
+// Kustomize builds a kustomization directory, as 'kustomize build' does.  The source is named '<dir> (kustomize
+// output)', since its lines are those of the built output rather than of any file in the directory.
+func Kustomize(dir string) (*kube.YamlSource, error) {
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resources, err := kustomizer.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to build kustomization %s", dir)
+	}
+	yaml, err := resources.AsYaml()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to marshal kustomization %s", dir)
+	}
+	return &kube.YamlSource{Path: fmt.Sprintf("%s (kustomize output)", filepath.Clean(dir)), Yaml: yaml}, nil
+}
+
+// HelmChart renders the templates of a local chart directory or archive, as 'helm template' does.  Values files are
+// merged in order, so that later files take precedence; the chart's default values are used for anything not set.
+// Dependencies must already be in the chart's charts/ directory: nothing is downloaded.
+// There's one source per rendered template, named after the template's path; lines are those of the rendered output.
+func HelmChart(chartPath string, valuesFiles []string) ([]*kube.YamlSource, error) {
+	chart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to load chart %s", chartPath)
+	}
+
+	values := map[string]interface{}{}
+	for i := len(valuesFiles) - 1; i >= 0; i-- {
+		fileValues, err := chartutil.ReadValuesFile(valuesFiles[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read values file %s", valuesFiles[i])
+		}
+		values = chartutil.CoalesceTables(values, fileValues)
+	}
+	if err := chartutil.ProcessDependencies(chart, values); err != nil {
+		return nil, errors.Wrapf(err, "unable to process dependencies of chart %s", chartPath)
+	}
+
+	options := chartutil.ReleaseOptions{Name: HelmReleaseName, Namespace: HelmReleaseNamespace, Revision: 1, IsInstall: true}
+	renderValues, err := chartutil.ToRenderValues(chart, values, options, chartutil.DefaultCapabilities)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to compute values of chart %s", chartPath)
+	}
+	rendered, err := engine.Render(chart, renderValues)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to render chart %s", chartPath)
+	}
+
+	var names []string
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sources []*kube.YamlSource
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml") {
+			logrus.Debugf("skipping rendered file %s", name)
+			continue
+		}
+		if strings.TrimSpace(rendered[name]) == "" {
+			continue
+		}
+		// rendered templates are named <chart name>/templates/..., where the chart name may differ from its directory's
+		path := filepath.Join(chartPath, name[strings.Index(name, "/")+1:])
+		sources = append(sources, &kube.YamlSource{Path: path, Yaml: []byte(rendered[name])})
+	}
+	return sources, nil
+}
@@ -0,0 +1,62 @@
+package render
+
+import (
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func RunRenderTests() {
+	Describe("Kustomize", func() {
+		It("Should build an overlay, with the base's policies transformed", func() {
+			source, err := Kustomize("../../test/rendered-policies/kustomize/overlay")
+			Expect(err).To(BeNil())
+			Expect(source.Path).To(Equal("../../test/rendered-policies/kustomize/overlay (kustomize output)"))
+
+			policies, err := kube.ReadPoliciesFromYaml([]*kube.YamlSource{source})
+			Expect(err).To(BeNil())
+			Expect(policies.NetworkPolicies).To(HaveLen(2))
+			Expect(policies.Source(kube.NetworkPolicyKind, "prod", "prod-deny-all")).ToNot(BeNil())
+			Expect(policies.Source(kube.NetworkPolicyKind, "prod", "prod-allow-web")).ToNot(BeNil())
+		})
+
+		It("Should fail on a directory without a kustomization", func() {
+			_, err := Kustomize("../../test/rendered-policies/helm")
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("HelmChart", func() {
+		It("Should render a chart with its default values, skipping empty and non-yaml templates", func() {
+			sources, err := HelmChart("../../test/rendered-policies/helm/netpols", nil)
+			Expect(err).To(BeNil())
+			Expect(sources).To(HaveLen(1))
+			Expect(sources[0].Path).To(Equal("../../test/rendered-policies/helm/netpols/templates/deny-all.yaml"))
+
+			policies, err := kube.ReadPoliciesFromYaml(sources)
+			Expect(err).To(BeNil())
+			Expect(policies.NetworkPolicies).To(HaveLen(1))
+			Expect(policies.NetworkPolicies[0].Name).To(Equal("release-name-deny-all"))
+			Expect(policies.NetworkPolicies[0].Namespace).To(Equal(HelmReleaseNamespace))
+		})
+
+		It("Should render a chart with values files", func() {
+			sources, err := HelmChart("../../test/rendered-policies/helm/netpols", []string{"../../test/rendered-policies/helm/web-values.yaml"})
+			Expect(err).To(BeNil())
+			Expect(sources).To(HaveLen(2))
+
+			policies, err := kube.ReadPoliciesFromYaml(sources)
+			Expect(err).To(BeNil())
+			Expect(policies.NetworkPolicies).To(HaveLen(2))
+			web := policies.NetworkPolicies[1]
+			Expect(web.Name).To(Equal("release-name-allow-web"))
+			Expect(*web.Spec.Ingress[0].Ports[0].Port).To(Equal(intstr.FromInt32(8080)))
+		})
+
+		It("Should fail on a missing values file", func() {
+			_, err := HelmChart("../../test/rendered-policies/helm/netpols", []string{"../../test/rendered-policies/helm/missing.yaml"})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+}
@@ -0,0 +1,200 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+const Prompt = "cyclonus> "
+
+const Help = `commands:
+  targets <namespace>/<pod>                      policies applying to a pod
+  selects <policy>                               pods a policy applies to; policies are netpol/<namespace>/<name>, anp/<name> or banp/<name>
+  verdict <src> <dst> <port>[/<protocol>]        whether traffic is allowed; peers are <namespace>/<pod> or an IP, ports are a number or name
+  label ns/<name> <key>=<value>|<key>- ...       set or remove namespace labels
+  label pod/<namespace>/<name> <key>=<value>|<key>- ...
+                                                 set or remove pod labels
+  add-policy <path>                              add policies from a file or directory, replacing those of the same name
+  policies                                       list loaded policies
+  undo                                           revert the latest label or add-policy
+  help                                           show this message
+  exit                                           end the session`
+
+// ErrExit is returned by Execute when the session should end.
+var ErrExit = errors.New("exit")
+
+// Run reads commands from in, one per line, and writes their output to out until in is exhausted or the
+// exit command is read.  Errors from commands are written to out, and don't end the session.
+func (s *Session) Run(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		if _, err := fmt.Fprint(out, Prompt); err != nil {
+			return errors.Wrapf(err, "unable to write prompt")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		output, err := s.Execute(scanner.Text())
+		if errors.Is(err, ErrExit) {
+			return nil
+		}
+		if err != nil {
+			output = "error: " + err.Error()
+		}
+		if output != "" {
+			if _, err := fmt.Fprintln(out, output); err != nil {
+				return errors.Wrapf(err, "unable to write output")
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(err, "unable to read command")
+	}
+	_, err := fmt.Fprintln(out)
+	return errors.Wrapf(err, "unable to write output")
+}
+
+// Execute runs a single command and returns its output.
+func (s *Session) Execute(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return "", nil
+	}
+	command, args := fields[0], fields[1:]
+	switch command {
+	case "targets":
+		if len(args) != 1 {
+			return "", errors.Errorf("usage: targets <namespace>/<pod>")
+		}
+		ingress, egress, err := s.Targets(args[0])
+		if err != nil {
+			return "", err
+		}
+		return targetsString(ingress, egress), nil
+	case "selects":
+		if len(args) != 1 {
+			return "", errors.Errorf("usage: selects <policy>")
+		}
+		pods, err := s.SelectedPods(args[0])
+		if err != nil {
+			return "", err
+		}
+		if len(pods) == 0 {
+			return "no pods", nil
+		}
+		return strings.Join(pods, "\n"), nil
+	case "verdict":
+		if len(args) != 3 {
+			return "", errors.Errorf("usage: verdict <src> <dst> <port>[/<protocol>]")
+		}
+		port, protocol, err := parsePortProtocol(args[2])
+		if err != nil {
+			return "", err
+		}
+		traffic, result, err := s.Verdict(args[0], args[1], port, protocol)
+		if err != nil {
+			return "", err
+		}
+		return verdictString(traffic, result), nil
+	case "label":
+		if len(args) < 2 {
+			return "", errors.Errorf("usage: label ns/<name>|pod/<namespace>/<name> <key>=<value>|<key>- ...")
+		}
+		return "", s.SetLabels(args[0], args[1:])
+	case "add-policy":
+		if len(args) != 1 {
+			return "", errors.Errorf("usage: add-policy <path>")
+		}
+		netpols, anps, banp, err := kube.ReadNetworkPoliciesFromPath(args[0])
+		if err != nil {
+			return "", err
+		}
+		before := len(s.history)
+		errs := s.AddPolicies(netpols, anps, banp)
+		var lines []string
+		for _, err := range errs {
+			lines = append(lines, "skipped: "+err.Error())
+		}
+		if len(s.history) == before {
+			lines = append(lines, "no policies added")
+		}
+		return strings.Join(lines, "\n"), nil
+	case "policies":
+		var refs []string
+		for _, policy := range s.Policies {
+			refs = append(refs, policy.Ref)
+		}
+		return strings.Join(refs, "\n"), nil
+	case "undo":
+		if !s.Undo() {
+			return "", errors.Errorf("nothing to undo")
+		}
+		return "", nil
+	case "help":
+		return Help, nil
+	case "exit", "quit":
+		return "", ErrExit
+	default:
+		return "", errors.Errorf("unknown command %s; run 'help' to list commands", command)
+	}
+}
+
+func parsePortProtocol(portProtocol string) (string, v1.Protocol, error) {
+	port, protocol, ok := strings.Cut(portProtocol, "/")
+	if !ok {
+		return port, v1.ProtocolTCP, nil
+	}
+	switch p := v1.Protocol(strings.ToUpper(protocol)); p {
+	case v1.ProtocolTCP, v1.ProtocolUDP, v1.ProtocolSCTP:
+		return port, p, nil
+	default:
+		return "", "", errors.Errorf("invalid protocol %s: must be one of TCP, UDP and SCTP", protocol)
+	}
+}
+
+func targetsString(ingress []*matcher.Target, egress []*matcher.Target) string {
+	var lines []string
+	for _, direction := range []struct {
+		name    string
+		targets []*matcher.Target
+	}{{"ingress", ingress}, {"egress", egress}} {
+		if len(direction.targets) == 0 {
+			lines = append(lines, fmt.Sprintf("%s: no policies", direction.name))
+			continue
+		}
+		lines = append(lines, direction.name+":")
+		for _, target := range direction.targets {
+			for _, rule := range sortedSourceRules(target) {
+				lines = append(lines, "  "+rule)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sortedSourceRules(target *matcher.Target) []string {
+	var rules []string
+	for _, rule := range target.SourceRules {
+		rules = append(rules, string(rule))
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+func verdictString(traffic *matcher.Traffic, result *matcher.AllowedResult) string {
+	flow := func(d matcher.DirectionResult, direction string) string {
+		if f := d.Flow(); f != "" {
+			return f
+		}
+		return "no policies targeting " + direction
+	}
+	return fmt.Sprintf("%s: %s\ningress: %s\negress: %s", traffic.PrettyString(), result.Verdict(), flow(result.Ingress, "ingress"), flow(result.Egress, "egress"))
+}
@@ -0,0 +1,436 @@
+package shell
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+// LoadedPolicy is a policy in a Session, along with the targets built from it alone.
+type LoadedPolicy struct {
+	// Ref is "netpol/<namespace>/<name>", "anp/<name>" or "banp/<name>"
+	Ref string
+	// Priority is only set for ANPs
+	Priority *int32
+	Ingress  *matcher.Target
+	Egress   *matcher.Target
+}
+
+func newLoadedPolicy(ref string, priority *int32, ingress *matcher.Target, egress *matcher.Target) *LoadedPolicy {
+	// targets of the same key are combined by appending peers: clip them so that combining never writes into
+	// a target that a previous combination, which may be restored by undo, shares an array with
+	for _, target := range []*matcher.Target{ingress, egress} {
+		if target != nil {
+			target.Peers = slices.Clip(target.Peers)
+		}
+	}
+	return &LoadedPolicy{Ref: ref, Priority: priority, Ingress: ingress, Egress: egress}
+}
+
+func (l *LoadedPolicy) kind() string {
+	return strings.SplitN(l.Ref, "/", 2)[0]
+}
+
+// Session keeps policies and cluster resources in memory, so that questions about them can be answered without
+// reloading.  Changes are applied incrementally: adding a policy only rebuilds the targets that share a primary key
+// with it, and changing labels doesn't touch the policies at all, since selectors are matched against labels when
+// traffic is evaluated.  Each change can be undone.
+type Session struct {
+	Policy   *matcher.Policy
+	Snapshot *kube.Snapshot
+	Policies []*LoadedPolicy
+	history  []func()
+}
+
+// NewSession builds a session from the policies and other resources of a snapshot.  Invalid policies are skipped
+// and returned as errors.
+func NewSession(snapshot *kube.Snapshot) (*Session, []error) {
+	s := &Session{Policy: matcher.NewPolicy(), Snapshot: snapshot}
+	var netpols []*networkingv1.NetworkPolicy
+	for i := range snapshot.NetworkPolicies {
+		netpols = append(netpols, &snapshot.NetworkPolicies[i])
+	}
+	var anps []*v1alpha1.AdminNetworkPolicy
+	for i := range snapshot.AdminNetworkPolicies {
+		anps = append(anps, &snapshot.AdminNetworkPolicies[i])
+	}
+	errs := s.AddPolicies(netpols, anps, snapshot.BaselineAdminNetworkPolicy)
+	s.history = nil
+	return s, errs
+}
+
+// AddPolicies adds policies, replacing loaded policies of the same kind, namespace and name, and the BANP if one
+// is given.  Invalid policies are skipped and returned as errors; the others are added as a single change.
+func (s *Session) AddPolicies(netpols []*networkingv1.NetworkPolicy, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) []error {
+	var loaded []*LoadedPolicy
+	var errs []error
+	for _, netpol := range netpols {
+		ingress, egress, policyErrs := matcher.BuildTarget(netpol)
+		if len(policyErrs) > 0 {
+			errs = appendPolicyErrors(errs, policyErrs)
+			continue
+		}
+		loaded = append(loaded, newLoadedPolicy(fmt.Sprintf("netpol/%s/%s", netpolNamespace(netpol), netpol.Name), nil, ingress, egress))
+	}
+	for _, anp := range anps {
+		ingress, egress, policyErrs := matcher.BuildTargetANP(anp)
+		if len(policyErrs) > 0 {
+			errs = appendPolicyErrors(errs, policyErrs)
+			continue
+		}
+		priority := anp.Spec.Priority
+		loaded = append(loaded, newLoadedPolicy("anp/"+anp.Name, &priority, ingress, egress))
+	}
+	if banp != nil {
+		ingress, egress, policyErrs := matcher.BuildTargetBANP(banp)
+		if len(policyErrs) > 0 {
+			errs = appendPolicyErrors(errs, policyErrs)
+		} else {
+			loaded = append(loaded, newLoadedPolicy("banp/"+banp.Name, nil, ingress, egress))
+		}
+	}
+
+	previousPolicies := s.Policies
+	policies := append([]*LoadedPolicy{}, s.Policies...)
+	var replaced []*LoadedPolicy
+	var added []*LoadedPolicy
+	for _, policy := range loaded {
+		index := indexOfReplacedPolicy(policies, policy)
+		if policy.Priority != nil {
+			if other := anpWithPriority(policies, *policy.Priority, index); other != nil {
+				errs = append(errs, errors.Errorf("%s has the same priority as %s: duplicate priorities are undefined", policy.Ref, other.Ref))
+				continue
+			}
+		}
+		if index >= 0 {
+			replaced = append(replaced, policies[index])
+			policies[index] = policy
+		} else {
+			policies = append(policies, policy)
+		}
+		added = append(added, policy)
+	}
+	if len(added) == 0 {
+		return errs
+	}
+
+	ingressKeys, egressKeys := targetKeys(append(replaced, added...))
+	previousIngress := s.rebuildTargets(true, ingressKeys, policies)
+	previousEgress := s.rebuildTargets(false, egressKeys, policies)
+	s.Policies = policies
+	s.history = append(s.history, func() {
+		restoreTargets(s.Policy.Ingress, previousIngress)
+		restoreTargets(s.Policy.Egress, previousEgress)
+		s.Policies = previousPolicies
+	})
+	return errs
+}
+
+func indexOfReplacedPolicy(policies []*LoadedPolicy, policy *LoadedPolicy) int {
+	for i, other := range policies {
+		// there can only be one BANP, regardless of its name
+		if other.Ref == policy.Ref || (other.kind() == "banp" && policy.kind() == "banp") {
+			return i
+		}
+	}
+	return -1
+}
+
+func anpWithPriority(policies []*LoadedPolicy, priority int32, ignoreIndex int) *LoadedPolicy {
+	for i, other := range policies {
+		if i != ignoreIndex && other.Priority != nil && *other.Priority == priority {
+			return other
+		}
+	}
+	return nil
+}
+
+func targetKeys(policies []*LoadedPolicy) (map[string]bool, map[string]bool) {
+	ingress, egress := map[string]bool{}, map[string]bool{}
+	for _, policy := range policies {
+		if policy.Ingress != nil {
+			ingress[policy.Ingress.GetPrimaryKey()] = true
+		}
+		if policy.Egress != nil {
+			egress[policy.Egress.GetPrimaryKey()] = true
+		}
+	}
+	return ingress, egress
+}
+
+// rebuildTargets combines the targets of the policies for each key, in the order the policies were loaded, and
+// returns the targets they replace.
+func (s *Session) rebuildTargets(isIngress bool, keys map[string]bool, policies []*LoadedPolicy) map[string]*matcher.Target {
+	dict := s.Policy.Egress
+	if isIngress {
+		dict = s.Policy.Ingress
+	}
+	previous := map[string]*matcher.Target{}
+	for key := range keys {
+		previous[key] = dict[key]
+		delete(dict, key)
+	}
+	for _, policy := range policies {
+		target := policy.Egress
+		if isIngress {
+			target = policy.Ingress
+		}
+		if target != nil && keys[target.GetPrimaryKey()] {
+			s.Policy.AddTarget(isIngress, target)
+		}
+	}
+	return previous
+}
+
+func restoreTargets(dict map[string]*matcher.Target, previous map[string]*matcher.Target) {
+	for key, target := range previous {
+		if target == nil {
+			delete(dict, key)
+		} else {
+			dict[key] = target
+		}
+	}
+}
+
+// FindPolicy returns the loaded policy with the given ref, or nil.
+func (s *Session) FindPolicy(ref string) *LoadedPolicy {
+	for _, policy := range s.Policies {
+		if policy.Ref == ref {
+			return policy
+		}
+	}
+	return nil
+}
+
+// Undo reverts the latest change, returning false if there is none.
+func (s *Session) Undo() bool {
+	if len(s.history) == 0 {
+		return false
+	}
+	undo := s.history[len(s.history)-1]
+	s.history = s.history[:len(s.history)-1]
+	undo()
+	return true
+}
+
+// SetLabels changes the labels of a namespace ("ns/<name>") or pod ("pod/<namespace>/<name>").  Changes are of the
+// form "key=value" to set a label, or "key-" to remove it, as for kubectl.
+func (s *Session) SetLabels(object string, changes []string) error {
+	var labels *map[string]string
+	parts := strings.Split(object, "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "ns":
+		ns, err := s.namespace(parts[1])
+		if err != nil {
+			return err
+		}
+		labels = &ns.Labels
+	case len(parts) == 3 && parts[0] == "pod":
+		pod, err := s.pod(parts[1], parts[2])
+		if err != nil {
+			return err
+		}
+		labels = &pod.Labels
+	default:
+		return errors.Errorf("invalid object %s: expected ns/<name> or pod/<namespace>/<name>", object)
+	}
+
+	updated := map[string]string{}
+	for k, v := range *labels {
+		updated[k] = v
+	}
+	for _, change := range changes {
+		if key, value, ok := strings.Cut(change, "="); ok && key != "" {
+			updated[key] = value
+		} else if key, ok := strings.CutSuffix(change, "-"); ok && key != "" {
+			delete(updated, key)
+		} else {
+			return errors.Errorf("invalid label change %s: expected key=value or key-", change)
+		}
+	}
+
+	previous := *labels
+	*labels = updated
+	s.history = append(s.history, func() { *labels = previous })
+	return nil
+}
+
+func (s *Session) namespace(name string) (*v1.Namespace, error) {
+	for i := range s.Snapshot.Namespaces {
+		if s.Snapshot.Namespaces[i].Name == name {
+			return &s.Snapshot.Namespaces[i], nil
+		}
+	}
+	return nil, errors.Errorf("namespace %s not found", name)
+}
+
+func (s *Session) pod(namespace string, name string) (*v1.Pod, error) {
+	for i := range s.Snapshot.Pods {
+		if s.Snapshot.Pods[i].Namespace == namespace && s.Snapshot.Pods[i].Name == name {
+			return &s.Snapshot.Pods[i], nil
+		}
+	}
+	return nil, errors.Errorf("pod %s/%s not found", namespace, name)
+}
+
+func (s *Session) internalPeer(pod *v1.Pod) (*matcher.InternalPeer, error) {
+	ns, err := s.namespace(pod.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &matcher.InternalPeer{
+		Workload:        pod.Namespace + "/pod/" + pod.Name,
+		PodLabels:       pod.Labels,
+		NamespaceLabels: ns.Labels,
+		Namespace:       pod.Namespace,
+	}, nil
+}
+
+// Targets returns the ingress and egress targets applying to a pod, given as "<namespace>/<name>".
+func (s *Session) Targets(podRef string) ([]*matcher.Target, []*matcher.Target, error) {
+	pod, err := s.podFromRef(podRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	subject, err := s.internalPeer(pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := func(targets []*matcher.Target) []*matcher.Target {
+		sort.Slice(targets, func(i, j int) bool { return targets[i].GetPrimaryKey() < targets[j].GetPrimaryKey() })
+		return targets
+	}
+	return key(s.Policy.TargetsApplyingToPod(true, subject)), key(s.Policy.TargetsApplyingToPod(false, subject)), nil
+}
+
+// SelectedPods returns the pods, as "<namespace>/<name>", that a loaded policy applies to.
+func (s *Session) SelectedPods(ref string) ([]string, error) {
+	policy := s.FindPolicy(ref)
+	if policy == nil {
+		return nil, errors.Errorf("policy %s not found", ref)
+	}
+	var pods []string
+	for i := range s.Snapshot.Pods {
+		pod := &s.Snapshot.Pods[i]
+		subject, err := s.internalPeer(pod)
+		if err != nil {
+			return nil, err
+		}
+		if (policy.Ingress != nil && policy.Ingress.Matches(subject)) || (policy.Egress != nil && policy.Egress.Matches(subject)) {
+			pods = append(pods, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	sort.Strings(pods)
+	return pods, nil
+}
+
+func (s *Session) podFromRef(podRef string) (*v1.Pod, error) {
+	namespace, name, ok := strings.Cut(podRef, "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, errors.Errorf("invalid pod %s: expected <namespace>/<name>", podRef)
+	}
+	return s.pod(namespace, name)
+}
+
+// Verdict evaluates traffic between two peers, each of which is a pod given as "<namespace>/<name>" or an IP
+// external to the cluster.  The port is a number or the name of a port of the destination pod.
+func (s *Session) Verdict(source string, destination string, port string, protocol v1.Protocol) (*matcher.Traffic, *matcher.AllowedResult, error) {
+	sourcePeer, _, err := s.trafficPeer(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	destinationPeer, destinationPod, err := s.trafficPeer(destination)
+	if err != nil {
+		return nil, nil, err
+	}
+	portInt, portName, err := resolvePort(destinationPod, port, protocol)
+	if err != nil {
+		return nil, nil, err
+	}
+	traffic := &matcher.Traffic{
+		Source:           sourcePeer,
+		Destination:      destinationPeer,
+		ResolvedPort:     portInt,
+		ResolvedPortName: portName,
+		Protocol:         protocol,
+	}
+	return traffic, s.Policy.IsTrafficAllowed(traffic), nil
+}
+
+func (s *Session) trafficPeer(peer string) (*matcher.TrafficPeer, *v1.Pod, error) {
+	if net.ParseIP(peer) != nil {
+		return &matcher.TrafficPeer{IP: peer}, nil, nil
+	}
+	pod, err := s.podFromRef(peer)
+	if err != nil {
+		return nil, nil, err
+	}
+	internal, err := s.internalPeer(pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &matcher.TrafficPeer{Internal: internal, IP: pod.Status.PodIP}, pod, nil
+}
+
+func resolvePort(pod *v1.Pod, port string, protocol v1.Protocol) (int, string, error) {
+	portInt, err := strconv.Atoi(port)
+	if err == nil && (portInt < 1 || portInt > 65535) {
+		return 0, "", errors.Errorf("invalid port %d: must be from 1 to 65535", portInt)
+	}
+	if pod == nil {
+		if err != nil {
+			return 0, "", errors.Errorf("unable to resolve named port %s: destination isn't a pod", port)
+		}
+		return portInt, "", nil
+	}
+	ports := map[string]int{}
+	for _, cont := range pod.Spec.Containers {
+		for _, containerPort := range cont.Ports {
+			containerProtocol := containerPort.Protocol
+			if containerProtocol == "" {
+				containerProtocol = v1.ProtocolTCP
+			}
+			if containerProtocol != protocol || containerPort.Name == "" {
+				continue
+			}
+			if err == nil && int(containerPort.ContainerPort) == portInt {
+				return portInt, containerPort.Name, nil
+			}
+			ports[containerPort.Name] = int(containerPort.ContainerPort)
+		}
+	}
+	if err == nil {
+		return portInt, "", nil
+	}
+	if number, ok := ports[port]; ok {
+		return number, port, nil
+	}
+	names := maps.Keys(ports)
+	sort.Strings(names)
+	return 0, "", errors.Errorf("pod %s/%s has no %s port named %s; named ports are [%s]", pod.Namespace, pod.Name, protocol, port, strings.Join(names, ", "))
+}
+
+func netpolNamespace(netpol *networkingv1.NetworkPolicy) string {
+	if netpol.Namespace == "" {
+		return v1.NamespaceDefault
+	}
+	return netpol.Namespace
+}
+
+func appendPolicyErrors(errs []error, policyErrors []*matcher.PolicyError) []error {
+	for _, policyError := range policyErrors {
+		errs = append(errs, policyError)
+	}
+	return errs
+}
@@ -0,0 +1,196 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const anpYaml = `apiVersion: policy.networking.k8s.io/v1alpha1
+kind: AdminNetworkPolicy
+metadata:
+  name: deny-from-x
+spec:
+  priority: 10
+  subject:
+    pods:
+      namespaceSelector:
+        matchLabels:
+          ns: "y"
+      podSelector:
+        matchLabels:
+          pod: b
+  ingress:
+  - name: deny-x
+    action: Deny
+    from:
+    - namespaces:
+        matchLabels:
+          ns: "x"
+`
+
+const replacementYaml = `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-from-x
+  namespace: "y"
+spec:
+  podSelector:
+    matchLabels:
+      pod: b
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - namespaceSelector:
+        matchLabels:
+          ns: "x"
+    ports:
+    - port: 9090
+`
+
+func newTestSession() *Session {
+	port := intstr.FromInt32(8080)
+	snapshot := &kube.Snapshot{
+		Version: kube.SnapshotVersion,
+		Namespaces: []v1.Namespace{
+			{ObjectMeta: metav1.ObjectMeta{Name: "x", Labels: map[string]string{"ns": "x"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "y", Labels: map[string]string{"ns": "y"}}},
+		},
+		Pods: []v1.Pod{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "a", Labels: map[string]string{"pod": "a"}},
+				Status:     v1.PodStatus{PodIP: "192.168.1.1"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "y", Name: "b", Labels: map[string]string{"pod": "b"}},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "cont", Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 8080}}}}},
+				Status:     v1.PodStatus{PodIP: "192.168.1.2"},
+			},
+		},
+		NetworkPolicies: []networkingv1.NetworkPolicy{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "y", Name: "allow-from-x"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"pod": "b"}},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+					Ingress: []networkingv1.NetworkPolicyIngressRule{{
+						From:  []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"ns": "x"}}}},
+						Ports: []networkingv1.NetworkPolicyPort{{Port: &port}},
+					}},
+				},
+			},
+		},
+	}
+	session, errs := NewSession(snapshot)
+	Expect(errs).To(BeEmpty())
+	return session
+}
+
+func writePolicy(yaml string) string {
+	path := filepath.Join(GinkgoT().TempDir(), "policy.yaml")
+	Expect(os.WriteFile(path, []byte(yaml), 0600)).To(Succeed())
+	return path
+}
+
+func RunSessionTests() {
+	Describe("Shell session", func() {
+		var session *Session
+		execute := func(line string) string {
+			output, err := session.Execute(line)
+			Expect(err).To(BeNil())
+			return output
+		}
+		verdict := func(line string) string {
+			return strings.SplitN(strings.SplitN(execute(line), "\n", 2)[0], ": ", 2)[1]
+		}
+
+		BeforeEach(func() {
+			session = newTestSession()
+		})
+
+		It("Should evaluate traffic between pods, by port number or name", func() {
+			Expect(execute("verdict x/a y/b 8080/tcp")).To(Equal("x/pod/a -> y/pod/b:8080 (TCP): Allowed\n" +
+				"ingress: [NPv1] Allow (y/allow-from-x)\n" +
+				"egress: no policies targeting egress"))
+			Expect(verdict("verdict x/a y/b 9090")).To(Equal("Denied"))
+			Expect(verdict("verdict x/a y/b http")).To(Equal("Allowed"))
+			Expect(verdict("verdict 10.0.0.1 y/b 8080/TCP")).To(Equal("Denied"))
+
+			_, err := session.Execute("verdict x/a y/b 8080/ICMP")
+			Expect(err).ToNot(BeNil())
+			_, err = session.Execute("verdict x/a 10.0.0.1 http")
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Should list the policies applying to a pod", func() {
+			Expect(execute("targets y/b")).To(Equal("ingress:\n  [NPv1] y/allow-from-x\negress: no policies"))
+			Expect(execute("targets x/a")).To(Equal("ingress: no policies\negress: no policies"))
+			Expect(execute("selects netpol/y/allow-from-x")).To(Equal("y/b"))
+		})
+
+		It("Should re-evaluate traffic after labels change, and undo the change", func() {
+			execute("label ns/x ns=z team=red")
+			Expect(verdict("verdict x/a y/b 8080")).To(Equal("Denied"))
+			execute("label ns/x ns=x")
+			Expect(verdict("verdict x/a y/b 8080")).To(Equal("Allowed"))
+
+			execute("label pod/y/b pod-")
+			Expect(execute("targets y/b")).To(Equal("ingress: no policies\negress: no policies"))
+
+			execute("undo")
+			execute("undo")
+			Expect(session.Snapshot.Namespaces[0].Labels).To(Equal(map[string]string{"ns": "z", "team": "red"}))
+			Expect(execute("selects netpol/y/allow-from-x")).To(Equal("y/b"))
+			execute("undo")
+			Expect(session.Snapshot.Namespaces[0].Labels).To(Equal(map[string]string{"ns": "x"}))
+
+			_, err := session.Execute("undo")
+			Expect(err).ToNot(BeNil())
+			_, err = session.Execute("label ns/missing a=b")
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Should add policies, and undo adding them", func() {
+			Expect(execute("add-policy " + writePolicy(anpYaml))).To(Equal(""))
+			Expect(execute("policies")).To(Equal("netpol/y/allow-from-x\nanp/deny-from-x"))
+			Expect(verdict("verdict x/a y/b 8080")).To(Equal("Denied"))
+			Expect(execute("selects anp/deny-from-x")).To(Equal("y/b"))
+			Expect(execute("targets y/b")).To(Equal("ingress:\n  [NPv1] y/allow-from-x\n  [ANP] default/deny-from-x\negress: no policies"))
+
+			execute("undo")
+			Expect(execute("policies")).To(Equal("netpol/y/allow-from-x"))
+			Expect(verdict("verdict x/a y/b 8080")).To(Equal("Allowed"))
+			Expect(session.Policy.Ingress).To(HaveLen(1))
+		})
+
+		It("Should replace policies of the same name, and undo replacing them", func() {
+			execute("add-policy " + writePolicy(replacementYaml))
+			Expect(execute("policies")).To(Equal("netpol/y/allow-from-x"))
+			Expect(verdict("verdict x/a y/b 8080")).To(Equal("Denied"))
+			Expect(verdict("verdict x/a y/b 9090")).To(Equal("Allowed"))
+
+			execute("undo")
+			Expect(verdict("verdict x/a y/b 8080")).To(Equal("Allowed"))
+			Expect(verdict("verdict x/a y/b 9090")).To(Equal("Denied"))
+		})
+
+		It("Should read commands until exit, reporting errors without stopping", func() {
+			out := &bytes.Buffer{}
+			in := strings.NewReader("verdict x/a y/b 8080\nbogus\n\nexit\nverdict x/a y/b 9090\n")
+			Expect(session.Run(in, out)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring(": Allowed"))
+			Expect(out.String()).To(ContainSubstring("error: unknown command bogus"))
+			Expect(out.String()).ToNot(ContainSubstring(": Denied"))
+		})
+	})
+}
@@ -0,0 +1,14 @@
+package shell
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestShell(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSessionTests()
+	RunSpecs(t, "shell suite")
+}
@@ -0,0 +1,251 @@
+package synthesize
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Endpoint is a known workload.  Pods with the same namespace and labels can't be told apart by policies,
+// so they're a single endpoint.  Labels generated by controllers, such as pod-template-hash, are left out.
+type Endpoint struct {
+	Namespace       string
+	NamespaceLabels map[string]string
+	Labels          map[string]string
+	// IP is one of the endpoint's IPs, used to verify traffic from it to IPs
+	IP string
+}
+
+func newEndpoint(peer *matcher.InternalPeer, ip string) *Endpoint {
+	nsLabels := map[string]string{kube.DefaultNamespaceLabel: peer.Namespace}
+	maps.Copy(nsLabels, peer.NamespaceLabels)
+	return &Endpoint{
+		Namespace:       peer.Namespace,
+		NamespaceLabels: nsLabels,
+		Labels:          kube.WorkloadLabels(peer.PodLabels),
+		IP:              ip,
+	}
+}
+
+// Key identifies an endpoint by its namespace and labels.
+func (e *Endpoint) Key() string {
+	return e.Namespace + "/" + labelsKey(e.Labels)
+}
+
+func (e *Endpoint) trafficPeer() *matcher.TrafficPeer {
+	return &matcher.TrafficPeer{
+		Internal: &matcher.InternalPeer{
+			PodLabels:       e.Labels,
+			NamespaceLabels: e.NamespaceLabels,
+			Namespace:       e.Namespace,
+		},
+		IP: e.IP,
+	}
+}
+
+func labelsKey(labels map[string]string) string {
+	keys := maps.Keys(labels)
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return "[" + strings.Join(pairs, ",") + "]"
+}
+
+// peerKey identifies the endpoint of an internal peer, or the IP of any other peer.
+func peerKey(peer *matcher.TrafficPeer) string {
+	if peer.Internal != nil {
+		return newEndpoint(peer.Internal, peer.IP).Key()
+	}
+	return peer.IP
+}
+
+// Endpoints returns the known workloads: those of the pods, and those of the internal peers of the flows.
+// Endpoints are sorted by key.
+func Endpoints(pods []v1.Pod, namespaces []v1.Namespace, flows []*matcher.Traffic) []*Endpoint {
+	nsLabels := map[string]map[string]string{}
+	for _, ns := range namespaces {
+		nsLabels[ns.Name] = ns.Labels
+	}
+	endpoints := map[string]*Endpoint{}
+	add := func(endpoint *Endpoint) {
+		if _, ok := endpoints[endpoint.Key()]; !ok {
+			endpoints[endpoint.Key()] = endpoint
+		}
+	}
+	for _, pod := range pods {
+		ip := ""
+		if ips := kube.PodIPs(&pod); len(ips) > 0 {
+			ip = ips[0]
+		}
+		add(newEndpoint(&matcher.InternalPeer{PodLabels: pod.Labels, NamespaceLabels: nsLabels[pod.Namespace], Namespace: pod.Namespace}, ip))
+	}
+	for _, flow := range flows {
+		for _, peer := range []*matcher.TrafficPeer{flow.Source, flow.Destination} {
+			if peer.Internal != nil {
+				add(newEndpoint(peer.Internal, peer.IP))
+			}
+		}
+	}
+	keys := maps.Keys(endpoints)
+	sort.Strings(keys)
+	var sorted []*Endpoint
+	for _, key := range keys {
+		sorted = append(sorted, endpoints[key])
+	}
+	return sorted
+}
+
+// ReadFlowsFromPath reads flows from a file of Traffic JSON, or from a flow-log CSV if the path ends in .csv.
+// Pods and namespaces are used to resolve the peers of CSV flows.
+func ReadFlowsFromPath(path string, pods []v1.Pod, namespaces []v1.Namespace) ([]*matcher.Traffic, error) {
+	if !strings.HasSuffix(strings.ToLower(path), ".csv") {
+		flows, err := json.ParseFile[[]*matcher.Traffic](path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read traffic json from %s", path)
+		}
+		return *flows, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open %s", path)
+	}
+	defer file.Close()
+	return ReadFlowsCSV(file, pods, namespaces)
+}
+
+// ReadFlowsCSV reads a flow log of src,dst,port,proto records; a header row is skipped.
+// Peers are <namespace>/<pod> or an IP, which is resolved to a pod if one has it.
+// Ports are a number, or a port name declared by the destination pod.  The protocol defaults to TCP.
+func ReadFlowsCSV(reader io.Reader, pods []v1.Pod, namespaces []v1.Namespace) ([]*matcher.Traffic, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	csvReader.Comment = '#'
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read csv")
+	}
+	resolver := newPeerResolver(pods, namespaces)
+	var flows []*matcher.Traffic
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && (record[0] == "src" || record[0] == "source") {
+			continue
+		}
+		flow, err := resolver.flow(record)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid flow on line %d", i+1)
+		}
+		flows = append(flows, flow)
+	}
+	return flows, nil
+}
+
+type peerResolver struct {
+	pods     map[string]*v1.Pod
+	podsByIP map[string]*v1.Pod
+	nsLabels map[string]map[string]string
+}
+
+func newPeerResolver(pods []v1.Pod, namespaces []v1.Namespace) *peerResolver {
+	resolver := &peerResolver{pods: map[string]*v1.Pod{}, podsByIP: map[string]*v1.Pod{}, nsLabels: map[string]map[string]string{}}
+	for i := range pods {
+		pod := &pods[i]
+		resolver.pods[pod.Namespace+"/"+pod.Name] = pod
+		for _, ip := range kube.PodIPs(pod) {
+			resolver.podsByIP[ip] = pod
+		}
+	}
+	for _, ns := range namespaces {
+		resolver.nsLabels[ns.Name] = ns.Labels
+	}
+	return resolver
+}
+
+func (r *peerResolver) flow(record []string) (*matcher.Traffic, error) {
+	if len(record) < 3 || len(record) > 4 {
+		return nil, errors.Errorf("expected src,dst,port[,proto], found %d fields", len(record))
+	}
+	source, _, err := r.peer(record[0])
+	if err != nil {
+		return nil, err
+	}
+	destination, destinationPod, err := r.peer(record[1])
+	if err != nil {
+		return nil, err
+	}
+	protocol := v1.ProtocolTCP
+	if len(record) == 4 && record[3] != "" {
+		protocol, err = kube.ParseProtocol(record[3])
+		if err != nil {
+			return nil, err
+		}
+	}
+	traffic := &matcher.Traffic{Source: source, Destination: destination, Protocol: protocol}
+	if port, err := strconv.Atoi(record[2]); err == nil {
+		traffic.ResolvedPort = port
+		return traffic, nil
+	}
+	if destinationPod == nil {
+		return nil, errors.Errorf("unable to resolve named port %s: destination %s isn't a known pod", record[2], record[1])
+	}
+	for _, cont := range destinationPod.Spec.Containers {
+		for _, port := range cont.Ports {
+			if port.Name == record[2] && (port.Protocol == protocol || port.Protocol == "" && protocol == v1.ProtocolTCP) {
+				traffic.ResolvedPort = int(port.ContainerPort)
+				traffic.ResolvedPortName = port.Name
+				return traffic, nil
+			}
+		}
+	}
+	return nil, errors.Errorf("pod %s/%s has no port named %s on %s", destinationPod.Namespace, destinationPod.Name, record[2], protocol)
+}
+
+func (r *peerResolver) peer(peer string) (*matcher.TrafficPeer, *v1.Pod, error) {
+	pod, ok := r.podsByIP[peer]
+	if !ok && net.ParseIP(peer) != nil {
+		return &matcher.TrafficPeer{IP: peer}, nil, nil
+	}
+	if !ok {
+		pod, ok = r.pods[peer]
+		if !ok {
+			return nil, nil, errors.Errorf("unable to resolve peer %s: expected <namespace>/<pod> of a known pod, or an IP", peer)
+		}
+	}
+	ip := peer
+	if net.ParseIP(peer) == nil {
+		ip = ""
+		if ips := kube.PodIPs(pod); len(ips) > 0 {
+			ip = ips[0]
+		}
+	}
+	return &matcher.TrafficPeer{
+		Internal: &matcher.InternalPeer{
+			PodLabels:       pod.Labels,
+			NamespaceLabels: r.nsLabels[pod.Namespace],
+			Namespace:       pod.Namespace,
+		},
+		IP: ip,
+	}, pod, nil
+}
+
+// portKey identifies the port of a flow by number, or by name if it has no number.
+func portKey(flow *matcher.Traffic) string {
+	if flow.ResolvedPort == 0 {
+		return fmt.Sprintf("%s/%s", flow.ResolvedPortName, flow.Protocol)
+	}
+	return fmt.Sprintf("%d/%s", flow.ResolvedPort, flow.Protocol)
+}
@@ -0,0 +1,14 @@
+package synthesize
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSynthesize(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSynthesizeTests()
+	RunSpecs(t, "synthesize suite")
+}
@@ -0,0 +1,253 @@
+package synthesize
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattfenwick/cyclonus/pkg/kube"
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+// Result is a set of policies synthesized from flows.
+type Result struct {
+	NetworkPolicies            []*networkingv1.NetworkPolicy
+	BaselineAdminNetworkPolicy *v1alpha1.BaselineAdminNetworkPolicy
+}
+
+// destination is the endpoint of a synthesized policy, and the ports its sources may reach it on.
+type destination struct {
+	endpoint *Endpoint
+	sources  map[string]*source
+}
+
+type source struct {
+	peer  networkingv1.NetworkPolicyPeer
+	ports map[string]networkingv1.NetworkPolicyPort
+}
+
+// Synthesize builds ingress NetworkPolicies allowing the flows between the endpoints, one per destination
+// endpoint, with a rule for each set of ports shared by its sources.  Endpoints which aren't the destination of
+// any flow are default-denied, by a NetworkPolicy in their namespace or, if defaultDenyBANP is set, by a BANP.
+//
+// Egress isn't restricted, so flows to peers outside the cluster are allowed without policies.
+func Synthesize(flows []*matcher.Traffic, endpoints []*Endpoint, defaultDenyBANP bool) (*Result, error) {
+	destinations := map[string]*destination{}
+	for _, flow := range flows {
+		if flow.Destination.Service != nil {
+			return nil, errors.Errorf("unable to synthesize policies for flow to service %s/%s: use the flows to its pods", flow.Destination.Service.Namespace, flow.Destination.Service.Name)
+		}
+		if flow.Destination.Internal == nil {
+			continue
+		}
+		dest := newEndpoint(flow.Destination.Internal, flow.Destination.IP)
+		if _, ok := destinations[dest.Key()]; !ok {
+			destinations[dest.Key()] = &destination{endpoint: dest, sources: map[string]*source{}}
+		}
+		sources := destinations[dest.Key()].sources
+
+		srcKey := peerKey(flow.Source)
+		if _, ok := sources[srcKey]; !ok {
+			peer, err := sourcePeer(flow.Source, dest.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			sources[srcKey] = &source{peer: peer, ports: map[string]networkingv1.NetworkPolicyPort{}}
+		}
+		sources[srcKey].ports[portKey(flow)] = policyPort(flow)
+	}
+
+	result := &Result{}
+	names := map[string]bool{}
+	for _, key := range sortedKeys(destinations) {
+		dest := destinations[key]
+		name := uniqueName(names, dest.endpoint.Namespace, policyName(dest.endpoint.Labels))
+		result.NetworkPolicies = append(result.NetworkPolicies, &networkingv1.NetworkPolicy{
+			TypeMeta:   metav1.TypeMeta{Kind: "NetworkPolicy", APIVersion: "networking.k8s.io/v1"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: dest.endpoint.Namespace, Name: name},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: dest.endpoint.Labels},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				Ingress:     ingressRules(dest.sources),
+			},
+		})
+	}
+
+	deniedNamespaces := map[string]bool{}
+	for _, endpoint := range endpoints {
+		if _, ok := destinations[endpoint.Key()]; !ok {
+			deniedNamespaces[endpoint.Namespace] = true
+		}
+	}
+	if len(deniedNamespaces) == 0 {
+		return result, nil
+	}
+	if defaultDenyBANP {
+		result.BaselineAdminNetworkPolicy = defaultDenyBaselineAdminNetworkPolicy()
+		return result, nil
+	}
+	for _, ns := range sortedKeys(deniedNamespaces) {
+		result.NetworkPolicies = append(result.NetworkPolicies, &networkingv1.NetworkPolicy{
+			TypeMeta:   metav1.TypeMeta{Kind: "NetworkPolicy", APIVersion: "networking.k8s.io/v1"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: uniqueName(names, ns, "default-deny-ingress")},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		})
+	}
+	return result, nil
+}
+
+func sourcePeer(peer *matcher.TrafficPeer, destinationNamespace string) (networkingv1.NetworkPolicyPeer, error) {
+	if peer.Internal != nil {
+		npPeer := networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: kube.WorkloadLabels(peer.Internal.PodLabels)}}
+		if peer.Internal.Namespace != destinationNamespace {
+			npPeer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{kube.DefaultNamespaceLabel: peer.Internal.Namespace}}
+		}
+		return npPeer, nil
+	}
+	if peer.Service != nil || peer.IP == "" {
+		return networkingv1.NetworkPolicyPeer{}, errors.Errorf("unable to synthesize policies for flow from %+v: sources must be pods or IPs", peer)
+	}
+	if _, err := kube.IPFamily(peer.IP); err != nil {
+		return networkingv1.NetworkPolicyPeer{}, err
+	}
+	return networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: kube.MakeCIDRFromZeroes(peer.IP, 0)}}, nil
+}
+
+func policyPort(flow *matcher.Traffic) networkingv1.NetworkPolicyPort {
+	port := intstr.FromInt32(int32(flow.ResolvedPort))
+	if flow.ResolvedPort == 0 {
+		port = intstr.FromString(flow.ResolvedPortName)
+	}
+	protocol := flow.Protocol
+	return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port}
+}
+
+// ingressRules groups sources reaching a destination on the same ports into a rule.
+func ingressRules(sources map[string]*source) []networkingv1.NetworkPolicyIngressRule {
+	rulesByPorts := map[string]*networkingv1.NetworkPolicyIngressRule{}
+	var order []string
+	for _, srcKey := range sortedKeys(sources) {
+		src := sources[srcKey]
+		portKeys := sortedKeys(src.ports)
+		key := strings.Join(portKeys, ",")
+		if _, ok := rulesByPorts[key]; !ok {
+			rule := &networkingv1.NetworkPolicyIngressRule{}
+			for _, portKey := range portKeys {
+				rule.Ports = append(rule.Ports, src.ports[portKey])
+			}
+			rulesByPorts[key] = rule
+			order = append(order, key)
+		}
+		rulesByPorts[key].From = append(rulesByPorts[key].From, src.peer)
+	}
+	var rules []networkingv1.NetworkPolicyIngressRule
+	for _, key := range order {
+		rules = append(rules, *rulesByPorts[key])
+	}
+	return rules
+}
+
+func defaultDenyBaselineAdminNetworkPolicy() *v1alpha1.BaselineAdminNetworkPolicy {
+	return &v1alpha1.BaselineAdminNetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{Kind: "BaselineAdminNetworkPolicy", APIVersion: "policy.networking.k8s.io/v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{{
+				Name:   "default-deny",
+				Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+				From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+			}},
+		},
+		Status: v1alpha1.BaselineAdminNetworkPolicyStatus{Conditions: []metav1.Condition{}},
+	}
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// policyName names the policy of a destination after the values of its labels, e.g. allow-ingress-to-web-v1.
+func policyName(labels map[string]string) string {
+	var values []string
+	for _, key := range sortedKeys(labels) {
+		values = append(values, labels[key])
+	}
+	if len(values) == 0 {
+		return "allow-ingress-to-all-pods"
+	}
+	name := "allow-ingress-to-" + invalidNameChars.ReplaceAllString(strings.ToLower(strings.Join(values, "-")), "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.TrimRight(name, "-")
+}
+
+func uniqueName(names map[string]bool, namespace string, name string) string {
+	unique := name
+	for i := 2; names[namespace+"/"+unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	names[namespace+"/"+unique] = true
+	return unique
+}
+
+func sortedKeys[V any](dict map[string]V) []string {
+	keys := maps.Keys(dict)
+	sort.Strings(keys)
+	return keys
+}
+
+// Mismatch is traffic whose verdict under the synthesized policies isn't that of the flows.
+type Mismatch struct {
+	Traffic *matcher.Traffic
+	// Expected is whether the flows allow the traffic
+	Expected bool
+}
+
+// Verify checks that the policies allow each flow, and that, on the ports of the flows, traffic between endpoints
+// is allowed only if it's one of the flows.
+func Verify(result *Result, flows []*matcher.Traffic, endpoints []*Endpoint) ([]*Mismatch, error) {
+	policy, policyErrors := matcher.BuildV1AndV2NetPols(true, result.NetworkPolicies, nil, result.BaselineAdminNetworkPolicy)
+	if len(policyErrors) > 0 {
+		return nil, errors.Errorf("synthesized invalid policies: %+v", policyErrors[0])
+	}
+
+	var mismatches []*Mismatch
+	allowed := map[string]bool{}
+	ports := map[string]*matcher.Traffic{}
+	for _, flow := range flows {
+		allowed[peerKey(flow.Source)+" -> "+peerKey(flow.Destination)+" "+portKey(flow)] = true
+		ports[portKey(flow)] = flow
+		if !policy.IsTrafficAllowed(flow).IsAllowed() {
+			mismatches = append(mismatches, &Mismatch{Traffic: flow, Expected: true})
+		}
+	}
+
+	for _, src := range endpoints {
+		for _, dst := range endpoints {
+			for _, key := range sortedKeys(ports) {
+				traffic := &matcher.Traffic{
+					Source:           src.trafficPeer(),
+					Destination:      dst.trafficPeer(),
+					ResolvedPort:     ports[key].ResolvedPort,
+					ResolvedPortName: ports[key].ResolvedPortName,
+					Protocol:         ports[key].Protocol,
+				}
+				expected := allowed[src.Key()+" -> "+dst.Key()+" "+key]
+				if !expected && policy.IsTrafficAllowed(traffic).IsAllowed() {
+					mismatches = append(mismatches, &Mismatch{Traffic: traffic, Expected: false})
+				}
+			}
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,137 @@
+package synthesize
+
+import (
+	"strings"
+
+	"github.com/mattfenwick/cyclonus/pkg/matcher"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const flowsCSV = `src,dst,port,proto
+y/client,x/web-1,http,tcp
+10.0.1.1,10.0.0.2,8080,TCP
+x/web-1,x/db,5432
+203.0.113.7,x/web-1,8080
+`
+
+func newPod(namespace string, name string, labels map[string]string, ip string, ports ...v1.ContainerPort) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "cont", Ports: ports}}},
+		Status:     v1.PodStatus{PodIP: ip},
+	}
+}
+
+func RunSynthesizeTests() {
+	Describe("Synthesize", func() {
+		namespaces := []v1.Namespace{
+			{ObjectMeta: metav1.ObjectMeta{Name: "x", Labels: map[string]string{"kubernetes.io/metadata.name": "x"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "y", Labels: map[string]string{"kubernetes.io/metadata.name": "y"}}},
+		}
+		pods := []v1.Pod{
+			newPod("x", "web-1", map[string]string{"app": "web"}, "10.0.0.1", v1.ContainerPort{Name: "http", ContainerPort: 8080}),
+			newPod("x", "web-2", map[string]string{"app": "web"}, "10.0.0.2"),
+			newPod("x", "db", map[string]string{"app": "db"}, "10.0.0.3"),
+			newPod("y", "client", map[string]string{"app": "client"}, "10.0.1.1"),
+			newPod("y", "other", map[string]string{"app": "other"}, "10.0.1.2"),
+		}
+
+		readFlows := func(pods []v1.Pod) []*matcher.Traffic {
+			flows, err := ReadFlowsCSV(strings.NewReader(flowsCSV), pods, namespaces)
+			Expect(err).To(BeNil())
+			return flows
+		}
+
+		It("Should resolve the peers and ports of csv flows", func() {
+			flows := readFlows(pods)
+			Expect(flows).To(HaveLen(4))
+			Expect(flows[0].Source.Internal.PodLabels).To(Equal(map[string]string{"app": "client"}))
+			Expect(flows[0].ResolvedPort).To(Equal(8080))
+			Expect(flows[0].ResolvedPortName).To(Equal("http"))
+			Expect(flows[1].Destination.Internal.Namespace).To(Equal("x"))
+			Expect(flows[2].Protocol).To(Equal(v1.ProtocolTCP))
+			Expect(flows[3].Source.Internal).To(BeNil())
+
+			_, err := ReadFlowsCSV(strings.NewReader("x/missing,x/db,80\n"), pods, namespaces)
+			Expect(err).ToNot(BeNil())
+			_, err = ReadFlowsCSV(strings.NewReader("x/db,x/web-2,http\n"), pods, namespaces)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Should allow exactly the flows, with a policy per destination and default-deny namespaces", func() {
+			flows := readFlows(pods)
+			endpoints := Endpoints(pods, namespaces, flows)
+			Expect(endpoints).To(HaveLen(4))
+
+			result, err := Synthesize(flows, endpoints, false)
+			Expect(err).To(BeNil())
+			var names []string
+			for _, netpol := range result.NetworkPolicies {
+				names = append(names, netpol.Namespace+"/"+netpol.Name)
+			}
+			Expect(names).To(Equal([]string{"x/allow-ingress-to-db", "x/allow-ingress-to-web", "y/default-deny-ingress"}))
+			Expect(result.NetworkPolicies[1].Spec.Ingress).To(HaveLen(1))
+			Expect(result.NetworkPolicies[1].Spec.Ingress[0].From).To(HaveLen(2))
+			Expect(result.BaselineAdminNetworkPolicy).To(BeNil())
+
+			mismatches, err := Verify(result, flows, endpoints)
+			Expect(err).To(BeNil())
+			Expect(mismatches).To(BeEmpty())
+		})
+
+		It("Should default-deny with a BANP", func() {
+			flows := readFlows(pods)
+			endpoints := Endpoints(pods, namespaces, flows)
+			result, err := Synthesize(flows, endpoints, true)
+			Expect(err).To(BeNil())
+			Expect(result.NetworkPolicies).To(HaveLen(2))
+			Expect(result.BaselineAdminNetworkPolicy).ToNot(BeNil())
+
+			mismatches, err := Verify(result, flows, endpoints)
+			Expect(err).To(BeNil())
+			Expect(mismatches).To(BeEmpty())
+		})
+
+		It("Should leave controller-generated labels out of selectors", func() {
+			hashedPods := []v1.Pod{
+				newPod("x", "web-1", map[string]string{"app": "web", "pod-template-hash": "5d4f8c"}, "10.0.0.1", v1.ContainerPort{Name: "http", ContainerPort: 8080}),
+				newPod("x", "web-2", map[string]string{"app": "web", "pod-template-hash": "7b9c2d"}, "10.0.0.2"),
+				newPod("x", "db", map[string]string{"app": "db", "controller-revision-hash": "db-6c8f", "statefulset.kubernetes.io/pod-name": "db-0"}, "10.0.0.3"),
+				newPod("y", "client", map[string]string{"app": "client", "pod-template-hash": "9f8e7d"}, "10.0.1.1"),
+			}
+			flows := readFlows(hashedPods)
+			endpoints := Endpoints(hashedPods, namespaces, flows)
+			Expect(endpoints).To(HaveLen(3))
+
+			result, err := Synthesize(flows, endpoints, false)
+			Expect(err).To(BeNil())
+			Expect(result.NetworkPolicies[0].Spec.PodSelector.MatchLabels).To(Equal(map[string]string{"app": "db"}))
+			Expect(result.NetworkPolicies[0].Spec.Ingress[0].From[0].PodSelector.MatchLabels).To(Equal(map[string]string{"app": "web"}))
+			Expect(result.NetworkPolicies[1].Spec.PodSelector.MatchLabels).To(Equal(map[string]string{"app": "web"}))
+			for _, from := range result.NetworkPolicies[1].Spec.Ingress[0].From {
+				if from.PodSelector != nil {
+					Expect(from.PodSelector.MatchLabels).To(Equal(map[string]string{"app": "client"}))
+				}
+			}
+		})
+
+		It("Should report traffic allowed to workloads whose labels include those of a destination", func() {
+			canaryPods := append([]v1.Pod{newPod("x", "web-canary", map[string]string{"app": "web", "track": "canary"}, "10.0.0.9")}, pods...)
+			flows := readFlows(canaryPods)
+			endpoints := Endpoints(canaryPods, namespaces, flows)
+			result, err := Synthesize(flows, endpoints, false)
+			Expect(err).To(BeNil())
+
+			mismatches, err := Verify(result, flows, endpoints)
+			Expect(err).To(BeNil())
+			Expect(mismatches).ToNot(BeEmpty())
+			for _, mismatch := range mismatches {
+				Expect(mismatch.Expected).To(BeFalse())
+				Expect(mismatch.Traffic.PrettyString()).To(ContainSubstring("track=canary"))
+			}
+		})
+	})
+}
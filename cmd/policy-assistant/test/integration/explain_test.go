@@ -95,7 +95,8 @@ func TestExplain(t *testing.T) {
 			"|         |    all pods        |                                                                            |                      |                    |                           |\n" +
 			"+---------+--------------------+----------------------------------------------------------------------------+----------------------+--------------------+---------------------------+\n" +
 			""
-		policies := matcher.BuildV1AndV2NetPols(true, netpol.AllExamples, nil, nil)
+		policies, policyErrors := matcher.BuildV1AndV2NetPols(true, netpol.AllExamples, nil, nil)
+		require.Empty(t, policyErrors)
 		require.Equal(t, expected, policies.ExplainTable())
 	})
 
@@ -129,26 +130,17 @@ func TestExplain(t *testing.T) {
 			"+---------+------------------------------------------+-----------------------------+------------------------------------------------------------------------+--------------------------------------------------------------------------------------+----------------------------+\n" +
 			"|         |                                          |                             |                                                                        |                                                                                      |                            |\n" +
 			"+---------+------------------------------------------+-----------------------------+------------------------------------------------------------------------+--------------------------------------------------------------------------------------+----------------------------+\n" +
-			"| Egress  | Namespace:                               | [ANP] default/example-anp   | Namespace:                                                             | BANP:                                                                                | all ports, all protocols   |\n" +
-			"|         |    kubernetes.io/metadata.name Exists [] | [ANP] default/example-anp-2 |    Not Same labels - Test1                                             |    Deny                                                                              |                            |\n" +
-			"|         |                                          | [BANP] default/default      | Pod:                                                                   |                                                                                      |                            |\n" +
-			"|         |                                          |                             |    all                                                                 |                                                                                      |                            |\n" +
-			"+         +                                          +                             +------------------------------------------------------------------------+--------------------------------------------------------------------------------------+                            +\n" +
-			"|         |                                          |                             | Namespace:                                                             | BANP:                                                                                |                            |\n" +
-			"|         |                                          |                             |    Same labels - Test                                                  |    Allow                                                                             |                            |\n" +
-			"|         |                                          |                             | Pod:                                                                   |                                                                                      |                            |\n" +
-			"|         |                                          |                             |    all                                                                 |                                                                                      |                            |\n" +
-			"+         +                                          +                             +------------------------------------------------------------------------+--------------------------------------------------------------------------------------+                            +\n" +
-			"|         |                                          |                             | Namespace:                                                             | ANP:                                                                                 |                            |\n" +
-			"|         |                                          |                             |    kubernetes.io/metadata.name = network-policy-conformance-hufflepuff |    pri=16 (deny-to-hufflepuff-everything-else-2): Deny                               |                            |\n" +
-			"|         |                                          |                             | Pod:                                                                   |    pri=20 (deny-to-hufflepuff-everything-else): Deny                                 |                            |\n" +
+			"| Egress  | Namespace:                               | [ANP] default/example-anp   | Namespace:                                                             | ANP:                                                                                 | all ports, all protocols   |\n" +
+			"|         |    kubernetes.io/metadata.name Exists [] | [ANP] default/example-anp-2 |    kubernetes.io/metadata.name = network-policy-conformance-hufflepuff |    pri=16 (deny-to-hufflepuff-everything-else-2): Deny                               |                            |\n" +
+			"|         |                                          | [BANP] default/default      | Pod:                                                                   |    pri=20 (deny-to-hufflepuff-everything-else): Deny                                 |                            |\n" +
 			"|         |                                          |                             |    all                                                                 | BANP:                                                                                |                            |\n" +
 			"|         |                                          |                             |                                                                        |    Deny                                                                              |                            |\n" +
 			"+         +                                          +                             +------------------------------------------------------------------------+--------------------------------------------------------------------------------------+                            +\n" +
 			"|         |                                          |                             | Namespace:                                                             | ANP:                                                                                 |                            |\n" +
 			"|         |                                          |                             |    kubernetes.io/metadata.name = network-policy-conformance-ravenclaw  |    pri=16 (allow-to-ravenclaw-everything-2): Allow (ineffective rules: Deny, Pass)   |                            |\n" +
 			"|         |                                          |                             | Pod:                                                                   |    pri=20 (allow-to-ravenclaw-everything): Allow (ineffective rules: Deny, Pass)     |                            |\n" +
-			"|         |                                          |                             |    all                                                                 |                                                                                      |                            |\n" +
+			"|         |                                          |                             |    all                                                                 | BANP:                                                                                |                            |\n" +
+			"|         |                                          |                             |                                                                        |    Allow (ineffective rules: Deny)                                                   |                            |\n" +
 			"+         +                                          +                             +------------------------------------------------------------------------+--------------------------------------------------------------------------------------+----------------------------+\n" +
 			"|         |                                          |                             | Namespace:                                                             | ANP:                                                                                 | port 80 on protocol TCP    |\n" +
 			"|         |                                          |                             |    kubernetes.io/metadata.name = network-policy-conformance-slytherin  |    pri=16 (deny-to-slytherin-at-ports-80-53-9003-2): Deny (ineffective rules: Pass)  | port 53 on protocol UDP    |\n" +
@@ -167,7 +159,8 @@ func TestExplain(t *testing.T) {
 			"|         |                                          |                             |                                                                        |    Allow                                                                             |                            |\n" +
 			"+---------+------------------------------------------+-----------------------------+------------------------------------------------------------------------+--------------------------------------------------------------------------------------+----------------------------+\n" +
 			""
-		policies := matcher.BuildV1AndV2NetPols(false, nil, examples.CoreGressRulesCombinedANB, examples.CoreGressRulesCombinedBANB)
+		policies, policyErrors := matcher.BuildV1AndV2NetPols(false, nil, examples.CoreGressRulesCombinedANB, examples.CoreGressRulesCombinedBANB)
+		require.Empty(t, policyErrors)
 		require.Equal(t, expected, policies.ExplainTable())
 	})
 }
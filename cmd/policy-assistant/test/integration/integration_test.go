@@ -141,7 +141,7 @@ func TestANPConnectivity(t *testing.T) {
 						Spec: v1alpha1.AdminNetworkPolicySpec{
 							Priority: 100,
 							Subject: v1alpha1.AdminNetworkPolicySubject{
-								Pods: &v1alpha1.NamespacedPodSubject{
+								Pods: &v1alpha1.NamespacedPod{
 									NamespaceSelector: metav1.LabelSelector{
 										MatchLabels: map[string]string{"ns": "x"},
 									},
@@ -153,13 +153,11 @@ func TestANPConnectivity(t *testing.T) {
 							Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
-									To: []v1alpha1.AdminNetworkPolicyPeer{
+									To: []v1alpha1.AdminNetworkPolicyEgressPeer{
 										{
-											Pods: &v1alpha1.NamespacedPodPeer{
-												Namespaces: v1alpha1.NamespacedPeer{
-													NamespaceSelector: &metav1.LabelSelector{
-														MatchLabels: map[string]string{"ns": "x"},
-													},
+											Pods: &v1alpha1.NamespacedPod{
+												NamespaceSelector: metav1.LabelSelector{
+													MatchLabels: map[string]string{"ns": "x"},
 												},
 												PodSelector: metav1.LabelSelector{
 													MatchLabels: map[string]string{"pod": "b"},
@@ -195,7 +193,7 @@ func TestANPConnectivity(t *testing.T) {
 						Spec: v1alpha1.AdminNetworkPolicySpec{
 							Priority: 100,
 							Subject: v1alpha1.AdminNetworkPolicySubject{
-								Pods: &v1alpha1.NamespacedPodSubject{
+								Pods: &v1alpha1.NamespacedPod{
 									NamespaceSelector: metav1.LabelSelector{
 										MatchLabels: map[string]string{"ns": "x"},
 									},
@@ -207,13 +205,11 @@ func TestANPConnectivity(t *testing.T) {
 							Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Pods: &v1alpha1.NamespacedPodPeer{
-												Namespaces: v1alpha1.NamespacedPeer{
-													NamespaceSelector: &metav1.LabelSelector{
-														MatchLabels: map[string]string{"ns": "x"},
-													},
+											Pods: &v1alpha1.NamespacedPod{
+												NamespaceSelector: metav1.LabelSelector{
+													MatchLabels: map[string]string{"ns": "x"},
 												},
 												PodSelector: metav1.LabelSelector{
 													MatchLabels: map[string]string{"pod": "b"},
@@ -249,7 +245,7 @@ func TestANPConnectivity(t *testing.T) {
 						Spec: v1alpha1.AdminNetworkPolicySpec{
 							Priority: 100,
 							Subject: v1alpha1.AdminNetworkPolicySubject{
-								Pods: &v1alpha1.NamespacedPodSubject{
+								Pods: &v1alpha1.NamespacedPod{
 									NamespaceSelector: metav1.LabelSelector{
 										MatchLabels: map[string]string{"ns": "x"},
 									},
@@ -261,13 +257,11 @@ func TestANPConnectivity(t *testing.T) {
 							Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Pods: &v1alpha1.NamespacedPodPeer{
-												Namespaces: v1alpha1.NamespacedPeer{
-													NamespaceSelector: &metav1.LabelSelector{
-														MatchLabels: map[string]string{"ns": "x"},
-													},
+											Pods: &v1alpha1.NamespacedPod{
+												NamespaceSelector: metav1.LabelSelector{
+													MatchLabels: map[string]string{"ns": "x"},
 												},
 												PodSelector: metav1.LabelSelector{
 													MatchLabels: map[string]string{"pod": "b"},
@@ -304,7 +298,7 @@ func TestANPConnectivity(t *testing.T) {
 						Spec: v1alpha1.AdminNetworkPolicySpec{
 							Priority: 100,
 							Subject: v1alpha1.AdminNetworkPolicySubject{
-								Pods: &v1alpha1.NamespacedPodSubject{
+								Pods: &v1alpha1.NamespacedPod{
 									NamespaceSelector: metav1.LabelSelector{
 										MatchLabels: map[string]string{"ns": "x"},
 									},
@@ -325,11 +319,11 @@ func TestANPConnectivity(t *testing.T) {
 											},
 										},
 									}),
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Pods: &v1alpha1.NamespacedPodPeer{
-												Namespaces: v1alpha1.NamespacedPeer{
-													SameLabels: []string{"ns"},
+											Pods: &v1alpha1.NamespacedPod{
+												NamespaceSelector: metav1.LabelSelector{
+													MatchLabels: map[string]string{"ns": "x"},
 												},
 												PodSelector: metav1.LabelSelector{},
 											},
@@ -357,7 +351,7 @@ func TestANPConnectivity(t *testing.T) {
 						Spec: v1alpha1.AdminNetworkPolicySpec{
 							Priority: 100,
 							Subject: v1alpha1.AdminNetworkPolicySubject{
-								Pods: &v1alpha1.NamespacedPodSubject{
+								Pods: &v1alpha1.NamespacedPod{
 									NamespaceSelector: metav1.LabelSelector{
 										MatchLabels: map[string]string{"ns": "x"},
 									},
@@ -369,10 +363,12 @@ func TestANPConnectivity(t *testing.T) {
 							Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NotSameLabels: []string{"ns"},
+											Namespaces: &metav1.LabelSelector{
+												MatchExpressions: []metav1.LabelSelectorRequirement{
+													{Key: "ns", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"x"}},
+												},
 											},
 										},
 									},
@@ -405,7 +401,7 @@ func TestANPConnectivity(t *testing.T) {
 						Spec: v1alpha1.AdminNetworkPolicySpec{
 							Priority: 100,
 							Subject: v1alpha1.AdminNetworkPolicySubject{
-								Pods: &v1alpha1.NamespacedPodSubject{
+								Pods: &v1alpha1.NamespacedPod{
 									NamespaceSelector: metav1.LabelSelector{
 										MatchLabels: map[string]string{"ns": "x"},
 									},
@@ -417,13 +413,11 @@ func TestANPConnectivity(t *testing.T) {
 							Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Pods: &v1alpha1.NamespacedPodPeer{
-												Namespaces: v1alpha1.NamespacedPeer{
-													NamespaceSelector: &metav1.LabelSelector{
-														MatchLabels: map[string]string{"ns": "y"},
-													},
+											Pods: &v1alpha1.NamespacedPod{
+												NamespaceSelector: metav1.LabelSelector{
+													MatchLabels: map[string]string{"ns": "y"},
 												},
 												PodSelector: metav1.LabelSelector{
 													MatchLabels: map[string]string{"pod": "a"},
@@ -442,10 +436,12 @@ func TestANPConnectivity(t *testing.T) {
 								},
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NotSameLabels: []string{"ns"},
+											Namespaces: &metav1.LabelSelector{
+												MatchExpressions: []metav1.LabelSelectorRequirement{
+													{Key: "ns", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"x"}},
+												},
 											},
 										},
 									},
@@ -479,7 +475,7 @@ func TestANPConnectivity(t *testing.T) {
 						Spec: v1alpha1.AdminNetworkPolicySpec{
 							Priority: 100,
 							Subject: v1alpha1.AdminNetworkPolicySubject{
-								Pods: &v1alpha1.NamespacedPodSubject{
+								Pods: &v1alpha1.NamespacedPod{
 									NamespaceSelector: metav1.LabelSelector{
 										MatchLabels: map[string]string{"ns": "x"},
 									},
@@ -491,10 +487,12 @@ func TestANPConnectivity(t *testing.T) {
 							Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NotSameLabels: []string{"ns"},
+											Namespaces: &metav1.LabelSelector{
+												MatchExpressions: []metav1.LabelSelectorRequirement{
+													{Key: "ns", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"x"}},
+												},
 											},
 										},
 									},
@@ -509,13 +507,11 @@ func TestANPConnectivity(t *testing.T) {
 								},
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Pods: &v1alpha1.NamespacedPodPeer{
-												Namespaces: v1alpha1.NamespacedPeer{
-													NamespaceSelector: &metav1.LabelSelector{
-														MatchLabels: map[string]string{"ns": "y"},
-													},
+											Pods: &v1alpha1.NamespacedPod{
+												NamespaceSelector: metav1.LabelSelector{
+													MatchLabels: map[string]string{"ns": "y"},
 												},
 												PodSelector: metav1.LabelSelector{
 													MatchLabels: map[string]string{"pod": "a"},
@@ -554,11 +550,9 @@ func TestANPConnectivity(t *testing.T) {
 							Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
-									To: []v1alpha1.AdminNetworkPolicyPeer{
+									To: []v1alpha1.AdminNetworkPolicyEgressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{},
-											},
+											Namespaces: &metav1.LabelSelector{},
 										},
 									},
 									Ports: &([]v1alpha1.AdminNetworkPolicyPort{
@@ -600,11 +594,9 @@ func TestANPConnectivity(t *testing.T) {
 							Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
-									To: []v1alpha1.AdminNetworkPolicyPeer{
+									To: []v1alpha1.AdminNetworkPolicyEgressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{},
-											},
+											Namespaces: &metav1.LabelSelector{},
 										},
 									},
 									Ports: &([]v1alpha1.AdminNetworkPolicyPort{
@@ -636,11 +628,9 @@ func TestANPConnectivity(t *testing.T) {
 							Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
-									To: []v1alpha1.AdminNetworkPolicyPeer{
+									To: []v1alpha1.AdminNetworkPolicyEgressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{},
-											},
+											Namespaces: &metav1.LabelSelector{},
 										},
 									},
 									Ports: &([]v1alpha1.AdminNetworkPolicyPort{
@@ -682,11 +672,9 @@ func TestANPConnectivity(t *testing.T) {
 							Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
-									To: []v1alpha1.AdminNetworkPolicyPeer{
+									To: []v1alpha1.AdminNetworkPolicyEgressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{},
-											},
+											Namespaces: &metav1.LabelSelector{},
 										},
 									},
 									Ports: &([]v1alpha1.AdminNetworkPolicyPort{
@@ -718,11 +706,9 @@ func TestANPConnectivity(t *testing.T) {
 							Egress: []v1alpha1.AdminNetworkPolicyEgressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
-									To: []v1alpha1.AdminNetworkPolicyPeer{
+									To: []v1alpha1.AdminNetworkPolicyEgressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{},
-											},
+											Namespaces: &metav1.LabelSelector{},
 										},
 									},
 									Ports: &([]v1alpha1.AdminNetworkPolicyPort{
@@ -774,7 +760,7 @@ func TestBANPConnectivity(t *testing.T) {
 				banp: &v1alpha1.BaselineAdminNetworkPolicy{
 					Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
 						Subject: v1alpha1.AdminNetworkPolicySubject{
-							Pods: &v1alpha1.NamespacedPodSubject{
+							Pods: &v1alpha1.NamespacedPod{
 								NamespaceSelector: metav1.LabelSelector{
 									MatchLabels: map[string]string{"ns": "x"},
 								},
@@ -786,12 +772,10 @@ func TestBANPConnectivity(t *testing.T) {
 						Egress: []v1alpha1.BaselineAdminNetworkPolicyEgressRule{
 							{
 								Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
-								To: []v1alpha1.AdminNetworkPolicyPeer{
+								To: []v1alpha1.BaselineAdminNetworkPolicyEgressPeer{
 									{
-										Pods: &v1alpha1.NamespacedPodPeer{
-											Namespaces: v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{},
-											},
+										Pods: &v1alpha1.NamespacedPod{
+											NamespaceSelector: metav1.LabelSelector{},
 											PodSelector: metav1.LabelSelector{
 												MatchLabels: map[string]string{"pod": "b"},
 											},
@@ -823,7 +807,7 @@ func TestBANPConnectivity(t *testing.T) {
 				banp: &v1alpha1.BaselineAdminNetworkPolicy{
 					Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
 						Subject: v1alpha1.AdminNetworkPolicySubject{
-							Pods: &v1alpha1.NamespacedPodSubject{
+							Pods: &v1alpha1.NamespacedPod{
 								NamespaceSelector: metav1.LabelSelector{
 									MatchLabels: map[string]string{"ns": "x"},
 								},
@@ -835,12 +819,10 @@ func TestBANPConnectivity(t *testing.T) {
 						Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{
 							{
 								Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
-								From: []v1alpha1.AdminNetworkPolicyPeer{
+								From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 									{
-										Pods: &v1alpha1.NamespacedPodPeer{
-											Namespaces: v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{},
-											},
+										Pods: &v1alpha1.NamespacedPod{
+											NamespaceSelector: metav1.LabelSelector{},
 											PodSelector: metav1.LabelSelector{
 												MatchLabels: map[string]string{"pod": "b"},
 											},
@@ -872,13 +854,11 @@ func TestBANPConnectivity(t *testing.T) {
 						Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{
 							{
 								Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow,
-								From: []v1alpha1.AdminNetworkPolicyPeer{
+								From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 									{
-										Pods: &v1alpha1.NamespacedPodPeer{
-											Namespaces: v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{
-													MatchLabels: map[string]string{"ns": "y"},
-												},
+										Pods: &v1alpha1.NamespacedPod{
+											NamespaceSelector: metav1.LabelSelector{
+												MatchLabels: map[string]string{"ns": "y"},
 											},
 											PodSelector: metav1.LabelSelector{
 												MatchLabels: map[string]string{"pod": "b"},
@@ -889,11 +869,9 @@ func TestBANPConnectivity(t *testing.T) {
 							},
 							{
 								Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
-								From: []v1alpha1.AdminNetworkPolicyPeer{
+								From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 									{
-										Namespaces: &v1alpha1.NamespacedPeer{
-											NamespaceSelector: &metav1.LabelSelector{},
-										},
+										Namespaces: &metav1.LabelSelector{},
 									},
 								},
 							},
@@ -916,23 +894,19 @@ func TestBANPConnectivity(t *testing.T) {
 						Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{
 							{
 								Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
-								From: []v1alpha1.AdminNetworkPolicyPeer{
+								From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 									{
-										Namespaces: &v1alpha1.NamespacedPeer{
-											NamespaceSelector: &metav1.LabelSelector{},
-										},
+										Namespaces: &metav1.LabelSelector{},
 									},
 								},
 							},
 							{
 								Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
-								From: []v1alpha1.AdminNetworkPolicyPeer{
+								From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 									{
-										Pods: &v1alpha1.NamespacedPodPeer{
-											Namespaces: v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{
-													MatchLabels: map[string]string{"ns": "y"},
-												},
+										Pods: &v1alpha1.NamespacedPod{
+											NamespaceSelector: metav1.LabelSelector{
+												MatchLabels: map[string]string{"ns": "y"},
 											},
 											PodSelector: metav1.LabelSelector{
 												MatchLabels: map[string]string{"pod": "b"},
@@ -993,11 +967,9 @@ func TestANPWithNetPolV1(t *testing.T) {
 							Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{},
-											},
+											Namespaces: &metav1.LabelSelector{},
 										},
 									},
 									Ports: &([]v1alpha1.AdminNetworkPolicyPort{
@@ -1071,12 +1043,10 @@ func TestANPWithNetPolV1(t *testing.T) {
 							Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{
-													MatchLabels: map[string]string{"ns": "x"},
-												},
+											Namespaces: &metav1.LabelSelector{
+												MatchLabels: map[string]string{"ns": "x"},
 											},
 										},
 									},
@@ -1158,11 +1128,9 @@ func TestBANPWithNetPolV1(t *testing.T) {
 						Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{
 							{
 								Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
-								From: []v1alpha1.AdminNetworkPolicyPeer{
+								From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 									{
-										Namespaces: &v1alpha1.NamespacedPeer{
-											NamespaceSelector: &metav1.LabelSelector{},
-										},
+										Namespaces: &metav1.LabelSelector{},
 									},
 								},
 							},
@@ -1192,7 +1160,7 @@ func TestANPWithBANP(t *testing.T) {
 						Spec: v1alpha1.AdminNetworkPolicySpec{
 							Priority: 100,
 							Subject: v1alpha1.AdminNetworkPolicySubject{
-								Pods: &v1alpha1.NamespacedPodSubject{
+								Pods: &v1alpha1.NamespacedPod{
 									NamespaceSelector: metav1.LabelSelector{
 										MatchLabels: map[string]string{"ns": "x"},
 									},
@@ -1204,10 +1172,10 @@ func TestANPWithBANP(t *testing.T) {
 							Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												SameLabels: []string{"ns"},
+											Namespaces: &metav1.LabelSelector{
+												MatchLabels: map[string]string{"ns": "x"},
 											},
 										},
 									},
@@ -1232,11 +1200,9 @@ func TestANPWithBANP(t *testing.T) {
 						Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{
 							{
 								Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
-								From: []v1alpha1.AdminNetworkPolicyPeer{
+								From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 									{
-										Namespaces: &v1alpha1.NamespacedPeer{
-											NamespaceSelector: &metav1.LabelSelector{},
-										},
+										Namespaces: &metav1.LabelSelector{},
 									},
 								},
 							},
@@ -1259,7 +1225,7 @@ func TestANPWithBANP(t *testing.T) {
 						Spec: v1alpha1.AdminNetworkPolicySpec{
 							Priority: 100,
 							Subject: v1alpha1.AdminNetworkPolicySubject{
-								Pods: &v1alpha1.NamespacedPodSubject{
+								Pods: &v1alpha1.NamespacedPod{
 									NamespaceSelector: metav1.LabelSelector{
 										MatchLabels: map[string]string{"ns": "x"},
 									},
@@ -1271,10 +1237,10 @@ func TestANPWithBANP(t *testing.T) {
 							Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionPass,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												SameLabels: []string{"ns"},
+											Namespaces: &metav1.LabelSelector{
+												MatchLabels: map[string]string{"ns": "x"},
 											},
 										},
 									},
@@ -1299,11 +1265,9 @@ func TestANPWithBANP(t *testing.T) {
 							Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{
 								{
 									Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
-									From: []v1alpha1.AdminNetworkPolicyPeer{
+									From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 										{
-											Namespaces: &v1alpha1.NamespacedPeer{
-												NamespaceSelector: &metav1.LabelSelector{},
-											},
+											Namespaces: &metav1.LabelSelector{},
 										},
 									},
 								},
@@ -1319,11 +1283,9 @@ func TestANPWithBANP(t *testing.T) {
 						Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{
 							{
 								Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
-								From: []v1alpha1.AdminNetworkPolicyPeer{
+								From: []v1alpha1.AdminNetworkPolicyIngressPeer{
 									{
-										Namespaces: &v1alpha1.NamespacedPeer{
-											NamespaceSelector: &metav1.LabelSelector{},
-										},
+										Namespaces: &metav1.LabelSelector{},
 									},
 								},
 							},
@@ -1363,7 +1325,8 @@ func runConnectivityTests(t *testing.T, tests ...connectivityTest) {
 				}
 			}
 
-			parsedPolicy := matcher.BuildV1AndV2NetPols(false, tt.args.netpols, tt.args.anps, tt.args.banp)
+			parsedPolicy, policyErrors := matcher.BuildV1AndV2NetPols(false, tt.args.netpols, tt.args.anps, tt.args.banp)
+			require.Empty(t, policyErrors)
 			jobBuilder := &probe.JobBuilder{TimeoutSeconds: 3}
 			simRunner := probe.NewSimulatedRunner(parsedPolicy, jobBuilder)
 			simTable := simRunner.RunProbeForConfig(generator.ProbeAllAvailable, tt.args.resources)
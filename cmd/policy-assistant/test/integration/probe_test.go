@@ -14,7 +14,8 @@ func TestProbe(t *testing.T) {
 		npv1, anp, banp, err := kube.ReadNetworkPoliciesFromPath("../../examples/demos/kubecon-eu-2024/policies/")
 		require.Nil(t, err)
 
-		policies := matcher.BuildV1AndV2NetPols(false, npv1, anp, banp)
+		policies, policyErrors := matcher.BuildV1AndV2NetPols(false, npv1, anp, banp)
+		require.Empty(t, policyErrors)
 
 		cli.ProbeSyntheticConnectivity(policies, "../../examples/demos/kubecon-eu-2024/demo-probe.json", nil, nil)
 